@@ -53,6 +53,7 @@ type benchConfig struct {
 	sizeMean     float64
 	sizeSD       float64
 	sizeEdgeSD   float64
+	sizeCurve    string
 	jobs         int
 	threads      int
 	buildWorkers int
@@ -140,7 +141,7 @@ func run(args []string, stdout, stderr io.Writer) error {
 		return err
 	}
 
-	selector, err := sizeselect.New(sizeselect.Config{
+	sizeConfig, err := sizeselect.Config{
 		Model:    sizeselect.Model(cfg.sizeModel),
 		Min:      cfg.minLen,
 		Max:      cfg.maxLen,
@@ -149,7 +150,11 @@ func run(args []string, stdout, stderr io.Writer) error {
 		Mean:     cfg.sizeMean,
 		SD:       cfg.sizeSD,
 		EdgeSD:   cfg.sizeEdgeSD,
-	})
+	}.WithCurve(cfg.sizeCurve)
+	if err != nil {
+		return usageError{err: err}
+	}
+	selector, err := sizeselect.New(sizeConfig)
 	if err != nil {
 		return err
 	}
@@ -211,10 +216,11 @@ func parseArgs(args []string, stderr io.Writer) (benchConfig, error) {
 	fs.IntVar(&cfg.maxLen, "max", 600, "maximum fragment length (bp) for hard size selection")
 	fs.IntVar(&cfg.scoreMin, "score-min", 1, "minimum fragment length included in size-selection scoring")
 	fs.IntVar(&cfg.scoreMax, "score-max", 2000, "maximum fragment length included in size-selection scoring")
-	fs.StringVar(&cfg.sizeModel, "size-model", "normal", "size-selection model: hard, normal, triangular, or soft-window")
+	fs.StringVar(&cfg.sizeModel, "size-model", "normal", "size-selection model: hard, normal, triangular, soft-window, or empirical")
 	fs.Float64Var(&cfg.sizeMean, "size-mean", 275, "target/peak insert length for normal/triangular models")
 	fs.Float64Var(&cfg.sizeSD, "size-sd", 85, "standard deviation for --size-model normal")
 	fs.Float64Var(&cfg.sizeEdgeSD, "size-edge-sd", 25, "edge softness for --size-model soft-window")
+	fs.StringVar(&cfg.sizeCurve, "size-curve", "", "length-to-weight table (TSV or JSON) for --size-model empirical")
 	fs.IntVar(&cfg.jobs, "jobs", 0, "parallel pair-scoring workers (default: --threads)")
 	fs.IntVar(&cfg.threads, "threads", runtime.NumCPU(), "worker count alias used when --jobs is not set")
 	fs.IntVar(&cfg.buildWorkers, "build-workers", 0, "parallel cut-index build workers (default: --jobs, then --threads); scans candidate enzymes concurrently per FASTA record")
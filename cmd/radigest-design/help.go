@@ -28,7 +28,7 @@ func writeDesignUsage(w io.Writer, version string, weights design.ScoreWeights)
 			Items: []clihelp.Flag{
 				{Names: []string{"--ref", "--fasta"}, Arg: "PATH", Text: "Reference FASTA. Plain or .gz."},
 				{Names: []string{"--enzymes"}, Arg: "LIST|FILE|all", Text: "Candidate enzymes as comma-separated names, a one-per-line file, or 'all'."},
-				{Names: []string{"--pct", "--target-genome-pct"}, Arg: "FLOAT", Text: "Target weighted genome percentage, for example 2.5."},
+				{Names: []string{"--pct", "--target-genome-pct"}, Arg: "FLOAT", Text: "Target weighted genome percentage, for example 2.5. Use --target-snps instead to target SNP yield."},
 				{Names: []string{"--depth", "--target-depth", "--desired-depth"}, Arg: "FLOAT", Text: "Target mean read-pair depth per recovered locus."},
 				{Names: []string{"--samples"}, Arg: "INT", Text: "Planned number of samples."},
 				{Names: []string{"--read-length"}, Arg: "INT", Text: "Sequencing read length in bp."},
@@ -36,6 +36,16 @@ func writeDesignUsage(w io.Writer, version string, weights design.ScoreWeights)
 				{Names: []string{"--lane-read-pairs"}, Arg: "COUNT", Text: "Read pairs per lane, for example 300M. Mutually exclusive with --flowcell-read-pairs."},
 			},
 		},
+		{
+			Title: "SNP yield",
+			Intro: []string{"Predicts SNPs in the bases read from recovered loci: --read-length from the first enzyme's end, or from both ends for pe."},
+			Items: []clihelp.Flag{
+				{Names: []string{"--theta"}, Arg: "FLOAT", Text: "Per-base nucleotide diversity. Predicted SNPs are theta x Watterson's a_n for 2 x --samples chromosomes x weighted sequenced bases."},
+				{Names: []string{"--snp-vcf"}, Arg: "PATH", Text: "VCF of known variants (plain or gzip). Counts SNP records inside sequenced bases, weighted by size selection. Mutually exclusive with --theta."},
+				{Names: []string{"--target-snps"}, Arg: "FLOAT", Text: "Target SNP count. Replaces --target-genome-pct for feasibility, fit loss, and ranking."},
+				{Names: []string{"--snp-tolerance-pct"}, Arg: "FLOAT", Default: "10", Text: "Relative tolerance around --target-snps, in percent."},
+			},
+		},
 		{
 			Title: "Size selection and recovery model",
 			Items: []clihelp.Flag{
@@ -43,10 +53,25 @@ func writeDesignUsage(w io.Writer, version string, weights design.ScoreWeights)
 				{Names: []string{"--max"}, Arg: "INT", Default: "600", Text: "Hard upper insert-size bound in bp."},
 				{Names: []string{"--score-min"}, Arg: "INT", Default: "1", Text: "Lower insert-size bound included in recovery-weight scoring."},
 				{Names: []string{"--score-max"}, Arg: "INT", Default: "2000", Text: "Upper insert-size bound included in recovery-weight scoring."},
-				{Names: []string{"--size-model"}, Arg: "MODEL", Default: "normal", Text: "Size-selection/recovery weighting model: hard, normal, triangular, or soft-window."},
-				{Names: []string{"--size-mean"}, Arg: "FLOAT", Default: "275", Text: "Peak/target insert length for normal and triangular models."},
-				{Names: []string{"--size-sd"}, Arg: "FLOAT", Default: "85", Text: "Standard deviation for the normal model."},
+				{Names: []string{"--size-model"}, Arg: "MODEL", Default: "normal", Text: "Size-selection/recovery weighting model: hard, normal, triangular, soft-window, log-normal, skew-normal, gamma, mixture, spri, or empirical."},
+				{Names: []string{"--size-mean"}, Arg: "FLOAT", Default: "275", Text: "Peak/target insert length for normal and triangular models, mean insert length for log-normal and gamma, location for skew-normal, and the first component's mean for mixture."},
+				{Names: []string{"--size-sd"}, Arg: "FLOAT", Default: "85", Text: "Standard deviation for the normal, log-normal, gamma, and mixture (first component) models; scale for skew-normal. Gamma needs --size-sd below --size-mean."},
 				{Names: []string{"--size-edge-sd"}, Arg: "FLOAT", Default: "25", Text: "Edge softness for the soft-window model."},
+				{Names: []string{"--size-skew"}, Arg: "FLOAT", Default: "0", Text: "Skew-normal shape (alpha). Positive values lean right toward long inserts; 0 is the normal model."},
+				{Names: []string{"--size-mean2"}, Arg: "FLOAT", Text: "Mean of the second mixture component, such as a short-insert shoulder."},
+				{Names: []string{"--size-sd2"}, Arg: "FLOAT", Text: "Standard deviation of the second mixture component."},
+				{Names: []string{"--size-mix"}, Arg: "FLOAT", Text: "Share of the mixture's mass in the second component, in (0,1)."},
+				{Names: []string{"--size-curve"}, Arg: "PATH", Text: "Length-to-weight table for the empirical model, such as a Pippin or gel calibration: two columns (length, weight) or JSON [{\"length\":..,\"weight\":..}]. Weights are interpolated linearly, scaled so the peak is 1, and 0 outside the curve. The curve is recorded verbatim in JSON."},
+				{Names: []string{"--spri-left"}, Arg: "FLOAT", Text: "Left-side bead ratio for the spri model, such as 0.75. The larger ratio; fragments above its cutoff are kept."},
+				{Names: []string{"--spri-right"}, Arg: "FLOAT", Text: "Right-side bead ratio for the spri model, such as 0.55. Added first; fragments above its cutoff are bound and discarded."},
+				{Names: []string{"--spri-calibration"}, Arg: "PATH", Default: "built-in", Text: "Bead ratio to cutoff (bp) table for the spri model: two columns (ratio, cutoff) or JSON. Cutoffs are interpolated in log length; the built-in table approximates SPRIselect/AMPure XP guides."},
+				{Names: []string{"--size-config"}, Arg: "PATH", Text: "Size-model JSON written by radigest-fit-size-model -config. Replaces --size-model and its parameters; hard, triangular, and soft-window models must be used with the --min/--max they were fitted for."},
+				{Names: []string{"--adapter-p1-length"}, Arg: "INT", Default: "0", Text: "P1 adapter bases ligated to each insert before size selection. With any adapter or barcode length, --min/--max, the score range, and the size model apply to library length (insert + adapters + barcodes), while reported lengths stay insert lengths."},
+				{Names: []string{"--adapter-p2-length"}, Arg: "INT", Default: "0", Text: "P2 adapter bases ligated to the other end."},
+				{Names: []string{"--barcode-lengths"}, Arg: "LIST", Text: "Comma-separated combined inline-barcode lengths in the pool, such as 4,6,8. Weights average over them, but a fragment is kept, and counted in raw_fragments_in_window, if any length passes: under the hard model a fragment kept for one of four lengths counts once there with weight 0.25."},
+				{Names: []string{"--search-min"}, Arg: "GRID", Text: "Search --min over comma-separated values and LO:HI:STEP ranges, and keep the window the objective ranks best for each pair. The score range stays fixed."},
+				{Names: []string{"--search-max"}, Arg: "GRID", Text: "Search --max the same way; windows with max < min are skipped."},
+				{Names: []string{"--search-mean"}, Arg: "GRID", Text: "Search --size-mean the same way, for size models with a mean."},
 			},
 		},
 		{
@@ -55,6 +80,24 @@ func writeDesignUsage(w io.Writer, version string, weights design.ScoreWeights)
 				{Names: []string{"--lanes"}, Arg: "INT", Default: "1", Text: "Number of lanes. Only valid with --lane-read-pairs."},
 				{Names: []string{"--usable-read-fraction"}, Arg: "FLOAT", Default: "1", Text: "Fraction of read pairs usable after demultiplexing/QC/deduplication."},
 				{Names: []string{"--read-layout"}, Arg: "pe|se", Default: "pe", Text: "Read layout used for insert diagnostics."},
+				{Names: []string{"--depth-denominator"}, Arg: "MODE", Default: "weighted-fragments", Text: "Locus count used for depth: weighted-fragments, or effective-unique-loci to count each duplicate cluster once. The latter requires --duplicates."},
+				{Names: []string{"--min-call-depth"}, Arg: "INT", Default: "0 (off)", Text: "Read pairs per sample needed to call a locus. Reports callable_fraction, the share of depth loci expected to reach it, and callable_loci."},
+				{Names: []string{"--depth-dispersion"}, Arg: "FLOAT", Default: "0", Text: "Negative binomial overdispersion phi of per-locus depth, with variance mean + phi*mean^2. 0 is Poisson."},
+				{Names: []string{"--depth-weights"}, Arg: "MODE", Default: "uniform", Text: "Relative read share of each locus: uniform, size (size weight), pcr (PCR amplification weight; requires --pcr-cycles), or size-pcr. Shares are scaled so the mean locus depth is unchanged."},
+				{Names: []string{"--sample-cv"}, Arg: "FLOAT", Default: "0", Text: "Coefficient of variation of read pairs per sample from unequal pooling, modeled as log-normal."},
+				{Names: []string{"--present-pct"}, Arg: "PCT", Default: "0 (off)", Text: "Report present_fraction and present_loci: depth loci expected to reach --min-call-depth in at least this percentage of --samples."},
+				{Names: []string{"--min-present-loci"}, Arg: "FLOAT", Default: "0", Text: "Mark pairs with fewer present loci infeasible."},
+				{Names: []string{"--min-callable-loci"}, Arg: "FLOAT", Default: "0", Text: "Mark pairs with fewer callable loci infeasible. Requires --min-call-depth."},
+			},
+		},
+		{
+			Title: "Cost",
+			Intro: []string{"Any price adds total_cost, cost_per_sample, and (with --min-call-depth) cost_per_callable_locus for the budget, and optimal_lanes, optimal_samples_per_lane, and optimal_cost: the fewest lanes, each holding optimal_samples_per_lane of the --samples samples and read only by them, at which a sample on the fullest lane meets --depth, --min-present-loci, and --min-callable-loci."},
+			Items: []clihelp.Flag{
+				{Names: []string{"--cost-per-lane"}, Arg: "FLOAT", Default: "0", Text: "Sequencing cost per lane, or per flowcell with --flowcell-read-pairs."},
+				{Names: []string{"--cost-per-sample"}, Arg: "FLOAT", Default: "0", Text: "Library prep cost per sample."},
+				{Names: []string{"--cost-per-enzyme-unit"}, Arg: "FLOAT", Default: "0", Text: "Cost per enzyme unit."},
+				{Names: []string{"--enzyme-units-per-sample"}, Arg: "FLOAT", Default: "10", Text: "Units of each enzyme used per sample digest."},
 			},
 		},
 		{
@@ -70,19 +113,39 @@ func writeDesignUsage(w io.Writer, version string, weights design.ScoreWeights)
 				{Names: []string{"--allow-same"}, Text: "In double-digest scoring, also keep AA/BB adjacent fragments."},
 				{Names: []string{"--include-ends"}, Text: "Include terminal fragments from contig ends to nearest cut."},
 				{Names: []string{"--strict-cuts"}, Text: "Error if an enzyme lacks an explicit cut coordinate."},
+				{Names: []string{"--duplicates"}, Arg: "off|exact|kmer", Default: "off", Text: "Cluster fragments whose read-length end sequences are identical (exact) or have an estimated k-mer Jaccard similarity of at least 0.5 at both ends (kmer) and report effective unique loci."},
+				{Names: []string{"--duplicate-k"}, Arg: "INT", Default: "21", Text: "k-mer length for --duplicates kmer, at most 32 and --read-length."},
+				{Names: []string{"--mappability"}, Text: "Index every --read-length reference window and report mappable loci: weighted fragments whose two end windows occur nowhere else, on either strand."},
+				{Names: []string{"--pcr-cycles"}, Arg: "INT", Default: "0 (off)", Text: "Library PCR cycles. Weights each scored fragment by its amplification ((1+e)/(1+efficiency))^cycles, where per-cycle efficiency e falls with length and GC distance from the optimum, and reports amplified_fragments and amplified_bases."},
+				{Names: []string{"--pcr-efficiency"}, Arg: "FLOAT", Default: "0.95", Text: "Per-cycle efficiency of a short fragment at the GC optimum, in (0,1]."},
+				{Names: []string{"--pcr-length-scale"}, Arg: "BP", Default: "5000", Text: "Length over which per-cycle efficiency falls by 1/e."},
+				{Names: []string{"--pcr-gc-optimum"}, Arg: "FLOAT", Default: "0.5", Text: "GC fraction with the highest efficiency."},
+				{Names: []string{"--pcr-gc-width"}, Arg: "FLOAT", Default: "0.25", Text: "Gaussian width, in GC fraction, of the efficiency falloff around the optimum."},
 			},
 		},
 		{
 			Title: "Ranking and scoring",
 			Items: []clihelp.Flag{
 				{Names: []string{"--coverage-tolerance-pct"}, Arg: "FLOAT", Default: "0.25", Text: "Absolute tolerance around --target-genome-pct."},
-				{Names: []string{"--objective"}, Arg: "OBJECTIVE", Default: "balanced", Text: "Ranking objective: balanced, closest-coverage, depth-first, feasible-lowest-coverage, or max-depth."},
+				{Names: []string{"--objective"}, Arg: "OBJECTIVE", Default: "balanced", Text: "Ranking objective: balanced, closest-coverage, depth-first, feasible-lowest-coverage, max-depth, callable-loci (most callable loci; requires --min-call-depth), or min-cost (cheapest optimal lanes among pairs meeting the targets; requires a cost)."},
 				{Names: []string{"--weight-coverage"}, Arg: "FLOAT", Default: formatHelpFloat(weights.Coverage), Text: "Fit-loss weight for coverage error."},
 				{Names: []string{"--weight-depth"}, Arg: "FLOAT", Default: formatHelpFloat(weights.Depth), Text: "Fit-loss weight for depth shortfall."},
 				{Names: []string{"--weight-overcoverage"}, Arg: "FLOAT", Default: formatHelpFloat(weights.Overcoverage), Text: "Additional fit-loss weight for overcoverage."},
 				{Names: []string{"--weight-insert"}, Arg: "FLOAT", Default: formatHelpFloat(weights.Insert), Text: "Fit-loss weight for insert-size risk."},
 			},
 		},
+		{
+			Title: "Sensitivity sweep",
+			Intro: []string{"Any sweep re-evaluates the top --sweep-top pairs, each in its chosen window, at every combination of swept values, ranks them at each point, and writes one row per pair and point to the sweep TSV. JSON records each pair's rank stability and the range of swept values over which it stays feasible. A size setting that is not a valid model in a pair's window is skipped for that pair, which then counts as infeasible and ranks last at that point."},
+			Items: []clihelp.Flag{
+				{Names: []string{"--sweep-size-mean"}, Arg: "GRID", Text: "Sweep --size-mean over comma-separated values and LO:HI:STEP ranges, for size models with a mean."},
+				{Names: []string{"--sweep-size-sd"}, Arg: "GRID", Text: "Sweep --size-sd the same way, for size models with an SD."},
+				{Names: []string{"--sweep-usable-fraction"}, Arg: "GRID", Text: "Sweep --usable-read-fraction the same way; values in (0,1]."},
+				{Names: []string{"--sweep-samples"}, Arg: "GRID", Text: "Sweep --samples the same way."},
+				{Names: []string{"--sweep-top"}, Arg: "INT", Default: "10", Text: "Pairs re-evaluated at each point, from the top of the ranking."},
+				{Names: []string{"--sweep-tsv"}, Arg: "PATH", Default: "<out-dir>/design.sweep.tsv", Text: "Tidy sweep table for heatmaps."},
+			},
+		},
 		{
 			Title: "Outputs",
 			Items: []clihelp.Flag{
@@ -125,7 +188,8 @@ func writeDesignUsage(w io.Writer, version string, weights design.ScoreWeights)
 	_, _ = fmt.Fprintln(w, "Notes:")
 	_, _ = fmt.Fprintln(w, "  Genome percentage means weighted recovered genome percentage under the specified size-selection/recovery model.")
 	_, _ = fmt.Fprintln(w, "  Depth means mean read-pair depth per recovered locus, not basewise WGS depth.")
-	_, _ = fmt.Fprintln(w, "  The model is sequence-level only; it does not model methylation sensitivity, partial digestion, enzyme efficiency, buffer compatibility, or per-locus depth dispersion.")
+	_, _ = fmt.Fprintln(w, "  pareto_rank 1 marks pairs no other pair beats on coverage error, depth margin, insert penalty, and optimal_cost or mappable_loci when reported.")
+	_, _ = fmt.Fprintln(w, "  The model is sequence-level only; it does not model methylation sensitivity, partial digestion, enzyme efficiency, or buffer compatibility.")
 }
 
 func formatHelpFloat(v float64) string {
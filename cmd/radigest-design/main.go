@@ -19,8 +19,11 @@ import (
 	"github.com/ericksamera/radigest/internal/design"
 	"github.com/ericksamera/radigest/internal/digest"
 	"github.com/ericksamera/radigest/internal/enzyme"
+	"github.com/ericksamera/radigest/internal/paralog"
+	"github.com/ericksamera/radigest/internal/pcrbias"
 	"github.com/ericksamera/radigest/internal/screen"
 	"github.com/ericksamera/radigest/internal/sizeselect"
+	"github.com/ericksamera/radigest/internal/vcf"
 )
 
 var version = "dev"
@@ -48,9 +51,38 @@ type cliConfig struct {
 	sizeMean             float64
 	sizeSD               float64
 	sizeEdgeSD           float64
+	sizeSkew             float64
+	sizeMean2            float64
+	sizeSD2              float64
+	sizeMix              float64
+	sizeCurvePath        string
+	sizeConfigPath       string
+	spriLeft             float64
+	spriRight            float64
+	spriCalibrationPath  string
+	adapterP1Len         int
+	adapterP2Len         int
+	barcodeLengths       []int
 	allowSame            bool
 	includeEnds          bool
 	strictCuts           bool
+	duplicates           string
+	duplicateK           int
+	mappability          bool
+	pcr                  pcrbias.Model
+	depthDenominator     string
+	minCallDepth         int
+	depthDispersion      float64
+	depthWeights         string
+	sampleCV             float64
+	presentPct           float64
+	minPresentLoci       float64
+	minCallableLoci      float64
+	cost                 design.CostSchedule
+	windowGrid           design.WindowGrid
+	sweepGrid            design.SweepGrid
+	sweepTop             int
+	sweepTSVPath         string
 	readLayout           string
 	readLength           int
 	laneReadPairs        float64
@@ -61,6 +93,10 @@ type cliConfig struct {
 	desiredDepth         float64
 	targetGenomePct      float64
 	coverageTolerancePct float64
+	targetSNPs           float64
+	snpTolerancePct      float64
+	theta                float64
+	snpVCFPath           string
 	objective            string
 	weightCoverage       float64
 	weightDepth          float64
@@ -85,9 +121,35 @@ type digestParameters struct {
 	SizeMean    float64 `json:"size_mean,omitempty"`
 	SizeSD      float64 `json:"size_sd,omitempty"`
 	SizeEdgeSD  float64 `json:"size_edge_sd,omitempty"`
+	SizeSkew    float64 `json:"size_skew,omitempty"`
+	SizeMean2   float64 `json:"size_mean2,omitempty"`
+	SizeSD2     float64 `json:"size_sd2,omitempty"`
+	SizeMix     float64 `json:"size_mix,omitempty"`
 	AllowSame   bool    `json:"allow_same"`
 	IncludeEnds bool    `json:"include_ends"`
 	StrictCuts  bool    `json:"strict_cuts"`
+	Duplicates  string  `json:"duplicates"`
+	DuplicateK  int     `json:"duplicate_k,omitempty"`
+	Mappability bool    `json:"mappability"`
+	// PCR is the amplification model, when --pcr-cycles is set.
+	PCR *pcrbias.Model `json:"pcr,omitempty"`
+	// WindowGrid lists the searched size settings; each result records
+	// the window chosen for its pair.
+	WindowGrid *design.WindowGrid `json:"window_grid,omitempty"`
+	// SizeCurve records the --size-model empirical curve verbatim.
+	SizeCurve     []sizeselect.CurvePoint `json:"size_curve,omitempty"`
+	SizeCurvePath string                  `json:"size_curve_path,omitempty"`
+	// SizeConfigPath is the --size-config file, if any.
+	SizeConfigPath string `json:"size_config_path,omitempty"`
+	// SPRI bead ratios and calibration file of --size-model spri.
+	SPRILeft            float64 `json:"spri_left,omitempty"`
+	SPRIRight           float64 `json:"spri_right,omitempty"`
+	SPRICalibrationPath string  `json:"spri_calibration_path,omitempty"`
+	// AdapterP1Length, AdapterP2Length, and BarcodeLengths are set when
+	// size selection runs on library length.
+	AdapterP1Length int   `json:"adapter_p1_length,omitempty"`
+	AdapterP2Length int   `json:"adapter_p2_length,omitempty"`
+	BarcodeLengths  []int `json:"barcode_lengths,omitempty"`
 }
 
 type inputSummary struct {
@@ -105,11 +167,35 @@ type runSummary struct {
 	BestPair         []string `json:"best_pair,omitempty"`
 }
 
+// paretoSummary lists the first Pareto front in ranking order. Values
+// follow Criteria.
+type paretoSummary struct {
+	Criteria []string      `json:"criteria"`
+	Fronts   int           `json:"fronts"`
+	Front    []paretoPoint `json:"front"`
+}
+
+type paretoPoint struct {
+	Enzymes []string  `json:"enzymes"`
+	Rank    int       `json:"rank"`
+	Values  []float64 `json:"values"`
+}
+
 type outputSummary struct {
 	TSV        string `json:"tsv"`
 	SummaryTSV string `json:"summary_tsv"`
 	JSON       string `json:"json"`
 	Report     string `json:"report"`
+	SweepTSV   string `json:"sweep_tsv,omitempty"`
+}
+
+// sweepSummary describes a sensitivity sweep over the top pairs; the
+// per-point rows are in the sweep TSV.
+type sweepSummary struct {
+	Grid      design.SweepGrid        `json:"grid"`
+	Pairs     int                     `json:"pairs"`
+	Points    int                     `json:"points"`
+	Stability []design.SweepStability `json:"stability"`
 }
 
 type designReport struct {
@@ -125,6 +211,8 @@ type designReport struct {
 	Outputs         outputSummary           `json:"outputs"`
 	Warnings        []string                `json:"warnings"`
 	Summary         runSummary              `json:"summary"`
+	Pareto          paretoSummary           `json:"pareto"`
+	Sweep           *sweepSummary           `json:"sweep,omitempty"`
 	Results         []design.Candidate      `json:"results"`
 }
 
@@ -164,19 +252,95 @@ func run(args []string, stdout, stderr io.Writer) error {
 	if err != nil {
 		return usageError{err: err}
 	}
-	selector, err := sizeselect.New(sizeselect.Config{
-		Model:    sizeselect.Model(cfg.sizeModel),
-		Min:      cfg.minLen,
-		Max:      cfg.maxLen,
-		ScoreMin: cfg.scoreMin,
-		ScoreMax: cfg.scoreMax,
-		Mean:     cfg.sizeMean,
-		SD:       cfg.sizeSD,
-		EdgeSD:   cfg.sizeEdgeSD,
-	})
+	depthDenominator, err := design.ValidateDepthDenominator(cfg.depthDenominator)
+	if err != nil {
+		return usageError{err: err}
+	}
+	endHasher := paralog.Hasher{Mode: paralog.Mode(cfg.duplicates), ReadLength: cfg.readLength, K: cfg.duplicateK}
+	if err := endHasher.Validate(); err != nil {
+		return usageError{err: err}
+	}
+	if depthDenominator == design.DepthEffectiveUniqueLoci && !endHasher.Enabled() {
+		return usageError{err: errors.New("--depth-denominator effective-unique-loci requires --duplicates exact or kmer")}
+	}
+	depthWeights, err := screen.ParseDepthWeighting(cfg.depthWeights)
+	if err != nil {
+		return usageError{err: fmt.Errorf("--depth-weights: %w", err)}
+	}
+	if cfg.minCallDepth == 0 && (cfg.depthDispersion > 0 || depthWeights != screen.DepthWeightsUniform || cfg.sampleCV > 0 || cfg.presentPct > 0) {
+		return usageError{err: errors.New("--depth-dispersion, --depth-weights, --sample-cv, and --present-pct require --min-call-depth")}
+	}
+	if cfg.minPresentLoci > 0 && cfg.presentPct == 0 {
+		return usageError{err: errors.New("--min-present-loci requires --present-pct")}
+	}
+	if cfg.minCallableLoci > 0 && cfg.minCallDepth == 0 {
+		return usageError{err: errors.New("--min-callable-loci requires --min-call-depth")}
+	}
+	costEnabled := cfg.cost.PerLane > 0 || cfg.cost.PerSample > 0 || cfg.cost.PerEnzymeUnit > 0
+	if objective == design.ObjectiveMinCost && !costEnabled {
+		return usageError{err: errors.New("--objective min-cost requires --cost-per-lane, --cost-per-sample, or --cost-per-enzyme-unit")}
+	}
+	if objective == design.ObjectiveCallableLoci && cfg.minCallDepth == 0 {
+		return usageError{err: errors.New("--objective callable-loci requires --min-call-depth")}
+	}
+	if depthWeights.UsesPCR() && !cfg.pcr.Enabled() {
+		return usageError{err: fmt.Errorf("--depth-weights %s requires --pcr-cycles", depthWeights)}
+	}
+	sizeConfig, err := sizeselect.Config{
+		Model:          sizeselect.Model(cfg.sizeModel),
+		Min:            cfg.minLen,
+		Max:            cfg.maxLen,
+		ScoreMin:       cfg.scoreMin,
+		ScoreMax:       cfg.scoreMax,
+		Mean:           cfg.sizeMean,
+		SD:             cfg.sizeSD,
+		EdgeSD:         cfg.sizeEdgeSD,
+		Skew:           cfg.sizeSkew,
+		Mean2:          cfg.sizeMean2,
+		SD2:            cfg.sizeSD2,
+		Mix:            cfg.sizeMix,
+		SPRILeft:       cfg.spriLeft,
+		SPRIRight:      cfg.spriRight,
+		AdapterP1:      cfg.adapterP1Len,
+		AdapterP2:      cfg.adapterP2Len,
+		BarcodeLengths: cfg.barcodeLengths,
+	}.WithCurve(cfg.sizeCurvePath)
+	if err == nil {
+		sizeConfig, err = sizeConfig.WithSPRICalibration(cfg.spriCalibrationPath)
+	}
+	if err == nil {
+		sizeConfig, err = sizeConfig.WithModelFile(cfg.sizeConfigPath)
+	}
+	if err != nil {
+		return usageError{err: err}
+	}
+	selector, err := sizeselect.New(sizeConfig)
 	if err != nil {
 		return err
 	}
+	selectors := []sizeselect.Selector{selector}
+	if cfg.windowGrid.Enabled() {
+		if len(cfg.windowGrid.Mean) > 0 && !modelHasMean(selector.Config().Model) {
+			return usageError{err: fmt.Errorf("--search-mean requires a size model with a mean (got %s)", selector.Config().Model)}
+		}
+		selectors = selectors[:0]
+		for _, c := range cfg.windowGrid.Configs(sizeConfig) {
+			s, err := sizeselect.New(c)
+			if err != nil {
+				return usageError{err: fmt.Errorf("--search-min/--search-max/--search-mean: window %d-%d bp (mean %g): %w", c.Min, c.Max, c.Mean, err)}
+			}
+			selectors = append(selectors, s)
+		}
+		if len(selectors) == 0 {
+			return usageError{err: errors.New("--search-min/--search-max/--search-mean: the grid has no window with max >= min")}
+		}
+	}
+	if len(cfg.sweepGrid.SizeMean) > 0 && !modelHasMean(selector.Config().Model) {
+		return usageError{err: fmt.Errorf("--sweep-size-mean requires a size model with a mean (got %s)", selector.Config().Model)}
+	}
+	if len(cfg.sweepGrid.SizeSD) > 0 && !modelHasSD(selector.Config().Model) {
+		return usageError{err: fmt.Errorf("--sweep-size-sd requires a size model with an SD (got %s)", selector.Config().Model)}
+	}
 
 	enzymeNames, err := readEnzymeNames(cfg.enzFlag)
 	if err != nil {
@@ -214,9 +378,19 @@ func run(args []string, stdout, stderr io.Writer) error {
 	if err := writeDesignSizeSelectionSummary(stderr, selector.Config()); err != nil {
 		return err
 	}
+	if cfg.windowGrid.Enabled() {
+		if _, err := fmt.Fprintf(stderr, "size_windows_searched\t%d\n", len(selectors)); err != nil {
+			return err
+		}
+	}
 
 	buildWorkers := resolveBuildWorkers(cfg.buildWorkers, cfg.jobs, cfg.threads, len(enzymes))
-	idx, err := screen.BuildCutIndexFromFASTAParallel(cfg.fastaPath, enzymes, digest.Options{StrictCuts: cfg.strictCuts}, buildWorkers)
+	build := screen.BuildOptions{Workers: buildWorkers, Ends: endHasher}
+	if cfg.mappability {
+		build.MappabilityReadLength = cfg.readLength
+	}
+	build.GC = cfg.pcr.Enabled()
+	idx, err := screen.BuildCutIndexFromFASTAWithOptions(cfg.fastaPath, enzymes, digest.Options{StrictCuts: cfg.strictCuts}, build)
 	if err != nil {
 		return err
 	}
@@ -238,10 +412,22 @@ func run(args []string, stdout, stderr io.Writer) error {
 		return err
 	}
 
-	opt := digest.Options{AllowSame: cfg.allowSame, IncludeEnds: cfg.includeEnds, StrictCuts: cfg.strictCuts}
-	summaries, err := scorePairs(idx, pairs, selector, opt, workers)
-	if err != nil {
-		return err
+	var snpModel *design.SNPModel
+	var score screen.ScoreOptions
+	if cfg.theta > 0 || cfg.snpVCFPath != "" {
+		snpModel = &design.SNPModel{Theta: cfg.theta, KnownVCF: cfg.snpVCFPath}
+		score = screen.ScoreOptions{ReadLength: cfg.readLength, Paired: cfg.readLayout == "pe"}
+	}
+	if cfg.snpVCFPath != "" {
+		known, err := vcf.Read(cfg.snpVCFPath)
+		if err != nil {
+			return usageError{err: fmt.Errorf("--snp-vcf: %w", err)}
+		}
+		score.KnownSNPs = known.SNPPositions()
+	}
+	score.Amplification = cfg.pcr
+	if cfg.minCallDepth > 0 && depthWeights != screen.DepthWeightsUniform {
+		score.DepthWeights = depthWeights
 	}
 
 	budget := design.SequencingBudget{
@@ -252,11 +438,32 @@ func run(args []string, stdout, stderr io.Writer) error {
 		UsableReadFraction:   cfg.usableReadFraction,
 		Samples:              cfg.samples,
 		TargetMeanLocusDepth: cfg.desiredDepth,
+		DepthDenominator:     depthDenominator,
+	}
+	if cfg.minCallDepth > 0 {
+		budget.MinCallDepth = cfg.minCallDepth
+		budget.DepthDispersion = cfg.depthDispersion
+		budget.DepthWeights = depthWeights
+		budget.SampleCV = cfg.sampleCV
+	}
+	if costEnabled {
+		cost := cfg.cost
+		budget.Cost = &cost
 	}
 	target := design.DesignTarget{
 		TargetGenomePct:      cfg.targetGenomePct,
 		CoverageTolerancePct: cfg.coverageTolerancePct,
 		Objective:            objective,
+		SNPModel:             snpModel,
+	}
+	if cfg.presentPct > 0 {
+		target.PresentPct = cfg.presentPct
+		target.MinPresentLoci = cfg.minPresentLoci
+	}
+	target.MinCallableLoci = cfg.minCallableLoci
+	if cfg.targetSNPs > 0 {
+		target.TargetSNPs = cfg.targetSNPs
+		target.SNPTolerancePct = cfg.snpTolerancePct
 	}
 	weights := design.ScoreWeights{
 		Coverage:     cfg.weightCoverage,
@@ -265,16 +472,41 @@ func run(args []string, stdout, stderr io.Writer) error {
 		Insert:       cfg.weightInsert,
 	}
 
-	candidates := make([]design.Candidate, 0, len(summaries))
-	for _, summary := range summaries {
-		candidates = append(candidates, design.EvaluateSummary(summary, genomeBases, budget, target, weights))
+	opt := digest.Options{AllowSame: cfg.allowSame, IncludeEnds: cfg.includeEnds, StrictCuts: cfg.strictCuts}
+	evaluate := func(summary screen.PairSummary) design.Candidate {
+		return design.EvaluateSummary(summary, genomeBases, budget, target, weights)
+	}
+	candidates, err := scorePairs(idx, pairs, selectors, opt, score, workers, evaluate, objective)
+	if err != nil {
+		return err
 	}
 	design.SortCandidates(candidates, objective)
+	design.AssignParetoRanks(candidates, paretoOptions(cfg, budget))
 	reported := candidates
 	if cfg.top > 0 && cfg.top < len(reported) {
 		reported = append([]design.Candidate(nil), reported[:cfg.top]...)
 	}
 
+	var sweepResults []design.SweepResult
+	if cfg.sweepGrid.Enabled() {
+		base := design.SweepPoint{UsableReadFraction: budget.UsableReadFraction, Samples: budget.Samples}
+		if modelHasSD(selector.Config().Model) {
+			base.SizeSD = selector.Config().SD
+		}
+		points := cfg.sweepGrid.Points(base)
+		swept := candidates[:min(cfg.sweepTop, len(candidates))]
+		if _, err := fmt.Fprintf(stderr, "sweep_points\t%d\tpairs\t%d\n", len(points), len(swept)); err != nil {
+			return err
+		}
+		sweepEvaluate := func(summary screen.PairSummary, point design.SweepPoint) design.Candidate {
+			return design.EvaluateSummary(summary, genomeBases, point.Budget(budget), target, weights)
+		}
+		sweepResults, err = sweepCandidates(idx, swept, points, sizeConfig, opt, score, workers, sweepEvaluate, objective)
+		if err != nil {
+			return err
+		}
+	}
+
 	warnings := make([]string, 0)
 	feasiblePairs := 0
 	for _, candidate := range candidates {
@@ -282,7 +514,9 @@ func run(args []string, stdout, stderr io.Writer) error {
 			feasiblePairs++
 		}
 	}
-	if feasiblePairs == 0 {
+	if feasiblePairs == 0 && target.TargetSNPs > 0 {
+		warnings = append(warnings, "no enzyme pair matched both target SNP tolerance and target mean locus depth under the supplied budget")
+	} else if feasiblePairs == 0 {
 		warnings = append(warnings, "no enzyme pair matched both target coverage tolerance and target mean locus depth under the supplied budget")
 	}
 	if len(candidates) == 0 {
@@ -290,11 +524,32 @@ func run(args []string, stdout, stderr io.Writer) error {
 	}
 
 	tsvPath, summaryTSVPath, jsonPath, reportPath := resolveOutputPaths(cfg)
-	if err := ensureOutputPaths(tsvPath, summaryTSVPath, jsonPath, reportPath, cfg.force); err != nil {
+	var extraOutputs []namedOutputPath
+	sweepTSVPath := ""
+	if cfg.sweepGrid.Enabled() {
+		sweepTSVPath = strings.TrimSpace(cfg.sweepTSVPath)
+		if sweepTSVPath == "" {
+			sweepTSVPath = filepath.Join(cfg.outDir, "design.sweep.tsv")
+		}
+		extraOutputs = append(extraOutputs, namedOutputPath{name: "sweep TSV", path: sweepTSVPath})
+	}
+	if err := ensureOutputPaths(tsvPath, summaryTSVPath, jsonPath, reportPath, cfg.force, extraOutputs...); err != nil {
 		return err
 	}
 
 	report := buildReport(args, cfg, idx, refBases, genomeBases, selector.Config(), budget, target, weights, warnings, candidates, reported, tsvPath, summaryTSVPath, jsonPath, reportPath)
+	if cfg.sweepGrid.Enabled() {
+		report.Outputs.SweepTSV = sweepTSVPath
+		report.Sweep = &sweepSummary{
+			Grid:      cfg.sweepGrid,
+			Pairs:     min(cfg.sweepTop, len(candidates)),
+			Points:    len(cfg.sweepGrid.Points(design.SweepPoint{})),
+			Stability: design.SummarizeSweep(sweepResults),
+		}
+		if err := writeSweepTSV(sweepTSVPath, sweepResults); err != nil {
+			return err
+		}
+	}
 	if err := writeCandidatesTSV(tsvPath, report.Results); err != nil {
 		return err
 	}
@@ -320,6 +575,11 @@ func run(args []string, stdout, stderr io.Writer) error {
 	if _, err := fmt.Fprintf(stderr, "design_report\t%s\n", reportPath); err != nil {
 		return err
 	}
+	if sweepTSVPath != "" {
+		if _, err := fmt.Fprintf(stderr, "design_sweep_tsv\t%s\n", sweepTSVPath); err != nil {
+			return err
+		}
+	}
 	if err := writeTerminalRecommendationSummary(stderr, report); err != nil {
 		return err
 	}
@@ -348,13 +608,54 @@ func parseArgs(args []string, stderr io.Writer) (cliConfig, error) {
 	fs.IntVar(&cfg.maxLen, "max", 600, "maximum fragment length (bp) for hard size selection")
 	fs.IntVar(&cfg.scoreMin, "score-min", 1, "minimum fragment length included in size-selection scoring")
 	fs.IntVar(&cfg.scoreMax, "score-max", 2000, "maximum fragment length included in size-selection scoring")
-	fs.StringVar(&cfg.sizeModel, "size-model", "normal", "size-selection model: hard, normal, triangular, or soft-window")
-	fs.Float64Var(&cfg.sizeMean, "size-mean", 275, "target/peak insert length for normal/triangular models")
-	fs.Float64Var(&cfg.sizeSD, "size-sd", 85, "standard deviation for --size-model normal")
+	fs.StringVar(&cfg.sizeModel, "size-model", "normal", "size-selection model: hard, normal, triangular, soft-window, log-normal, skew-normal, gamma, mixture, spri, or empirical")
+	fs.Float64Var(&cfg.sizeMean, "size-mean", 275, "target/peak insert length for normal/triangular models, mean for log-normal/gamma, location for skew-normal")
+	fs.Float64Var(&cfg.sizeSD, "size-sd", 85, "standard deviation for normal/log-normal/gamma/mixture models, scale for skew-normal")
 	fs.Float64Var(&cfg.sizeEdgeSD, "size-edge-sd", 25, "edge softness for --size-model soft-window")
+	fs.Float64Var(&cfg.sizeSkew, "size-skew", 0, "shape (alpha) for --size-model skew-normal; positive leans right")
+	fs.Float64Var(&cfg.sizeMean2, "size-mean2", 0, "mean of the second --size-model mixture component")
+	fs.Float64Var(&cfg.sizeSD2, "size-sd2", 0, "standard deviation of the second --size-model mixture component")
+	fs.Float64Var(&cfg.sizeMix, "size-mix", 0, "share of the second --size-model mixture component, in (0,1)")
+	fs.StringVar(&cfg.sizeCurvePath, "size-curve", "", "length-to-weight table (TSV or JSON) for --size-model empirical")
+	fs.Float64Var(&cfg.spriLeft, "spri-left", 0, "left-side (larger) bead ratio for --size-model spri, e.g. 0.75")
+	fs.Float64Var(&cfg.spriRight, "spri-right", 0, "right-side (smaller) bead ratio for --size-model spri, e.g. 0.55")
+	fs.StringVar(&cfg.spriCalibrationPath, "spri-calibration", "", "bead-ratio-to-cutoff table (TSV or JSON) for --size-model spri; default a built-in table")
+	fs.StringVar(&cfg.sizeConfigPath, "size-config", "", "size-model JSON from radigest-fit-size-model; replaces --size-model and its parameters")
+	fs.IntVar(&cfg.adapterP1Len, "adapter-p1-length", 0, "P1 adapter bases added to each insert before size selection; --min/--max and the score range become library lengths")
+	fs.IntVar(&cfg.adapterP2Len, "adapter-p2-length", 0, "P2 adapter bases added to each insert before size selection")
+	barcodeLengthsFlag := fs.String("barcode-lengths", "", "comma-separated combined inline-barcode lengths in the pool; weights average over them")
+	searchMinFlag := fs.String("search-min", "", "search --min over values and LO:HI:STEP ranges, keeping each pair's best window")
+	searchMaxFlag := fs.String("search-max", "", "search --max over values and LO:HI:STEP ranges")
+	searchMeanFlag := fs.String("search-mean", "", "search --size-mean over values and LO:HI:STEP ranges")
+	sweepMeanFlag := fs.String("sweep-size-mean", "", "sensitivity sweep: --size-mean values and LO:HI:STEP ranges")
+	sweepSDFlag := fs.String("sweep-size-sd", "", "sensitivity sweep: --size-sd values and LO:HI:STEP ranges")
+	sweepUsableFlag := fs.String("sweep-usable-fraction", "", "sensitivity sweep: --usable-read-fraction values and LO:HI:STEP ranges")
+	sweepSamplesFlag := fs.String("sweep-samples", "", "sensitivity sweep: --samples values and LO:HI:STEP ranges")
+	fs.IntVar(&cfg.sweepTop, "sweep-top", 10, "pairs re-evaluated at each sweep point, from the top of the ranking")
+	fs.StringVar(&cfg.sweepTSVPath, "sweep-tsv", "", "explicit sweep TSV path; default <out-dir>/design.sweep.tsv")
 	fs.BoolVar(&cfg.allowSame, "allow-same", false, "double digest: also keep AA/BB neighbors (default AB/BA only)")
 	fs.BoolVar(&cfg.includeEnds, "include-ends", false, "also score terminal fragments from contig ends to nearest cut")
 	fs.BoolVar(&cfg.strictCuts, "strict-cuts", false, "error if an enzyme lacks a caret and CutIndex==0")
+	fs.StringVar(&cfg.duplicates, "duplicates", string(paralog.ModeOff), "duplicate-locus detection from read-length fragment ends: off, exact, or kmer")
+	fs.IntVar(&cfg.duplicateK, "duplicate-k", paralog.DefaultK, "k-mer length for --duplicates kmer")
+	fs.BoolVar(&cfg.mappability, "mappability", false, "count fragments whose read-length end windows are unique in the reference")
+	fs.IntVar(&cfg.pcr.Cycles, "pcr-cycles", 0, "library PCR cycles; > 0 also reports amplification-weighted fragments and bases")
+	fs.Float64Var(&cfg.pcr.Efficiency, "pcr-efficiency", pcrbias.DefaultEfficiency, "per-cycle efficiency of a short fragment at the GC optimum, in (0,1]")
+	fs.Float64Var(&cfg.pcr.LengthScale, "pcr-length-scale", pcrbias.DefaultLengthScale, "length (bp) over which per-cycle efficiency falls by 1/e")
+	fs.Float64Var(&cfg.pcr.GCOptimum, "pcr-gc-optimum", pcrbias.DefaultGCOptimum, "GC fraction with the highest PCR efficiency")
+	fs.Float64Var(&cfg.pcr.GCWidth, "pcr-gc-width", pcrbias.DefaultGCWidth, "GC fraction SD over which efficiency falls off around --pcr-gc-optimum")
+	fs.StringVar(&cfg.depthDenominator, "depth-denominator", string(design.DepthWeightedFragments), "locus count used for depth: weighted-fragments or effective-unique-loci")
+	fs.IntVar(&cfg.minCallDepth, "min-call-depth", 0, "read pairs per sample needed to call a locus; > 0 reports callable loci")
+	fs.Float64Var(&cfg.depthDispersion, "depth-dispersion", 0, "negative binomial overdispersion of per-locus depth; 0 is Poisson")
+	fs.StringVar(&cfg.depthWeights, "depth-weights", string(screen.DepthWeightsUniform), "relative read share per locus: uniform, size, pcr, or size-pcr")
+	fs.Float64Var(&cfg.sampleCV, "sample-cv", 0, "coefficient of variation of read pairs per sample from unequal pooling")
+	fs.Float64Var(&cfg.presentPct, "present-pct", 0, "report loci called in at least this percentage of samples")
+	fs.Float64Var(&cfg.minPresentLoci, "min-present-loci", 0, "mark pairs with fewer --present-pct loci infeasible")
+	fs.Float64Var(&cfg.minCallableLoci, "min-callable-loci", 0, "mark pairs with fewer callable loci infeasible")
+	fs.Float64Var(&cfg.cost.PerLane, "cost-per-lane", 0, "sequencing cost per lane, or per flowcell with --flowcell-read-pairs")
+	fs.Float64Var(&cfg.cost.PerSample, "cost-per-sample", 0, "library prep cost per sample")
+	fs.Float64Var(&cfg.cost.PerEnzymeUnit, "cost-per-enzyme-unit", 0, "cost per enzyme unit")
+	fs.Float64Var(&cfg.cost.EnzymeUnitsPerSample, "enzyme-units-per-sample", design.DefaultEnzymeUnitsPerSample, "units of each enzyme per sample digest")
 
 	fs.StringVar(&cfg.readLayout, "read-layout", "pe", "sequencing layout for insert diagnostics: pe or se")
 	fs.IntVar(&cfg.readLength, "read-length", 0, "read length in bp, e.g. 150")
@@ -369,7 +670,11 @@ func parseArgs(args []string, stderr io.Writer) (cliConfig, error) {
 	fs.Float64Var(&cfg.targetGenomePct, "target-genome-pct", 0, "target weighted genome percentage")
 	fs.Float64Var(&cfg.targetGenomePct, "pct", 0, "alias for --target-genome-pct")
 	fs.Float64Var(&cfg.coverageTolerancePct, "coverage-tolerance-pct", 0.25, "absolute genome-percentage tolerance around --target-genome-pct")
-	fs.StringVar(&cfg.objective, "objective", string(design.ObjectiveBalanced), "ranking objective: balanced, closest-coverage, depth-first, feasible-lowest-coverage, or max-depth")
+	fs.Float64Var(&cfg.targetSNPs, "target-snps", 0, "target SNP count in sequenced bases; alternative to --target-genome-pct, requires --theta or --snp-vcf")
+	fs.Float64Var(&cfg.snpTolerancePct, "snp-tolerance-pct", 10, "relative tolerance around --target-snps, in percent")
+	fs.Float64Var(&cfg.theta, "theta", 0, "per-base nucleotide diversity used to predict SNPs in sequenced bases")
+	fs.StringVar(&cfg.snpVCFPath, "snp-vcf", "", "VCF of known variants; SNPs inside sequenced bases are counted per pair")
+	fs.StringVar(&cfg.objective, "objective", string(design.ObjectiveBalanced), "ranking objective: balanced, closest-coverage, depth-first, feasible-lowest-coverage, max-depth, callable-loci, or min-cost")
 	fs.Float64Var(&cfg.weightCoverage, "weight-coverage", defaults.Coverage, "fit-loss weight for coverage error")
 	fs.Float64Var(&cfg.weightDepth, "weight-depth", defaults.Depth, "fit-loss weight for depth shortfall")
 	fs.Float64Var(&cfg.weightOvercoverage, "weight-overcoverage", defaults.Overcoverage, "additional fit-loss weight for overcoverage")
@@ -394,10 +699,13 @@ func parseArgs(args []string, stderr io.Writer) (cliConfig, error) {
 		}
 		return cfg, usageError{err: err}
 	}
-	lanesExplicit := false
+	lanesExplicit, sizeFlagsExplicit := false, false
 	fs.Visit(func(f *flag.Flag) {
-		if f.Name == "lanes" {
+		switch f.Name {
+		case "lanes":
 			lanesExplicit = true
+		case "size-model", "size-mean", "size-sd", "size-edge-sd", "size-skew", "size-mean2", "size-sd2", "size-mix", "size-curve", "spri-left", "spri-right", "spri-calibration":
+			sizeFlagsExplicit = true
 		}
 	})
 	if cfg.showVersion {
@@ -418,8 +726,58 @@ func parseArgs(args []string, stderr io.Writer) (cliConfig, error) {
 	if cfg.desiredDepth <= 0 || math.IsNaN(cfg.desiredDepth) || math.IsInf(cfg.desiredDepth, 0) {
 		return cfg, usageError{err: errors.New("--desired-depth/--depth is required and must be a finite value > 0")}
 	}
-	if cfg.targetGenomePct <= 0 || math.IsNaN(cfg.targetGenomePct) || math.IsInf(cfg.targetGenomePct, 0) {
-		return cfg, usageError{err: errors.New("--target-genome-pct/--pct is required and must be a finite value > 0")}
+	if cfg.minCallDepth < 0 {
+		return cfg, usageError{err: errors.New("--min-call-depth must be >= 0")}
+	}
+	if cfg.depthDispersion < 0 || math.IsNaN(cfg.depthDispersion) || math.IsInf(cfg.depthDispersion, 0) {
+		return cfg, usageError{err: errors.New("--depth-dispersion must be a finite value >= 0")}
+	}
+	for _, c := range []struct {
+		name  string
+		value float64
+	}{
+		{"--cost-per-lane", cfg.cost.PerLane},
+		{"--cost-per-sample", cfg.cost.PerSample},
+		{"--cost-per-enzyme-unit", cfg.cost.PerEnzymeUnit},
+		{"--enzyme-units-per-sample", cfg.cost.EnzymeUnitsPerSample},
+	} {
+		if c.value < 0 || math.IsNaN(c.value) || math.IsInf(c.value, 0) {
+			return cfg, usageError{err: fmt.Errorf("%s must be a finite value >= 0", c.name)}
+		}
+	}
+	if cfg.sampleCV < 0 || math.IsNaN(cfg.sampleCV) || math.IsInf(cfg.sampleCV, 0) {
+		return cfg, usageError{err: errors.New("--sample-cv must be a finite value >= 0")}
+	}
+	if cfg.presentPct < 0 || cfg.presentPct > 100 || math.IsNaN(cfg.presentPct) {
+		return cfg, usageError{err: errors.New("--present-pct must be in [0,100]")}
+	}
+	if cfg.minPresentLoci < 0 || math.IsNaN(cfg.minPresentLoci) || math.IsInf(cfg.minPresentLoci, 0) {
+		return cfg, usageError{err: errors.New("--min-present-loci must be a finite value >= 0")}
+	}
+	if cfg.minCallableLoci < 0 || math.IsNaN(cfg.minCallableLoci) || math.IsInf(cfg.minCallableLoci, 0) {
+		return cfg, usageError{err: errors.New("--min-callable-loci must be a finite value >= 0")}
+	}
+	if cfg.targetSNPs != 0 {
+		if cfg.targetGenomePct != 0 {
+			return cfg, usageError{err: errors.New("use only one of --target-genome-pct/--pct or --target-snps")}
+		}
+		if cfg.targetSNPs < 0 || math.IsNaN(cfg.targetSNPs) || math.IsInf(cfg.targetSNPs, 0) {
+			return cfg, usageError{err: errors.New("--target-snps must be a finite value > 0")}
+		}
+		if cfg.theta == 0 && cfg.snpVCFPath == "" {
+			return cfg, usageError{err: errors.New("--target-snps requires --theta or --snp-vcf")}
+		}
+	} else if cfg.targetGenomePct <= 0 || math.IsNaN(cfg.targetGenomePct) || math.IsInf(cfg.targetGenomePct, 0) {
+		return cfg, usageError{err: errors.New("--target-genome-pct/--pct or --target-snps is required and must be a finite value > 0")}
+	}
+	if cfg.theta < 0 || cfg.theta > 1 || math.IsNaN(cfg.theta) {
+		return cfg, usageError{err: fmt.Errorf("--theta must be in [0,1] (got %g)", cfg.theta)}
+	}
+	if cfg.theta > 0 && cfg.snpVCFPath != "" {
+		return cfg, usageError{err: errors.New("use only one of --theta or --snp-vcf")}
+	}
+	if cfg.snpTolerancePct < 0 || math.IsNaN(cfg.snpTolerancePct) || math.IsInf(cfg.snpTolerancePct, 0) {
+		return cfg, usageError{err: fmt.Errorf("--snp-tolerance-pct must be >= 0 (got %g)", cfg.snpTolerancePct)}
 	}
 	if cfg.coverageTolerancePct < 0 || math.IsNaN(cfg.coverageTolerancePct) || math.IsInf(cfg.coverageTolerancePct, 0) {
 		return cfg, usageError{err: fmt.Errorf("--coverage-tolerance-pct must be >= 0 (got %g)", cfg.coverageTolerancePct)}
@@ -430,12 +788,92 @@ func parseArgs(args []string, stderr io.Writer) (cliConfig, error) {
 	if cfg.scoreMin < 0 || cfg.scoreMax < cfg.scoreMin {
 		return cfg, usageError{err: fmt.Errorf("invalid score window: score-min=%d score-max=%d", cfg.scoreMin, cfg.scoreMax)}
 	}
+	if cfg.sizeConfigPath != "" && sizeFlagsExplicit {
+		return cfg, usageError{err: errors.New("--size-config replaces --size-model and its parameters (--size-mean, --size-sd, --size-edge-sd, --size-skew, --size-mean2, --size-sd2, --size-mix, --size-curve, --spri-left, --spri-right, --spri-calibration)")}
+	}
+	if cfg.adapterP1Len < 0 || cfg.adapterP2Len < 0 {
+		return cfg, usageError{err: fmt.Errorf("--adapter-p1-length and --adapter-p2-length must be >= 0 (got %d and %d)", cfg.adapterP1Len, cfg.adapterP2Len)}
+	}
+	barcodeLengths, err := sizeselect.ParseBarcodeLengths(*barcodeLengthsFlag)
+	if err != nil {
+		return cfg, usageError{err: fmt.Errorf("--barcode-lengths: %w", err)}
+	}
+	cfg.barcodeLengths = barcodeLengths
+	if *searchMinFlag != "" {
+		if cfg.windowGrid.Min, err = design.ParseIntGrid(*searchMinFlag); err != nil {
+			return cfg, usageError{err: fmt.Errorf("--search-min: %w", err)}
+		}
+	}
+	if *searchMaxFlag != "" {
+		if cfg.windowGrid.Max, err = design.ParseIntGrid(*searchMaxFlag); err != nil {
+			return cfg, usageError{err: fmt.Errorf("--search-max: %w", err)}
+		}
+	}
+	if *searchMeanFlag != "" {
+		if cfg.windowGrid.Mean, err = design.ParseGrid(*searchMeanFlag); err != nil {
+			return cfg, usageError{err: fmt.Errorf("--search-mean: %w", err)}
+		}
+	}
+	if *sweepMeanFlag != "" {
+		if cfg.sweepGrid.SizeMean, err = design.ParseGrid(*sweepMeanFlag); err != nil {
+			return cfg, usageError{err: fmt.Errorf("--sweep-size-mean: %w", err)}
+		}
+	}
+	if *sweepSDFlag != "" {
+		if cfg.sweepGrid.SizeSD, err = design.ParseGrid(*sweepSDFlag); err != nil {
+			return cfg, usageError{err: fmt.Errorf("--sweep-size-sd: %w", err)}
+		}
+	}
+	if *sweepUsableFlag != "" {
+		if cfg.sweepGrid.UsableReadFraction, err = design.ParseGrid(*sweepUsableFlag); err != nil {
+			return cfg, usageError{err: fmt.Errorf("--sweep-usable-fraction: %w", err)}
+		}
+	}
+	if *sweepSamplesFlag != "" {
+		if cfg.sweepGrid.Samples, err = design.ParseIntGrid(*sweepSamplesFlag); err != nil {
+			return cfg, usageError{err: fmt.Errorf("--sweep-samples: %w", err)}
+		}
+	}
+	for _, v := range cfg.sweepGrid.SizeMean {
+		if v <= 0 {
+			return cfg, usageError{err: fmt.Errorf("--sweep-size-mean values must be > 0 (got %g)", v)}
+		}
+	}
+	for _, v := range cfg.sweepGrid.SizeSD {
+		if v <= 0 {
+			return cfg, usageError{err: fmt.Errorf("--sweep-size-sd values must be > 0 (got %g)", v)}
+		}
+	}
+	for _, v := range cfg.sweepGrid.UsableReadFraction {
+		if v <= 0 || v > 1 {
+			return cfg, usageError{err: fmt.Errorf("--sweep-usable-fraction values must be in (0,1] (got %g)", v)}
+		}
+	}
+	for _, v := range cfg.sweepGrid.Samples {
+		if v <= 0 {
+			return cfg, usageError{err: fmt.Errorf("--sweep-samples values must be > 0 (got %d)", v)}
+		}
+	}
+	if cfg.sweepTop <= 0 {
+		return cfg, usageError{err: fmt.Errorf("--sweep-top must be > 0 (got %d)", cfg.sweepTop)}
+	}
+	if cfg.sweepTSVPath != "" && !cfg.sweepGrid.Enabled() {
+		return cfg, usageError{err: errors.New("--sweep-tsv requires --sweep-size-mean, --sweep-size-sd, --sweep-usable-fraction, or --sweep-samples")}
+	}
+	if err := cfg.pcr.Validate(); err != nil {
+		return cfg, usageError{err: fmt.Errorf("--pcr-cycles: %w", err)}
+	}
 	if cfg.lanes <= 0 {
 		return cfg, usageError{err: fmt.Errorf("--lanes must be > 0 (got %d)", cfg.lanes)}
 	}
 	if cfg.usableReadFraction <= 0 || cfg.usableReadFraction > 1 || math.IsNaN(cfg.usableReadFraction) || math.IsInf(cfg.usableReadFraction, 0) {
 		return cfg, usageError{err: fmt.Errorf("--usable-read-fraction must be in (0,1] (got %g)", cfg.usableReadFraction)}
 	}
+	mode, err := paralog.ParseMode(cfg.duplicates)
+	if err != nil {
+		return cfg, usageError{err: fmt.Errorf("--duplicates: %w", err)}
+	}
+	cfg.duplicates = string(mode)
 	cfg.readLayout = strings.ToLower(strings.TrimSpace(cfg.readLayout))
 	if cfg.readLayout != "pe" && cfg.readLayout != "se" {
 		return cfg, usageError{err: fmt.Errorf("invalid --read-layout %q; use pe or se", cfg.readLayout)}
@@ -501,7 +939,7 @@ func writeDesignSizeSelectionSummary(stderr io.Writer, cfg sizeselect.Config) er
 	mean := "NA"
 	sd := "NA"
 	switch cfg.Model {
-	case sizeselect.ModelNormal:
+	case sizeselect.ModelNormal, sizeselect.ModelLogNormal, sizeselect.ModelSkewNormal, sizeselect.ModelGamma, sizeselect.ModelMixture:
 		mean = formatDesignStderrFloat(cfg.Mean)
 		sd = formatDesignStderrFloat(cfg.SD)
 	case sizeselect.ModelTriangular:
@@ -518,9 +956,48 @@ func writeDesignSizeSelectionSummary(stderr io.Writer, cfg sizeselect.Config) er
 			return err
 		}
 	}
+	if cfg.Model == sizeselect.ModelSkewNormal {
+		if _, err := fmt.Fprintf(stderr, "size_skew\t%s\n", formatDesignStderrFloat(cfg.Skew)); err != nil {
+			return err
+		}
+	}
+	if cfg.Model == sizeselect.ModelMixture {
+		if _, err := fmt.Fprintf(stderr, "size_mean2_bp\t%s\nsize_sd2_bp\t%s\nsize_mix\t%s\n", formatDesignStderrFloat(cfg.Mean2), formatDesignStderrFloat(cfg.SD2), formatDesignStderrFloat(cfg.Mix)); err != nil {
+			return err
+		}
+	}
+	if cfg.Model == sizeselect.ModelEmpirical {
+		if _, err := fmt.Fprintf(stderr, "size_curve\t%s (%d points)\n", cfg.CurvePath, len(cfg.Curve)); err != nil {
+			return err
+		}
+	}
+	if cfg.Model == sizeselect.ModelSPRI {
+		lower, upper := cfg.SPRICutoffs()
+		if _, err := fmt.Fprintf(stderr, "spri_ratios\t%sx/%sx\nspri_cutoffs_bp\t%s-%s\n", formatDesignStderrFloat(cfg.SPRIRight), formatDesignStderrFloat(cfg.SPRILeft), formatDesignStderrFloat(lower), formatDesignStderrFloat(upper)); err != nil {
+			return err
+		}
+	}
+	if cfg.ConfigPath != "" {
+		if _, err := fmt.Fprintf(stderr, "size_config\t%s\n", cfg.ConfigPath); err != nil {
+			return err
+		}
+	}
+	if cfg.HasFlanks() {
+		if _, err := fmt.Fprintf(stderr, "library_flanks_bp\t%s\n", formatDesignFlanks(cfg.Flanks())); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func formatDesignFlanks(flanks []int) string {
+	parts := make([]string, len(flanks))
+	for i, f := range flanks {
+		parts[i] = strconv.Itoa(f)
+	}
+	return strings.Join(parts, ",")
+}
+
 func formatDesignStderrFloat(value float64) string {
 	if math.IsNaN(value) || math.IsInf(value, 0) {
 		return "NA"
@@ -632,21 +1109,32 @@ func resolveBuildWorkers(buildWorkers, jobs, threads, enzymeCount int) int {
 	return workers
 }
 
-func scorePairs(idx screen.CutIndex, pairs []screen.Pair, selector sizeselect.Selector, opt digest.Options, workers int) ([]screen.PairSummary, error) {
+// scorePairs scores every pair with each selector and keeps, per pair, the
+// window the objective ranks best.
+func scorePairs(idx screen.CutIndex, pairs []screen.Pair, selectors []sizeselect.Selector, opt digest.Options, score screen.ScoreOptions, workers int, evaluate func(screen.PairSummary) design.Candidate, objective design.Objective) ([]design.Candidate, error) {
 	if len(pairs) == 0 {
 		return nil, nil
 	}
 	if workers < 1 {
 		workers = 1
 	}
+	// Size windows after the first are rescored from the first window's
+	// length histogram when no scored total needs the fragments themselves.
+	if len(selectors) > 1 && score.Rescorable(idx) {
+		score.LengthHistogram = true
+		for _, selector := range selectors {
+			_, hi := selector.InsertRange()
+			score.LengthHistogramMax = max(score.LengthHistogramMax, hi)
+		}
+	}
 	type job struct {
 		idx  int
 		pair screen.Pair
 	}
 	type result struct {
-		idx     int
-		summary screen.PairSummary
-		err     error
+		idx       int
+		candidate design.Candidate
+		err       error
 	}
 	jobCh := make(chan job)
 	resultCh := make(chan result, len(pairs))
@@ -656,8 +1144,29 @@ func scorePairs(idx screen.CutIndex, pairs []screen.Pair, selector sizeselect.Se
 		go func() {
 			defer wg.Done()
 			for j := range jobCh {
-				summary, err := screen.ScorePair(idx, j.pair.A, j.pair.B, selector, opt)
-				resultCh <- result{idx: j.idx, summary: summary, err: err}
+				windows := make([]design.Candidate, 0, len(selectors))
+				var first screen.PairSummary
+				var err error
+				for i, selector := range selectors {
+					var summary screen.PairSummary
+					if i > 0 && first.LengthHistogram != nil {
+						summary, err = screen.Rescore(first, selector)
+					} else {
+						summary, err = screen.ScorePairWithOptions(idx, j.pair.A, j.pair.B, selector, opt, score)
+						first = summary
+					}
+					if err != nil {
+						break
+					}
+					windows = append(windows, evaluate(summary))
+				}
+				res := result{idx: j.idx, err: err}
+				if err == nil && len(windows) == 1 {
+					res.candidate = windows[0]
+				} else if err == nil {
+					res.candidate = design.BestWindow(windows, objective)
+				}
+				resultCh <- res
 			}
 		}()
 	}
@@ -668,18 +1177,263 @@ func scorePairs(idx screen.CutIndex, pairs []screen.Pair, selector sizeselect.Se
 	wg.Wait()
 	close(resultCh)
 
-	summaries := make([]screen.PairSummary, len(pairs))
+	candidates := make([]design.Candidate, len(pairs))
 	var firstErr error
 	for res := range resultCh {
 		if res.err != nil && firstErr == nil {
 			firstErr = res.err
 		}
-		summaries[res.idx] = res.summary
+		candidates[res.idx] = res.candidate
 	}
 	if firstErr != nil {
 		return nil, firstErr
 	}
-	return summaries, nil
+	return candidates, nil
+}
+
+// sweepCandidates re-evaluates each swept pair at every sweep point, in the
+// pair's chosen size window, and ranks the pairs at each point. Each size
+// setting is scored once per pair, from the pair's length histogram when
+// the score options allow; budget settings reuse the scored summary. A size
+// setting that is not a valid model in a pair's window is recorded as a
+// skipped point for that pair rather than failing the sweep.
+func sweepCandidates(idx screen.CutIndex, swept []design.Candidate, points []design.SweepPoint, sizeConfig sizeselect.Config, opt digest.Options, score screen.ScoreOptions, workers int, evaluate func(screen.PairSummary, design.SweepPoint) design.Candidate, objective design.Objective) ([]design.SweepResult, error) {
+	if len(swept) == 0 {
+		return nil, nil
+	}
+	workers = max(1, min(workers, len(swept)))
+	score.LengthHistogram = score.Rescorable(idx)
+	type result struct {
+		idx       int
+		evaluated []design.Candidate
+		skipped   []string
+		err       error
+	}
+	jobCh := make(chan int)
+	resultCh := make(chan result, len(swept))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobCh {
+				evaluated, skipped, err := sweepPair(idx, swept[i], points, sizeConfig, opt, score, evaluate)
+				resultCh <- result{idx: i, evaluated: evaluated, skipped: skipped, err: err}
+			}
+		}()
+	}
+	for i := range swept {
+		jobCh <- i
+	}
+	close(jobCh)
+	wg.Wait()
+	close(resultCh)
+
+	byPair := make([][]design.Candidate, len(swept))
+	skippedByPair := make([][]string, len(swept))
+	for res := range resultCh {
+		if res.err != nil {
+			return nil, res.err
+		}
+		byPair[res.idx] = res.evaluated
+		skippedByPair[res.idx] = res.skipped
+	}
+	results := make([]design.SweepResult, 0, len(points)*len(swept))
+	for p, point := range points {
+		atPoint := make([]design.Candidate, len(swept))
+		skipped := make([]string, len(swept))
+		for i := range swept {
+			atPoint[i] = byPair[i][p]
+			skipped[i] = skippedByPair[i][p]
+		}
+		results = append(results, design.RankSweepPoint(point, atPoint, skipped, objective)...)
+	}
+	return results, nil
+}
+
+// sweepPair evaluates one pair at every point, keeping its baseline rank.
+// skipped[i] is set when points[i] is not a valid size model in the pair's
+// window; evaluated[i] then only names the pair and its window.
+func sweepPair(idx screen.CutIndex, c design.Candidate, points []design.SweepPoint, sizeConfig sizeselect.Config, opt digest.Options, score screen.ScoreOptions, evaluate func(screen.PairSummary, design.SweepPoint) design.Candidate) (evaluated []design.Candidate, skipped []string, err error) {
+	base := sizeConfig
+	base.Min, base.Max = c.SizeMin, c.SizeMax
+	if c.SizeMean != 0 {
+		base.Mean = c.SizeMean
+	}
+	type sizeKey struct{ mean, sd float64 }
+	keys := make([]sizeKey, len(points))
+	var order []sizeKey
+	selectors := make(map[sizeKey]sizeselect.Selector)
+	invalid := make(map[sizeKey]string)
+	for i, point := range points {
+		cfg := base
+		if point.SizeMean != 0 {
+			cfg.Mean = point.SizeMean
+		}
+		if point.SizeSD != 0 {
+			cfg.SD = point.SizeSD
+		}
+		key := sizeKey{cfg.Mean, cfg.SD}
+		keys[i] = key
+		if _, ok := selectors[key]; ok {
+			continue
+		}
+		if _, ok := invalid[key]; ok {
+			continue
+		}
+		selector, err := sizeselect.New(cfg)
+		if err != nil {
+			invalid[key] = fmt.Sprintf("size mean %g sd %g invalid in %d-%d bp window: %v", cfg.Mean, cfg.SD, cfg.Min, cfg.Max, err)
+			continue
+		}
+		selectors[key] = selector
+		order = append(order, key)
+	}
+	if len(order) < 2 {
+		score.LengthHistogram = false
+	}
+	if score.LengthHistogram {
+		for _, selector := range selectors {
+			_, hi := selector.InsertRange()
+			score.LengthHistogramMax = max(score.LengthHistogramMax, hi)
+		}
+	}
+
+	summaries := make(map[sizeKey]screen.PairSummary, len(order))
+	for i, key := range order {
+		var summary screen.PairSummary
+		var err error
+		if first := summaries[order[0]]; i > 0 && first.LengthHistogram != nil {
+			summary, err = screen.Rescore(first, selectors[key])
+		} else {
+			summary, err = screen.ScorePairWithOptions(idx, c.EnzymeA, c.EnzymeB, selectors[key], opt, score)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		summaries[key] = summary
+	}
+	evaluated = make([]design.Candidate, len(points))
+	skipped = make([]string, len(points))
+	for i, point := range points {
+		if why, ok := invalid[keys[i]]; ok {
+			evaluated[i] = design.Candidate{
+				EnzymeA:  c.EnzymeA,
+				EnzymeB:  c.EnzymeB,
+				Enzymes:  c.Enzymes,
+				SizeMin:  c.SizeMin,
+				SizeMax:  c.SizeMax,
+				SizeMean: c.SizeMean,
+			}
+			skipped[i] = why
+		} else {
+			evaluated[i] = evaluate(summaries[keys[i]], point)
+		}
+		evaluated[i].Rank = c.Rank
+	}
+	return evaluated, skipped, nil
+}
+
+func writeSweepTSV(path string, results []design.SweepResult) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	writer := csv.NewWriter(f)
+	writer.Comma = '\t'
+	if err := writer.Write(sweepTSVHeader()); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := writer.Write(sweepTSVRow(r)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func sweepTSVHeader() []string {
+	return []string{
+		"size_mean_bp",
+		"size_sd_bp",
+		"usable_read_fraction",
+		"samples",
+		"enzyme_a",
+		"enzyme_b",
+		"base_rank",
+		"rank",
+		"rank_shift",
+		"feasible",
+		"decision_reason",
+		"fit_score",
+		"fit_loss",
+		"predicted_weighted_genome_pct",
+		"coverage_error_rel",
+		"predicted_mean_locus_depth",
+		"depth_margin",
+		"read_pairs_per_sample",
+		"callable_loci",
+		"present_loci",
+		"mean_weighted_length",
+	}
+}
+
+// sweepTSVRow leaves the metric columns empty for a skipped point.
+func sweepTSVRow(r design.SweepResult) []string {
+	c := r.Candidate
+	row := []string{
+		formatFloat(r.Point.SizeMean),
+		formatFloat(r.Point.SizeSD),
+		formatFloat(r.Point.UsableReadFraction),
+		strconv.Itoa(r.Point.Samples),
+		c.EnzymeA,
+		c.EnzymeB,
+		strconv.Itoa(r.BaseRank),
+		strconv.Itoa(r.Rank),
+		strconv.Itoa(r.Rank - r.BaseRank),
+		strconv.FormatBool(c.Feasible),
+		c.DecisionReason,
+		formatFloat(c.FitScore),
+		formatFloat(c.FitLoss),
+		formatFloat(c.PredictedWeightedGenomePct),
+		formatFloat(c.CoverageErrorRel),
+		formatFloat(c.PredictedMeanLocusDepth),
+		formatFloat(c.DepthMargin),
+		formatFloat(c.ReadPairsPerSample),
+		formatFloat(c.CallableLoci),
+		formatFloat(c.PresentLoci),
+		formatFloat(c.MeanWeightedLength),
+	}
+	if r.Skipped != "" {
+		for i := 11; i < len(row); i++ {
+			row[i] = ""
+		}
+	}
+	return row
+}
+
+// modelHasSD reports whether --size-sd shapes model.
+func modelHasSD(model sizeselect.Model) bool {
+	switch model {
+	case sizeselect.ModelNormal, sizeselect.ModelLogNormal, sizeselect.ModelSkewNormal, sizeselect.ModelGamma, sizeselect.ModelMixture:
+		return true
+	}
+	return false
+}
+
+// modelHasMean reports whether --size-mean shapes model.
+func modelHasMean(model sizeselect.Model) bool {
+	switch model {
+	case sizeselect.ModelNormal, sizeselect.ModelTriangular, sizeselect.ModelLogNormal, sizeselect.ModelSkewNormal, sizeselect.ModelGamma, sizeselect.ModelMixture:
+		return true
+	}
+	return false
 }
 
 func buildReport(args []string, cfg cliConfig, idx screen.CutIndex, refBases design.GenomeBases, genomeBases int64, selectorCfg sizeselect.Config, budget design.SequencingBudget, target design.DesignTarget, weights design.ScoreWeights, warnings []string, allCandidates []design.Candidate, reported []design.Candidate, tsvPath, summaryTSVPath, jsonPath, reportPath string) designReport {
@@ -711,16 +1465,50 @@ func buildReport(args []string, cfg cliConfig, idx screen.CutIndex, refBases des
 		AllowSame:   cfg.allowSame,
 		IncludeEnds: cfg.includeEnds,
 		StrictCuts:  cfg.strictCuts,
+		Duplicates:  cfg.duplicates,
+		Mappability: cfg.mappability,
+	}
+	if cfg.pcr.Enabled() {
+		pcr := cfg.pcr
+		digestParams.PCR = &pcr
+	}
+	if cfg.windowGrid.Enabled() {
+		grid := cfg.windowGrid
+		digestParams.WindowGrid = &grid
+	}
+	if idx.EndHasher.Mode == paralog.ModeKmer {
+		digestParams.DuplicateK = idx.EndHasher.K
 	}
 	switch selectorCfg.Model {
-	case sizeselect.ModelNormal:
+	case sizeselect.ModelNormal, sizeselect.ModelLogNormal, sizeselect.ModelGamma:
 		digestParams.SizeMean = selectorCfg.Mean
 		digestParams.SizeSD = selectorCfg.SD
+	case sizeselect.ModelSkewNormal:
+		digestParams.SizeMean = selectorCfg.Mean
+		digestParams.SizeSD = selectorCfg.SD
+		digestParams.SizeSkew = selectorCfg.Skew
+	case sizeselect.ModelMixture:
+		digestParams.SizeMean = selectorCfg.Mean
+		digestParams.SizeSD = selectorCfg.SD
+		digestParams.SizeMean2 = selectorCfg.Mean2
+		digestParams.SizeSD2 = selectorCfg.SD2
+		digestParams.SizeMix = selectorCfg.Mix
 	case sizeselect.ModelTriangular:
 		digestParams.SizeMean = selectorCfg.Mean
 	case sizeselect.ModelSoftWindow:
 		digestParams.SizeEdgeSD = selectorCfg.EdgeSD
-	}
+	case sizeselect.ModelEmpirical:
+		digestParams.SizeCurve = selectorCfg.Curve
+		digestParams.SizeCurvePath = selectorCfg.CurvePath
+	case sizeselect.ModelSPRI:
+		digestParams.SPRILeft = selectorCfg.SPRILeft
+		digestParams.SPRIRight = selectorCfg.SPRIRight
+		digestParams.SPRICalibrationPath = selectorCfg.SPRICalibrationPath
+	}
+	digestParams.SizeConfigPath = selectorCfg.ConfigPath
+	digestParams.AdapterP1Length = selectorCfg.AdapterP1
+	digestParams.AdapterP2Length = selectorCfg.AdapterP2
+	digestParams.BarcodeLengths = selectorCfg.BarcodeLengths
 
 	return designReport{
 		SchemaVersion:   design.SchemaVersion,
@@ -746,10 +1534,28 @@ func buildReport(args []string, cfg cliConfig, idx screen.CutIndex, refBases des
 		Outputs:  outputSummary{TSV: tsvPath, SummaryTSV: summaryTSVPath, JSON: jsonPath, Report: reportPath},
 		Warnings: warnings,
 		Summary:  summary,
+		Pareto:   buildParetoSummary(allCandidates, paretoOptions(cfg, budget)),
 		Results:  append([]design.Candidate(nil), reported...),
 	}
 }
 
+// paretoOptions compares cost when it is priced and mappable loci when they
+// are counted.
+func paretoOptions(cfg cliConfig, budget design.SequencingBudget) design.ParetoOptions {
+	return design.ParetoOptions{Cost: budget.Cost != nil, MappableLoci: cfg.mappability}
+}
+
+func buildParetoSummary(candidates []design.Candidate, opt design.ParetoOptions) paretoSummary {
+	summary := paretoSummary{Criteria: opt.Criteria(), Front: []paretoPoint{}}
+	for _, c := range candidates {
+		summary.Fronts = max(summary.Fronts, c.ParetoRank)
+		if c.ParetoRank == 1 {
+			summary.Front = append(summary.Front, paretoPoint{Enzymes: append([]string(nil), c.Enzymes...), Rank: c.Rank, Values: opt.Values(c)})
+		}
+	}
+	return summary
+}
+
 func resolveOutputPaths(cfg cliConfig) (string, string, string, string) {
 	tsvPath := strings.TrimSpace(cfg.tsvPath)
 	summaryTSVPath := strings.TrimSpace(cfg.summaryTSVPath)
@@ -770,13 +1576,14 @@ func resolveOutputPaths(cfg cliConfig) (string, string, string, string) {
 	return tsvPath, summaryTSVPath, jsonPath, reportPath
 }
 
-func ensureOutputPaths(tsvPath, summaryTSVPath, jsonPath, reportPath string, force bool) error {
+func ensureOutputPaths(tsvPath, summaryTSVPath, jsonPath, reportPath string, force bool, extra ...namedOutputPath) error {
 	outputs := []namedOutputPath{
 		{name: "TSV", path: tsvPath},
 		{name: "summary TSV", path: summaryTSVPath},
 		{name: "JSON", path: jsonPath},
 		{name: "report", path: reportPath},
 	}
+	outputs = append(outputs, extra...)
 	for _, output := range outputs {
 		if strings.TrimSpace(output.path) == "" {
 			return fmt.Errorf("%s output path must not be empty", output.name)
@@ -878,6 +1685,7 @@ func designSummaryTSVHeader() []string {
 		"read_pairs_per_sample",
 		"max_samples",
 		"weighted_fragments",
+		"predicted_snps",
 		"mean_insert_bp",
 		"insert_status",
 		"fit_score",
@@ -898,6 +1706,7 @@ func candidateSummaryTSVRow(c design.Candidate) []string {
 		formatFloat(c.ReadPairsPerSample),
 		strconv.Itoa(c.MaxSamplesTotalFullTarget),
 		formatFloat(c.WeightedFragments),
+		formatFloat(c.PredictedSNPs),
 		formatFloat(c.MeanWeightedLength),
 		c.MeanInsertCategory,
 		formatFloat(c.FitScore),
@@ -923,6 +1732,7 @@ func designTSVHeader() []string {
 		"decision_reason",
 		"fit_score",
 		"fit_loss",
+		"pareto_rank",
 		"target_genome_pct",
 		"predicted_weighted_genome_pct",
 		"coverage_error_pct_points",
@@ -937,14 +1747,42 @@ func designTSVHeader() []string {
 		"required_pairs_per_sample_full_target",
 		"weighted_bases",
 		"weighted_fragments",
+		"effective_unique_loci",
+		"duplicate_clusters",
+		"mappable_loci",
+		"amplified_fragments",
+		"amplified_bases",
+		"sequenced_bases",
+		"predicted_snps",
+		"target_snps",
+		"depth_loci",
+		"min_call_depth",
+		"callable_fraction",
+		"callable_loci",
+		"min_callable_loci",
+		"present_pct",
+		"present_fraction",
+		"present_loci",
+		"min_present_loci",
 		"mean_weighted_length",
 		"raw_bases_in_window",
 		"raw_fragments_in_window",
+		"size_min_bp",
+		"size_max_bp",
+		"size_mean_bp",
+		"windows_searched",
 		"budget_supported_genome_pct",
 		"budget_supported_weighted_bases",
 		"max_samples_per_lane_full_target",
 		"max_samples_total_full_target",
 		"lanes_required_full_target",
+		"total_cost",
+		"cost_per_sample",
+		"cost_per_callable_locus",
+		"optimal_lanes",
+		"optimal_samples_per_lane",
+		"optimal_cost",
+		"optimal_feasible",
 		"adapter_threshold_bp",
 		"overlap_threshold_bp",
 		"mean_insert_category",
@@ -964,6 +1802,7 @@ func candidateTSVRow(c design.Candidate) []string {
 		c.DecisionReason,
 		formatFloat(c.FitScore),
 		formatFloat(c.FitLoss),
+		strconv.Itoa(c.ParetoRank),
 		formatFloat(c.TargetGenomePct),
 		formatFloat(c.PredictedWeightedGenomePct),
 		formatFloat(c.CoverageErrorPctPoints),
@@ -978,14 +1817,42 @@ func candidateTSVRow(c design.Candidate) []string {
 		formatFloat(c.RequiredPairsPerSampleTarget),
 		formatFloat(c.WeightedBases),
 		formatFloat(c.WeightedFragments),
+		formatFloat(c.EffectiveUniqueLoci),
+		strconv.Itoa(c.DuplicateClusters),
+		formatFloat(c.MappableLoci),
+		formatFloat(c.AmplifiedFragments),
+		formatFloat(c.AmplifiedBases),
+		formatFloat(c.SequencedBases),
+		formatFloat(c.PredictedSNPs),
+		formatFloat(c.TargetSNPs),
+		formatFloat(c.DepthLoci),
+		strconv.Itoa(c.MinCallDepth),
+		formatFloat(c.CallableFraction),
+		formatFloat(c.CallableLoci),
+		formatFloat(c.MinCallableLoci),
+		formatFloat(c.PresentPct),
+		formatFloat(c.PresentFraction),
+		formatFloat(c.PresentLoci),
+		formatFloat(c.MinPresentLoci),
 		formatFloat(c.MeanWeightedLength),
 		strconv.FormatInt(c.RawBasesInWindow, 10),
 		strconv.Itoa(c.RawFragmentsInWindow),
+		strconv.Itoa(c.SizeMin),
+		strconv.Itoa(c.SizeMax),
+		formatFloat(c.SizeMean),
+		strconv.Itoa(c.WindowsSearched),
 		formatFloat(c.BudgetSupportedGenomePct),
 		formatFloat(c.BudgetSupportedWeightedBases),
 		strconv.Itoa(c.MaxSamplesPerLaneFullTarget),
 		strconv.Itoa(c.MaxSamplesTotalFullTarget),
 		strconv.Itoa(c.LanesRequiredFullTarget),
+		formatFloat(c.TotalCost),
+		formatFloat(c.CostPerSample),
+		costPerCallableLocus(c),
+		strconv.Itoa(c.OptimalLanes),
+		strconv.Itoa(c.OptimalSamplesPerLane),
+		formatFloat(c.OptimalCost),
+		strconv.FormatBool(c.OptimalFeasible),
 		strconv.Itoa(c.AdapterThresholdBP),
 		zeroIntBlank(c.OverlapThresholdBP),
 		c.MeanInsertCategory,
@@ -1041,7 +1908,17 @@ func designReportRows(report designReport) []reportRow {
 		{"feasible_pairs", strconv.Itoa(report.Summary.FeasiblePairs)},
 		{"target_genome_pct", formatFloat(report.Target.TargetGenomePct)},
 		{"coverage_tolerance_pct", formatFloat(report.Target.CoverageTolerancePct)},
+		{"target_snps", formatFloat(report.Target.TargetSNPs)},
 		{"target_mean_locus_depth", formatFloat(report.Sequencing.TargetMeanLocusDepth)},
+		{"depth_denominator", string(report.Sequencing.DepthDenominator)},
+		{"min_call_depth", strconv.Itoa(report.Sequencing.MinCallDepth)},
+		{"depth_dispersion", formatFloat(report.Sequencing.DepthDispersion)},
+		{"sample_cv", formatFloat(report.Sequencing.SampleCV)},
+		{"present_pct", formatFloat(report.Target.PresentPct)},
+		{"min_present_loci", formatFloat(report.Target.MinPresentLoci)},
+		{"min_callable_loci", formatFloat(report.Target.MinCallableLoci)},
+		{"duplicates", report.Digest.Duplicates},
+		{"mappability", strconv.FormatBool(report.Digest.Mappability)},
 		{"samples", strconv.Itoa(report.Sequencing.Samples)},
 		{"read_layout", report.Sequencing.ReadLayout},
 		{"read_length", strconv.Itoa(report.Sequencing.ReadLength)},
@@ -1070,12 +1947,24 @@ func designReportRows(report designReport) []reportRow {
 			reportRow{"best_coverage_error_pct_points", formatFloat(best.CoverageErrorPctPoints)},
 			reportRow{"best_predicted_mean_locus_depth", formatFloat(best.PredictedMeanLocusDepth)},
 			reportRow{"best_depth_margin", formatFloat(best.DepthMargin)},
+			reportRow{"best_callable_loci", formatFloat(best.CallableLoci)},
+			reportRow{"best_present_loci", formatFloat(best.PresentLoci)},
+			reportRow{"best_cost_per_sample", formatFloat(best.CostPerSample)},
+			reportRow{"best_cost_per_callable_locus", costPerCallableLocus(best)},
+			reportRow{"best_optimal_lanes", strconv.Itoa(best.OptimalLanes)},
+			reportRow{"best_optimal_cost", formatFloat(best.OptimalCost)},
 			reportRow{"best_read_pairs_per_sample", formatFloat(best.ReadPairsPerSample)},
 			reportRow{"best_max_samples_total_full_target", strconv.Itoa(best.MaxSamplesTotalFullTarget)},
 			reportRow{"best_weighted_fragments", formatFloat(best.WeightedFragments)},
+			reportRow{"best_effective_unique_loci", formatFloat(best.EffectiveUniqueLoci)},
+			reportRow{"best_duplicate_clusters", strconv.Itoa(best.DuplicateClusters)},
+			reportRow{"best_mappable_loci", formatFloat(best.MappableLoci)},
+			reportRow{"best_amplified_fragments", formatFloat(best.AmplifiedFragments)},
+			reportRow{"best_predicted_snps", formatFloat(best.PredictedSNPs)},
 			reportRow{"best_weighted_bases", formatFloat(best.WeightedBases)},
 			reportRow{"best_mean_weighted_length_bp", formatFloat(best.MeanWeightedLength)},
 			reportRow{"best_mean_insert_category", best.MeanInsertCategory},
+			reportRow{"best_size_window_bp", fmt.Sprintf("%d-%d", best.SizeMin, best.SizeMax)},
 		)
 	} else {
 		rows = append(rows,
@@ -1085,6 +1974,40 @@ func designReportRows(report designReport) []reportRow {
 		)
 	}
 
+	rows = append(rows,
+		reportRow{"pareto_criteria", strings.Join(report.Pareto.Criteria, ",")},
+		reportRow{"pareto_fronts", strconv.Itoa(report.Pareto.Fronts)},
+		reportRow{"pareto_front_size", strconv.Itoa(len(report.Pareto.Front))},
+	)
+	for i, p := range report.Pareto.Front {
+		parts := []string{strings.Join(p.Enzymes, ","), "rank=" + strconv.Itoa(p.Rank)}
+		for j, name := range report.Pareto.Criteria {
+			parts = append(parts, name+"="+formatFloat(p.Values[j]))
+		}
+		rows = append(rows, reportRow{fmt.Sprintf("pareto_front.%d", i+1), strings.Join(parts, " ")})
+	}
+
+	if sw := report.Sweep; sw != nil {
+		rows = append(rows,
+			reportRow{"sweep_points", strconv.Itoa(sw.Points)},
+			reportRow{"sweep_pairs", strconv.Itoa(sw.Pairs)},
+		)
+		for _, st := range sw.Stability {
+			parts := []string{
+				strings.Join(st.Enzymes, ","),
+				"feasible_points=" + strconv.Itoa(st.FeasiblePoints) + "/" + strconv.Itoa(st.Points),
+				"same_rank_points=" + strconv.Itoa(st.SameRankPoints),
+				"top_points=" + strconv.Itoa(st.TopPoints),
+				"mean_rank=" + formatFloat(st.MeanRank),
+				"worst_rank=" + strconv.Itoa(st.WorstRank),
+			}
+			if st.SkippedPoints > 0 {
+				parts = append(parts, "skipped_points="+strconv.Itoa(st.SkippedPoints))
+			}
+			rows = append(rows, reportRow{fmt.Sprintf("sweep.%d", st.BaseRank), strings.Join(parts, " ")})
+		}
+	}
+
 	rows = append(rows, reportRow{"warning_count", strconv.Itoa(len(report.Warnings))})
 	for i, warning := range report.Warnings {
 		rows = append(rows, reportRow{fmt.Sprintf("warning.%d", i+1), warning})
@@ -1096,6 +2019,9 @@ func designReportRows(report designReport) []reportRow {
 		reportRow{"output.json", report.Outputs.JSON},
 		reportRow{"output.report", report.Outputs.Report},
 	)
+	if report.Outputs.SweepTSV != "" {
+		rows = append(rows, reportRow{"output.sweep_tsv", report.Outputs.SweepTSV})
+	}
 
 	return rows
 }
@@ -1129,13 +2055,20 @@ func writeTerminalRecommendationSummary(w io.Writer, report designReport) error
 		status = "feasible"
 	}
 
+	why := fmt.Sprintf("predicted %s%% genome vs target %s%%",
+		formatTerminalFloat(best.PredictedWeightedGenomePct, 2),
+		formatTerminalFloat(best.TargetGenomePct, 2))
+	if best.TargetSNPs > 0 {
+		why = fmt.Sprintf("predicted %s SNPs vs target %s",
+			formatTerminalFloat(best.PredictedSNPs, 0),
+			formatTerminalFloat(best.TargetSNPs, 0))
+	}
 	lines := []string{
 		fmt.Sprintf("Recommended pair: %s", enzymePair(best)),
 		fmt.Sprintf("Status: %s", status),
 		fmt.Sprintf(
-			"Why: predicted %s%% genome vs target %s%%; predicted %sx mean locus depth vs target %sx; %s",
-			formatTerminalFloat(best.PredictedWeightedGenomePct, 2),
-			formatTerminalFloat(best.TargetGenomePct, 2),
+			"Why: %s; predicted %sx mean locus depth vs target %sx; %s",
+			why,
 			formatTerminalFloat(best.PredictedMeanLocusDepth, 2),
 			formatTerminalFloat(best.TargetMeanLocusDepth, 2),
 			formatTerminalDecisionReason(best.DecisionReason),
@@ -1351,6 +2284,15 @@ func formatFloat(value float64) string {
 	return strconv.FormatFloat(value, 'f', 6, 64)
 }
 
+// costPerCallableLocus is blank when c has no callable loci to price, as
+// without --min-call-depth, rather than a cost of 0.
+func costPerCallableLocus(c design.Candidate) string {
+	if c.CallableLoci <= 0 {
+		return ""
+	}
+	return formatFloat(c.CostPerCallableLocus)
+}
+
 func zeroIntBlank(value int) string {
 	if value == 0 {
 		return ""
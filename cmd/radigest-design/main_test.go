@@ -5,10 +5,15 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/ericksamera/radigest/internal/design"
 )
 
 func TestRunWritesDesignOutputs(t *testing.T) {
@@ -211,6 +216,93 @@ func TestRunAcceptsConciseDesignAliases(t *testing.T) {
 	}
 }
 
+func TestRunTargetSNPsFromKnownVCF(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	if err := os.WriteFile(fastaPath, []byte(">ecori_msei_double\nAAAAGAATTCTTAAAGAATTCTTT\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	// Two SNPs fall in the 5-11 and 11-16 fragments; one SNP and one
+	// deletion fall outside or are not SNPs.
+	vcfPath := filepath.Join(dir, "known.vcf")
+	vcfText := "##fileformat=VCFv4.2\n" +
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n" +
+		"ecori_msei_double\t7\t.\tA\tG\t.\tPASS\t.\n" +
+		"ecori_msei_double\t9\t.\tTC\tT\t.\tPASS\t.\n" +
+		"ecori_msei_double\t13\t.\tA\tC\t.\tPASS\t.\n" +
+		"ecori_msei_double\t20\t.\tT\tA\t.\tPASS\t.\n"
+	if err := os.WriteFile(vcfPath, []byte(vcfText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outDir := filepath.Join(dir, "design")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--ref", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--min", "1",
+		"--max", "100",
+		"--score-min", "1",
+		"--score-max", "100",
+		"--size-model", "hard",
+		"--target-snps", "2",
+		"--snp-vcf", vcfPath,
+		"--depth", "10",
+		"--samples", "1",
+		"--read-length", "150",
+		"--flowcell-read-pairs", "1000",
+		"--out-dir", outDir,
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Target struct {
+			TargetSNPs float64 `json:"target_snps"`
+		} `json:"design_target"`
+		Results []struct {
+			Feasible       bool    `json:"feasible"`
+			DecisionReason string  `json:"decision_reason"`
+			SequencedBases float64 `json:"sequenced_bases"`
+			PredictedSNPs  float64 `json:"predicted_snps"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Target.TargetSNPs != 2 || len(report.Results) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	got := report.Results[0]
+	if got.PredictedSNPs != 2 || got.SequencedBases != 11 || !got.Feasible || !strings.HasPrefix(got.DecisionReason, "matches target SNPs") {
+		t.Fatalf("unexpected SNP candidate: %+v", got)
+	}
+	if !strings.Contains(stderr.String(), "predicted 2 SNPs vs target 2") {
+		t.Fatalf("terminal summary missing SNP rationale:\n%s", stderr.String())
+	}
+}
+
+func TestRunRejectsTargetSNPsWithoutModel(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--fasta", "ref.fa",
+		"--enzymes", "EcoRI,MseI",
+		"--target-snps", "5000",
+		"--desired-depth", "10",
+		"--samples", "1",
+		"--read-length", "150",
+		"--lane-read-pairs", "1000",
+	}, &stdout, &stderr)
+	if err == nil || exitCode(err) != 2 || !strings.Contains(err.Error(), "--theta or --snp-vcf") {
+		t.Fatalf("err = %v, want usage error naming --theta or --snp-vcf", err)
+	}
+}
+
 func TestRunHelpShowsGroupedDesignHelp(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	err := run([]string{"--help"}, &stdout, &stderr)
@@ -260,6 +352,723 @@ func TestRunRejectsMissingBudget(t *testing.T) {
 	}
 }
 
+func TestRunEffectiveUniqueLociDepthDenominator(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "repeat.fa")
+	repeat := "GAATTCAGCGCGATCAGTCCAGCATGCAGGTCAGCACGCTAGGTTAA"
+	if err := os.WriteFile(fastaPath, []byte(">chr1\nCCGCACCAGT"+repeat+"GCAGCGCCAGGCA"+repeat+"GGCAGCCAGC\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--min", "1",
+		"--max", "100",
+		"--score-min", "1",
+		"--score-max", "100",
+		"--size-model", "hard",
+		"--pct", "50",
+		"--depth", "10",
+		"--samples", "1",
+		"--read-length", "12",
+		"--lane-read-pairs", "1000",
+		"--duplicates", "exact",
+		"--depth-denominator", "effective-unique-loci",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+	if err != nil {
+		t.Fatalf("read JSON: %v", err)
+	}
+	var report struct {
+		Digest struct {
+			Duplicates string `json:"duplicates"`
+		} `json:"digest_parameters"`
+		Results []struct {
+			WeightedFragments   float64 `json:"weighted_fragments"`
+			EffectiveUniqueLoci float64 `json:"effective_unique_loci"`
+			DuplicateClusters   int     `json:"duplicate_clusters"`
+			DepthLoci           float64 `json:"depth_loci"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parse JSON: %v", err)
+	}
+	if report.Digest.Duplicates != "exact" || len(report.Results) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	got := report.Results[0]
+	if got.DuplicateClusters != 1 || got.EffectiveUniqueLoci != got.WeightedFragments-1 || got.DepthLoci != got.EffectiveUniqueLoci {
+		t.Fatalf("duplicate-aware depth fields wrong: %+v", got)
+	}
+}
+
+func TestRunMappabilityReportsMappableLoci(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "repeat.fa")
+	repeat := "GAATTCAGCGCGATCAGTCCAGCATGCAGGTCAGCACGCTAGGTTAA"
+	if err := os.WriteFile(fastaPath, []byte(">chr1\nCCGCACCAGT"+repeat+"GCAGCGCCAGGCA"+repeat+"GGCAGCCAGC\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--min", "1",
+		"--max", "100",
+		"--score-min", "1",
+		"--score-max", "100",
+		"--size-model", "hard",
+		"--pct", "50",
+		"--depth", "10",
+		"--samples", "1",
+		"--read-length", "12",
+		"--lane-read-pairs", "1000",
+		"--mappability",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+	if err != nil {
+		t.Fatalf("read JSON: %v", err)
+	}
+	var report struct {
+		Digest struct {
+			Mappability bool `json:"mappability"`
+		} `json:"digest_parameters"`
+		Results []struct {
+			WeightedFragments float64 `json:"weighted_fragments"`
+			MappableLoci      float64 `json:"mappable_loci"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parse JSON: %v", err)
+	}
+	if !report.Digest.Mappability || len(report.Results) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	got := report.Results[0]
+	if got.MappableLoci != 1 || got.WeightedFragments != 3 {
+		t.Fatalf("only the fragment between the repeats should be mappable: %+v", got)
+	}
+}
+
+func TestRunPCRCyclesReportsAmplifiedTotals(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	if err := os.WriteFile(fastaPath, []byte(">chr1\nCCGCGAATTCGCGCGCTTAAGGATATGAATTCATATATTAAGG\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--min", "1",
+		"--max", "100",
+		"--score-min", "1",
+		"--score-max", "100",
+		"--size-model", "hard",
+		"--pct", "50",
+		"--depth", "10",
+		"--samples", "1",
+		"--read-length", "12",
+		"--lane-read-pairs", "1000",
+		"--pcr-cycles", "12",
+		"--pcr-gc-width", "0.1",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+	if err != nil {
+		t.Fatalf("read JSON: %v", err)
+	}
+	var report struct {
+		Digest struct {
+			PCR *struct {
+				Cycles  int     `json:"cycles"`
+				GCWidth float64 `json:"gc_width"`
+			} `json:"pcr"`
+		} `json:"digest_parameters"`
+		Results []struct {
+			WeightedFragments  float64 `json:"weighted_fragments"`
+			WeightedBases      float64 `json:"weighted_bases"`
+			AmplifiedFragments float64 `json:"amplified_fragments"`
+			AmplifiedBases     float64 `json:"amplified_bases"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parse JSON: %v", err)
+	}
+	if report.Digest.PCR == nil || report.Digest.PCR.Cycles != 12 || report.Digest.PCR.GCWidth != 0.1 || len(report.Results) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	got := report.Results[0]
+	if got.WeightedFragments == 0 || !(got.AmplifiedFragments > 0 && got.AmplifiedFragments < got.WeightedFragments) || !(got.AmplifiedBases > 0 && got.AmplifiedBases < got.WeightedBases) {
+		t.Fatalf("amplified totals should be positive and below the size-weighted totals: %+v", got)
+	}
+
+	tsv, err := os.ReadFile(filepath.Join(outDir, "design.tsv"))
+	if err != nil {
+		t.Fatalf("read TSV: %v", err)
+	}
+	if !strings.Contains(string(tsv), "\tamplified_fragments\tamplified_bases\t") {
+		t.Fatalf("design TSV missing amplified columns:\n%s", tsv)
+	}
+}
+
+func TestRunMinCallDepthReportsCallableLoci(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	if err := os.WriteFile(fastaPath, []byte(">chr1\nCCGCGAATTCGCGCGCTTAAGGATATGAATTCATATATTAAGG\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	args := []string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--min", "1",
+		"--max", "100",
+		"--score-min", "1",
+		"--score-max", "100",
+		"--size-model", "hard",
+		"--pct", "50",
+		"--depth", "10",
+		"--samples", "1",
+		"--read-length", "12",
+		"--lane-read-pairs", "30",
+		"--pcr-cycles", "12",
+		"--pcr-gc-width", "0.1",
+		"--min-call-depth", "8",
+		"--depth-dispersion", "0.2",
+		"--objective", "callable-loci",
+		"--jobs", "1",
+	}
+
+	type result struct {
+		WeightedFragments float64 `json:"weighted_fragments"`
+		CallableFraction  float64 `json:"callable_fraction"`
+		CallableLoci      float64 `json:"callable_loci"`
+	}
+	runWith := func(name string, extra ...string) result {
+		t.Helper()
+		outDir := filepath.Join(dir, name)
+		var stdout, stderr bytes.Buffer
+		if err := run(append(append(args, "--out-dir", outDir), extra...), &stdout, &stderr); err != nil {
+			t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+		if err != nil {
+			t.Fatalf("read JSON: %v", err)
+		}
+		var report struct {
+			Sequencing struct {
+				MinCallDepth    int     `json:"min_call_depth"`
+				DepthDispersion float64 `json:"depth_dispersion"`
+			} `json:"sequencing_budget"`
+			Results []result `json:"results"`
+		}
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatalf("parse JSON: %v", err)
+		}
+		if report.Sequencing.MinCallDepth != 8 || report.Sequencing.DepthDispersion != 0.2 || len(report.Results) != 1 {
+			t.Fatalf("unexpected report: %s", data)
+		}
+		return report.Results[0]
+	}
+
+	uniform := runWith("uniform")
+	if !(uniform.CallableFraction > 0 && uniform.CallableFraction < 1) || math.Abs(uniform.CallableLoci-uniform.CallableFraction*uniform.WeightedFragments) > 1e-9 {
+		t.Fatalf("uniform callable loci wrong: %+v", uniform)
+	}
+	weighted := runWith("pcr", "--depth-weights", "pcr")
+	if weighted.CallableFraction == uniform.CallableFraction {
+		t.Fatalf("pcr depth weights should change the callable fraction: %+v vs %+v", weighted, uniform)
+	}
+
+	var stdout, stderr bytes.Buffer
+	bad := []string{"--fasta", fastaPath, "--enzymes", "EcoRI,MseI", "--pct", "50", "--depth", "10", "--samples", "1", "--read-length", "12", "--lane-read-pairs", "30", "--objective", "callable-loci", "--out-dir", filepath.Join(dir, "bad")}
+	var usage usageError
+	if err := run(bad, &stdout, &stderr); !errors.As(err, &usage) {
+		t.Fatalf("callable-loci without --min-call-depth: got %v, want usage error", err)
+	}
+}
+
+func TestRunPresentPctConstrainsRanking(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	if err := os.WriteFile(fastaPath, []byte(">chr1\nCCGCGAATTCGCGCGCTTAAGGATATGAATTCATATATTAAGG\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--min", "1",
+		"--max", "100",
+		"--score-min", "1",
+		"--score-max", "100",
+		"--size-model", "hard",
+		"--pct", "50",
+		"--coverage-tolerance-pct", "100",
+		"--depth", "10",
+		"--samples", "10",
+		"--read-length", "12",
+		"--lane-read-pairs", "600",
+		"--min-call-depth", "8",
+		"--sample-cv", "0.4",
+		"--present-pct", "80",
+		"--min-present-loci", "3",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	f, err := os.Open(filepath.Join(outDir, "design.tsv"))
+	if err != nil {
+		t.Fatalf("open TSV: %v", err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comma = '\t'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("read TSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("TSV rows = %d, want header and one pair", len(rows))
+	}
+	col := map[string]string{}
+	for i, name := range rows[0] {
+		col[name] = rows[1][i]
+	}
+	if col["present_pct"] != "80.000000" || col["min_present_loci"] != "3.000000" || col["feasible"] != "false" {
+		t.Fatalf("unexpected present-loci columns: %v", col)
+	}
+	if !strings.Contains(col["decision_reason"], "present loci shortfall") {
+		t.Fatalf("decision reason %q should name the present-loci shortfall", col["decision_reason"])
+	}
+	frac, err := strconv.ParseFloat(col["present_fraction"], 64)
+	if err != nil || !(frac > 0 && frac < 1) {
+		t.Fatalf("present_fraction = %q, want a value in (0,1)", col["present_fraction"])
+	}
+}
+
+func TestRunMinCostReportsOptimalLanes(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	if err := os.WriteFile(fastaPath, []byte(">chr1\nCCGCGAATTCGCGCGCTTAAGGATATGAATTCATATATTAAGG\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+	args := []string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--min", "1",
+		"--max", "100",
+		"--score-min", "1",
+		"--score-max", "100",
+		"--size-model", "hard",
+		"--pct", "50",
+		"--coverage-tolerance-pct", "100",
+		"--depth", "10",
+		"--samples", "10",
+		"--read-length", "12",
+		"--lane-read-pairs", "100",
+		"--objective", "min-cost",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}
+
+	var stdout, stderr bytes.Buffer
+	var usage usageError
+	if err := run(args, &stdout, &stderr); !errors.As(err, &usage) {
+		t.Fatalf("min-cost without prices: got %v, want usage error", err)
+	}
+	args = append(args, "--cost-per-lane", "1000", "--cost-per-sample", "25", "--cost-per-enzyme-unit", "0.5")
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+	if err != nil {
+		t.Fatalf("read JSON: %v", err)
+	}
+	var report struct {
+		Sequencing struct {
+			Cost *design.CostSchedule `json:"cost"`
+		} `json:"sequencing_budget"`
+		Results []design.Candidate `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parse JSON: %v", err)
+	}
+	if report.Sequencing.Cost == nil || report.Sequencing.Cost.EnzymeUnitsPerSample != design.DefaultEnzymeUnitsPerSample || len(report.Results) != 1 {
+		t.Fatalf("unexpected report: %s", data)
+	}
+	// 10 samples at 10x over 3 loci need 30 pairs each. Lanes of 100 hold 3
+	// such samples, so 3 lanes pooling 300 pairs fall short: 4 lanes of 3.
+	got := report.Results[0]
+	if got.OptimalLanes != 4 || got.OptimalSamplesPerLane != 3 || got.OptimalCost != 4*1000+10*(25+2*10*0.5) || !got.OptimalFeasible {
+		t.Fatalf("unexpected optimal configuration: %+v", got)
+	}
+	if got.TotalCost != 1000+10*35 || got.CostPerSample != got.TotalCost/10 {
+		t.Fatalf("unexpected budget cost: %+v", got)
+	}
+	// Without --min-call-depth there are no callable loci to price.
+	rows := readTSV(t, filepath.Join(outDir, "design.tsv"))
+	if cell := rows[1][indexHeader(rows[0])["cost_per_callable_locus"]]; cell != "" {
+		t.Fatalf("cost_per_callable_locus = %q without --min-call-depth, want blank", cell)
+	}
+}
+
+func TestRunReportsParetoFront(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	if err := os.WriteFile(fastaPath, []byte(">ecori_msei_double\nAAAAGAATTCTTAAAGAATTCTTT\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI,PstI",
+		"--min", "1",
+		"--max", "100",
+		"--score-min", "1",
+		"--score-max", "100",
+		"--size-model", "hard",
+		"--target-genome-pct", "45.833333",
+		"--desired-depth", "10",
+		"--samples", "1",
+		"--read-length", "150",
+		"--lane-read-pairs", "1000",
+		"--cost-per-lane", "1000",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+
+	rows := readTSV(t, filepath.Join(outDir, "design.tsv"))
+	header := indexHeader(rows[0])
+	onFront := 0
+	for _, row := range rows[1:] {
+		rank, err := strconv.Atoi(row[header["pareto_rank"]])
+		if err != nil || rank < 1 {
+			t.Fatalf("pareto_rank = %q, want a rank >= 1", row[header["pareto_rank"]])
+		}
+		if rank == 1 {
+			onFront++
+		}
+	}
+	if onFront == 0 {
+		t.Fatal("no candidate is on the first Pareto front")
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+	if err != nil {
+		t.Fatalf("read JSON: %v", err)
+	}
+	var report struct {
+		Pareto struct {
+			Criteria []string `json:"criteria"`
+			Fronts   int      `json:"fronts"`
+			Front    []struct {
+				Enzymes []string  `json:"enzymes"`
+				Values  []float64 `json:"values"`
+			} `json:"front"`
+		} `json:"pareto"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parse JSON: %v", err)
+	}
+	p := report.Pareto
+	if strings.Join(p.Criteria, ",") != "coverage_error_rel,depth_margin,insert_penalty,optimal_cost" || p.Fronts < 1 || len(p.Front) != onFront {
+		t.Fatalf("unexpected Pareto summary: %+v", p)
+	}
+	for _, point := range p.Front {
+		if len(point.Enzymes) != 2 || len(point.Values) != len(p.Criteria) {
+			t.Fatalf("malformed front point: %+v", point)
+		}
+	}
+
+	reportRows := readKeyValueReport(t, filepath.Join(outDir, "design.report.txt"))
+	if reportRows["pareto_front_size"] != strconv.Itoa(onFront) || !strings.Contains(reportRows["pareto_front.1"], "coverage_error_rel=") {
+		t.Fatalf("report missing Pareto view: %+v", reportRows)
+	}
+}
+
+func TestRunSearchesSizeWindowPerPair(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	// EcoRI-MseI inserts of 6 and 5 bp; only the 5-bp window hits 5/24.
+	if err := os.WriteFile(fastaPath, []byte(">ecori_msei_double\nAAAAGAATTCTTAAAGAATTCTTT\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+	args := []string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--size-model", "hard",
+		"--target-genome-pct", "20.833333",
+		"--coverage-tolerance-pct", "1",
+		"--desired-depth", "10",
+		"--samples", "1",
+		"--read-length", "150",
+		"--lane-read-pairs", "1000",
+		"--search-min", "5:6:1",
+		"--search-max", "5,6",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "size_windows_searched\t3\n") {
+		t.Fatalf("stderr missing searched windows:\n%s", stderr.String())
+	}
+	rows := readTSV(t, filepath.Join(outDir, "design.tsv"))
+	header := indexHeader(rows[0])
+	row := rows[1]
+	if row[header["size_min_bp"]] != "5" || row[header["size_max_bp"]] != "5" || row[header["windows_searched"]] != "3" || row[header["feasible"]] != "true" {
+		t.Fatalf("best window = %s-%s of %s (feasible %s), want 5-5 of 3", row[header["size_min_bp"]], row[header["size_max_bp"]], row[header["windows_searched"]], row[header["feasible"]])
+	}
+
+	var usage usageError
+	bad := append(append([]string(nil), args...), "--search-mean", "5:6:1", "--force")
+	if err := run(bad, &stdout, &stderr); !errors.As(err, &usage) {
+		t.Fatalf("--search-mean with a hard window: got %v, want usage error", err)
+	}
+	triangular := append(append([]string(nil), args...), "--size-model", "triangular", "--min", "5", "--max", "6", "--size-mean", "5.5", "--search-mean", "5.5", "--force")
+	if err := run(triangular, &stdout, &stderr); !errors.As(err, &usage) || !strings.Contains(err.Error(), "window 5-5 bp (mean 5.5)") {
+		t.Fatalf("triangular mean outside a searched window: got %v, want usage error naming it", err)
+	}
+}
+
+func TestRunSweepWritesTidyTSV(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	// One EcoRI-MseI insert in the 5-bp window: 1000 read pairs give 1000
+	// pairs per locus for one sample and 5 for 200.
+	if err := os.WriteFile(fastaPath, []byte(">ecori_msei_double\nAAAAGAATTCTTAAAGAATTCTTT\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+	args := []string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--size-model", "hard",
+		"--min", "5",
+		"--max", "5",
+		"--target-genome-pct", "20.833333",
+		"--coverage-tolerance-pct", "1",
+		"--desired-depth", "10",
+		"--samples", "1",
+		"--read-length", "150",
+		"--lane-read-pairs", "1000",
+		"--sweep-samples", "1,200",
+		"--sweep-usable-fraction", "0.5,1",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "sweep_points\t4\tpairs\t") {
+		t.Fatalf("stderr missing sweep points:\n%s", stderr.String())
+	}
+	rows := readTSV(t, filepath.Join(outDir, "design.sweep.tsv"))
+	header := indexHeader(rows[0])
+	feasible := map[string]string{}
+	for _, row := range rows[1:] {
+		if row[header["enzyme_a"]] != "EcoRI" || row[header["enzyme_b"]] != "MseI" {
+			continue
+		}
+		key := row[header["usable_read_fraction"]] + "/" + row[header["samples"]]
+		feasible[key] = row[header["feasible"]]
+	}
+	want := map[string]string{
+		"0.500000/1":   "true",
+		"0.500000/200": "false",
+		"1.000000/1":   "true",
+		"1.000000/200": "false",
+	}
+	if !reflect.DeepEqual(feasible, want) {
+		t.Fatalf("EcoRI-MseI feasibility by point = %v, want %v", feasible, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+	if err != nil {
+		t.Fatalf("read JSON: %v", err)
+	}
+	var report designReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if report.Sweep == nil || report.Sweep.Points != 4 || report.Outputs.SweepTSV == "" {
+		t.Fatalf("sweep summary = %+v", report.Sweep)
+	}
+	var stability *design.SweepStability
+	for i, st := range report.Sweep.Stability {
+		if reflect.DeepEqual(st.Enzymes, []string{"EcoRI", "MseI"}) {
+			stability = &report.Sweep.Stability[i]
+		}
+	}
+	if stability == nil || stability.FeasiblePoints != 2 || stability.FeasibleRegion == nil || stability.FeasibleRegion.Max.Samples != 1 || stability.FeasibleRegion.Min.UsableReadFraction != 0.5 {
+		t.Fatalf("EcoRI-MseI stability = %+v", stability)
+	}
+
+	var usage usageError
+	bad := append(append([]string(nil), args...), "--sweep-size-sd", "20,40", "--force")
+	if err := run(bad, &stdout, &stderr); !errors.As(err, &usage) {
+		t.Fatalf("--sweep-size-sd with a hard window: got %v, want usage error", err)
+	}
+
+	// A triangular mean of 9 bp lies outside the 5-6 bp window: that point is
+	// skipped for the pair instead of failing the sweep.
+	triangularDir := filepath.Join(dir, "triangular")
+	triangular := []string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--size-model", "triangular",
+		"--min", "5",
+		"--max", "6",
+		"--size-mean", "5.5",
+		"--target-genome-pct", "20.833333",
+		"--desired-depth", "10",
+		"--samples", "1",
+		"--read-length", "150",
+		"--lane-read-pairs", "1000",
+		"--sweep-size-mean", "5.5,9",
+		"--out-dir", triangularDir,
+		"--jobs", "1",
+	}
+	if err := run(triangular, &stdout, &stderr); err != nil {
+		t.Fatalf("sweep with an out-of-window mean: %v", err)
+	}
+	rows = readTSV(t, filepath.Join(triangularDir, "design.sweep.tsv"))
+	header = indexHeader(rows[0])
+	skippedRows := 0
+	for _, row := range rows[1:] {
+		if row[header["size_mean_bp"]] != "9.000000" {
+			continue
+		}
+		skippedRows++
+		if row[header["feasible"]] != "false" || !strings.Contains(row[header["decision_reason"]], "invalid in 5-6 bp window") || row[header["fit_loss"]] != "" {
+			t.Fatalf("out-of-window point row = %v", row)
+		}
+	}
+	if skippedRows == 0 {
+		t.Fatal("sweep TSV has no rows for the out-of-window mean")
+	}
+}
+
+func TestRunSizeSelectsLibraryLength(t *testing.T) {
+	dir := t.TempDir()
+	fastaPath := filepath.Join(dir, "toy.fa")
+	// EcoRI-MseI inserts of 6 and 5 bp; only the 5-bp one is a 125-bp
+	// library molecule with 120 bp of adapters.
+	if err := os.WriteFile(fastaPath, []byte(">ecori_msei_double\nAAAAGAATTCTTAAAGAATTCTTT\n"), 0o644); err != nil {
+		t.Fatalf("write FASTA: %v", err)
+	}
+	outDir := filepath.Join(dir, "design")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--fasta", fastaPath,
+		"--enzymes", "EcoRI,MseI",
+		"--min", "125",
+		"--max", "125",
+		"--score-min", "120",
+		"--score-max", "130",
+		"--size-model", "hard",
+		"--adapter-p1-length", "60",
+		"--adapter-p2-length", "60",
+		"--pct", "20",
+		"--depth", "10",
+		"--samples", "1",
+		"--read-length", "150",
+		"--lane-read-pairs", "1000",
+		"--out-dir", outDir,
+		"--jobs", "1",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "library_flanks_bp\t120\n") {
+		t.Fatalf("stderr missing library flanks:\n%s", stderr.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "design.json"))
+	if err != nil {
+		t.Fatalf("read JSON: %v", err)
+	}
+	var report struct {
+		Digest struct {
+			AdapterP1Length int `json:"adapter_p1_length"`
+			AdapterP2Length int `json:"adapter_p2_length"`
+		} `json:"digest_parameters"`
+		Results []struct {
+			WeightedFragments float64 `json:"weighted_fragments"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("parse JSON: %v", err)
+	}
+	if report.Digest.AdapterP1Length != 60 || report.Digest.AdapterP2Length != 60 || len(report.Results) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if got := report.Results[0].WeightedFragments; got != 1 {
+		t.Fatalf("weighted fragments = %g, want only the 5-bp insert", got)
+	}
+}
+
+func TestRunRejectsEffectiveLociWithoutDuplicates(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"--fasta", "ref.fa",
+		"--enzymes", "EcoRI,MseI",
+		"--pct", "2.5",
+		"--depth", "10",
+		"--samples", "1",
+		"--read-length", "150",
+		"--lane-read-pairs", "1000",
+		"--depth-denominator", "effective-unique-loci",
+	}, &stdout, &stderr)
+	if err == nil || exitCode(err) != 2 {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
 func TestParsePositiveCount(t *testing.T) {
 	got, err := parsePositiveCount("300M")
 	if err != nil {
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/enzyme"
+	"github.com/ericksamera/radigest/internal/fragdiff"
+)
+
+var version = "dev"
+
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// side describes where one set of fragments comes from: a fragment TSV/BED
+// file, or a FASTA digested with its own enzymes and size window.
+type side struct {
+	name    string
+	path    string
+	fasta   string
+	enzymes string
+	min     int
+	max     int
+}
+
+type sideJSON struct {
+	Fragments string   `json:"fragments,omitempty"`
+	FASTA     string   `json:"fasta,omitempty"`
+	Enzymes   []string `json:"enzymes,omitempty"`
+	MinLength int      `json:"min_length,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+}
+
+type diffJSON struct {
+	SchemaVersion   int              `json:"schema_version"`
+	RadigestVersion string           `json:"radigest_version,omitempty"`
+	Command         []string         `json:"command,omitempty"`
+	A               sideJSON         `json:"a"`
+	B               sideJSON         `json:"b"`
+	Match           fragdiff.Mode    `json:"match"`
+	MinOverlap      float64          `json:"min_overlap,omitempty"`
+	Flank           int              `json:"flank,omitempty"`
+	Summary         fragdiff.Summary `json:"summary"`
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		code := 1
+		var usage usageError
+		if errors.As(err, &usage) {
+			code = 2
+		}
+		_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(code)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	fs := flag.NewFlagSet("radigest-diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var a, b side
+	a.name, b.name = "a", "b"
+	fs.StringVar(&a.path, "a", "", "fragment TSV or BED for set A (from radigest -fragments-tsv or -bed)")
+	fs.StringVar(&b.path, "b", "", "fragment TSV or BED for set B")
+	fs.StringVar(&a.fasta, "a-fasta", "", "FASTA for set A: digested when -a is not set, otherwise read for -match flank keys")
+	fs.StringVar(&b.fasta, "b-fasta", "", "FASTA for set B: digested when -b is not set, otherwise read for -match flank keys")
+	fs.StringVar(&a.enzymes, "enzymes", "", "one or two comma-separated enzymes used to digest -a-fasta")
+	fs.StringVar(&b.enzymes, "b-enzymes", "", "enzymes used to digest -b-fasta (default: -enzymes)")
+	fs.IntVar(&a.min, "min", 300, "minimum fragment length (bp) when digesting -a-fasta")
+	fs.IntVar(&a.max, "max", 600, "maximum fragment length (bp) when digesting -a-fasta")
+	fs.IntVar(&b.min, "b-min", -1, "minimum fragment length when digesting -b-fasta (default: -min)")
+	fs.IntVar(&b.max, "b-max", -1, "maximum fragment length when digesting -b-fasta (default: -max)")
+	allowSame := fs.Bool("allow-same", false, "double digest: also keep AA/BB neighbors (default AB/BA only)")
+	includeEnds := fs.Bool("include-ends", false, "also keep terminal fragments from contig ends to nearest cut")
+	matchFlag := fs.String("match", "overlap", "match fragments by coordinate overlap or by flanking cut-site sequence: overlap or flank")
+	minOverlap := fs.Float64("min-overlap", fragdiff.DefaultMinOverlap, "minimum overlap as a fraction of the longer fragment for -match overlap")
+	flank := fs.Int("flank", 20, "bases read from each fragment end for -match flank")
+	tsvPath := fs.String("tsv", "", "write per-locus shared/lost/gained rows to PATH ('-' = stdout)")
+	jsonPath := fs.String("json", "", "write the JSON summary to PATH ('-' = stdout; default when -tsv is not set)")
+	showVersion := fs.Bool("version", false, "print version and exit")
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "radigest-diff — compare the loci of two digests")
+		_, _ = fmt.Fprintln(stderr)
+		_, _ = fmt.Fprintln(stderr, "Usage:")
+		_, _ = fmt.Fprintln(stderr, "  radigest-diff -a run1.fragments.tsv -b run2.fragments.tsv [options]")
+		_, _ = fmt.Fprintln(stderr, "  radigest-diff -a-fasta old.fa -b-fasta new.fa -enzymes EcoRI,MseI -match flank [options]")
+		_, _ = fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return usageError{err: err}
+	}
+	if *showVersion {
+		_, err := fmt.Fprintf(stdout, "radigest-diff %s\n", version)
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError{err: fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))}
+	}
+	mode, err := fragdiff.ParseMode(*matchFlag)
+	if err != nil {
+		return usageError{err: err}
+	}
+	if b.enzymes == "" {
+		b.enzymes = a.enzymes
+	}
+	if b.min < 0 {
+		b.min = a.min
+	}
+	if b.max < 0 {
+		b.max = a.max
+	}
+	for _, s := range []side{a, b} {
+		if err := s.validate(mode); err != nil {
+			return usageError{err: err}
+		}
+	}
+	if mode == fragdiff.ModeOverlap && (*minOverlap <= 0 || *minOverlap > 1) {
+		return usageError{err: fmt.Errorf("-min-overlap must be in (0,1] (got %g)", *minOverlap)}
+	}
+	if mode == fragdiff.ModeFlank && *flank < 1 {
+		return usageError{err: fmt.Errorf("-flank must be >= 1 (got %d)", *flank)}
+	}
+	if *tsvPath == "-" && *jsonPath == "-" {
+		return usageError{err: errors.New("-tsv and -json cannot both write to stdout")}
+	}
+	if *tsvPath == "" && *jsonPath == "" {
+		*jsonPath = "-"
+	}
+
+	opt := digest.Options{AllowSame: *allowSame, IncludeEnds: *includeEnds}
+	keyFlank := 0
+	if mode == fragdiff.ModeFlank {
+		keyFlank = *flank
+	}
+	lociA, infoA, err := a.load(opt, keyFlank)
+	if err != nil {
+		return err
+	}
+	lociB, infoB, err := b.load(opt, keyFlank)
+	if err != nil {
+		return err
+	}
+
+	result := fragdiff.Compare(lociA, lociB, fragdiff.Options{Mode: mode, MinOverlap: *minOverlap})
+	summary := result.Summary()
+	if _, err := fmt.Fprintf(stderr, "shared\t%d\nlost\t%d\ngained\t%d\n", summary.Shared, summary.Lost, summary.Gained); err != nil {
+		return err
+	}
+
+	tsv, err := fragdiff.NewTo(*tsvPath, stdout)
+	if err != nil {
+		return err
+	}
+	if err := tsv.WriteResult(result); err != nil {
+		_ = tsv.Close()
+		return err
+	}
+	if err := tsv.Close(); err != nil {
+		return err
+	}
+
+	if *jsonPath == "" {
+		return nil
+	}
+	doc := diffJSON{
+		SchemaVersion:   1,
+		RadigestVersion: version,
+		Command:         append([]string{"radigest-diff"}, args...),
+		A:               infoA,
+		B:               infoB,
+		Match:           mode,
+		Summary:         summary,
+	}
+	if mode == fragdiff.ModeOverlap {
+		doc.MinOverlap = *minOverlap
+	} else {
+		doc.Flank = *flank
+	}
+	return writeJSON(*jsonPath, stdout, doc)
+}
+
+func (s side) validate(mode fragdiff.Mode) error {
+	switch {
+	case s.path == "" && s.fasta == "":
+		return fmt.Errorf("set -%s or -%s-fasta", s.name, s.name)
+	case s.path == "" && s.enzymes == "":
+		return fmt.Errorf("-enzymes is required to digest -%s-fasta", s.name)
+	case mode == fragdiff.ModeFlank && s.fasta == "":
+		return fmt.Errorf("-match flank needs -%s-fasta to read the flanks of -%s", s.name, s.name)
+	case mode == fragdiff.ModeFlank && s.enzymes == "":
+		return errors.New("-match flank needs -enzymes to locate the cut sites")
+	case s.path == "" && (s.min < 0 || s.max < s.min):
+		return fmt.Errorf("invalid size window for set %s: min=%d max=%d", strings.ToUpper(s.name), s.min, s.max)
+	}
+	return nil
+}
+
+// load reads or digests the fragments of s. When flank > 0 every locus is
+// keyed by its flanking sequence.
+func (s side) load(opt digest.Options, flank int) ([]fragdiff.Locus, sideJSON, error) {
+	info := sideJSON{Fragments: s.path, FASTA: s.fasta}
+	var plan digest.Plan
+	if s.enzymes != "" && (s.path == "" || flank > 0) {
+		ens, names, err := parseEnzymes(s.enzymes)
+		if err != nil {
+			return nil, info, usageError{err: err}
+		}
+		if plan, err = digest.TryNewPlanWithOptions(ens, opt); err != nil {
+			return nil, info, usageError{err: err}
+		}
+		info.Enzymes = names
+	}
+	if s.path == "" {
+		info.MinLength, info.MaxLength = s.min, s.max
+		loci, err := fragdiff.Digest(s.fasta, plan, s.min, s.max, flank)
+		return loci, info, err
+	}
+
+	loci, err := fragdiff.Read(s.path)
+	if err != nil {
+		return nil, info, err
+	}
+	if flank > 0 {
+		if err := fragdiff.AddKeys(loci, s.fasta, flank, plan); err != nil {
+			return nil, info, err
+		}
+	}
+	return loci, info, nil
+}
+
+func parseEnzymes(value string) ([]enzyme.Enzyme, []string, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) > 2 {
+		return nil, nil, fmt.Errorf("invalid enzymes %q: specify one or two enzymes", value)
+	}
+	ens := make([]enzyme.Enzyme, 0, len(parts))
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		name := strings.TrimSpace(part)
+		e, ok := enzyme.DB[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown enzyme %q", name)
+		}
+		ens = append(ens, e)
+		names = append(names, e.Name)
+	}
+	if len(ens) == 2 && ens[0].Name == ens[1].Name {
+		return nil, nil, fmt.Errorf("enzymes must differ (got %s,%s)", ens[0].Name, ens[1].Name)
+	}
+	return ens, names, nil
+}
+
+func writeJSON(path string, stdout io.Writer, doc diffJSON) error {
+	var w io.Writer = stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
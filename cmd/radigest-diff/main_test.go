@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunFlankMatchesAcrossReverseComplementedAssembly(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.fa")
+	newPath := filepath.Join(dir, "new.fa")
+	// EcoRI/MseI fragments 5-13, 13-20 and 20-33; the new assembly is
+	// reverse complemented and loses the last MseI site.
+	if err := os.WriteFile(oldPath, []byte(">ctg1\nCCCCGAATTCCCTTAACCCGAATTCGGGGGGGTTAACCC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newPath, []byte(">scaffold1\nGGGTGAACCCCCCCGAATTCGGGTTAAGGGAATTCGGGG\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tsvPath := filepath.Join(dir, "diff.tsv")
+
+	var stdout, stderr bytes.Buffer
+	err := run([]string{
+		"-a-fasta", oldPath, "-b-fasta", newPath,
+		"-enzymes", "EcoRI,MseI", "-min", "1", "-max", "100",
+		"-match", "flank", "-flank", "6", "-tsv", tsvPath, "-json", "-",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+	var doc diffJSON
+	if err := json.Unmarshal(stdout.Bytes(), &doc); err != nil {
+		t.Fatalf("decode JSON: %v\n%s", err, stdout.String())
+	}
+	if s := doc.Summary; s.Shared != 2 || s.Lost != 1 || s.Gained != 0 {
+		t.Fatalf("summary = %+v", s)
+	}
+	data, err := os.ReadFile(tsvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "shared\tctg1\t5\t13\t8\tscaffold1\t24\t30\t6\t-2\t0\n"; !strings.Contains(string(data), want) {
+		t.Fatalf("TSV missing %q:\n%s", want, data)
+	}
+}
+
+func TestRunOverlapComparesFragmentFiles(t *testing.T) {
+	dir := t.TempDir()
+	aPath := filepath.Join(dir, "a.bed")
+	bPath := filepath.Join(dir, "b.tsv")
+	if err := os.WriteFile(aPath, []byte("chr1\t100\t200\tchr1_1\t0\t+\nchr1\t300\t400\tchr1_2\t0\t+\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bPath, []byte("chrom\tstart0\tend0\tlength\thard_kept\nchr1\t100\t210\t110\ttrue\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"-a", aPath, "-b", bPath, "-tsv", "-"}, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "shared\tchr1\t100\t200\t100\tchr1\t100\t210\t110\t10\t") || !strings.HasPrefix(lines[2], "lost\tchr1\t300") {
+		t.Fatalf("TSV:\n%s", stdout.String())
+	}
+}
+
+func TestRunRejectsFlankWithoutFASTA(t *testing.T) {
+	err := run([]string{"-a", "a.bed", "-b", "b.bed", "-match", "flank"}, nil, nil)
+	var usage usageError
+	if !errors.As(err, &usage) {
+		t.Fatalf("run() error = %v, want usage error", err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/sizefit"
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+var version = "dev"
+
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		code := 1
+		var usage usageError
+		if errors.As(err, &usage) {
+			code = 2
+		}
+		_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(code)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	fs := flag.NewFlagSet("radigest-fit-size-model", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fragmentsPath := fs.String("fragments", "", "radigest fragment TSV (plain or .gz) written with a wide -score-min/-score-max")
+	tlensPath := fs.String("tlens", "", "observed insert lengths, one per line; SAM TLEN signs are ignored ('-' = stdin)")
+	minLen := fs.Int("min", -1, "nominal lower bound (bp) of the hard size window")
+	maxLen := fs.Int("max", -1, "nominal upper bound (bp) of the hard size window")
+	scoreMin := fs.Int("score-min", 1, "shortest insert length (bp) included in the fit")
+	scoreMax := fs.Int("score-max", 2000, "longest insert length (bp) included in the fit")
+	curveBin := fs.Int("curve-bin", 25, "bin width (bp) of the fitted empirical curve; 0 skips the empirical model")
+	outPath := fs.String("out", "-", "write the ranked fits as TSV to PATH ('-' = stdout)")
+	configPath := fs.String("config", "", "write the chosen model as size-model JSON for -size-config to PATH ('-' = stdout)")
+	configModel := fs.String("config-model", "", "model to write with -config; default the lowest AIC")
+	showVersion := fs.Bool("version", false, "print version and exit")
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "radigest-fit-size-model — fit size-selection models to observed insert lengths by maximum likelihood")
+		_, _ = fmt.Fprintln(stderr)
+		_, _ = fmt.Fprintln(stderr, "Usage:")
+		_, _ = fmt.Fprintln(stderr, "  radigest-fit-size-model -fragments fragments.tsv -tlens tlens.txt -min 300 -max 600 -out fits.tsv -config size_model.json")
+		_, _ = fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return usageError{err: err}
+	}
+	if *showVersion {
+		_, err := fmt.Fprintf(stdout, "radigest-fit-size-model %s\n", version)
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError{err: fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))}
+	}
+	if *fragmentsPath == "" {
+		return usageError{err: errors.New("-fragments is required")}
+	}
+	if *tlensPath == "" {
+		return usageError{err: errors.New("-tlens is required")}
+	}
+	if *minLen < 0 || *maxLen < 0 {
+		return usageError{err: errors.New("-min and -max are required")}
+	}
+	if *maxLen < *minLen {
+		return usageError{err: fmt.Errorf("-max must be >= -min (got min=%d max=%d)", *minLen, *maxLen)}
+	}
+	if *scoreMin < 1 || *scoreMax < *scoreMin {
+		return usageError{err: fmt.Errorf("invalid score range: score-min=%d score-max=%d", *scoreMin, *scoreMax)}
+	}
+	if *curveBin < 0 {
+		return usageError{err: fmt.Errorf("-curve-bin must be >= 0 (got %d)", *curveBin)}
+	}
+	if *outPath == "" {
+		return usageError{err: errors.New("-out must not be empty")}
+	}
+	if *outPath == "-" && *configPath == "-" {
+		return usageError{err: errors.New("-out and -config cannot both write to stdout")}
+	}
+	if *configModel != "" && *configPath == "" {
+		return usageError{err: errors.New("-config-model requires -config")}
+	}
+
+	data, err := sizefit.NewData(*scoreMin, *scoreMax)
+	if err != nil {
+		return usageError{err: err}
+	}
+	if err := sizefit.ReadFragments(*fragmentsPath, data); err != nil {
+		return err
+	}
+	if err := sizefit.ReadTLENs(*tlensPath, stdin, data); err != nil {
+		return err
+	}
+	results, err := sizefit.Fit(data, sizefit.Options{Min: *minLen, Max: *maxLen, CurveBin: *curveBin})
+	if err != nil {
+		return err
+	}
+
+	chosen := results[0]
+	if *configModel != "" {
+		found := false
+		for _, r := range results {
+			if string(r.Model) == strings.ToLower(strings.TrimSpace(*configModel)) {
+				chosen, found = r, true
+			}
+		}
+		if !found {
+			return usageError{err: fmt.Errorf("-config-model %q was not fitted", *configModel)}
+		}
+	}
+
+	if err := writeTo(*outPath, stdout, func(w io.Writer) error { return sizefit.WriteTable(w, results) }); err != nil {
+		return err
+	}
+	if *configPath != "" {
+		if err := writeTo(*configPath, stdout, func(w io.Writer) error { return sizefit.WriteModelFile(w, chosen) }); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(stderr, "observed_pairs\t%d\nbest_model\t%s\n", int(data.ObservedTotal), describe(results[0]))
+	return err
+}
+
+func describe(r sizefit.Result) string {
+	if r.Model == sizeselect.ModelEmpirical {
+		return fmt.Sprintf("%s (%d curve points)", r.Model, len(r.Config.Curve))
+	}
+	parts := []string{string(r.Model)}
+	for _, p := range r.Params {
+		parts = append(parts, fmt.Sprintf("%s=%.2f", p.Name, p.Value))
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeTo(path string, stdout io.Writer, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+func TestRunWritesRankingAndConfig(t *testing.T) {
+	dir := t.TempDir()
+	fragmentsPath := filepath.Join(dir, "fragments.tsv")
+	configPath := filepath.Join(dir, "size_model.json")
+	var tsv, tlens strings.Builder
+	tsv.WriteString("chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\n")
+	for l := 100; l <= 700; l++ {
+		tsv.WriteString("chr1\t0\t" + strconv.Itoa(l) + "\t" + strconv.Itoa(l) + "\tfalse\t0\n")
+		if l >= 300 && l <= 500 {
+			for i := 0; i < 10-abs(l-400)/20; i++ {
+				tlens.WriteString("-" + strconv.Itoa(l) + "\n")
+			}
+		}
+	}
+	if err := os.WriteFile(fragmentsPath, []byte(tsv.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-fragments", fragmentsPath, "-tlens", "-", "-min", "250", "-max", "550", "-score-max", "1000", "-config", configPath, "-config-model", "normal"}
+	if err := run(args, strings.NewReader(tlens.String()), &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 10 || !strings.HasPrefix(lines[0], "rank\tmodel\tparams\tci95\tk\tlog_likelihood\taic\tdelta_aic\tks\t") {
+		t.Fatalf("ranking = %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "observed_pairs\t") || !strings.Contains(stderr.String(), "best_model\t") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+
+	cfg, err := sizeselect.Config{Min: 250, Max: 550, ScoreMin: 1, ScoreMax: 1000}.WithModelFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Model != sizeselect.ModelNormal || cfg.Mean < 390 || cfg.Mean > 410 || cfg.SD <= 0 {
+		t.Fatalf("loaded config = %+v", cfg)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func TestRunRequiresWindow(t *testing.T) {
+	err := run([]string{"-fragments", "fragments.tsv", "-tlens", "tlens.txt"}, nil, nil, nil)
+	var usage usageError
+	if !errors.As(err, &usage) || !strings.Contains(err.Error(), "-min and -max") {
+		t.Fatalf("run() error = %v, want usage error", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/liftover"
+)
+
+var version = "dev"
+
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		code := 1
+		var usage usageError
+		if errors.As(err, &usage) {
+			code = 2
+		}
+		_, _ = fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(code)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	fs := flag.NewFlagSet("radigest-liftover", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	chainPath := fs.String("chain", "", "UCSC chain file from the source to the destination assembly (plain or .gz)")
+	inPath := fs.String("in", "", "radigest BED, GFF3, or fragment TSV to lift ('-' = stdin)")
+	outPath := fs.String("out", "-", "write lifted fragments in the input format to PATH ('-' = stdout)")
+	unmappedPath := fs.String("unmapped", "", "write fragments that did not lift, with a reason, to PATH ('-' = stdout)")
+	formatFlag := fs.String("format", "auto", "input format: auto, bed, gff, or tsv")
+	minMatch := fs.Float64("min-match", liftover.DefaultMinMatch, "minimum fraction of a fragment's bases one chain must align")
+	showVersion := fs.Bool("version", false, "print version and exit")
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "radigest-liftover — lift radigest fragment coordinates through a chain file")
+		_, _ = fmt.Fprintln(stderr)
+		_, _ = fmt.Fprintln(stderr, "Usage:")
+		_, _ = fmt.Fprintln(stderr, "  radigest-liftover -chain old_to_new.over.chain.gz -in fragments.bed -out lifted.bed -unmapped unmapped.tsv")
+		_, _ = fmt.Fprintln(stderr)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return usageError{err: err}
+	}
+	if *showVersion {
+		_, err := fmt.Fprintf(stdout, "radigest-liftover %s\n", version)
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError{err: fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))}
+	}
+	if *chainPath == "" {
+		return usageError{err: errors.New("-chain is required")}
+	}
+	if *inPath == "" {
+		return usageError{err: errors.New("-in is required")}
+	}
+	if *outPath == "" {
+		return usageError{err: errors.New("-out must not be empty")}
+	}
+	if *outPath == "-" && *unmappedPath == "-" {
+		return usageError{err: errors.New("-out and -unmapped cannot both write to stdout")}
+	}
+	if *minMatch <= 0 || *minMatch > 1 {
+		return usageError{err: fmt.Errorf("-min-match must be in (0,1] (got %g)", *minMatch)}
+	}
+	format, err := liftover.ParseFormat(*formatFlag)
+	if err != nil {
+		return usageError{err: err}
+	}
+
+	chains, err := liftover.Read(*chainPath)
+	if err != nil {
+		return err
+	}
+	in, closeIn, err := liftover.Open(*inPath, stdin)
+	if err != nil {
+		return err
+	}
+	defer closeIn.Close()
+
+	var out io.Writer = stdout
+	var outFile *os.File
+	if *outPath != "-" {
+		if outFile, err = os.Create(*outPath); err != nil {
+			return err
+		}
+		out = outFile
+	}
+	unmapped, err := liftover.NewUnmappedTo(*unmappedPath, stdout)
+	if err != nil {
+		if outFile != nil {
+			_ = outFile.Close()
+		}
+		return err
+	}
+
+	stats, err := liftover.Rewrite(in, out, format, chains, *minMatch, unmapped.Write)
+	if closeErr := unmapped.Close(); err == nil {
+		err = closeErr
+	}
+	if outFile != nil {
+		if closeErr := outFile.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return writeStats(stderr, stats)
+}
+
+func writeStats(w io.Writer, stats liftover.Stats) error {
+	if _, err := fmt.Fprintf(w, "format\t%s\nlifted\t%d\n", stats.Format, stats.Lifted); err != nil {
+		return err
+	}
+	reasons := make([]string, 0, len(stats.Unmapped))
+	for reason := range stats.Unmapped {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		if _, err := fmt.Fprintf(w, "unmapped_%s\t%d\n", reason, stats.Unmapped[liftover.Reason(reason)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
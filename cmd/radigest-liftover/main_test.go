@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLiftsBEDAndReportsUnmapped(t *testing.T) {
+	dir := t.TempDir()
+	chainPath := filepath.Join(dir, "old_to_new.chain")
+	bedPath := filepath.Join(dir, "fragments.bed")
+	outPath := filepath.Join(dir, "lifted.bed")
+	chain := "chain 100 chr1 1000 + 0 100 chrA 800 - 100 200 1\n100\n"
+	if err := os.WriteFile(chainPath, []byte(chain), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bed := "chr1\t10\t40\tchr1_1\t0\t+\nchr1\t300\t350\tchr1_2\t0\t+\n"
+	if err := os.WriteFile(bedPath, []byte(bed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"-chain", chainPath, "-in", bedPath, "-out", outPath, "-unmapped", "-"}, nil, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v\nstderr:\n%s", err, stderr.String())
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "chrA\t660\t690\tchr1_1\t0\t-\n"; string(data) != want {
+		t.Fatalf("lifted BED = %q, want %q", data, want)
+	}
+	if want := "chr1_2\tchr1\t300\t350\tno_chain\t0\n"; !strings.Contains(stdout.String(), want) {
+		t.Fatalf("unmapped = %q, want row %q", stdout.String(), want)
+	}
+	if !strings.Contains(stderr.String(), "lifted\t1\n") || !strings.Contains(stderr.String(), "unmapped_no_chain\t1\n") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestRunRequiresChain(t *testing.T) {
+	err := run([]string{"-in", "fragments.bed"}, nil, nil, nil)
+	var usage usageError
+	if !errors.As(err, &usage) {
+		t.Fatalf("run() error = %v, want usage error", err)
+	}
+}
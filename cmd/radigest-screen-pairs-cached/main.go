@@ -87,10 +87,11 @@ func run(args []string, stdout, stderr io.Writer) error {
 	maxLen := fs.Int("max", 600, "maximum fragment length (bp) for hard size selection")
 	scoreMin := fs.Int("score-min", 1, "minimum fragment length included in size-selection scoring")
 	scoreMax := fs.Int("score-max", 2000, "maximum fragment length included in size-selection scoring")
-	sizeModel := fs.String("size-model", "normal", "size-selection model: hard, normal, triangular, or soft-window")
+	sizeModel := fs.String("size-model", "normal", "size-selection model: hard, normal, triangular, soft-window, or empirical")
 	sizeMean := fs.Float64("size-mean", 275, "target/peak insert length for normal/triangular models")
 	sizeSD := fs.Float64("size-sd", 85, "standard deviation for -size-model normal")
 	sizeEdgeSD := fs.Float64("size-edge-sd", 25, "edge softness for -size-model soft-window")
+	sizeCurve := fs.String("size-curve", "", "length-to-weight table (TSV or JSON) for -size-model empirical")
 	jobsFlag := fs.Int("jobs", 0, "parallel pair-scoring workers (default: -threads)")
 	threadsFlag := fs.Int("threads", runtime.NumCPU(), "worker count alias used when -jobs is not set")
 	buildWorkersFlag := fs.Int("build-workers", 0, "parallel cut-index build workers (default: --jobs, then --threads); scans candidate enzymes concurrently per FASTA record")
@@ -163,7 +164,7 @@ func run(args []string, stdout, stderr io.Writer) error {
 		return nil
 	}
 
-	selector, err := sizeselect.New(sizeselect.Config{
+	sizeConfig, err := sizeselect.Config{
 		Model:    sizeselect.Model(*sizeModel),
 		Min:      *minLen,
 		Max:      *maxLen,
@@ -172,7 +173,11 @@ func run(args []string, stdout, stderr io.Writer) error {
 		Mean:     *sizeMean,
 		SD:       *sizeSD,
 		EdgeSD:   *sizeEdgeSD,
-	})
+	}.WithCurve(*sizeCurve)
+	if err != nil {
+		return usageError{err: err}
+	}
+	selector, err := sizeselect.New(sizeConfig)
 	if err != nil {
 		return err
 	}
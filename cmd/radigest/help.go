@@ -45,10 +45,33 @@ func writeRadigestUsage(w io.Writer, version string) {
 			Items: []clihelp.Flag{
 				{Names: []string{"-score-min"}, Arg: "INT", Default: "-min", Text: "Lower insert-size bound included in size-selection scoring and fragment TSV output."},
 				{Names: []string{"-score-max"}, Arg: "INT", Default: "-max", Text: "Upper insert-size bound included in size-selection scoring and fragment TSV output."},
-				{Names: []string{"-size-model"}, Arg: "MODEL", Default: "hard", Text: "Size-selection/recovery weighting model: hard, normal, triangular, or soft-window."},
-				{Names: []string{"-size-mean"}, Arg: "FLOAT", Default: "midpoint of -min/-max", Text: "Peak/target insert length for normal and triangular models."},
-				{Names: []string{"-size-sd"}, Arg: "FLOAT", Default: "35", Text: "Standard deviation for the normal model."},
+				{Names: []string{"-size-model"}, Arg: "MODEL", Default: "hard", Text: "Size-selection/recovery weighting model: hard, normal, triangular, soft-window, log-normal, skew-normal, gamma, mixture, spri, or empirical."},
+				{Names: []string{"-size-mean"}, Arg: "FLOAT", Default: "midpoint of -min/-max", Text: "Peak/target insert length for normal and triangular models, mean insert length for log-normal and gamma, location for skew-normal, and the first component's mean for mixture."},
+				{Names: []string{"-size-sd"}, Arg: "FLOAT", Default: "35", Text: "Standard deviation for the normal, log-normal, gamma, and mixture (first component) models; scale for skew-normal. Gamma needs -size-sd below -size-mean."},
 				{Names: []string{"-size-edge-sd"}, Arg: "FLOAT", Default: "25", Text: "Edge softness for the soft-window model."},
+				{Names: []string{"-size-skew"}, Arg: "FLOAT", Default: "0", Text: "Skew-normal shape (alpha). Positive values lean right toward long inserts; 0 is the normal model."},
+				{Names: []string{"-size-mean2"}, Arg: "FLOAT", Text: "Mean of the second mixture component, such as a short-insert shoulder."},
+				{Names: []string{"-size-sd2"}, Arg: "FLOAT", Text: "Standard deviation of the second mixture component."},
+				{Names: []string{"-size-mix"}, Arg: "FLOAT", Text: "Share of the mixture's mass in the second component, in (0,1)."},
+				{Names: []string{"-size-curve"}, Arg: "PATH", Text: "Length-to-weight table for the empirical model, such as a Pippin or gel calibration: two columns (length, weight) or JSON [{\"length\":..,\"weight\":..}]. Weights are interpolated linearly, scaled so the peak is 1, and 0 outside the curve. The curve is recorded verbatim in JSON."},
+				{Names: []string{"-spri-left"}, Arg: "FLOAT", Text: "Left-side bead ratio for the spri model, such as 0.75. The larger ratio; fragments above its cutoff are kept."},
+				{Names: []string{"-spri-right"}, Arg: "FLOAT", Text: "Right-side bead ratio for the spri model, such as 0.55. Added first; fragments above its cutoff are bound and discarded."},
+				{Names: []string{"-spri-calibration"}, Arg: "PATH", Default: "built-in", Text: "Bead ratio to cutoff (bp) table for the spri model: two columns (ratio, cutoff) or JSON [{\"ratio\":..,\"cutoff\":..}]. Cutoffs are interpolated in log length; the built-in table approximates SPRIselect/AMPure XP guides."},
+				{Names: []string{"-size-config"}, Arg: "PATH", Text: "Size-model JSON written by radigest-fit-size-model -config. Replaces -size-model and its parameters; hard, triangular, and soft-window models must be used with the -min/-max they were fitted for."},
+				{Names: []string{"-adapter-p1-length"}, Arg: "INT", Default: "0", Text: "P1 adapter bases ligated to each insert before size selection. With any adapter or barcode length, -min/-max, the score range, and the size model apply to library length (insert + adapters + barcodes), while reported lengths stay insert lengths."},
+				{Names: []string{"-adapter-p2-length"}, Arg: "INT", Default: "0", Text: "P2 adapter bases ligated to the other end."},
+				{Names: []string{"-barcode-lengths"}, Arg: "LIST", Text: "Comma-separated combined inline-barcode lengths in the pool, such as 4,6,8. Weights average over them, but a fragment is kept, and counted in raw_fragments_in_window, if any length passes: under the hard model a fragment kept for one of four lengths counts once there with weight 0.25. Use -library instead to size molecules from a barcode sheet."},
+			},
+		},
+		{
+			Title: "PCR amplification bias",
+			Intro: []string{"Per-cycle PCR efficiency falls for long and GC-extreme fragments. With -pcr-cycles, each scored fragment gets an amplification weight ((1+e)/(1+efficiency))^cycles that multiplies its size weight in simulated reads, duplicate, mappability, composition, and variant summaries; the JSON size_selection reports amplified_fragments and amplified_bases beside the size-weighted totals."},
+			Items: []clihelp.Flag{
+				{Names: []string{"-pcr-cycles"}, Arg: "INT", Default: "0 (off)", Text: "Library PCR cycles. Adds an amplification_weight column to -fragments-tsv."},
+				{Names: []string{"-pcr-efficiency"}, Arg: "FLOAT", Default: "0.95", Text: "Per-cycle efficiency of a short fragment at the GC optimum, in (0,1]."},
+				{Names: []string{"-pcr-length-scale"}, Arg: "BP", Default: "5000", Text: "Length over which per-cycle efficiency falls by 1/e."},
+				{Names: []string{"-pcr-gc-optimum"}, Arg: "FLOAT", Default: "0.5", Text: "GC fraction with the highest efficiency."},
+				{Names: []string{"-pcr-gc-width"}, Arg: "FLOAT", Default: "0.25", Text: "Gaussian width, in GC fraction, of the efficiency falloff around the optimum."},
 			},
 		},
 		{
@@ -60,6 +83,63 @@ func writeRadigestUsage(w io.Writer, version string) {
 				{Names: []string{"-bed"}, Arg: "PATH|-", Text: "BED6 for hard-kept fragments."},
 				{Names: []string{"-fragments-tsv"}, Arg: "PATH|-", Text: "Per-fragment TSV for score-range fragments."},
 				{Names: []string{"-fragments-fasta"}, Arg: "PATH|-", Text: "FASTA sequences for hard-kept fragments."},
+				{Names: []string{"-length-histogram"}, Text: "Add length_histogram to JSON: every digested fragment length, in 1 bp bins up to the digest range and coarser bins beyond, so other size models can be evaluated without digesting again. Requires JSON output; not allowed with -library, -pcr-cycles, or -ambiguity half, whose selection or weights the histogram cannot hold."},
+				{Names: []string{"-composition"}, Text: "Add GC fraction, CpG, N count, longest homopolymer, and DUST low-complexity score to TSV/GFF rows and weighted distributions to JSON."},
+			},
+		},
+		{
+			Title: "Duplicate loci",
+			Intro: []string{"Fragments whose read-length end sequences match are likely to collapse into one locus after mapping."},
+			Items: []clihelp.Flag{
+				{Names: []string{"-duplicates"}, Arg: "off|exact|kmer", Default: "off", Text: "Cluster score-range fragments by identical end sequences (exact) or an estimated k-mer Jaccard similarity of at least 0.5 at both ends (kmer). Adds a duplicates object with effective unique loci to JSON."},
+				{Names: []string{"-read-length"}, Arg: "INT", Default: "150", Text: "Bases hashed from each fragment end. Shorter fragments are counted as unique."},
+				{Names: []string{"-duplicate-k"}, Arg: "INT", Default: "21", Text: "k-mer length for -duplicates kmer, at most 32 and -read-length."},
+				{Names: []string{"-duplicates-tsv"}, Arg: "PATH|-", Text: "TSV listing every member of each multi-fragment cluster."},
+			},
+		},
+		{
+			Title: "Mappability",
+			Intro: []string{"Reads map uniquely only when the read-length window at each fragment end occurs once in the reference, on either strand."},
+			Items: []clihelp.Flag{
+				{Names: []string{"-mappability"}, Text: "Index every -read-length reference window, add left/right end copy counts and a unique_ends flag to -fragments-tsv, and add a mappability object with mappable loci to JSON. Holds the whole reference in memory."},
+			},
+		},
+		{
+			Title: "Simulated reads",
+			Intro: []string{"Draws a Poisson number of reads from each score-range fragment with mean -read-depth times its size weight. Reads start at the cut-site remnant; in a double digest read 1 comes from the first enzyme's end."},
+			Items: []clihelp.Flag{
+				{Names: []string{"-reads-r1"}, Arg: "PATH|-", Text: "Simulated single-end FASTQ, or read 1 when -reads-r2 is set."},
+				{Names: []string{"-reads-r2"}, Arg: "PATH|-", Text: "Read-2 FASTQ for paired-end output. Requires -reads-r1."},
+				{Names: []string{"-read-depth"}, Arg: "FLOAT", Default: "10", Text: "Mean reads (pairs) per fragment at size weight 1."},
+				{Names: []string{"-read-error-rate"}, Arg: "FLOAT", Default: "0", Text: "Per-base substitution rate. Quality scores are set to the matching Phred value."},
+				{Names: []string{"-read-seed"}, Arg: "INT", Default: "1", Text: "PRNG seed; 0 uses a time-based seed recorded in JSON reads.seed."},
+				{Names: []string{"-adapter-r1"}, Arg: "SEQ", Default: "TruSeq read 1", Text: "Adapter read 1 runs into when the insert is shorter than -read-length."},
+				{Names: []string{"-adapter-r2"}, Arg: "SEQ", Default: "TruSeq read 2", Text: "Adapter read 2 runs into when the insert is shorter than -read-length."},
+			},
+		},
+		{
+			Title: "Variants",
+			Intro: []string{"Splices each SNP or small-indel ALT allele into the reference and reports the cut sites it abolishes or creates. A fragment is disrupted when an allele abolishes a cut at either end or creates one inside it."},
+			Items: []clihelp.Flag{
+				{Names: []string{"-vcf"}, Arg: "PATH", Text: "VCF (plain or gzip). Adds variant_alleles, variant_afs, and dropout_risk to -fragments-tsv and a variants object to JSON. AF comes from INFO AF or sample genotypes."},
+				{Names: []string{"-haplotypes"}, Arg: "PATH|-", Text: "Digest both haplotypes of every -vcf sample and write a locus-by-sample matrix for hard-kept reference fragments. Cells list haplotype lengths as a|b, with '!' before lengths outside the size window and '.' where the locus is lost. Adds missing-data totals to JSON."},
+			},
+		},
+		{
+			Title: "Assembly layout",
+			Intro: []string{"Reads an AGP describing how -fasta scaffolds are built from contigs and gaps. Fragments that reach into a gap or span two components are usually scaffolding artefacts."},
+			Items: []clihelp.Flag{
+				{Names: []string{"-agp"}, Arg: "PATH", Text: "AGP 2.x (plain or gzip). Adds component, component_start0, component_end0, component_strand, and crosses_component to -fragments-tsv and an agp object to JSON."},
+				{Names: []string{"-agp-gap-ends"}, Text: "Digest each gap-free stretch as its own contig, so no fragment spans a gap and -include-ends keeps fragments from gap edges to the nearest cut. -haplotypes digests each sample haplotype the same way."},
+			},
+		},
+		{
+			Title: "Library construct",
+			Intro: []string{"Builds P1 + barcode 1 + insert + barcode 2 + P2 molecules for each sample in a barcode sheet. Size bounds then apply to molecule length, simulated reads start with the sample barcode, and JSON warns when an adapter junction recreates an enzyme site."},
+			Items: []clihelp.Flag{
+				{Names: []string{"-library"}, Arg: "PATH", Text: "Whitespace-separated sheet: sample, barcode1, optional barcode2."},
+				{Names: []string{"-library-p1"}, Arg: "SEQ", Default: "TruSeq P5", Text: "Adapter ligated before barcode 1."},
+				{Names: []string{"-library-p2"}, Arg: "SEQ", Default: "TruSeq P7", Text: "Adapter ligated after barcode 2, written 5'→3' on its own strand."},
 			},
 		},
 		{
@@ -6,21 +6,34 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"runtime"
 	"sort"
 	"strings"
 	"sync"
 
+	"github.com/ericksamera/radigest/internal/agp"
 	"github.com/ericksamera/radigest/internal/bed"
 	"github.com/ericksamera/radigest/internal/collector"
+	"github.com/ericksamera/radigest/internal/composition"
 	"github.com/ericksamera/radigest/internal/digest"
 	"github.com/ericksamera/radigest/internal/enzyme"
 	"github.com/ericksamera/radigest/internal/fasta"
+	"github.com/ericksamera/radigest/internal/fastq"
 	"github.com/ericksamera/radigest/internal/fragmentfasta"
 	"github.com/ericksamera/radigest/internal/fragmenttsv"
+	"github.com/ericksamera/radigest/internal/gff"
+	"github.com/ericksamera/radigest/internal/haplotype"
+	"github.com/ericksamera/radigest/internal/library"
+	"github.com/ericksamera/radigest/internal/mappability"
+	"github.com/ericksamera/radigest/internal/paralog"
+	"github.com/ericksamera/radigest/internal/pcrbias"
+	"github.com/ericksamera/radigest/internal/readsim"
 	"github.com/ericksamera/radigest/internal/sim"
 	"github.com/ericksamera/radigest/internal/sizeselect"
+	"github.com/ericksamera/radigest/internal/varsite"
+	"github.com/ericksamera/radigest/internal/vcf"
 )
 
 var (
@@ -47,14 +60,26 @@ type runSummary struct {
 	Warnings        []string         `json:"warnings"`
 
 	// Backward-compatible top-level fields retained for existing downstream tools.
-	Enzymes        []string         `json:"enzymes"`
-	MinLength      int              `json:"min_length"`
-	MaxLength      int              `json:"max_length"`
-	GFF            string           `json:"gff,omitempty"`
-	BED            string           `json:"bed,omitempty"`
-	FragmentsTSV   string           `json:"fragments_tsv,omitempty"`
-	FragmentsFASTA string           `json:"fragments_fasta,omitempty"`
-	SizeSelection  sizeselect.Stats `json:"size_selection"`
+	Enzymes        []string             `json:"enzymes"`
+	MinLength      int                  `json:"min_length"`
+	MaxLength      int                  `json:"max_length"`
+	GFF            string               `json:"gff,omitempty"`
+	BED            string               `json:"bed,omitempty"`
+	FragmentsTSV   string               `json:"fragments_tsv,omitempty"`
+	FragmentsFASTA string               `json:"fragments_fasta,omitempty"`
+	SizeSelection  sizeselect.Stats     `json:"size_selection"`
+	Composition    *composition.Summary `json:"composition,omitempty"`
+	Duplicates     *paralog.Summary     `json:"duplicates,omitempty"`
+	Mappability    *mappability.Summary `json:"mappability,omitempty"`
+	Reads          *readsim.Stats       `json:"reads,omitempty"`
+	Library        *library.Summary     `json:"library,omitempty"`
+	PossibleSites  *int                 `json:"possible_sites,omitempty"`
+	Variants       *varsite.Summary     `json:"variants,omitempty"`
+	Haplotypes     *haplotype.Summary   `json:"haplotypes,omitempty"`
+	AGP            *agp.Summary         `json:"agp,omitempty"`
+	// LengthHistogram counts every digested fragment length, when
+	// -length-histogram is set.
+	LengthHistogram *sizeselect.Histogram `json:"length_histogram,omitempty"`
 	collector.Stats
 }
 
@@ -76,10 +101,36 @@ type parameterSummary struct {
 	SizeMean    float64 `json:"size_mean,omitempty"`
 	SizeSD      float64 `json:"size_sd,omitempty"`
 	SizeEdgeSD  float64 `json:"size_edge_sd,omitempty"`
+	SizeSkew    float64 `json:"size_skew,omitempty"`
+	SizeMean2   float64 `json:"size_mean2,omitempty"`
+	SizeSD2     float64 `json:"size_sd2,omitempty"`
+	SizeMix     float64 `json:"size_mix,omitempty"`
 	Threads     int     `json:"threads"`
 	AllowSame   bool    `json:"allow_same"`
 	StrictCuts  bool    `json:"strict_cuts"`
 	IncludeEnds bool    `json:"include_ends"`
+	Composition bool    `json:"composition"`
+	Duplicates  string  `json:"duplicates"`
+	ReadLength  int     `json:"read_length,omitempty"`
+	DuplicateK  int     `json:"duplicate_k,omitempty"`
+	Mappability bool    `json:"mappability"`
+	Ambiguity   string  `json:"ambiguity"`
+	Library     string  `json:"library,omitempty"`
+	VCF         string  `json:"vcf,omitempty"`
+	AGP         string  `json:"agp,omitempty"`
+	SizeCurve   string  `json:"size_curve,omitempty"`
+	SizeConfig  string  `json:"size_config,omitempty"`
+	// SPRI bead ratios and calibration file of -size-model spri.
+	SPRILeft        float64 `json:"spri_left,omitempty"`
+	SPRIRight       float64 `json:"spri_right,omitempty"`
+	SPRICalibration string  `json:"spri_calibration,omitempty"`
+	// AdapterP1Length, AdapterP2Length, and BarcodeLengths are set when
+	// size selection runs on library length.
+	AdapterP1Length int   `json:"adapter_p1_length,omitempty"`
+	AdapterP2Length int   `json:"adapter_p2_length,omitempty"`
+	BarcodeLengths  []int `json:"barcode_lengths,omitempty"`
+	// PCR is the amplification model, when -pcr-cycles is set.
+	PCR *pcrbias.Model `json:"pcr,omitempty"`
 }
 
 type outputSummary struct {
@@ -88,6 +139,10 @@ type outputSummary struct {
 	BED            string `json:"bed,omitempty"`
 	FragmentsTSV   string `json:"fragments_tsv,omitempty"`
 	FragmentsFASTA string `json:"fragments_fasta,omitempty"`
+	DuplicatesTSV  string `json:"duplicates_tsv,omitempty"`
+	ReadsR1        string `json:"reads_r1,omitempty"`
+	ReadsR2        string `json:"reads_r2,omitempty"`
+	Haplotypes     string `json:"haplotypes,omitempty"`
 }
 
 type usageError struct {
@@ -144,6 +199,7 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	fragmentsTSVPath := fs.String("fragments-tsv", "", "optional per-fragment TSV for score-range fragments (path or '-' for stdout); empty string disables")
 	fragmentsFASTAPath := fs.String("fragments-fasta", "", "optional FASTA output for hard-selected fragments (path or '-' for stdout); empty string disables")
 	jsonPath := fs.String("json", "", "optional run summary JSON output (path or '-' for stdout); if no output flags are set, JSON is written to stdout")
+	lengthHistogram := fs.Bool("length-histogram", false, "add a histogram of every digested fragment length to the JSON summary so other size models can be rescored from it")
 	threads := fs.Int("threads", runtime.NumCPU(), "number of worker goroutines")
 	verbose := fs.Bool("v", false, "verbose progress to stderr")
 	showVer := fs.Bool("version", false, "print version and exit")
@@ -152,15 +208,69 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	// size-selection scoring
 	scoreMinFlag := fs.Int("score-min", -1, "minimum fragment length included in fragments TSV and size-selection stats; default -min")
 	scoreMaxFlag := fs.Int("score-max", -1, "maximum fragment length included in fragments TSV and size-selection stats; default -max")
-	sizeModel := fs.String("size-model", "hard", "size-selection model: hard, normal, triangular, or soft-window")
-	sizeMean := fs.Float64("size-mean", 0, "target/peak insert length for normal/triangular models; default midpoint of -min/-max")
-	sizeSD := fs.Float64("size-sd", 35, "standard deviation for -size-model normal")
+	sizeModel := fs.String("size-model", "hard", "size-selection model: hard, normal, triangular, soft-window, log-normal, skew-normal, gamma, mixture, spri, or empirical")
+	sizeMean := fs.Float64("size-mean", 0, "target/peak insert length for normal/triangular models, mean for log-normal/gamma, location for skew-normal; default midpoint of -min/-max")
+	sizeSD := fs.Float64("size-sd", 35, "standard deviation for normal/log-normal/gamma/mixture models, scale for skew-normal")
 	sizeEdgeSD := fs.Float64("size-edge-sd", 25, "edge softness for -size-model soft-window")
+	sizeSkew := fs.Float64("size-skew", 0, "shape (alpha) for -size-model skew-normal; positive leans right")
+	sizeMean2 := fs.Float64("size-mean2", 0, "mean of the second -size-model mixture component")
+	sizeSD2 := fs.Float64("size-sd2", 0, "standard deviation of the second -size-model mixture component")
+	sizeMix := fs.Float64("size-mix", 0, "share of the second -size-model mixture component, in (0,1)")
+	sizeCurve := fs.String("size-curve", "", "length-to-weight table (TSV or JSON) for -size-model empirical, interpolated linearly")
+	spriLeft := fs.Float64("spri-left", 0, "left-side (larger) bead ratio for -size-model spri; keeps fragments above its cutoff, e.g. 0.75")
+	spriRight := fs.Float64("spri-right", 0, "right-side (smaller) bead ratio for -size-model spri; removes fragments above its cutoff, e.g. 0.55")
+	spriCalibration := fs.String("spri-calibration", "", "bead-ratio-to-cutoff table (TSV or JSON) for -size-model spri; default a built-in SPRIselect/AMPure table")
+	sizeConfigPath := fs.String("size-config", "", "size-model JSON from radigest-fit-size-model; replaces -size-model and its parameters")
+	adapterP1Len := fs.Int("adapter-p1-length", 0, "P1 adapter bases added to each insert before size selection; -min/-max and the score range become library lengths")
+	adapterP2Len := fs.Int("adapter-p2-length", 0, "P2 adapter bases added to each insert before size selection")
+	barcodeLengths := fs.String("barcode-lengths", "", "comma-separated combined inline-barcode lengths in the pool; weights average over them")
+
+	// PCR amplification bias
+	pcrCycles := fs.Int("pcr-cycles", 0, "library PCR cycles; > 0 adds amplification weights from fragment length and GC")
+	pcrEfficiency := fs.Float64("pcr-efficiency", pcrbias.DefaultEfficiency, "per-cycle efficiency of a short fragment at the GC optimum, in (0,1]")
+	pcrLengthScale := fs.Float64("pcr-length-scale", pcrbias.DefaultLengthScale, "length (bp) over which per-cycle efficiency falls by 1/e")
+	pcrGCOptimum := fs.Float64("pcr-gc-optimum", pcrbias.DefaultGCOptimum, "GC fraction with the highest PCR efficiency")
+	pcrGCWidth := fs.Float64("pcr-gc-width", pcrbias.DefaultGCWidth, "GC fraction SD over which efficiency falls off around -pcr-gc-optimum")
 
 	// digest behavior & validation
 	allowSame := fs.Bool("allow-same", false, "double digest: also keep AA/BB neighbors (default AB/BA only)")
 	includeEnds := fs.Bool("include-ends", false, "also emit terminal fragments from chromosome/contig ends to the nearest cut")
 	strictCuts := fs.Bool("strict-cuts", false, "error if an enzyme lacks a caret and CutIndex==0 (no mid-site fallback)")
+	ambiguityFlag := fs.String("ambiguity", digest.AmbiguityOff.String(), "reference IUPAC ambiguity codes at sites: off, cut, nocut, or half (cut at half weight)")
+
+	// per-fragment sequence metrics
+	compositionFlag := fs.Bool("composition", false, "add GC/CpG/N/homopolymer/low-complexity metrics to fragment TSV, GFF, and JSON outputs")
+
+	// duplicate-locus detection
+	readLength := fs.Int("read-length", 150, "read length (bp) hashed from each fragment end for -duplicates and -mappability")
+	duplicatesFlag := fs.String("duplicates", string(paralog.ModeOff), "duplicate-locus detection from fragment end sequences: off, exact, or kmer")
+	duplicateK := fs.Int("duplicate-k", paralog.DefaultK, "k-mer length for -duplicates kmer")
+	duplicatesTSVPath := fs.String("duplicates-tsv", "", "optional TSV listing duplicate-fragment clusters (path or '-' for stdout); requires -duplicates")
+
+	// read-end mappability
+	mappabilityFlag := fs.Bool("mappability", false, "index every read-length reference window and report how often each fragment end recurs (holds the reference in memory)")
+
+	// simulated reads
+	readsR1Path := fs.String("reads-r1", "", "optional simulated FASTQ reads, or read 1 when -reads-r2 is set (path or '-' for stdout)")
+	readsR2Path := fs.String("reads-r2", "", "optional simulated read-2 FASTQ for paired-end output (path or '-' for stdout); requires -reads-r1")
+	readDepth := fs.Float64("read-depth", 10, "mean simulated reads (pairs) per score-range fragment at size weight 1")
+	readErrorRate := fs.Float64("read-error-rate", 0, "per-base substitution error rate for simulated reads")
+	readSeed := fs.Int64("read-seed", 1, "PRNG seed for simulated reads (0 ⇒ time-based)")
+	adapterR1 := fs.String("adapter-r1", readsim.DefaultAdapter1, "adapter sequence read 1 runs into when the insert is shorter than -read-length")
+	adapterR2 := fs.String("adapter-r2", readsim.DefaultAdapter2, "adapter sequence read 2 runs into when the insert is shorter than -read-length")
+
+	// variant-aware digestion
+	vcfPath := fs.String("vcf", "", "optional VCF of SNPs and small indels; reports cut sites each ALT allele abolishes or creates and the fragments it disrupts")
+	haplotypesPath := fs.String("haplotypes", "", "optional locus-by-sample matrix from digesting each phased -vcf haplotype (path or '-' for stdout); requires -vcf with samples")
+
+	// assembly layout
+	agpPath := fs.String("agp", "", "optional AGP describing -fasta scaffolds; adds component coordinates to fragment TSV and flags fragments crossing component boundaries")
+	agpGapEnds := fs.Bool("agp-gap-ends", false, "treat AGP gaps as contig ends: no fragment spans a gap, and -include-ends keeps fragments from gap edges; requires -agp")
+
+	// library construct
+	libraryPath := fs.String("library", "", "optional barcode sheet (sample, barcode1[, barcode2]); size selection and simulated reads then use full adapter+barcode molecules")
+	libraryP1 := fs.String("library-p1", library.DefaultP1, "P1 adapter sequence ligated upstream of barcode 1 with -library")
+	libraryP2 := fs.String("library-p2", library.DefaultP2, "P2 adapter sequence, 5'→3' on its own strand, ligated after barcode 2 with -library")
 
 	// synthetic genome flags
 	simLen := fs.Int("sim-len", 0, "synthesize a single-chromosome genome of this length (bp) instead of reading -fasta")
@@ -183,7 +293,11 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	fragmentsTSVOutputPath := normalizeOutputPath(*fragmentsTSVPath)
 	fragmentsFASTAOutputPath := normalizeOutputPath(*fragmentsFASTAPath)
 	jsonOutputPath := normalizeOutputPath(*jsonPath)
-	if !anyFlagSet(fs, "gff", "bed", "fragments-tsv", "fragments-fasta", "json") {
+	duplicatesTSVOutputPath := normalizeOutputPath(*duplicatesTSVPath)
+	readsR1OutputPath := normalizeOutputPath(*readsR1Path)
+	readsR2OutputPath := normalizeOutputPath(*readsR2Path)
+	haplotypesOutputPath := normalizeOutputPath(*haplotypesPath)
+	if !anyFlagSet(fs, "gff", "bed", "fragments-tsv", "fragments-fasta", "json", "duplicates-tsv", "reads-r1", "reads-r2", "haplotypes") {
 		jsonOutputPath = "-"
 	}
 
@@ -222,16 +336,117 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	if *minLen > *maxLen {
 		return fmt.Errorf("invalid range: -min (%d) > -max (%d)", *minLen, *maxLen)
 	}
+	if *sizeConfigPath != "" && anyFlagSet(fs, "size-model", "size-mean", "size-sd", "size-edge-sd", "size-skew", "size-mean2", "size-sd2", "size-mix", "size-curve", "spri-left", "spri-right", "spri-calibration") {
+		return usageError{err: errors.New("-size-config replaces -size-model and its parameters (-size-mean, -size-sd, -size-edge-sd, -size-skew, -size-mean2, -size-sd2, -size-mix, -size-curve, -spri-left, -spri-right, -spri-calibration)")}
+	}
+	if *libraryPath != "" && anyFlagSet(fs, "adapter-p1-length", "adapter-p2-length", "barcode-lengths") {
+		return usageError{err: errors.New("-library already sizes whole molecules from its adapters and barcodes; drop -adapter-p1-length, -adapter-p2-length, and -barcode-lengths")}
+	}
+	amplification := pcrbias.Model{Cycles: *pcrCycles, Efficiency: *pcrEfficiency, LengthScale: *pcrLengthScale, GCOptimum: *pcrGCOptimum, GCWidth: *pcrGCWidth}
+	if err := amplification.Validate(); err != nil {
+		return usageError{err: fmt.Errorf("-pcr-cycles: %w", err)}
+	}
+	barcodeLens, err := sizeselect.ParseBarcodeLengths(*barcodeLengths)
+	if err != nil {
+		return usageError{err: fmt.Errorf("-barcode-lengths: %w", err)}
+	}
 	if *simLen > 0 {
 		if err := validateSimGC(*simGC); err != nil {
 			return err
 		}
 	}
 
-	if err := validateOutputSelection(gffOutputPath, bedOutputPath, fragmentsTSVOutputPath, fragmentsFASTAOutputPath, jsonOutputPath); err != nil {
+	duplicateMode, err := paralog.ParseMode(*duplicatesFlag)
+	if err != nil {
+		return usageError{err: fmt.Errorf("-duplicates: %w", err)}
+	}
+	endHasher := paralog.Hasher{Mode: duplicateMode, ReadLength: *readLength, K: *duplicateK}
+	if err := endHasher.Validate(); err != nil {
+		return usageError{err: err}
+	}
+	if duplicatesTSVOutputPath != "" && !endHasher.Enabled() {
+		return usageError{err: errors.New("-duplicates-tsv requires -duplicates exact or kmer")}
+	}
+	var windows *mappability.Index
+	if *mappabilityFlag {
+		windows, err = mappability.NewIndex(*readLength)
+		if err != nil {
+			return usageError{err: err}
+		}
+	}
+
+	readConfig := readsim.Config{
+		ReadLength: *readLength,
+		Paired:     readsR2OutputPath != "",
+		Depth:      *readDepth,
+		ErrorRate:  *readErrorRate,
+		Adapter1:   *adapterR1,
+		Adapter2:   *adapterR2,
+	}
+	var variants *vcf.Set
+	if *vcfPath != "" {
+		variants, err = vcf.Read(*vcfPath)
+		if err != nil {
+			return usageError{err: fmt.Errorf("-vcf: %w", err)}
+		}
+	}
+	if haplotypesOutputPath != "" {
+		if variants == nil {
+			return usageError{err: errors.New("-haplotypes requires -vcf")}
+		}
+		if len(variants.Samples) == 0 {
+			return usageError{err: errors.New("-haplotypes requires a -vcf with sample genotype columns")}
+		}
+	}
+
+	var layout *agp.Map
+	if *agpPath != "" {
+		layout, err = agp.Read(*agpPath)
+		if err != nil {
+			return usageError{err: fmt.Errorf("-agp: %w", err)}
+		}
+	}
+	if *agpGapEnds && layout == nil {
+		return usageError{err: errors.New("-agp-gap-ends requires -agp")}
+	}
+	if *lengthHistogram && jsonOutputPath == "" {
+		return usageError{err: errors.New("-length-histogram requires -json")}
+	}
+	// The histogram holds insert lengths only, so it cannot reproduce size
+	// selection on library molecules or weights it does not record.
+	if *lengthHistogram && (*libraryPath != "" || amplification.Enabled()) {
+		return usageError{err: errors.New("-length-histogram cannot be combined with -library or -pcr-cycles")}
+	}
+
+	var libModel *library.Model
+	if *libraryPath != "" {
+		samples, err := library.ReadSheet(*libraryPath)
+		if err != nil {
+			return usageError{err: fmt.Errorf("-library: %w", err)}
+		}
+		libModel = &library.Model{P1: *libraryP1, P2: *libraryP2, Samples: samples}
+		if err := libModel.Validate(); err != nil {
+			return usageError{err: fmt.Errorf("-library: %w", err)}
+		}
+		readConfig.Library = libModel
+	}
+	if readsR2OutputPath != "" && readsR1OutputPath == "" {
+		return usageError{err: errors.New("-reads-r2 requires -reads-r1")}
+	}
+	if readsR1OutputPath != "" {
+		if err := readConfig.Validate(); err != nil {
+			return usageError{err: err}
+		}
+	}
+
+	if err := validateOutputSelection(gffOutputPath, bedOutputPath, fragmentsTSVOutputPath, fragmentsFASTAOutputPath, jsonOutputPath, duplicatesTSVOutputPath, readsR1OutputPath, haplotypesOutputPath); err != nil {
 		return err
 	}
-	if err := validateOutputPaths(*fastaPath, gffOutputPath, bedOutputPath, fragmentsTSVOutputPath, fragmentsFASTAOutputPath, jsonOutputPath, *fastaPath != ""); err != nil {
+	if err := validateOutputPaths(*fastaPath, gffOutputPath, bedOutputPath, fragmentsTSVOutputPath, fragmentsFASTAOutputPath, jsonOutputPath, *fastaPath != "",
+		namedPath{name: "-duplicates-tsv", path: duplicatesTSVOutputPath, stdoutAllowed: true},
+		namedPath{name: "-reads-r1", path: readsR1OutputPath, stdoutAllowed: true},
+		namedPath{name: "-reads-r2", path: readsR2OutputPath, stdoutAllowed: true},
+		namedPath{name: "-haplotypes", path: haplotypesOutputPath, stdoutAllowed: true}); err != nil {
 		return err
 	}
 
@@ -243,34 +458,74 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	if scoreMax < 0 {
 		scoreMax = *maxLen
 	}
-	selector, err := sizeselect.New(sizeselect.Config{
-		Model:    sizeselect.Model(*sizeModel),
-		Min:      *minLen,
-		Max:      *maxLen,
-		ScoreMin: scoreMin,
-		ScoreMax: scoreMax,
-		Mean:     *sizeMean,
-		SD:       *sizeSD,
-		EdgeSD:   *sizeEdgeSD,
-	})
+	sizeConfig, err := sizeselect.Config{
+		Model:          sizeselect.Model(*sizeModel),
+		Min:            *minLen,
+		Max:            *maxLen,
+		ScoreMin:       scoreMin,
+		ScoreMax:       scoreMax,
+		Mean:           *sizeMean,
+		SD:             *sizeSD,
+		EdgeSD:         *sizeEdgeSD,
+		Skew:           *sizeSkew,
+		Mean2:          *sizeMean2,
+		SD2:            *sizeSD2,
+		Mix:            *sizeMix,
+		SPRILeft:       *spriLeft,
+		SPRIRight:      *spriRight,
+		AdapterP1:      *adapterP1Len,
+		AdapterP2:      *adapterP2Len,
+		BarcodeLengths: barcodeLens,
+	}.WithCurve(*sizeCurve)
+	if err == nil {
+		sizeConfig, err = sizeConfig.WithSPRICalibration(*spriCalibration)
+	}
+	if err == nil {
+		sizeConfig, err = sizeConfig.WithModelFile(*sizeConfigPath)
+	}
+	if err != nil {
+		return usageError{err: err}
+	}
+	selector, err := sizeselect.New(sizeConfig)
 	if err != nil {
 		return err
 	}
 
-	// Digest the union of the hard output window and the broader scoring window.
-	// Optional writers decide which fragments are serialized to artifact outputs.
-	digestMin := minInt(*minLen, scoreMin)
-	digestMax := maxInt(*maxLen, scoreMax)
+	// Digest the union of the hard output window and the broader scoring window,
+	// as insert lengths. Optional writers decide which fragments are serialized
+	// to artifact outputs.
+	digestMin, digestMax := selector.InsertRange()
+	if libModel != nil {
+		// Size bounds apply to whole molecules; widen the fragment window by
+		// the adapter+barcode flanks so every sample's molecule is scored.
+		minFlank, maxFlank := libModel.FlankRange()
+		digestMin = maxInt(1, digestMin-maxFlank)
+		digestMax = maxInt(digestMin, digestMax-minFlank)
+	}
+	// -length-histogram digests fragments of every length; only those in
+	// the range above are scored and written.
+	scanMin, scanMax := digestMin, digestMax
+	if *lengthHistogram {
+		scanMin, scanMax = minInt(digestMin, 1), math.MaxInt
+	}
 
 	// ---- compile enzymes ----------------------------------------------------
 	ens, enzymeNames, err := parseEnzymes(*enzFlag)
 	if err != nil {
 		return err
 	}
+	ambiguity, err := digest.ParseAmbiguity(*ambiguityFlag)
+	if err != nil {
+		return usageError{err: fmt.Errorf("-ambiguity: %w", err)}
+	}
+	if *lengthHistogram && ambiguity == digest.AmbiguityHalf {
+		return usageError{err: errors.New("-length-histogram cannot be combined with -ambiguity half")}
+	}
 	plan, err := digest.TryNewPlanWithOptions(ens, digest.Options{
 		AllowSame:   *allowSame,
 		StrictCuts:  *strictCuts,
 		IncludeEnds: *includeEnds,
+		Ambiguity:   ambiguity,
 	})
 	if err != nil {
 		return usageError{err: err}
@@ -283,7 +538,15 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 		resolvedSimSeed = sim.ResolveSeed(*simSeed)
 	}
 
-	if canUseStatsOnlyJSON(gffOutputPath, bedOutputPath, fragmentsTSVOutputPath, fragmentsFASTAOutputPath, jsonOutputPath, selector.Config()) {
+	var libEnds library.StickyEnds
+	if libModel != nil {
+		libEnds, err = library.NewStickyEnds(ens)
+		if err != nil {
+			return usageError{err: fmt.Errorf("-library: %w", err)}
+		}
+	}
+
+	if canUseStatsOnlyJSON(gffOutputPath, bedOutputPath, fragmentsTSVOutputPath, fragmentsFASTAOutputPath, jsonOutputPath, selector.Config(), *compositionFlag || endHasher.Enabled() || windows != nil || readsR1OutputPath != "" || libModel != nil || variants != nil || ambiguity != digest.AmbiguityOff || layout != nil || amplification.Enabled() || *lengthHistogram) {
 		return runStatsOnlyJSON(runStatsOnlyInput{
 			Args:             args,
 			Stdin:            stdin,
@@ -317,7 +580,7 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	if err != nil {
 		return fmt.Errorf("bed: %w", err)
 	}
-	fragWriter, err := fragmenttsv.NewTo(fragmentsTSVOutputPath, stdout)
+	fragWriter, err := fragmenttsv.NewToWithOptions(fragmentsTSVOutputPath, stdout, fragmenttsv.Options{Composition: *compositionFlag, Mappability: windows != nil, Variants: variants != nil, Components: layout != nil, Amplification: amplification.Enabled()})
 	if err != nil {
 		return fmt.Errorf("fragments tsv: %w", err)
 	}
@@ -325,7 +588,68 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	if err != nil {
 		return fmt.Errorf("fragments fasta: %w", err)
 	}
-	wantFragmentFASTA := fragmentsFASTAOutputPath != ""
+	readsR1Writer, err := fastq.NewTo(readsR1OutputPath, stdout)
+	if err != nil {
+		return fmt.Errorf("reads r1: %w", err)
+	}
+	readsR2Writer, err := fastq.NewTo(readsR2OutputPath, stdout)
+	if err != nil {
+		return fmt.Errorf("reads r2: %w", err)
+	}
+	var haplotypeSamples []string
+	if haplotypesOutputPath != "" {
+		haplotypeSamples = variants.Samples
+	}
+	hapWriter, err := haplotype.NewTo(haplotypesOutputPath, stdout, haplotypeSamples)
+	if err != nil {
+		return fmt.Errorf("haplotypes: %w", err)
+	}
+	scored := &scoredRun{
+		gff:           writer,
+		bed:           bedWriter,
+		tsv:           fragWriter,
+		fasta:         fragFASTAWriter,
+		selector:      selector,
+		library:       libModel,
+		ends:          libEnds,
+		plan:          plan,
+		variants:      variants,
+		haps:          hapWriter,
+		amplification: amplification,
+	}
+	if variants != nil {
+		scored.variantSummary = &varsite.Summary{Variants: variants.Len()}
+	}
+	if layout != nil {
+		scored.layout = layout
+		scored.layoutSummary = &agp.Summary{Objects: layout.Objects(), Gaps: layout.Gaps(), GapEnds: *agpGapEnds}
+	}
+	if haplotypesOutputPath != "" {
+		summary := haplotype.NewSummary(variants.Samples)
+		scored.haplotypes = &summary
+	}
+	if *compositionFlag {
+		summary := composition.NewSummary()
+		scored.composition = &summary
+	}
+	if *lengthHistogram {
+		scored.histogram = sizeselect.NewHistogram(digestMax)
+		scored.digestMin, scored.digestMax = digestMin, digestMax
+	}
+	if endHasher.Enabled() {
+		scored.duplicates = paralog.NewDetector(endHasher, duplicatesTSVOutputPath != "")
+	}
+	if windows != nil {
+		scored.windows = windows
+		scored.mappability = &mappability.Summary{ReadLength: windows.ReadLength()}
+	}
+	if readsR1OutputPath != "" {
+		scored.reads, err = readsim.New(readConfig, ens, sim.ResolveSeed(*readSeed), readsR1Writer, readsR2Writer)
+		if err != nil {
+			return usageError{err: err}
+		}
+	}
+	wantSequence := fragmentsFASTAOutputPath != "" || *compositionFlag || endHasher.Enabled() || windows != nil || scored.reads != nil || libModel != nil || variants != nil || ambiguity != digest.AmbiguityOff || amplification.Enabled()
 
 	// ---- worker pool --------------------------------------------------------
 	type job struct {
@@ -343,15 +667,21 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 				fragCh := make(chan digest.Fragment, 64)
 				errCh := make(chan error, 1)
 				var seq []byte
-				if wantFragmentFASTA {
+				if wantSequence {
 					seq = j.rec.Seq
 				}
 				results <- digestResult{idx: j.idx, chr: j.rec.ID, seq: seq, frags: fragCh, errors: errCh}
 
-				err := plan.DigestEach(j.rec.Seq, digestMin, digestMax, func(fr digest.Fragment) error {
+				emit := func(fr digest.Fragment) error {
 					fragCh <- fr
 					return nil
-				})
+				}
+				var err error
+				if *agpGapEnds {
+					err = digestSegments(plan, j.rec.Seq, layout.Segments(j.rec.ID, len(j.rec.Seq)), scanMin, scanMax, emit)
+				} else {
+					err = plan.DigestEach(j.rec.Seq, scanMin, scanMax, emit)
+				}
 				close(fragCh)
 				errCh <- err
 				close(errCh)
@@ -369,11 +699,19 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	go func() {
 		if *simLen > 0 {
 			seq := sim.Make(*simLen, *simGC, resolvedSimSeed) // chr1
+			if windows != nil {
+				windows.Add(seq)
+				windows.Freeze()
+			}
 			faCh <- fasta.Record{ID: "chr1", Seq: seq}
 			close(faCh)
 			sourceErrCh <- nil
 			return
 		}
+		if windows != nil {
+			sourceErrCh <- indexThenStream(*fastaPath, stdin, windows, faCh)
+			return
+		}
 		sourceErrCh <- fasta.StreamFrom(*fastaPath, stdin, faCh)
 	}()
 	go func() {
@@ -386,11 +724,14 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	}()
 
 	// ---- wait + finalize ----------------------------------------------------
-	sizeStats, streamErr := writeResultStreamsScoredTo(writer, bedWriter, fragWriter, fragFASTAWriter, selector, results, *verbose, stderr)
+	sizeStats, streamErr := writeResultStreamsScoredTo(scored, results, *verbose, stderr)
 	stats, closeErr := writer.Close()
 	bedCloseErr := bedWriter.Close()
 	fragCloseErr := fragWriter.Close()
 	fragFASTACloseErr := fragFASTAWriter.Close()
+	readsR1CloseErr := readsR1Writer.Close()
+	readsR2CloseErr := readsR2Writer.Close()
+	hapCloseErr := hapWriter.Close()
 	if streamErr != nil {
 		return fmt.Errorf("digest/write: %w", streamErr)
 	}
@@ -410,11 +751,41 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	if fragFASTACloseErr != nil {
 		return fmt.Errorf("fragments fasta: %w", fragFASTACloseErr)
 	}
+	if readsR1CloseErr != nil {
+		return fmt.Errorf("reads r1: %w", readsR1CloseErr)
+	}
+	if readsR2CloseErr != nil {
+		return fmt.Errorf("reads r2: %w", readsR2CloseErr)
+	}
+	if hapCloseErr != nil {
+		return fmt.Errorf("haplotypes: %w", hapCloseErr)
+	}
+	var readStats *readsim.Stats
+	if scored.reads != nil {
+		stats := scored.reads.Stats()
+		readStats = &stats
+	}
+	var duplicateSummary *paralog.Summary
+	if scored.duplicates != nil {
+		summary := scored.duplicates.Summary()
+		duplicateSummary = &summary
+		if err := writeDuplicatesTSVTo(duplicatesTSVOutputPath, scored.duplicates, stdout); err != nil {
+			return fmt.Errorf("duplicates tsv: %w", err)
+		}
+	}
+	if scored.mappability != nil {
+		scored.mappability.IndexedWindows = windows.Windows()
+	}
 
 	if _, err := fmt.Fprintf(stderr, "Fragments kept: %d\nBases covered: %d\nChromosomes: %d\n",
 		stats.TotalFragments, stats.TotalBases, len(stats.PerChr)); err != nil {
 		return fmt.Errorf("write final stats: %w", err)
 	}
+	var librarySummary *library.Summary
+	if libModel != nil {
+		summary := libModel.Summary(libEnds)
+		librarySummary = &summary
+	}
 	if jsonOutputPath != "" {
 		summary := buildRunSummary(runSummaryInput{
 			Args:               args,
@@ -431,11 +802,32 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 			StrictCuts:         *strictCuts,
 			IncludeEnds:        *includeEnds,
 			SelectorConfig:     selector.Config(),
+			Amplification:      amplification,
+			Composition:        scored.composition,
+			Duplicates:         duplicateSummary,
+			EndHasher:          endHasher,
+			Mappability:        scored.mappability,
+			Reads:              readStats,
+			ReadSeedRequested:  *readSeed,
+			Ambiguity:          ambiguity,
+			PossibleSites:      scored.possibleSites,
+			LibraryPath:        *libraryPath,
+			VCFPath:            *vcfPath,
+			Variants:           scored.variantSummary,
+			AGPPath:            *agpPath,
+			AGP:                scored.layoutSummary,
+			LengthHistogram:    scored.histogram,
+			Haplotypes:         scored.haplotypes,
+			HaplotypesPath:     haplotypesOutputPath,
+			Library:            librarySummary,
 			JSONPath:           jsonOutputPath,
 			GFFPath:            gffOutputPath,
 			BEDPath:            bedOutputPath,
 			FragmentsTSVPath:   fragmentsTSVOutputPath,
 			FragmentsFASTAPath: fragmentsFASTAOutputPath,
+			DuplicatesTSVPath:  duplicatesTSVOutputPath,
+			ReadsR1Path:        readsR1OutputPath,
+			ReadsR2Path:        readsR2OutputPath,
 			SizeSelection:      sizeStats,
 			Stats:              stats,
 		})
@@ -475,13 +867,18 @@ type runStatsOnlyInput struct {
 	JSONPath         string
 }
 
-func canUseStatsOnlyJSON(gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAPath, jsonPath string, cfg sizeselect.Config) bool {
+// canUseStatsOnlyJSON reports whether the run can skip per-fragment streaming.
+// needsFragments is set when a requested analysis, such as composition
+// metrics, must see every fragment.
+func canUseStatsOnlyJSON(gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAPath, jsonPath string, cfg sizeselect.Config, needsFragments bool) bool {
 	return jsonPath != "" &&
+		!needsFragments &&
 		gffPath == "" &&
 		bedPath == "" &&
 		fragmentsTSVPath == "" &&
 		fragmentsFASTAPath == "" &&
 		cfg.Model == sizeselect.ModelHard &&
+		!cfg.HasFlanks() &&
 		cfg.ScoreMin == cfg.Min &&
 		cfg.ScoreMax == cfg.Max
 }
@@ -668,14 +1065,75 @@ func writeResultStreamsTo(w *collector.Writer, results <-chan digestResult, verb
 	return nil
 }
 
-func writeResultStreamsScoredTo(w *collector.Writer, bedWriter *bed.Writer, tsv *fragmenttsv.Writer, fastaWriter *fragmentfasta.Writer, selector sizeselect.Selector, results <-chan digestResult, verbose bool, stderr io.Writer) (sizeselect.Stats, error) {
+// indexThenStream reads every FASTA record into memory, adding each to
+// windows, and sends the records to out only after the index is frozen, so
+// fragment ends can be scored as soon as digestion starts. It closes out.
+func indexThenStream(path string, stdin io.Reader, windows *mappability.Index, out chan<- fasta.Record) error {
+	defer close(out)
+	in := make(chan fasta.Record)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fasta.StreamFrom(path, stdin, in)
+	}()
+	var records []fasta.Record
+	for rec := range in {
+		windows.Add(rec.Seq)
+		records = append(records, rec)
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+	windows.Freeze()
+	for _, rec := range records {
+		out <- rec
+	}
+	return nil
+}
+
+// scoredRun bundles the writers and optional accumulators fed by the scored
+// streaming path. Disabled writers are no-ops, and nil accumulators are skipped.
+type scoredRun struct {
+	gff         *collector.Writer
+	bed         *bed.Writer
+	tsv         *fragmenttsv.Writer
+	fasta       *fragmentfasta.Writer
+	selector    sizeselect.Selector
+	composition *composition.Summary
+	duplicates  *paralog.Detector
+	windows     *mappability.Index
+	mappability *mappability.Summary
+	reads       *readsim.Simulator
+	library     *library.Model
+	ends        library.StickyEnds
+	// plan and variants drive per-chromosome cut-site polymorphism scans.
+	plan           digest.Plan
+	variants       *vcf.Set
+	variantSummary *varsite.Summary
+	haps           *haplotype.Writer
+	haplotypes     *haplotype.Summary
+	// possibleSites totals reference sites matched only through ambiguity
+	// codes when the plan matches them.
+	possibleSites int
+	// layout places fragments on AGP components.
+	layout        *agp.Map
+	layoutSummary *agp.Summary
+	// amplification weights scored fragments by PCR bias when enabled.
+	amplification pcrbias.Model
+	// histogram counts every digested fragment length when set; the digest
+	// then spans all lengths, and only fragments in [digestMin, digestMax]
+	// go on to be scored.
+	histogram            *sizeselect.Histogram
+	digestMin, digestMax int
+}
+
+func writeResultStreamsScoredTo(run *scoredRun, results <-chan digestResult, verbose bool, stderr io.Writer) (sizeselect.Stats, error) {
 	pending := make(map[int]digestResult)
 	next := 0
-	stats := sizeselect.NewStats(selector)
+	stats := sizeselect.NewStats(run.selector)
 
 	for results != nil || len(pending) > 0 {
 		if r, ok := pending[next]; ok {
-			cs, writeErr := writeScoredChromosome(w, bedWriter, tsv, fastaWriter, selector, &stats, r.chr, r.seq, r.frags)
+			cs, writeErr := writeScoredChromosome(run, &stats, r.chr, r.seq, r.frags)
 			digestErr := <-r.errors
 			delete(pending, next)
 			next++
@@ -707,33 +1165,133 @@ func writeResultStreamsScoredTo(w *collector.Writer, bedWriter *bed.Writer, tsv
 	return stats, nil
 }
 
-func writeScoredChromosome(w *collector.Writer, bedWriter *bed.Writer, tsv *fragmenttsv.Writer, fastaWriter *fragmentfasta.Writer, selector sizeselect.Selector, stats *sizeselect.Stats, chr string, seq []byte, frags <-chan digest.Fragment) (collector.ChrStats, error) {
+func writeScoredChromosome(run *scoredRun, stats *sizeselect.Stats, chr string, seq []byte, frags <-chan digest.Fragment) (collector.ChrStats, error) {
 	var local collector.ChrStats
 	var firstErr error
 	ordinal := 1
+	selector := run.selector
+	var chrVariants varsite.Chromosome
+	if run.variantSummary != nil {
+		chrVariants = varsite.Scan(run.plan, seq, run.variants.Records(chr))
+		run.variantSummary.AddChromosome(chrVariants)
+	}
+	if run.plan.Ambiguity() != digest.AmbiguityOff {
+		run.possibleSites += run.plan.PossibleSites(seq)
+	}
+	var chrHaps haplotype.Chromosome
+	if run.haplotypes != nil {
+		var segments [][2]int
+		if run.layoutSummary != nil && run.layoutSummary.GapEnds {
+			segments = run.layout.Segments(chr, len(seq))
+		}
+		refKept := func(fr digest.Fragment) bool { return run.haplotypeKept(seq, fr, fr.End-fr.Start) }
+		chrHaps = haplotype.Digest(run.plan, seq, run.variants.Records(chr), run.haplotypes.Samples, segments, refKept)
+		run.haplotypes.AddChromosome(chrHaps)
+	}
 
 	for fr := range frags {
 		length := fr.End - fr.Start
+		if run.histogram != nil {
+			run.histogram.Add(length)
+			if length < run.digestMin || length > run.digestMax {
+				continue
+			}
+		}
 		hardKept := selector.InHardWindow(length)
+		inScoreRange := selector.InScoreRange(length)
+		weight := 0.0
+		if run.library != nil {
+			sel := run.library.Select(selector, run.ends.Insert(seq, fr).Len())
+			hardKept, inScoreRange, weight = sel.HardKept, sel.InScoreRange, sel.Weight
+		} else if inScoreRange {
+			weight = selector.Weight(length)
+		}
+		weight *= run.plan.Weight(fr)
 		if hardKept {
 			stats.AddHardKept(length)
 		}
-		if selector.InScoreRange(length) {
-			weight := selector.Weight(length)
+		var metrics composition.Metrics
+		var gffAttrs string
+		if run.composition != nil && (hardKept || inScoreRange) {
+			if fr.Start >= 0 && fr.End <= len(seq) {
+				metrics = composition.Compute(seq[fr.Start:fr.End])
+			}
+			gffAttrs = gff.CompositionAttributes(metrics)
+		}
+		var ends mappability.Ends
+		if run.windows != nil && inScoreRange {
+			ends = run.windows.Fragment(seq, fr)
+		}
+		var placement agp.Placement
+		if run.layout != nil && (hardKept || inScoreRange) {
+			placement = run.layout.Place(chr, fr.Start, fr.End)
+			if hardKept {
+				run.layoutSummary.Add(placement)
+			}
+		}
+		// With -pcr-cycles, every per-fragment consumer sees the recovered
+		// weight, size selection times amplification; size_selection keeps
+		// the size-only totals and reports the amplified ones beside them.
+		ampWeight := 0.0
+		recovered := weight
+		if run.amplification.Enabled() && inScoreRange {
+			ampWeight = run.amplification.SeqWeight(seq[max(fr.Start, 0):min(fr.End, len(seq))])
+			recovered = weight * ampWeight
+		}
+		var poly varsite.Fragment
+		if run.variantSummary != nil && inScoreRange {
+			poly = chrVariants.Fragment(fr)
+			run.variantSummary.AddFragment(poly, recovered)
+		}
+		if inScoreRange {
 			stats.AddScored(length, weight)
+			if run.amplification.Enabled() {
+				stats.AddAmplified(length, recovered)
+			}
+			if fr.Possible != 0 {
+				stats.AddPossible()
+			}
+			if run.composition != nil {
+				run.composition.Add(metrics, recovered)
+			}
+			if run.duplicates != nil {
+				run.duplicates.Add(chr, seq, fr, recovered)
+			}
+			if run.mappability != nil {
+				run.mappability.Add(ends, recovered)
+			}
+			if run.reads != nil && firstErr == nil {
+				if err := run.reads.Fragment(chr, seq, fr, recovered); err != nil {
+					firstErr = err
+				}
+			}
+			if firstErr == nil {
+				row := fragmenttsv.Row{Chr: chr, Fragment: fr, HardKept: hardKept, SizeWeight: weight, Composition: metrics, Mappability: ends, Variants: poly, Component: placement, AmplificationWeight: ampWeight}
+				if err := run.tsv.WriteRow(row); err != nil {
+					firstErr = err
+				}
+			}
+		}
+		if hardKept && run.haplotypes != nil {
+			lengths := chrHaps.Lengths(fr)
+			kept := make([]bool, len(lengths))
+			for i, n := range lengths {
+				kept[i] = n != haplotype.Absent && run.haplotypeKept(seq, fr, n)
+			}
+			run.haplotypes.AddLocus(length, lengths, kept)
 			if firstErr == nil {
-				if err := tsv.Write(chr, fr, hardKept, weight); err != nil {
+				if err := run.haps.Write(chr, fr, lengths, kept); err != nil {
 					firstErr = err
 				}
 			}
 		}
 		if hardKept {
 			if firstErr == nil {
-				if err := w.WriteFragment(chr, ordinal, fr); err != nil {
+				if err := run.gff.WriteFragmentWithAttributes(chr, ordinal, fr, gffAttrs); err != nil {
 					firstErr = err
-				} else if err := bedWriter.Write(chr, ordinal, fr); err != nil {
+				} else if err := run.bed.Write(chr, ordinal, fr); err != nil {
 					firstErr = err
-				} else if err := fastaWriter.Write(chr, ordinal, fr, seq); err != nil {
+				} else if err := run.fasta.Write(chr, ordinal, fr, seq); err != nil {
 					firstErr = err
 				} else {
 					local.Fragments++
@@ -746,6 +1304,34 @@ func writeScoredChromosome(w *collector.Writer, bedWriter *bed.Writer, tsv *frag
 	return local, firstErr
 }
 
+// digestSegments digests each [start, end) segment of seq as its own contig,
+// shifting fragments back to seq coordinates, so no fragment spans a segment
+// boundary and -include-ends treats each boundary as a contig end.
+func digestSegments(plan digest.Plan, seq []byte, segments [][2]int, min, max int, emit func(digest.Fragment) error) error {
+	for _, seg := range segments {
+		err := plan.DigestEach(seq[seg[0]:seg[1]], min, max, func(fr digest.Fragment) error {
+			fr.Start += seg[0]
+			fr.End += seg[0]
+			return emit(fr)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// haplotypeKept reports whether a haplotype's copy of reference fragment fr,
+// n bases long, passes the hard size window, using the library molecule
+// length when one is configured.
+func (run *scoredRun) haplotypeKept(seq []byte, fr digest.Fragment, n int) bool {
+	if run.library != nil {
+		shift := n - (fr.End - fr.Start)
+		return run.library.Select(run.selector, run.ends.Insert(seq, fr).Len()+shift).HardKept
+	}
+	return run.selector.InHardWindow(n)
+}
+
 type runSummaryInput struct {
 	Args               []string
 	Enzymes            []string
@@ -761,11 +1347,32 @@ type runSummaryInput struct {
 	StrictCuts         bool
 	IncludeEnds        bool
 	SelectorConfig     sizeselect.Config
+	Amplification      pcrbias.Model
+	Composition        *composition.Summary
+	Duplicates         *paralog.Summary
+	EndHasher          paralog.Hasher
+	Mappability        *mappability.Summary
+	Reads              *readsim.Stats
+	ReadSeedRequested  int64
+	Ambiguity          digest.Ambiguity
+	PossibleSites      int
+	LibraryPath        string
+	VCFPath            string
+	Variants           *varsite.Summary
+	AGPPath            string
+	AGP                *agp.Summary
+	Haplotypes         *haplotype.Summary
+	HaplotypesPath     string
+	Library            *library.Summary
+	LengthHistogram    *sizeselect.Histogram
 	JSONPath           string
 	GFFPath            string
 	BEDPath            string
 	FragmentsTSVPath   string
 	FragmentsFASTAPath string
+	DuplicatesTSVPath  string
+	ReadsR1Path        string
+	ReadsR2Path        string
 	SizeSelection      sizeselect.Stats
 	Stats              collector.Stats
 }
@@ -781,15 +1388,56 @@ func buildRunSummary(in runSummaryInput) runSummary {
 		AllowSame:   in.AllowSame,
 		StrictCuts:  in.StrictCuts,
 		IncludeEnds: in.IncludeEnds,
+		Composition: in.Composition != nil,
+		Duplicates:  string(paralog.ModeOff),
+		Mappability: in.Mappability != nil,
+		Ambiguity:   in.Ambiguity.String(),
+		Library:     in.LibraryPath,
+		VCF:         in.VCFPath,
+		AGP:         in.AGPPath,
+	}
+	if in.Mappability != nil {
+		params.ReadLength = in.Mappability.ReadLength
+	}
+	if in.EndHasher.Enabled() {
+		params.Duplicates = string(in.EndHasher.Mode)
+		params.ReadLength = in.EndHasher.ReadLength
+		if in.EndHasher.Mode == paralog.ModeKmer {
+			params.DuplicateK = in.EndHasher.K
+		}
 	}
 	switch in.SelectorConfig.Model {
-	case sizeselect.ModelNormal:
+	case sizeselect.ModelNormal, sizeselect.ModelLogNormal, sizeselect.ModelGamma:
+		params.SizeMean = in.SelectorConfig.Mean
+		params.SizeSD = in.SelectorConfig.SD
+	case sizeselect.ModelSkewNormal:
+		params.SizeMean = in.SelectorConfig.Mean
+		params.SizeSD = in.SelectorConfig.SD
+		params.SizeSkew = in.SelectorConfig.Skew
+	case sizeselect.ModelMixture:
 		params.SizeMean = in.SelectorConfig.Mean
 		params.SizeSD = in.SelectorConfig.SD
+		params.SizeMean2 = in.SelectorConfig.Mean2
+		params.SizeSD2 = in.SelectorConfig.SD2
+		params.SizeMix = in.SelectorConfig.Mix
 	case sizeselect.ModelTriangular:
 		params.SizeMean = in.SelectorConfig.Mean
 	case sizeselect.ModelSoftWindow:
 		params.SizeEdgeSD = in.SelectorConfig.EdgeSD
+	case sizeselect.ModelEmpirical:
+		params.SizeCurve = in.SelectorConfig.CurvePath
+	case sizeselect.ModelSPRI:
+		params.SPRILeft = in.SelectorConfig.SPRILeft
+		params.SPRIRight = in.SelectorConfig.SPRIRight
+		params.SPRICalibration = in.SelectorConfig.SPRICalibrationPath
+	}
+	params.SizeConfig = in.SelectorConfig.ConfigPath
+	params.AdapterP1Length = in.SelectorConfig.AdapterP1
+	params.AdapterP2Length = in.SelectorConfig.AdapterP2
+	params.BarcodeLengths = in.SelectorConfig.BarcodeLengths
+	if in.Amplification.Enabled() {
+		amp := in.Amplification
+		params.PCR = &amp
 	}
 
 	input := inputSummary{
@@ -812,10 +1460,33 @@ func buildRunSummary(in runSummaryInput) runSummary {
 			warnings = append(warnings, "-sim-seed 0 requested a time-based seed; resolved seed is recorded in input.sim_seed_resolved")
 		}
 	}
+	if in.Reads != nil && in.ReadSeedRequested == 0 {
+		warnings = append(warnings, "-read-seed 0 requested a time-based seed; resolved seed is recorded in reads.seed")
+	}
+	if in.Library != nil {
+		for _, site := range in.Library.RecreatedSites {
+			warnings = append(warnings, fmt.Sprintf("sample %s: %s adapter and barcode recreate the %s site %s after ligation to a %s end", site.Sample, site.Adapter, site.Enzyme, site.Site, site.LigateTo))
+		}
+	}
+	if in.Variants != nil && in.Variants.RefMismatches > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d VCF records have a REF allele that does not match the reference and were skipped", in.Variants.RefMismatches))
+	}
+	if in.Haplotypes != nil && in.Haplotypes.Unphased > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d unphased heterozygous calls were applied to haplotypes in listed allele order", in.Haplotypes.Unphased))
+	}
+	if in.AGP != nil && in.AGP.Crossing > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d hard-kept fragments cross AGP component boundaries or gaps", in.AGP.Crossing))
+	}
 	if in.Stats.TotalFragments == 0 {
 		warnings = append(warnings, "no fragments passed the hard size-selection window")
 	}
 
+	var possibleSites *int
+	if in.Ambiguity != digest.AmbiguityOff {
+		n := in.PossibleSites
+		possibleSites = &n
+	}
+
 	command := make([]string, 0, len(in.Args)+1)
 	command = append(command, "radigest")
 	command = append(command, in.Args...)
@@ -826,6 +1497,10 @@ func buildRunSummary(in runSummaryInput) runSummary {
 		BED:            in.BEDPath,
 		FragmentsTSV:   in.FragmentsTSVPath,
 		FragmentsFASTA: in.FragmentsFASTAPath,
+		DuplicatesTSV:  in.DuplicatesTSVPath,
+		ReadsR1:        in.ReadsR1Path,
+		ReadsR2:        in.ReadsR2Path,
+		Haplotypes:     in.HaplotypesPath,
 	}
 
 	return runSummary{
@@ -837,15 +1512,25 @@ func buildRunSummary(in runSummaryInput) runSummary {
 		Outputs:         outputs,
 		Warnings:        warnings,
 
-		Enzymes:        in.Enzymes,
-		MinLength:      in.MinLen,
-		MaxLength:      in.MaxLen,
-		GFF:            in.GFFPath,
-		BED:            in.BEDPath,
-		FragmentsTSV:   in.FragmentsTSVPath,
-		FragmentsFASTA: in.FragmentsFASTAPath,
-		SizeSelection:  in.SizeSelection,
-		Stats:          in.Stats,
+		Enzymes:         in.Enzymes,
+		MinLength:       in.MinLen,
+		MaxLength:       in.MaxLen,
+		GFF:             in.GFFPath,
+		BED:             in.BEDPath,
+		FragmentsTSV:    in.FragmentsTSVPath,
+		FragmentsFASTA:  in.FragmentsFASTAPath,
+		SizeSelection:   in.SizeSelection,
+		Composition:     in.Composition,
+		Duplicates:      in.Duplicates,
+		Mappability:     in.Mappability,
+		Reads:           in.Reads,
+		Library:         in.Library,
+		PossibleSites:   possibleSites,
+		Variants:        in.Variants,
+		Haplotypes:      in.Haplotypes,
+		AGP:             in.AGP,
+		LengthHistogram: in.LengthHistogram,
+		Stats:           in.Stats,
 	}
 }
 
@@ -867,6 +1552,25 @@ func normalizeOutputPath(path string) string {
 	return strings.TrimSpace(path)
 }
 
+func writeDuplicatesTSVTo(path string, d *paralog.Detector, stdout io.Writer) error {
+	if path == "" {
+		return nil
+	}
+	if path == "-" {
+		return d.WriteTSV(stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := d.WriteTSV(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func writeSummaryJSONTo(path string, summary runSummary, stdout io.Writer) error {
 	if path == "" {
 		return nil
@@ -3,10 +3,17 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/ericksamera/radigest/internal/sizeselect"
 )
 
 func TestNoOutputFlagsWritesJSONToStdoutOnly(t *testing.T) {
@@ -295,3 +302,911 @@ func containsWarning(warnings []string, needle string) bool {
 	}
 	return false
 }
+
+func TestCompositionFlagAddsMetricsToOutputs(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	tsvPath := filepath.Join(dir, "fragments.tsv")
+	gffPath := filepath.Join(dir, "fragments.gff3")
+	if err := os.WriteFile(refPath, []byte(">chr1\nAAAAGAATTCTTAAAGAATTC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{
+		"-fasta", refPath,
+		"-enzymes", "EcoRI,MseI",
+		"-composition",
+		"-fragments-tsv", tsvPath,
+		"-gff", gffPath,
+		"-json", "-",
+		"-threads", "1",
+	}, "")
+
+	tsv, err := os.ReadFile(tsvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(tsv)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header plus 2 rows, got:\n%s", tsv)
+	}
+	if !strings.HasSuffix(lines[0], "\tgc_fraction\tcpg_count\tn_count\thomopolymer_max\tlow_complexity") {
+		t.Fatalf("composition columns missing from header: %q", lines[0])
+	}
+	if got := len(strings.Split(lines[1], "\t")); got != len(strings.Split(lines[0], "\t")) {
+		t.Fatalf("row has %d columns, header has %d", got, len(strings.Split(lines[0], "\t")))
+	}
+
+	gffData, err := os.ReadFile(gffPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gffData), ";gc_fraction=") || !strings.Contains(string(gffData), ";low_complexity=") {
+		t.Fatalf("composition attributes missing from GFF:\n%s", gffData)
+	}
+
+	var doc struct {
+		Parameters struct {
+			Composition bool `json:"composition"`
+		} `json:"parameters"`
+		Composition *struct {
+			Fragments int `json:"fragments"`
+			GC        struct {
+				Weight float64 `json:"weight"`
+			} `json:"gc_fraction"`
+		} `json:"composition"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if !doc.Parameters.Composition {
+		t.Fatalf("parameters.composition should be true")
+	}
+	if doc.Composition == nil || doc.Composition.Fragments != 2 || doc.Composition.GC.Weight != 2 {
+		t.Fatalf("composition summary wrong: %+v", doc.Composition)
+	}
+}
+
+func TestDuplicatesFlagReportsClusters(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	clustersPath := filepath.Join(dir, "clusters.tsv")
+	repeat := "GAATTCAGCGCGATCAGTCCAGCATGCAGGTCAGCACGCTAGGTTAA"
+	if err := os.WriteFile(refPath, []byte(">chr1\nCCGCACCAGT"+repeat+"GCAGCGCCAGGCA"+repeat+"GGCAGCCAGC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{
+		"-fasta", refPath,
+		"-enzymes", "EcoRI,MseI",
+		"-min", "1",
+		"-max", "100",
+		"-read-length", "12",
+		"-duplicates", "exact",
+		"-duplicates-tsv", clustersPath,
+		"-json", "-",
+		"-threads", "1",
+	}, "")
+
+	var doc struct {
+		Parameters struct {
+			Duplicates string `json:"duplicates"`
+			ReadLength int    `json:"read_length"`
+		} `json:"parameters"`
+		Duplicates *struct {
+			Clusters            int     `json:"clusters"`
+			WeightedFragments   float64 `json:"weighted_fragments"`
+			EffectiveUniqueLoci float64 `json:"effective_unique_loci"`
+		} `json:"duplicates"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if doc.Parameters.Duplicates != "exact" || doc.Parameters.ReadLength != 12 {
+		t.Fatalf("duplicate parameters wrong: %+v", doc.Parameters)
+	}
+	if doc.Duplicates == nil || doc.Duplicates.Clusters != 1 || doc.Duplicates.EffectiveUniqueLoci != doc.Duplicates.WeightedFragments-1 {
+		t.Fatalf("duplicate summary wrong: %+v", doc.Duplicates)
+	}
+
+	data, err := os.ReadFile(clustersPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "1\t2\tchr1\t") || !strings.HasPrefix(lines[2], "1\t2\tchr1\t") {
+		t.Fatalf("unexpected clusters TSV:\n%s", data)
+	}
+}
+
+func TestDuplicatesTSVRequiresDuplicateMode(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"-sim-len", "1000", "-enzymes", "EcoRI", "-duplicates-tsv", "-"}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || exitCode(err) != 2 {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestMappabilityFlagScoresFragmentEnds(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	tsvPath := filepath.Join(dir, "fragments.tsv")
+	repeat := "GAATTCAGCGCGATCAGTCCAGCATGCAGGTCAGCACGCTAGGTTAA"
+	if err := os.WriteFile(refPath, []byte(">chr1\nCCGCACCAGT"+repeat+"GCAGCGCCAGGCA"+repeat+"GGCAGCCAGC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{
+		"-fasta", refPath,
+		"-enzymes", "EcoRI,MseI",
+		"-min", "1",
+		"-max", "100",
+		"-read-length", "12",
+		"-mappability",
+		"-fragments-tsv", tsvPath,
+		"-json", "-",
+		"-threads", "2",
+	}, "")
+
+	var doc struct {
+		Parameters struct {
+			Mappability bool `json:"mappability"`
+			ReadLength  int  `json:"read_length"`
+		} `json:"parameters"`
+		Mappability *struct {
+			IndexedWindows  int     `json:"indexed_windows"`
+			Fragments       int     `json:"fragments"`
+			UniqueFragments int     `json:"unique_fragments"`
+			MappableLoci    float64 `json:"mappable_loci"`
+		} `json:"mappability"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if !doc.Parameters.Mappability || doc.Parameters.ReadLength != 12 {
+		t.Fatalf("mappability parameters wrong: %+v", doc.Parameters)
+	}
+	m := doc.Mappability
+	if m == nil || m.IndexedWindows == 0 || m.Fragments != 3 || m.UniqueFragments != 1 || m.MappableLoci != 1 {
+		t.Fatalf("mappability summary wrong: %+v", m)
+	}
+
+	data, err := os.ReadFile(tsvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if !strings.HasSuffix(lines[0], "\tleft_end_copies\tright_end_copies\tunique_ends") {
+		t.Fatalf("missing mappability columns: %q", lines[0])
+	}
+	unique := 0
+	for _, line := range lines[1:] {
+		if strings.HasSuffix(line, "\t0\t0\ttrue") {
+			unique++
+		}
+	}
+	if len(lines) != 4 || unique != 1 {
+		t.Fatalf("expected one unique fragment among three:\n%s", data)
+	}
+}
+
+func TestReadsFlagsWritePairedFASTQ(t *testing.T) {
+	dir := t.TempDir()
+	r1Path := filepath.Join(dir, "reads_R1.fq")
+	r2Path := filepath.Join(dir, "reads_R2.fq")
+
+	stdout, _ := runCaptured(t, []string{
+		"-sim-len", "20000",
+		"-sim-seed", "11",
+		"-enzymes", "EcoRI,MseI",
+		"-min", "50",
+		"-max", "400",
+		"-read-length", "50",
+		"-read-depth", "2",
+		"-read-seed", "5",
+		"-reads-r1", r1Path,
+		"-reads-r2", r2Path,
+		"-json", "-",
+		"-threads", "2",
+	}, "")
+
+	var doc struct {
+		Reads *struct {
+			Layout           string `json:"layout"`
+			Seed             int64  `json:"seed"`
+			FragmentsSampled int    `json:"fragments_sampled"`
+			Reads            int    `json:"reads"`
+		} `json:"reads"`
+		Outputs struct {
+			ReadsR1 string `json:"reads_r1"`
+			ReadsR2 string `json:"reads_r2"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if doc.Reads == nil || doc.Reads.Layout != "pe" || doc.Reads.Seed != 5 || doc.Reads.Reads == 0 {
+		t.Fatalf("reads summary wrong: %+v", doc.Reads)
+	}
+	if doc.Outputs.ReadsR1 != r1Path || doc.Outputs.ReadsR2 != r2Path {
+		t.Fatalf("reads outputs wrong: %+v", doc.Outputs)
+	}
+
+	r1, err := os.ReadFile(r1Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := os.ReadFile(r2Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines1 := strings.Split(strings.TrimSpace(string(r1)), "\n")
+	lines2 := strings.Split(strings.TrimSpace(string(r2)), "\n")
+	if len(lines1) != 4*doc.Reads.Reads || len(lines2) != len(lines1) {
+		t.Fatalf("expected %d records per mate, got %d and %d lines", doc.Reads.Reads, len(lines1), len(lines2))
+	}
+	for i := 0; i < len(lines1); i += 4 {
+		if lines1[i] != lines2[i] {
+			t.Fatalf("mate names differ: %q vs %q", lines1[i], lines2[i])
+		}
+		if !strings.HasPrefix(lines1[i+1], "AATTC") || !strings.HasPrefix(lines2[i+1], "TAA") {
+			t.Fatalf("reads should start with EcoRI/MseI remnants: %q %q", lines1[i+1], lines2[i+1])
+		}
+	}
+}
+
+func TestReadsR2RequiresR1(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"-sim-len", "1000", "-enzymes", "EcoRI", "-reads-r2", "-"}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || exitCode(err) != 2 {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestLibraryAppliesSizeSelectionToMolecules(t *testing.T) {
+	dir := t.TempDir()
+	sheet := filepath.Join(dir, "barcodes.tsv")
+	if err := os.WriteFile(sheet, []byte("sample\tbarcode1\nrecut\tACGTG\nsafe\tACGTC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tsvPath := filepath.Join(dir, "fragments.tsv")
+
+	stdout, _ := runCaptured(t, []string{
+		"-sim-len", "50000",
+		"-sim-seed", "3",
+		"-enzymes", "EcoRI,MseI",
+		"-min", "200",
+		"-max", "300",
+		"-library", sheet,
+		"-fragments-tsv", tsvPath,
+		"-json", "-",
+		"-threads", "2",
+	}, "")
+
+	var doc struct {
+		Warnings   []string `json:"warnings"`
+		Parameters struct {
+			Library string `json:"library"`
+		} `json:"parameters"`
+		Library *struct {
+			Samples  int `json:"samples"`
+			MinFlank int `json:"min_flank"`
+			MaxFlank int `json:"max_flank"`
+		} `json:"library"`
+		TotalFragments int `json:"total_fragments"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if doc.Library == nil || doc.Library.Samples != 2 || doc.Parameters.Library != sheet {
+		t.Fatalf("library summary wrong: %+v %+v", doc.Library, doc.Parameters)
+	}
+	if !containsWarning(doc.Warnings, "sample recut: p1 adapter and barcode recreate the EcoRI site GAATTC") {
+		t.Fatalf("expected recreated-site warning, got %v", doc.Warnings)
+	}
+	if containsWarning(doc.Warnings, "sample safe: p1") {
+		t.Fatalf("unexpected warning for sample safe: %v", doc.Warnings)
+	}
+	if doc.TotalFragments == 0 {
+		t.Fatal("expected fragments to pass molecule-length selection")
+	}
+
+	raw, err := os.ReadFile(tsvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n")[1:] {
+		fields := strings.Split(line, "\t")
+		length, err := strconv.Atoi(fields[3])
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Inserts extend past the fragment by at most the two overhangs.
+		if fields[4] == "true" && (length+doc.Library.MinFlank > 300 || length+doc.Library.MaxFlank+8 < 200) {
+			t.Fatalf("kept fragment of %d bp falls outside 200-300 bp once flanks are added", length)
+		}
+	}
+}
+
+func TestAdapterLengthsSizeSelectLibraryLength(t *testing.T) {
+	type doc struct {
+		Parameters struct {
+			AdapterP1Length int   `json:"adapter_p1_length"`
+			AdapterP2Length int   `json:"adapter_p2_length"`
+			BarcodeLengths  []int `json:"barcode_lengths"`
+		} `json:"parameters"`
+		TotalFragments int   `json:"total_fragments"`
+		TotalBases     int64 `json:"total_bases"`
+	}
+	common := []string{"-sim-len", "50000", "-sim-seed", "3", "-enzymes", "EcoRI,MseI", "-json", "-", "-threads", "2"}
+
+	var insert, shifted doc
+	stdout, _ := runCaptured(t, append([]string{"-min", "200", "-max", "300"}, common...), "")
+	if err := json.Unmarshal([]byte(stdout), &insert); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _ = runCaptured(t, append([]string{"-min", "320", "-max", "420", "-adapter-p1-length", "60", "-adapter-p2-length", "60"}, common...), "")
+	if err := json.Unmarshal([]byte(stdout), &shifted); err != nil {
+		t.Fatal(err)
+	}
+	if insert.TotalFragments == 0 || shifted.TotalFragments != insert.TotalFragments || shifted.TotalBases != insert.TotalBases {
+		t.Fatalf("library-length window kept %d fragments/%d bp, want the insert-length %d/%d", shifted.TotalFragments, shifted.TotalBases, insert.TotalFragments, insert.TotalBases)
+	}
+	if shifted.Parameters.AdapterP1Length != 60 || shifted.Parameters.AdapterP2Length != 60 || shifted.Parameters.BarcodeLengths != nil {
+		t.Fatalf("adapter parameters = %+v", shifted.Parameters)
+	}
+
+	err := run([]string{"-sim-len", "1000", "-enzymes", "EcoRI,MseI", "-library", "barcodes.tsv", "-adapter-p1-length", "60"}, strings.NewReader(""), io.Discard, io.Discard)
+	var usage usageError
+	if !errors.As(err, &usage) || !strings.Contains(err.Error(), "-library") {
+		t.Fatalf("run() error = %v, want -library usage error", err)
+	}
+}
+
+func TestVCFReportsPolymorphicCutSites(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	vcfPath := filepath.Join(dir, "calls.vcf")
+	tsvPath := filepath.Join(dir, "fragments.tsv")
+	// EcoRI cuts at 5, 25, and 45.
+	if err := os.WriteFile(refPath, []byte(">chr1\nCCCCGAATTCCCCCCCCCCCCCCCGAATTCCCCCCCCCCCCCCCGAATTCCCCC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	vcfText := "##fileformat=VCFv4.2\n" +
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n" +
+		"chr1\t27\trs1\tA\tC\t.\tPASS\tAF=0.2\n" +
+		"chr1\t49\trs2\tT\tA\t.\tPASS\tAF=0.4\n"
+	if err := os.WriteFile(vcfPath, []byte(vcfText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{
+		"-fasta", refPath,
+		"-enzymes", "EcoRI",
+		"-min", "1",
+		"-max", "100",
+		"-vcf", vcfPath,
+		"-fragments-tsv", tsvPath,
+		"-json", "-",
+	}, "")
+
+	var doc struct {
+		Parameters struct {
+			VCF string `json:"vcf"`
+		} `json:"parameters"`
+		Variants *struct {
+			Variants             int     `json:"variants"`
+			SitesAbolished       int     `json:"sites_abolished"`
+			PolymorphicFragments int     `json:"polymorphic_fragments"`
+			ExpectedDropout      float64 `json:"expected_dropout"`
+		} `json:"variants"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	v := doc.Variants
+	if doc.Parameters.VCF != vcfPath || v == nil || v.Variants != 2 || v.SitesAbolished != 2 || v.PolymorphicFragments != 2 {
+		t.Fatalf("variant summary wrong: %+v %+v", doc.Parameters, v)
+	}
+	// rs1 disrupts both fragments; rs2 abolishes the final cut of the second.
+	if want := 0.2 + (1 - 0.8*0.6); math.Abs(v.ExpectedDropout-want) > 1e-9 {
+		t.Fatalf("expected_dropout = %g, want %g", v.ExpectedDropout, want)
+	}
+
+	raw, err := os.ReadFile(tsvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\tvariant_alleles\tvariant_afs\tdropout_risk\n" +
+		"chr1\t5\t25\t20\ttrue\t1\t1\t0.2\t0.2\n" +
+		"chr1\t25\t45\t20\ttrue\t1\t2\t0.2,0.4\t0.52\n"
+	if string(raw) != want {
+		t.Fatalf("unexpected TSV\nwant:\n%s\ngot:\n%s", want, raw)
+	}
+}
+
+func TestHaplotypesWriteLocusBySampleMatrix(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	vcfPath := filepath.Join(dir, "phased.vcf")
+	matrixPath := filepath.Join(dir, "haplotypes.tsv")
+	// EcoRI cuts at 5, 25, and 45.
+	if err := os.WriteFile(refPath, []byte(">chr1\nCCCCGAATTCCCCCCCCCCCCCCCGAATTCCCCCCCCCCCCCCCGAATTCCCCC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	vcfText := "##fileformat=VCFv4.2\n" +
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tA\tB\n" +
+		"chr1\t12\tdel\tCCCC\tC\t.\tPASS\t.\tGT\t0|0\t1|1\n" +
+		"chr1\t27\tsnp\tA\tC\t.\tPASS\t.\tGT\t0|1\t0|0\n"
+	if err := os.WriteFile(vcfPath, []byte(vcfText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{
+		"-fasta", refPath,
+		"-enzymes", "EcoRI",
+		"-min", "18",
+		"-max", "100",
+		"-vcf", vcfPath,
+		"-haplotypes", matrixPath,
+		"-json", "-",
+	}, "")
+
+	var doc struct {
+		Outputs struct {
+			Haplotypes string `json:"haplotypes"`
+		} `json:"outputs"`
+		Haplotypes *struct {
+			Loci            int     `json:"loci"`
+			MissingCells    int     `json:"missing_cells"`
+			MissingFraction float64 `json:"missing_fraction"`
+		} `json:"haplotypes"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	h := doc.Haplotypes
+	if doc.Outputs.Haplotypes != matrixPath || h == nil || h.Loci != 2 || h.MissingCells != 1 || h.MissingFraction != 0.25 {
+		t.Fatalf("haplotype summary wrong: %+v %+v", doc.Outputs, h)
+	}
+
+	raw, err := os.ReadFile(matrixPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tref_length\tpresent\tA\tB\n" +
+		"chr1\t5\t25\t20\t1\t20|.\t!17|!17\n" +
+		"chr1\t25\t45\t20\t2\t20|.\t20|20\n"
+	if string(raw) != want {
+		t.Fatalf("unexpected matrix\nwant:\n%s\ngot:\n%s", want, raw)
+	}
+}
+
+func TestHaplotypesRequireVCF(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"-sim-len", "1000", "-enzymes", "EcoRI", "-haplotypes", "-"}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || exitCode(err) != 2 {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestAmbiguityHalfWeightsPossibleSites(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	// EcoRI cuts at 5 and 37; GARTTC gives a possible cut at 21.
+	if err := os.WriteFile(refPath, []byte(">chr1\nAAAAGAATTCAAAAAAAAAAGARTTCAAAAAAAAAAGAATTCAAAA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{
+		"-fasta", refPath,
+		"-enzymes", "EcoRI",
+		"-ambiguity", "half",
+		"-json", "-",
+	}, "")
+
+	var doc struct {
+		Parameters struct {
+			Ambiguity string `json:"ambiguity"`
+		} `json:"parameters"`
+		PossibleSites *int `json:"possible_sites"`
+		SizeSelection struct {
+			RawFragmentsScored int     `json:"raw_fragments_scored"`
+			WeightedFragments  float64 `json:"weighted_fragments"`
+			PossibleFragments  int     `json:"possible_fragments"`
+		} `json:"size_selection"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	ss := doc.SizeSelection
+	if doc.Parameters.Ambiguity != "half" || doc.PossibleSites == nil || *doc.PossibleSites != 1 {
+		t.Fatalf("ambiguity summary wrong: %+v possible_sites=%v", doc.Parameters, doc.PossibleSites)
+	}
+	if ss.RawFragmentsScored != 2 || ss.WeightedFragments != 1 || ss.PossibleFragments != 2 {
+		t.Fatalf("size_selection = %+v, want 2 scored, weight 1, 2 possible", ss)
+	}
+}
+
+func TestAGPPlacesFragmentsAndTreatsGapsAsEnds(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	agpPath := filepath.Join(dir, "scaf.agp")
+	// EcoRI cuts at 5 in ctg1 and at 35 in ctg2, across a 10 bp gap.
+	if err := os.WriteFile(refPath, []byte(">scaf1\nAAAAGAATTCAAAAAAAAAANNNNNNNNNNAAAAGAATTCAAAAA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	agpText := "scaf1\t1\t20\t1\tW\tctg1\t1\t20\t+\n" +
+		"scaf1\t21\t30\t2\tN\t10\tscaffold\tyes\tpaired-ends\n" +
+		"scaf1\t31\t45\t3\tW\tctg2\t1\t15\t-\n"
+	if err := os.WriteFile(agpPath, []byte(agpText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{"-fasta", refPath, "-enzymes", "EcoRI", "-agp", agpPath, "-fragments-tsv", "-"}, "")
+	if want := "scaf1\t5\t35\t30\ttrue\t1\t.\t.\t.\t.\ttrue\n"; !strings.Contains(stdout, want) {
+		t.Fatalf("fragment TSV missing crossing row %q:\n%s", want, stdout)
+	}
+
+	stdout, _ = runCaptured(t, []string{"-fasta", refPath, "-enzymes", "EcoRI", "-agp", agpPath, "-agp-gap-ends", "-include-ends", "-fragments-tsv", "-"}, "")
+	want := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\tcomponent\tcomponent_start0\tcomponent_end0\tcomponent_strand\tcrosses_component\n" +
+		"scaf1\t0\t5\t5\ttrue\t1\tctg1\t0\t5\t+\tfalse\n" +
+		"scaf1\t5\t20\t15\ttrue\t1\tctg1\t5\t20\t+\tfalse\n" +
+		"scaf1\t30\t35\t5\ttrue\t1\tctg2\t10\t15\t-\tfalse\n" +
+		"scaf1\t35\t45\t10\ttrue\t1\tctg2\t0\t10\t-\tfalse\n"
+	if stdout != want {
+		t.Fatalf("gap-end fragment TSV =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestHaplotypesDigestAGPGapEndsPerSegment(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	agpPath := filepath.Join(dir, "scaf.agp")
+	vcfPath := filepath.Join(dir, "phased.vcf")
+	matrixPath := filepath.Join(dir, "haplotypes.tsv")
+	// EcoRI cuts at 5 and 35 either side of a 10 bp gap.
+	if err := os.WriteFile(refPath, []byte(">scaf1\nAAAAGAATTCAAAAAAAAAANNNNNNNNNNAAAAGAATTCAAAAA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	agpText := "scaf1\t1\t20\t1\tW\tctg1\t1\t20\t+\n" +
+		"scaf1\t21\t30\t2\tN\t10\tscaffold\tyes\tpaired-ends\n" +
+		"scaf1\t31\t45\t3\tW\tctg2\t1\t15\t-\n"
+	if err := os.WriteFile(agpPath, []byte(agpText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	vcfText := "##fileformat=VCFv4.2\n" +
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tA\tB\n" +
+		"scaf1\t12\tdel\tAAA\tA\t.\tPASS\t.\tGT\t0|0\t0|1\n"
+	if err := os.WriteFile(vcfPath, []byte(vcfText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runCaptured(t, []string{
+		"-fasta", refPath,
+		"-enzymes", "EcoRI",
+		"-min", "1",
+		"-max", "100",
+		"-agp", agpPath,
+		"-agp-gap-ends",
+		"-include-ends",
+		"-vcf", vcfPath,
+		"-haplotypes", matrixPath,
+	}, "")
+
+	raw, err := os.ReadFile(matrixPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tref_length\tpresent\tA\tB\n" +
+		"scaf1\t0\t5\t5\t2\t5|5\t5|5\n" +
+		"scaf1\t5\t20\t15\t2\t15|15\t15|13\n" +
+		"scaf1\t30\t35\t5\t2\t5|5\t5|5\n" +
+		"scaf1\t35\t45\t10\t2\t10|10\t10|10\n"
+	if string(raw) != want {
+		t.Fatalf("gap-end haplotype matrix\nwant:\n%s\ngot:\n%s", want, raw)
+	}
+}
+
+func TestEmpiricalSizeCurveWeightsAndProvenance(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	curvePath := filepath.Join(dir, "pippin.tsv")
+	// EcoRI fragments of 10 and 20 bp.
+	if err := os.WriteFile(refPath, []byte(">chr1\nAAAAGAATTCAAAAGAATTCAAAAAAAAAAAAAAGAATTCAAAA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(curvePath, []byte("length\tweight\n0\t0\n20\t80\n40\t0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{
+		"-fasta", refPath, "-enzymes", "EcoRI",
+		"-size-model", "empirical", "-size-curve", curvePath,
+		"-json", "-",
+	}, "")
+	var doc struct {
+		Parameters struct {
+			SizeCurve string `json:"size_curve"`
+		} `json:"parameters"`
+		SizeSelection struct {
+			Model             string  `json:"model"`
+			WeightedFragments float64 `json:"weighted_fragments"`
+			Curve             []struct {
+				Length float64 `json:"length"`
+				Weight float64 `json:"weight"`
+			} `json:"curve"`
+		} `json:"size_selection"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	ss := doc.SizeSelection
+	if ss.Model != "empirical" || doc.Parameters.SizeCurve != curvePath || len(ss.Curve) != 3 || ss.Curve[1].Weight != 80 {
+		t.Fatalf("curve provenance wrong: %+v params=%+v", ss, doc.Parameters)
+	}
+	if ss.WeightedFragments != 1.5 {
+		t.Fatalf("weighted_fragments = %g, want 0.5 + 1", ss.WeightedFragments)
+	}
+
+	var out, stderr bytes.Buffer
+	err := run([]string{"-fasta", refPath, "-enzymes", "EcoRI", "-size-model", "empirical"}, strings.NewReader(""), &out, &stderr)
+	if exitCode(err) != 2 {
+		t.Fatalf("empirical without -size-curve: err = %v, want usage error", err)
+	}
+}
+
+func TestSizeConfigLoadsFittedModel(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	configPath := filepath.Join(dir, "size_model.json")
+	// EcoRI fragments of 10 and 20 bp.
+	if err := os.WriteFile(refPath, []byte(">chr1\nAAAAGAATTCAAAAGAATTCAAAAAAAAAAAAAAGAATTCAAAA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	model := `{"size_model": "normal", "min": 300, "max": 600, "size_mean": 20, "size_sd": 5, "fit": {"aic": 1}}`
+	if err := os.WriteFile(configPath, []byte(model), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _ := runCaptured(t, []string{"-fasta", refPath, "-enzymes", "EcoRI", "-size-config", configPath, "-json", "-"}, "")
+	var doc struct {
+		Parameters struct {
+			SizeModel  string  `json:"size_model"`
+			SizeMean   float64 `json:"size_mean"`
+			SizeSD     float64 `json:"size_sd"`
+			SizeConfig string  `json:"size_config"`
+		} `json:"parameters"`
+		SizeSelection struct {
+			ConfigPath        string  `json:"config_path"`
+			WeightedFragments float64 `json:"weighted_fragments"`
+		} `json:"size_selection"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	p := doc.Parameters
+	if p.SizeModel != "normal" || p.SizeMean != 20 || p.SizeSD != 5 || p.SizeConfig != configPath || doc.SizeSelection.ConfigPath != configPath {
+		t.Fatalf("size config provenance wrong: params=%+v size_selection=%+v", p, doc.SizeSelection)
+	}
+	if want := 1 + math.Exp(-2); math.Abs(doc.SizeSelection.WeightedFragments-want) > 1e-9 {
+		t.Fatalf("weighted_fragments = %g, want %g", doc.SizeSelection.WeightedFragments, want)
+	}
+
+	var out, stderr bytes.Buffer
+	err := run([]string{"-fasta", refPath, "-enzymes", "EcoRI", "-size-config", configPath, "-size-sd", "10"}, strings.NewReader(""), &out, &stderr)
+	if exitCode(err) != 2 {
+		t.Fatalf("-size-config with -size-sd: err = %v, want usage error", err)
+	}
+}
+
+func TestSkewedSizeModelsRecordParameters(t *testing.T) {
+	dir := t.TempDir()
+	refPath := filepath.Join(dir, "ref.fa")
+	if err := os.WriteFile(refPath, []byte(">chr1\nAAAAGAATTCAAAAGAATTCAAAAAAAAAAAAAAGAATTCAAAA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _ := runCaptured(t, []string{
+		"-fasta", refPath, "-enzymes", "EcoRI",
+		"-size-model", "mixture", "-size-mean", "20", "-size-sd", "3", "-size-mean2", "10", "-size-sd2", "2", "-size-mix", "0.25",
+		"-json", "-",
+	}, "")
+	var doc struct {
+		Parameters struct {
+			SizeMean2 float64 `json:"size_mean2"`
+			SizeSD2   float64 `json:"size_sd2"`
+			SizeMix   float64 `json:"size_mix"`
+		} `json:"parameters"`
+		SizeSelection struct {
+			Model string  `json:"model"`
+			Mean2 float64 `json:"mean2"`
+			Mix   float64 `json:"mix"`
+		} `json:"size_selection"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if p, ss := doc.Parameters, doc.SizeSelection; p.SizeMean2 != 10 || p.SizeSD2 != 2 || p.SizeMix != 0.25 || ss.Model != "mixture" || ss.Mean2 != 10 || ss.Mix != 0.25 {
+		t.Fatalf("mixture provenance wrong: params=%+v size_selection=%+v", p, ss)
+	}
+
+	var out, stderr bytes.Buffer
+	err := run([]string{"-fasta", refPath, "-enzymes", "EcoRI", "-size-model", "gamma", "-size-mean", "20", "-size-sd", "25"}, strings.NewReader(""), &out, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "-size-sd must be < -size-mean") {
+		t.Fatalf("gamma with sd >= mean: err = %v", err)
+	}
+}
+
+func TestSPRISizeModelRecordsRatiosAndCutoffs(t *testing.T) {
+	stdout, _ := runCaptured(t, []string{
+		"-sim-len", "50000", "-sim-seed", "3", "-enzymes", "EcoRI,MseI",
+		"-min", "300", "-max", "650", "-score-max", "2000",
+		"-size-model", "spri", "-spri-left", "0.75", "-spri-right", "0.55",
+		"-json", "-",
+	}, "")
+	var doc struct {
+		Parameters struct {
+			SPRILeft  float64 `json:"spri_left"`
+			SPRIRight float64 `json:"spri_right"`
+		} `json:"parameters"`
+		SizeSelection struct {
+			Model             string  `json:"model"`
+			SPRILowerCutoff   float64 `json:"spri_lower_cutoff"`
+			SPRIUpperCutoff   float64 `json:"spri_upper_cutoff"`
+			WeightedFragments float64 `json:"weighted_fragments"`
+		} `json:"size_selection"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if p, ss := doc.Parameters, doc.SizeSelection; p.SPRILeft != 0.75 || p.SPRIRight != 0.55 || ss.Model != "spri" || ss.SPRILowerCutoff != 300 || ss.SPRIUpperCutoff != 650 || ss.WeightedFragments <= 0 {
+		t.Fatalf("spri provenance wrong: params=%+v size_selection=%+v", p, ss)
+	}
+
+	err := run([]string{"-sim-len", "1000", "-enzymes", "EcoRI", "-size-model", "spri", "-spri-left", "0.55", "-spri-right", "0.75"}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "-spri-right must be below -spri-left") {
+		t.Fatalf("swapped ratios: err = %v", err)
+	}
+}
+
+func TestPCRCyclesReportsAmplifiedTotals(t *testing.T) {
+	tsvPath := filepath.Join(t.TempDir(), "fragments.tsv")
+	stdout, _ := runCaptured(t, []string{
+		"-sim-len", "50000", "-sim-seed", "3", "-enzymes", "EcoRI,MseI",
+		"-min", "100", "-max", "600",
+		"-pcr-cycles", "12",
+		"-fragments-tsv", tsvPath,
+		"-json", "-",
+	}, "")
+	var doc struct {
+		Parameters struct {
+			PCR *struct {
+				Cycles     int     `json:"cycles"`
+				Efficiency float64 `json:"efficiency"`
+			} `json:"pcr"`
+		} `json:"parameters"`
+		SizeSelection struct {
+			WeightedFragments  float64 `json:"weighted_fragments"`
+			AmplifiedFragments float64 `json:"amplified_fragments"`
+			AmplifiedBases     float64 `json:"amplified_bases"`
+		} `json:"size_selection"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if pcr := doc.Parameters.PCR; pcr == nil || pcr.Cycles != 12 || pcr.Efficiency != 0.95 {
+		t.Fatalf("pcr parameters = %+v", pcr)
+	}
+	ss := doc.SizeSelection
+	if ss.AmplifiedFragments <= 0 || ss.AmplifiedFragments >= ss.WeightedFragments {
+		t.Fatalf("amplified fragments %g should be positive and below size-weighted %g", ss.AmplifiedFragments, ss.WeightedFragments)
+	}
+
+	raw, err := os.ReadFile(tsvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if header := strings.Split(lines[0], "\t"); header[len(header)-1] != "amplification_weight" {
+		t.Fatalf("header = %q", lines[0])
+	}
+	total := 0.0
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		size, err1 := strconv.ParseFloat(fields[5], 64)
+		amp, err2 := strconv.ParseFloat(fields[6], 64)
+		if err1 != nil || err2 != nil || amp <= 0 || amp > 1 {
+			t.Fatalf("bad row %q", line)
+		}
+		total += size * amp
+	}
+	if math.Abs(total-ss.AmplifiedFragments) > 1e-6*ss.AmplifiedFragments {
+		t.Fatalf("TSV amplified total %g, JSON %g", total, ss.AmplifiedFragments)
+	}
+}
+
+func TestPCRCyclesBiasSimulatedReads(t *testing.T) {
+	reads := func(extra ...string) int {
+		args := append([]string{
+			"-sim-len", "50000", "-sim-seed", "3", "-enzymes", "EcoRI,MseI",
+			"-min", "100", "-max", "600",
+			"-read-length", "50", "-read-depth", "20", "-read-seed", "5",
+			"-reads-r1", filepath.Join(t.TempDir(), "r1.fq"),
+			"-json", "-",
+		}, extra...)
+		stdout, _ := runCaptured(t, args, "")
+		var doc struct {
+			Reads struct {
+				Reads int `json:"reads"`
+			} `json:"reads"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+			t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+		}
+		return doc.Reads.Reads
+	}
+	// A 100 bp length scale leaves 100-600 bp fragments well under half
+	// their size-weighted reads.
+	plain, amplified := reads(), reads("-pcr-cycles", "12", "-pcr-length-scale", "100")
+	if plain == 0 || float64(amplified) > 0.5*float64(plain) {
+		t.Fatalf("reads with PCR bias = %d, without = %d; want under half", amplified, plain)
+	}
+}
+
+func TestLengthHistogramRescoresRunSizeSelection(t *testing.T) {
+	args := []string{
+		"-sim-len", "50000", "-sim-seed", "3", "-enzymes", "EcoRI,MseI",
+		"-min", "150", "-max", "450", "-score-min", "100", "-score-max", "600",
+		"-size-model", "normal", "-size-sd", "60",
+		"-json", "-",
+	}
+	plain, _ := runCaptured(t, args, "")
+	stdout, _ := runCaptured(t, append(args, "-length-histogram"), "")
+	var doc struct {
+		TotalFragments  int                   `json:"total_fragments"`
+		SizeSelection   sizeselect.Stats      `json:"size_selection"`
+		LengthHistogram *sizeselect.Histogram `json:"length_histogram"`
+	}
+	var before struct {
+		SizeSelection   sizeselect.Stats `json:"size_selection"`
+		LengthHistogram json.RawMessage  `json:"length_histogram"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("parse stdout JSON: %v\nstdout:\n%s", err, stdout)
+	}
+	if err := json.Unmarshal([]byte(plain), &before); err != nil {
+		t.Fatal(err)
+	}
+	if before.LengthHistogram != nil || !reflect.DeepEqual(before.SizeSelection, doc.SizeSelection) {
+		t.Fatalf("-length-histogram changed size selection: %+v vs %+v", doc.SizeSelection, before.SizeSelection)
+	}
+	h := doc.LengthHistogram
+	if h == nil || h.FineMax != 600 || h.Fragments() <= doc.SizeSelection.RawFragmentsScored || len(h.Coarse) == 0 {
+		t.Fatalf("histogram should hold every fragment length: %+v", h)
+	}
+
+	sel, err := sizeselect.New(sizeselect.Config{Model: sizeselect.ModelNormal, Min: 150, Max: 450, ScoreMin: 100, ScoreMax: 600, SD: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := h.Stats(sel), doc.SizeSelection
+	if got.RawFragmentsScored != want.RawFragmentsScored || got.RawFragmentsInWindow != doc.TotalFragments || math.Abs(got.WeightedBases-want.WeightedBases) > 1e-6*want.WeightedBases {
+		t.Fatalf("rescored %+v, want %+v", got, want)
+	}
+}
+
+func TestLengthHistogramRequiresJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"-sim-len", "1000", "-enzymes", "EcoRI", "-bed", "-", "-length-histogram"}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || exitCode(err) != 2 {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+	// Library molecules and PCR or ambiguity weights are not in the
+	// histogram, so it could not reproduce size_selection.
+	for _, extra := range [][]string{{"-library", "sheet.tsv"}, {"-pcr-cycles", "10"}, {"-ambiguity", "half"}} {
+		args := append([]string{"-sim-len", "1000", "-enzymes", "EcoRI", "-json", "-", "-length-histogram"}, extra...)
+		if err := run(args, strings.NewReader(""), &stdout, &stderr); err == nil || exitCode(err) != 2 || !strings.Contains(err.Error(), extra[0]) {
+			t.Fatalf("%v: expected usage error naming it, got %v", extra, err)
+		}
+	}
+}
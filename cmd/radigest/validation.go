@@ -54,8 +54,10 @@ func parseEnzymes(value string) ([]enzyme.Enzyme, []string, error) {
 	return ens, canonicalNames, nil
 }
 
-func validateOutputSelection(gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAPath, jsonPath string) error {
-	for _, path := range []string{gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAPath, jsonPath} {
+// validateOutputSelection requires at least one active output. extra lists the
+// paths of optional analysis outputs beyond the core artifacts.
+func validateOutputSelection(gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAPath, jsonPath string, extra ...string) error {
+	for _, path := range append([]string{gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAPath, jsonPath}, extra...) {
 		if activeOutputPath(path) {
 			return nil
 		}
@@ -63,7 +65,9 @@ func validateOutputSelection(gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAP
 	return fmt.Errorf("no outputs enabled; omit output flags to write JSON summary to stdout, or set -json, -gff, -bed, -fragments-tsv, or -fragments-fasta")
 }
 
-func validateOutputPaths(fastaPath, gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAPath, jsonPath string, hasFastaInput bool) error {
+// validateOutputPaths rejects outputs that overwrite the input FASTA or share a
+// destination. extra lists optional analysis outputs beyond the core artifacts.
+func validateOutputPaths(fastaPath, gffPath, bedPath, fragmentsTSVPath, fragmentsFASTAPath, jsonPath string, hasFastaInput bool, extra ...namedPath) error {
 	outputs := []namedPath{
 		{name: "-gff", path: gffPath, stdoutAllowed: true},
 		{name: "-bed", path: bedPath, stdoutAllowed: true},
@@ -71,6 +75,7 @@ func validateOutputPaths(fastaPath, gffPath, bedPath, fragmentsTSVPath, fragment
 		{name: "-fragments-fasta", path: fragmentsFASTAPath, stdoutAllowed: true},
 		{name: "-json", path: jsonPath, stdoutAllowed: true},
 	}
+	outputs = append(outputs, extra...)
 
 	if hasFastaInput && activeFilePath(fastaPath) {
 		inputKey, err := comparablePath(fastaPath)
@@ -0,0 +1,212 @@
+// Package agp reads AGP 2.x assembly descriptions and places scaffold
+// fragments on their component contigs.
+//
+// Scaffold (object) coordinates are converted to 0-based half-open intervals
+// on read. A fragment is placed on a component only when it lies wholly
+// inside that component; fragments that reach into a gap or another
+// component cross a boundary and are usually assembly artefacts.
+package agp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Part is one AGP line in 0-based half-open object coordinates.
+type Part struct {
+	Start int
+	End   int
+	// Gap marks N and U lines; the component fields are then unset.
+	Gap       bool
+	Component string
+	// CompStart is the 0-based component coordinate of the part's first
+	// base on the component's own strand.
+	CompStart int
+	// Orientation is '-' for reverse-complemented components and '+'
+	// otherwise, including the unknown orientations ?, 0, and na.
+	Orientation byte
+}
+
+// Map holds the parts of every object, sorted by start.
+type Map struct {
+	parts map[string][]Part
+	gaps  int
+}
+
+// Read loads an AGP file, plain or gzip.
+func Read(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	m, err := Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse reads AGP text.
+func Parse(r io.Reader) (*Map, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	m := &Map{parts: make(map[string][]Part)}
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if len(cols) < 8 {
+			return nil, fmt.Errorf("line %d: expected at least 8 columns, got %d", lineNo, len(cols))
+		}
+		start, err1 := strconv.Atoi(cols[1])
+		end, err2 := strconv.Atoi(cols[2])
+		if err1 != nil || err2 != nil || start < 1 || end < start {
+			return nil, fmt.Errorf("line %d: invalid object span %s-%s", lineNo, cols[1], cols[2])
+		}
+		p := Part{Start: start - 1, End: end}
+		switch cols[4] {
+		case "N", "U":
+			p.Gap = true
+			m.gaps++
+		default:
+			if len(cols) < 9 {
+				return nil, fmt.Errorf("line %d: component line needs 9 columns, got %d", lineNo, len(cols))
+			}
+			compStart, err1 := strconv.Atoi(cols[6])
+			compEnd, err2 := strconv.Atoi(cols[7])
+			if err1 != nil || err2 != nil || compStart < 1 || compEnd-compStart != end-start {
+				return nil, fmt.Errorf("line %d: component span %s-%s does not match object span %s-%s", lineNo, cols[6], cols[7], cols[1], cols[2])
+			}
+			p.Component = cols[5]
+			p.CompStart = compStart - 1
+			p.Orientation = '+'
+			if cols[8] == "-" {
+				p.Orientation = '-'
+			}
+		}
+		m.parts[cols[0]] = append(m.parts[cols[0]], p)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for _, parts := range m.parts {
+		sort.SliceStable(parts, func(i, j int) bool { return parts[i].Start < parts[j].Start })
+	}
+	return m, nil
+}
+
+// Objects returns the number of objects described.
+func (m *Map) Objects() int {
+	return len(m.parts)
+}
+
+// Gaps returns the number of gap lines.
+func (m *Map) Gaps() int {
+	return m.gaps
+}
+
+// Placement locates a fragment on its component.
+type Placement struct {
+	// Placed is false when the object is not in the AGP.
+	Placed bool
+	// Crosses is true when the fragment reaches into a gap or more than
+	// one component; the component fields are then unset.
+	Crosses   bool
+	Component string
+	Start     int
+	End       int
+	Strand    byte
+}
+
+// Place translates the object interval [start, end) to component coordinates.
+func (m *Map) Place(object string, start, end int) Placement {
+	parts, ok := m.parts[object]
+	if !ok {
+		return Placement{}
+	}
+	i := sort.Search(len(parts), func(i int) bool { return parts[i].End > start })
+	if i == len(parts) || parts[i].Gap || parts[i].Start > start || parts[i].End < end {
+		return Placement{Placed: true, Crosses: true}
+	}
+	p := parts[i]
+	out := Placement{Placed: true, Component: p.Component, Strand: p.Orientation}
+	if p.Orientation == '-' {
+		out.Start = p.CompStart + p.End - end
+		out.End = p.CompStart + p.End - start
+	} else {
+		out.Start = p.CompStart + start - p.Start
+		out.End = p.CompStart + end - p.Start
+	}
+	return out
+}
+
+// Segments returns the gap-free intervals of object within [0, length), so
+// gaps can be digested as contig ends. An object absent from the AGP is one
+// segment.
+func (m *Map) Segments(object string, length int) [][2]int {
+	parts, ok := m.parts[object]
+	if !ok {
+		return [][2]int{{0, length}}
+	}
+	var segs [][2]int
+	next := 0
+	for _, p := range parts {
+		if !p.Gap {
+			continue
+		}
+		if end := min(p.Start, length); end > next {
+			segs = append(segs, [2]int{next, end})
+		}
+		next = max(next, p.End)
+	}
+	if next < length {
+		segs = append(segs, [2]int{next, length})
+	}
+	return segs
+}
+
+// Summary counts hard-kept fragments by placement.
+type Summary struct {
+	Objects int  `json:"objects"`
+	Gaps    int  `json:"gaps"`
+	GapEnds bool `json:"gap_ends"`
+	// Placed fragments lie wholly inside one component.
+	Placed int `json:"placed"`
+	// Crossing fragments reach into a gap or span components.
+	Crossing int `json:"crossing"`
+	// Unplaced fragments lie on sequences the AGP does not describe.
+	Unplaced int `json:"unplaced"`
+}
+
+// Add counts one fragment placement.
+func (s *Summary) Add(p Placement) {
+	switch {
+	case !p.Placed:
+		s.Unplaced++
+	case p.Crosses:
+		s.Crossing++
+	default:
+		s.Placed++
+	}
+}
@@ -0,0 +1,49 @@
+package agp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAGP = "# AGP-version 2.1\n" +
+	"scaf1\t1\t100\t1\tW\tctg1\t1\t100\t+\n" +
+	"scaf1\t101\t150\t2\tN\t50\tscaffold\tyes\tpaired-ends\n" +
+	"scaf1\t151\t250\t3\tW\tctg2\t11\t110\t-\n"
+
+func TestPlaceTranslatesToComponentCoordinates(t *testing.T) {
+	m, err := Parse(strings.NewReader(testAGP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		start, end int
+		want       Placement
+	}{
+		{10, 40, Placement{Placed: true, Component: "ctg1", Start: 10, End: 40, Strand: '+'}},
+		{160, 200, Placement{Placed: true, Component: "ctg2", Start: 60, End: 100, Strand: '-'}},
+		{90, 160, Placement{Placed: true, Crosses: true}},
+		{110, 120, Placement{Placed: true, Crosses: true}},
+	}
+	for _, tc := range cases {
+		if got := m.Place("scaf1", tc.start, tc.end); got != tc.want {
+			t.Fatalf("Place(%d, %d) = %+v, want %+v", tc.start, tc.end, got, tc.want)
+		}
+	}
+	if got := m.Place("chrUn", 0, 10); got.Placed {
+		t.Fatalf("unknown object placed: %+v", got)
+	}
+}
+
+func TestSegmentsSplitAtGaps(t *testing.T) {
+	m, err := Parse(strings.NewReader(testAGP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Segments("scaf1", 250), [][2]int{{0, 100}, {150, 250}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Segments = %v, want %v", got, want)
+	}
+	if got, want := m.Segments("other", 30), [][2]int{{0, 30}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Segments(other) = %v, want %v", got, want)
+	}
+}
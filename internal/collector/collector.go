@@ -84,6 +84,13 @@ func NewWriterTo(gffPath string, stdout io.Writer) (*Writer, error) {
 // WriteFragment records one hard-kept fragment and, when GFF output is enabled,
 // writes one GFF3 feature using the caller-provided per-chromosome ordinal.
 func (w *Writer) WriteFragment(chr string, ordinal int, fr digest.Fragment) error {
+	return w.WriteFragmentWithAttributes(chr, ordinal, fr, "")
+}
+
+// WriteFragmentWithAttributes is like WriteFragment, but appends extra to the
+// GFF3 attribute column. extra must already be GFF3-escaped and must not start
+// with a separator; an empty extra writes the default attributes only.
+func (w *Writer) WriteFragmentWithAttributes(chr string, ordinal int, fr digest.Fragment, extra string) error {
 	if w == nil {
 		return nil
 	}
@@ -91,9 +98,13 @@ func (w *Writer) WriteFragment(chr string, ordinal int, fr digest.Fragment) erro
 	end := fr.End
 	ln := end - fr.Start
 	if !w.disabled {
+		attrs := gff.FragmentAttributes(chr, ordinal, ln)
+		if extra != "" {
+			attrs += ";" + extra
+		}
 		if _, err := fmt.Fprintf(w.bw,
 			"%s\tradigest\tfragment\t%d\t%d\t.\t+\t.\t%s\n",
-			gff.EscapeSeqID(chr), start, end, gff.FragmentAttributes(chr, ordinal, ln)); err != nil {
+			gff.EscapeSeqID(chr), start, end, attrs); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,209 @@
+// Package composition computes per-fragment sequence composition metrics and
+// weighted distributions of those metrics across a digest.
+package composition
+
+import "math"
+
+// DustWindow is the window length, in bases, used for the low-complexity
+// score. It matches the classic DUST window.
+const DustWindow = 64
+
+// Metrics summarizes the base composition of one fragment sequence.
+type Metrics struct {
+	// GC is the G+C fraction among unambiguous A/C/G/T bases. Fragments with
+	// no unambiguous bases report 0.
+	GC float64
+	// CpG counts CG dinucleotides on the forward strand.
+	CpG int
+	// N counts bases that are not A, C, G, or T, including IUPAC codes.
+	N int
+	// Homopolymer is the longest run of one repeated A/C/G/T base.
+	Homopolymer int
+	// LowComplexity is the maximum DUST triplet score across DustWindow-bp
+	// windows. Higher values indicate lower sequence complexity.
+	LowComplexity float64
+}
+
+var baseCode [256]int8
+
+func init() {
+	for i := range baseCode {
+		baseCode[i] = -1
+	}
+	for code, b := range []byte("ACGT") {
+		baseCode[b] = int8(code)
+		baseCode[b+'a'-'A'] = int8(code)
+	}
+}
+
+// Compute returns composition metrics for seq. Lower-case bases are treated
+// the same as upper-case bases.
+func Compute(seq []byte) Metrics {
+	var m Metrics
+	acgt := 0
+	gc := 0
+	run := 0
+	prev := int8(-1)
+	for i, b := range seq {
+		code := baseCode[b]
+		if code < 0 {
+			m.N++
+			run = 0
+			prev = -1
+			continue
+		}
+		acgt++
+		if code == 1 || code == 2 {
+			gc++
+		}
+		if code == 2 && i > 0 && baseCode[seq[i-1]] == 1 {
+			m.CpG++
+		}
+		if code == prev {
+			run++
+		} else {
+			run = 1
+			prev = code
+		}
+		if run > m.Homopolymer {
+			m.Homopolymer = run
+		}
+	}
+	if acgt > 0 {
+		m.GC = float64(gc) / float64(acgt)
+	}
+	m.LowComplexity = dustScore(seq)
+	return m
+}
+
+// dustScore returns the maximum DUST score over sliding windows of seq. For a
+// window with l valid triplets, the score is sum(c*(c-1)/2) / (l-1), where c
+// is the count of each of the 64 possible triplets. Triplets that include a
+// non-ACGT base are skipped.
+func dustScore(seq []byte) float64 {
+	if len(seq) < 4 {
+		return 0
+	}
+	tripletsPerWindow := DustWindow - 2
+	triplet := func(i int) int {
+		a, b, c := baseCode[seq[i]], baseCode[seq[i+1]], baseCode[seq[i+2]]
+		if a < 0 || b < 0 || c < 0 {
+			return -1
+		}
+		return int(a)<<4 | int(b)<<2 | int(c)
+	}
+
+	var counts [64]int
+	pairs := 0
+	valid := 0
+	best := 0.0
+	score := func() {
+		if valid > 1 {
+			if s := float64(pairs) / float64(valid-1); s > best {
+				best = s
+			}
+		}
+	}
+
+	last := len(seq) - 3
+	for i := 0; i <= last; i++ {
+		if t := triplet(i); t >= 0 {
+			pairs += counts[t]
+			counts[t]++
+			valid++
+		}
+		if drop := i - tripletsPerWindow; drop >= 0 {
+			if t := triplet(drop); t >= 0 {
+				counts[t]--
+				pairs -= counts[t]
+				valid--
+			}
+		}
+		if i >= tripletsPerWindow-1 || i == last {
+			score()
+		}
+	}
+	return best
+}
+
+// Distribution is a weighted histogram of one metric. Values at or beyond the
+// last bin edge are counted in the final, open-ended bin.
+type Distribution struct {
+	Weight   float64   `json:"weight"`
+	Mean     float64   `json:"mean"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	BinWidth float64   `json:"bin_width"`
+	Bins     []float64 `json:"bins"`
+
+	sum  float64
+	seen bool
+}
+
+func newDistribution(binWidth float64, bins int) Distribution {
+	return Distribution{BinWidth: binWidth, Bins: make([]float64, bins)}
+}
+
+// Add records value with the given weight. Min and Max track every added
+// value, including zero-weight values, so they describe the scored range.
+func (d *Distribution) Add(value, weight float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	if !d.seen || value < d.Min {
+		d.Min = value
+	}
+	if !d.seen || value > d.Max {
+		d.Max = value
+	}
+	d.seen = true
+	if weight <= 0 || len(d.Bins) == 0 {
+		return
+	}
+	// The epsilon keeps values that sit exactly on a bin edge, such as a GC
+	// fraction of 0.6, from falling into the previous bin through rounding.
+	idx := int(math.Floor(value/d.BinWidth + 1e-9))
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(d.Bins) {
+		idx = len(d.Bins) - 1
+	}
+	d.Bins[idx] += weight
+	d.Weight += weight
+	d.sum += value * weight
+	d.Mean = d.sum / d.Weight
+}
+
+// Summary holds weighted distributions for every composition metric.
+type Summary struct {
+	Fragments         int          `json:"fragments"`
+	WeightedFragments float64      `json:"weighted_fragments"`
+	GC                Distribution `json:"gc_fraction"`
+	CpG               Distribution `json:"cpg_count"`
+	N                 Distribution `json:"n_count"`
+	Homopolymer       Distribution `json:"homopolymer_max"`
+	LowComplexity     Distribution `json:"low_complexity"`
+}
+
+// NewSummary returns an empty summary with the default bin layout.
+func NewSummary() Summary {
+	return Summary{
+		GC:            newDistribution(0.05, 20),
+		CpG:           newDistribution(2, 50),
+		N:             newDistribution(1, 20),
+		Homopolymer:   newDistribution(1, 32),
+		LowComplexity: newDistribution(0.5, 40),
+	}
+}
+
+// Add records one fragment's metrics with its size-selection weight.
+func (s *Summary) Add(m Metrics, weight float64) {
+	s.Fragments++
+	s.WeightedFragments += weight
+	s.GC.Add(m.GC, weight)
+	s.CpG.Add(float64(m.CpG), weight)
+	s.N.Add(float64(m.N), weight)
+	s.Homopolymer.Add(float64(m.Homopolymer), weight)
+	s.LowComplexity.Add(m.LowComplexity, weight)
+}
@@ -0,0 +1,65 @@
+package composition
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestComputeCountsBasesAndRuns(t *testing.T) {
+	m := Compute([]byte("ACGCGNNTTTTA"))
+	if math.Abs(m.GC-0.4) > 1e-12 {
+		t.Fatalf("GC = %g, want 0.4", m.GC)
+	}
+	if m.CpG != 2 {
+		t.Fatalf("CpG = %d, want 2", m.CpG)
+	}
+	if m.N != 2 {
+		t.Fatalf("N = %d, want 2", m.N)
+	}
+	if m.Homopolymer != 4 {
+		t.Fatalf("homopolymer = %d, want 4", m.Homopolymer)
+	}
+}
+
+func TestComputeDoesNotCountCpGAcrossN(t *testing.T) {
+	m := Compute([]byte("CNG"))
+	if m.CpG != 0 || m.N != 1 {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+}
+
+func TestLowComplexityRanksRepeatsAboveRandom(t *testing.T) {
+	repeat := Compute([]byte(strings.Repeat("A", 100)))
+	mixed := Compute([]byte("ACGTTGCAAGCTTCGATCGGATCCATGCAGTACGATGACTGCATGCTAGCTAGGCTAACGTTCAGT"))
+	if repeat.LowComplexity <= mixed.LowComplexity {
+		t.Fatalf("poly-A score %g should exceed mixed score %g", repeat.LowComplexity, mixed.LowComplexity)
+	}
+	// 62 identical triplets in one window: 62*61/2 / 61 = 31.
+	if math.Abs(repeat.LowComplexity-31) > 1e-12 {
+		t.Fatalf("poly-A score = %g, want 31", repeat.LowComplexity)
+	}
+}
+
+func TestSummaryWeightsDistributions(t *testing.T) {
+	s := NewSummary()
+	s.Add(Metrics{GC: 0.2, CpG: 1}, 1)
+	s.Add(Metrics{GC: 0.6, CpG: 3}, 3)
+	s.Add(Metrics{GC: 0.9, CpG: 200}, 0)
+
+	if s.Fragments != 3 || s.WeightedFragments != 4 {
+		t.Fatalf("fragment totals wrong: %+v", s)
+	}
+	if math.Abs(s.GC.Mean-0.5) > 1e-12 {
+		t.Fatalf("weighted GC mean = %g, want 0.5", s.GC.Mean)
+	}
+	if s.GC.Min != 0.2 || s.GC.Max != 0.9 {
+		t.Fatalf("GC range = [%g,%g], want [0.2,0.9]", s.GC.Min, s.GC.Max)
+	}
+	if s.GC.Bins[4] != 1 || s.GC.Bins[12] != 3 {
+		t.Fatalf("GC bins wrong: %+v", s.GC.Bins)
+	}
+	if s.CpG.Bins[len(s.CpG.Bins)-1] != 0 {
+		t.Fatalf("zero-weight overflow value should not add bin weight: %+v", s.CpG.Bins)
+	}
+}
@@ -0,0 +1,70 @@
+package design
+
+import "github.com/ericksamera/radigest/internal/screen"
+
+// CostSchedule prices a sequencing run: each lane, each sample's library
+// prep, and the enzyme units used to digest each sample with every enzyme.
+type CostSchedule struct {
+	PerLane              float64 `json:"per_lane"`
+	PerSample            float64 `json:"per_sample"`
+	PerEnzymeUnit        float64 `json:"per_enzyme_unit"`
+	EnzymeUnitsPerSample float64 `json:"enzyme_units_per_sample"`
+}
+
+// DefaultEnzymeUnitsPerSample is the units of each enzyme used per sample
+// digest.
+const DefaultEnzymeUnitsPerSample = 10
+
+// Total returns the cost of sequencing samples on lanes lanes, digesting each
+// sample with enzymes enzymes.
+func (c CostSchedule) Total(lanes, samples, enzymes int) float64 {
+	return float64(lanes)*c.PerLane + float64(samples)*c.PerSampleCost(enzymes)
+}
+
+// PerSampleCost returns the library prep and enzyme cost of one sample.
+func (c CostSchedule) PerSampleCost(enzymes int) float64 {
+	return c.PerSample + float64(enzymes)*c.EnzymeUnitsPerSample*c.PerEnzymeUnit
+}
+
+// OptimizeLanes returns the cheapest lanes x samples-per-lane configuration
+// for budget.Samples samples: the fewest lanes at which a sample on the
+// fullest lane reaches the target mean locus depth and any present and
+// callable loci minimums of target. Each sample is read only from its own
+// lane, so a lane of budget.EffectiveReadPairsPerLane read pairs holds
+// samplesPerLane = ceil(Samples/lanes) samples. Both are 0 when even one
+// sample per lane falls short.
+func OptimizeLanes(summary screen.PairSummary, budget SequencingBudget, target DesignTarget) (lanes, samplesPerLane int) {
+	depthLoci := DepthLoci(summary, budget)
+	perLane := budget.EffectiveReadPairsPerLane()
+	if depthLoci <= 0 || perLane <= 0 || budget.Samples <= 0 || target.MinPresentLoci > depthLoci || target.MinCallableLoci > depthLoci {
+		return 0, 0
+	}
+	perLaneSamples := func(lanes int) int {
+		return ceilNonnegative(float64(budget.Samples) / float64(lanes))
+	}
+	// Every target only gets easier as lanes are added and fewer samples
+	// share each one.
+	enough := func(lanes int) bool {
+		depth := perLane / float64(perLaneSamples(lanes)) / depthLoci
+		if depth < budget.TargetMeanLocusDepth {
+			return false
+		}
+		if target.MinPresentLoci > 0 && PresentFraction(summary, depth, budget, target.PresentPct)*depthLoci < target.MinPresentLoci {
+			return false
+		}
+		return target.MinCallableLoci <= 0 || CallableFraction(summary, depth, budget)*depthLoci >= target.MinCallableLoci
+	}
+	if !enough(budget.Samples) {
+		return 0, 0
+	}
+	lo, hi := 0, budget.Samples
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if enough(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, perLaneSamples(hi)
+}
@@ -0,0 +1,81 @@
+package design
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/screen"
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+func TestEvaluateSummaryPricesBudgetAndOptimalLanes(t *testing.T) {
+	summary := screen.PairSummary{
+		Enzymes:       []string{"EcoRI", "MseI"},
+		SizeSelection: sizeselect.Stats{WeightedBases: 2500, WeightedFragments: 100, MeanWeightedLength: 400},
+	}
+	cost := &CostSchedule{PerLane: 1000, PerSample: 20, PerEnzymeUnit: 0.5, EnzymeUnitsPerSample: 10}
+	// 12 samples at 10x over 100 loci: 3 lanes of 5000 hold 4 samples each,
+	// 1250 pairs or 12.5x apiece.
+	budget := SequencingBudget{ReadLayout: "pe", ReadLength: 150, LaneReadPairs: 5000, Lanes: 4, UsableReadFraction: 1, Samples: 12, TargetMeanLocusDepth: 10, MinCallDepth: 5, Cost: cost}
+	target := DesignTarget{TargetGenomePct: 2.5, CoverageTolerancePct: 0.01, Objective: ObjectiveMinCost}
+
+	cand := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	// Per sample: 20 prep + 2 enzymes x 10 units x 0.5.
+	if cand.TotalCost != 4*1000+12*30 || cand.CostPerSample != cand.TotalCost/12 {
+		t.Fatalf("budget cost = %g (%g per sample)", cand.TotalCost, cand.CostPerSample)
+	}
+	if math.Abs(cand.CostPerCallableLocus-cand.TotalCost/cand.CallableLoci) > 1e-9 {
+		t.Fatalf("cost per callable locus = %g", cand.CostPerCallableLocus)
+	}
+	if cand.OptimalLanes != 3 || cand.OptimalSamplesPerLane != 4 || cand.OptimalCost != 3*1000+12*30 || !cand.OptimalFeasible {
+		t.Fatalf("optimal configuration wrong: %+v", cand)
+	}
+
+	// Requiring most loci in every sample takes more lanes.
+	target.PresentPct = 100
+	target.MinPresentLoci = 90
+	budget.MinCallDepth = 8
+	present := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	lanes := present.OptimalLanes
+	if lanes <= 3 {
+		t.Fatalf("present-loci minimum should need more than 3 lanes, got %d", lanes)
+	}
+	if want := (12 + lanes - 1) / lanes; present.OptimalSamplesPerLane != want {
+		t.Fatalf("samples per lane = %d on %d lanes, want %d", present.OptimalSamplesPerLane, lanes, want)
+	}
+	for _, l := range []int{lanes - 1, lanes} {
+		depth := 5000 / math.Ceil(12/float64(l)) / 100
+		got := PresentFraction(summary, depth, budget, 100) * 100
+		if (got >= 90) != (l == lanes) {
+			t.Fatalf("%d lanes give %g present loci; optimum %d is not the fewest passing", l, got, lanes)
+		}
+	}
+
+	// A callable-loci minimum binds the same way.
+	callable := target
+	callable.MinPresentLoci = 0
+	callable.MinCallableLoci = 99
+	got := EvaluateSummary(summary, 100000, budget, callable, DefaultScoreWeights())
+	if got.OptimalLanes <= 3 {
+		t.Fatalf("callable-loci minimum should need more than 3 lanes, got %d", got.OptimalLanes)
+	}
+	depth := 5000 / float64(got.OptimalSamplesPerLane) / 100
+	if CallableFraction(summary, depth, budget)*100 < 99 {
+		t.Fatalf("optimum %d lanes x %d samples misses the callable-loci minimum", got.OptimalLanes, got.OptimalSamplesPerLane)
+	}
+
+	target.MinPresentLoci = 101
+	if got := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights()); got.OptimalLanes != 0 || got.OptimalFeasible {
+		t.Fatalf("an unreachable present-loci minimum should have no optimum: %+v", got)
+	}
+
+	candidates := []Candidate{
+		{EnzymeA: "A", OptimalFeasible: true, OptimalCost: 5000},
+		{EnzymeA: "B", OptimalCost: 1000},
+		{EnzymeA: "C", OptimalFeasible: true, OptimalCost: 4000},
+	}
+	SortCandidates(candidates, ObjectiveMinCost)
+	if candidates[0].EnzymeA != "C" || candidates[1].EnzymeA != "A" {
+		t.Fatalf("min-cost order = %s %s %s", candidates[0].EnzymeA, candidates[1].EnzymeA, candidates[2].EnzymeA)
+	}
+}
@@ -0,0 +1,134 @@
+package design
+
+import (
+	"math"
+
+	"github.com/ericksamera/radigest/internal/screen"
+)
+
+// Read pairs per sample at a locus are negative binomial with mean μ and
+// variance μ + Dispersion·μ²; a zero dispersion is Poisson. Without relative
+// depth weights every locus has the predicted mean locus depth. With them,
+// a locus gets that mean scaled by its share over the size-weighted mean
+// share, so the mean over loci is unchanged. Unequal pooling scales every
+// locus of a sample by that sample's share of reads.
+
+// CallableFraction returns the fraction of depth loci expected to reach
+// budget.MinCallDepth read pairs per sample when loci average meanDepth in
+// the average sample, averaged over samples. It is 0 when MinCallDepth is
+// unset.
+func CallableFraction(summary screen.PairSummary, meanDepth float64, budget SequencingBudget) float64 {
+	if budget.MinCallDepth <= 0 {
+		return 0
+	}
+	loci, reached := 0.0, 0.0
+	for _, b := range locusDepths(summary, meanDepth) {
+		loci += b.Loci
+		reached += b.Loci * sampleCallable(b.RelativeWeight, budget)
+	}
+	return safeDiv(reached, loci)
+}
+
+// PresentFraction returns the fraction of depth loci expected to be called
+// in at least presentPct percent of budget.Samples samples. Samples are
+// independent given the locus; their read pairs vary as a log-normal with
+// coefficient of variation budget.SampleCV.
+func PresentFraction(summary screen.PairSummary, meanDepth float64, budget SequencingBudget, presentPct float64) float64 {
+	if budget.MinCallDepth <= 0 || budget.Samples <= 0 || !(presentPct > 0) {
+		return 0
+	}
+	need := int(math.Ceil(presentPct*float64(budget.Samples)/100 - 1e-9))
+	loci, present := 0.0, 0.0
+	for _, b := range locusDepths(summary, meanDepth) {
+		loci += b.Loci
+		present += b.Loci * binomialAtLeast(budget.Samples, sampleCallable(b.RelativeWeight, budget), need)
+	}
+	return safeDiv(present, loci)
+}
+
+// locusDepths returns the depth-weight bins of summary with RelativeWeight
+// replaced by the mean locus depth in the average sample.
+func locusDepths(summary screen.PairSummary, meanDepth float64) []screen.DepthWeightBin {
+	dw := summary.DepthWeights
+	if dw == nil || len(dw.Bins) == 0 {
+		return []screen.DepthWeightBin{{RelativeWeight: meanDepth, Loci: 1}}
+	}
+	meanShare := dw.MeanRelativeWeight()
+	bins := make([]screen.DepthWeightBin, len(dw.Bins))
+	for i, b := range dw.Bins {
+		bins[i] = screen.DepthWeightBin{RelativeWeight: meanDepth * b.RelativeWeight / meanShare, Loci: b.Loci}
+	}
+	return bins
+}
+
+// sampleQuantiles is the number of equal-probability points used to average
+// over the per-sample read distribution.
+const sampleQuantiles = 64
+
+// sampleCallable returns the probability that a locus with the given mean
+// depth in the average sample reaches MinCallDepth in a random sample.
+func sampleCallable(depth float64, budget SequencingBudget) float64 {
+	if !(budget.SampleCV > 0) {
+		return DepthAtLeast(depth, budget.DepthDispersion, budget.MinCallDepth)
+	}
+	// Log-normal with mean 1 and the requested CV.
+	sigma := math.Sqrt(math.Log1p(budget.SampleCV * budget.SampleCV))
+	p := 0.0
+	for k := 0; k < sampleQuantiles; k++ {
+		z := math.Sqrt2 * math.Erfinv(2*(float64(k)+0.5)/sampleQuantiles-1)
+		p += DepthAtLeast(depth*math.Exp(sigma*z-sigma*sigma/2), budget.DepthDispersion, budget.MinCallDepth)
+	}
+	return p / sampleQuantiles
+}
+
+// binomialAtLeast returns P(X >= k) for X binomial with n trials of
+// probability p.
+func binomialAtLeast(n int, p float64, k int) float64 {
+	switch {
+	case k <= 0:
+		return 1
+	case k > n || p <= 0:
+		return 0
+	case p >= 1:
+		return 1
+	}
+	lp, lq := math.Log(p), math.Log1p(-p)
+	lgn, _ := math.Lgamma(float64(n) + 1)
+	total := 0.0
+	for x := k; x <= n; x++ {
+		lgx, _ := math.Lgamma(float64(x) + 1)
+		lgr, _ := math.Lgamma(float64(n-x) + 1)
+		total += math.Exp(lgn - lgx - lgr + float64(x)*lp + float64(n-x)*lq)
+	}
+	return math.Min(1, total)
+}
+
+// DepthAtLeast returns P(X >= x) for X negative binomial with the given mean
+// and dispersion, or Poisson when dispersion is 0.
+func DepthAtLeast(mean, dispersion float64, x int) float64 {
+	if x <= 0 {
+		return 1
+	}
+	if !(mean > 0) {
+		return 0
+	}
+	if math.IsInf(mean, 1) {
+		return 1
+	}
+	below := 0.0
+	for k := 0; k < x; k++ {
+		below += math.Exp(logDepthPMF(mean, dispersion, k))
+	}
+	return math.Max(0, math.Min(1, 1-below))
+}
+
+func logDepthPMF(mean, dispersion float64, k int) float64 {
+	lk, _ := math.Lgamma(float64(k) + 1)
+	if dispersion <= 0 {
+		return float64(k)*math.Log(mean) - mean - lk
+	}
+	size := 1 / dispersion
+	lgk, _ := math.Lgamma(float64(k) + size)
+	lgs, _ := math.Lgamma(size)
+	return lgk - lgs - lk + size*math.Log(size/(size+mean)) + float64(k)*math.Log(mean/(size+mean))
+}
@@ -0,0 +1,109 @@
+package design
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/screen"
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+func TestDepthAtLeastPoissonAndNegativeBinomial(t *testing.T) {
+	// P(X >= 2) for Poisson(3) is 1 - e^-3(1+3).
+	if got, want := DepthAtLeast(3, 0, 2), 1-4*math.Exp(-3); math.Abs(got-want) > 1e-12 {
+		t.Fatalf("Poisson tail = %g, want %g", got, want)
+	}
+	// NB with size 1 is geometric: P(X >= x) = (mean/(1+mean))^x.
+	if got, want := DepthAtLeast(4, 1, 3), math.Pow(0.8, 3); math.Abs(got-want) > 1e-12 {
+		t.Fatalf("geometric tail = %g, want %g", got, want)
+	}
+	if DepthAtLeast(20, 0.5, 10) >= DepthAtLeast(20, 0, 10) {
+		t.Fatal("overdispersion should leave fewer loci above a threshold below the mean")
+	}
+	if DepthAtLeast(0, 0, 1) != 0 || DepthAtLeast(0, 0, 0) != 1 {
+		t.Fatal("zero-mean or zero-threshold tails are wrong")
+	}
+}
+
+func TestEvaluateSummaryCallableLociUseDepthWeights(t *testing.T) {
+	summary := screen.PairSummary{
+		Enzymes:       []string{"EcoRI", "MseI"},
+		SizeSelection: sizeselect.Stats{WeightedBases: 2500, WeightedFragments: 100, MeanWeightedLength: 400},
+	}
+	budget := SequencingBudget{ReadLayout: "pe", ReadLength: 150, LaneReadPairs: 1000, Lanes: 1, UsableReadFraction: 1, Samples: 1, TargetMeanLocusDepth: 10, MinCallDepth: 5}
+	target := DesignTarget{TargetGenomePct: 2.5, CoverageTolerancePct: 0.01, Objective: ObjectiveCallableLoci}
+
+	uniform := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	want := DepthAtLeast(10, 0, 5)
+	if uniform.MinCallDepth != 5 || math.Abs(uniform.CallableFraction-want) > 1e-12 || math.Abs(uniform.CallableLoci-100*want) > 1e-9 {
+		t.Fatalf("uniform callable = %g (%g loci), want %g", uniform.CallableFraction, uniform.CallableLoci, want)
+	}
+
+	// Half the loci get a third of the reads of the other half: means 5 and 15.
+	summary.DepthWeights = &screen.DepthWeightSummary{Weighting: screen.DepthWeightsPCR, Bins: []screen.DepthWeightBin{
+		{RelativeWeight: 0.25, Loci: 50},
+		{RelativeWeight: 0.75, Loci: 50},
+	}}
+	skewed := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	want = (DepthAtLeast(5, 0, 5) + DepthAtLeast(15, 0, 5)) / 2
+	if math.Abs(skewed.CallableFraction-want) > 1e-12 || skewed.CallableFraction >= uniform.CallableFraction {
+		t.Fatalf("skewed callable = %g, want %g below uniform %g", skewed.CallableFraction, want, uniform.CallableFraction)
+	}
+
+	budget.DepthDispersion = 0.5
+	dispersed := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	if dispersed.CallableLoci >= skewed.CallableLoci {
+		t.Fatalf("dispersion should lower callable loci: %g vs %g", dispersed.CallableLoci, skewed.CallableLoci)
+	}
+
+	candidates := []Candidate{
+		{EnzymeA: "A", CallableLoci: 10},
+		{EnzymeA: "B", CallableLoci: 30},
+	}
+	SortCandidates(candidates, ObjectiveCallableLoci)
+	if candidates[0].EnzymeA != "B" {
+		t.Fatalf("callable-loci objective ranked %+v first", candidates[0])
+	}
+}
+
+func TestPresentFractionAcrossSamples(t *testing.T) {
+	summary := screen.PairSummary{
+		Enzymes:       []string{"EcoRI", "MseI"},
+		SizeSelection: sizeselect.Stats{WeightedBases: 2500, WeightedFragments: 100, MeanWeightedLength: 400},
+	}
+	budget := SequencingBudget{ReadLayout: "pe", ReadLength: 150, LaneReadPairs: 4000, Lanes: 1, UsableReadFraction: 1, Samples: 4, TargetMeanLocusDepth: 10, MinCallDepth: 8}
+	target := DesignTarget{TargetGenomePct: 2.5, CoverageTolerancePct: 0.01, Objective: ObjectiveBalanced, PresentPct: 75}
+
+	cand := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	// Three or four of four samples reach 8 pairs at a mean of 10.
+	q := DepthAtLeast(10, 0, 8)
+	want := math.Pow(q, 4) + 4*math.Pow(q, 3)*(1-q)
+	if math.Abs(cand.PresentFraction-want) > 1e-12 || math.Abs(cand.PresentLoci-100*want) > 1e-9 || cand.PresentPct != 75 {
+		t.Fatalf("present fraction = %g (%g loci), want %g", cand.PresentFraction, cand.PresentLoci, want)
+	}
+	if !cand.Feasible {
+		t.Fatalf("candidate without a present-loci constraint should be feasible: %+v", cand)
+	}
+
+	budget.SampleCV = 0.5
+	uneven := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	if uneven.PresentFraction >= cand.PresentFraction {
+		t.Fatalf("unequal pooling should lose loci: %g vs %g", uneven.PresentFraction, cand.PresentFraction)
+	}
+
+	target.MinPresentLoci = 90
+	constrained := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	if constrained.Feasible || !strings.Contains(constrained.DecisionReason, "present loci shortfall") {
+		t.Fatalf("candidate below --min-present-loci should be infeasible: %+v", constrained)
+	}
+}
+
+func TestBinomialAtLeast(t *testing.T) {
+	if got, want := binomialAtLeast(3, 0.5, 2), 0.5; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("P(Bin(3,0.5) >= 2) = %g, want %g", got, want)
+	}
+	if binomialAtLeast(5, 0.3, 0) != 1 || binomialAtLeast(5, 0.3, 6) != 0 || binomialAtLeast(5, 1, 5) != 1 {
+		t.Fatal("binomial tail edge cases are wrong")
+	}
+}
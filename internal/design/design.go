@@ -22,6 +22,25 @@ const (
 	ObjectiveDepthFirst             Objective = "depth-first"
 	ObjectiveFeasibleLowestCoverage Objective = "feasible-lowest-coverage"
 	ObjectiveMaxDepth               Objective = "max-depth"
+	// ObjectiveCallableLoci ranks by CallableLoci; it requires a
+	// MinCallDepth.
+	ObjectiveCallableLoci Objective = "callable-loci"
+	// ObjectiveMinCost ranks pairs that meet the targets at their optimal
+	// lane count by OptimalCost; it requires a cost schedule.
+	ObjectiveMinCost Objective = "min-cost"
+)
+
+// DepthDenominator selects the locus count that read pairs are spread over
+// when predicting mean locus depth.
+type DepthDenominator string
+
+const (
+	// DepthWeightedFragments divides by size-selection weighted fragments.
+	DepthWeightedFragments DepthDenominator = "weighted-fragments"
+	// DepthEffectiveUniqueLoci divides by effective unique loci, counting each
+	// duplicate-fragment cluster once. It requires pair summaries with
+	// duplicate detection; otherwise weighted fragments are used.
+	DepthEffectiveUniqueLoci DepthDenominator = "effective-unique-loci"
 )
 
 // GenomeBases records reference-size denominators used for genome-percentage
@@ -33,13 +52,25 @@ type GenomeBases struct {
 }
 
 type SequencingBudget struct {
-	ReadLayout           string  `json:"read_layout"`
-	ReadLength           int     `json:"read_length"`
-	LaneReadPairs        float64 `json:"lane_read_pairs"`
-	Lanes                int     `json:"lanes"`
-	UsableReadFraction   float64 `json:"usable_read_fraction"`
-	Samples              int     `json:"samples"`
-	TargetMeanLocusDepth float64 `json:"target_mean_locus_depth"`
+	ReadLayout           string           `json:"read_layout"`
+	ReadLength           int              `json:"read_length"`
+	LaneReadPairs        float64          `json:"lane_read_pairs"`
+	Lanes                int              `json:"lanes"`
+	UsableReadFraction   float64          `json:"usable_read_fraction"`
+	Samples              int              `json:"samples"`
+	TargetMeanLocusDepth float64          `json:"target_mean_locus_depth"`
+	DepthDenominator     DepthDenominator `json:"depth_denominator,omitempty"`
+	// MinCallDepth, when > 0, is the read pairs per sample a locus needs to
+	// be called. DepthDispersion is the negative binomial overdispersion of
+	// per-locus depth, and DepthWeights the relative read shares used.
+	// SampleCV is the coefficient of variation of read pairs per sample
+	// from unequal pooling.
+	MinCallDepth    int                   `json:"min_call_depth,omitempty"`
+	DepthDispersion float64               `json:"depth_dispersion,omitempty"`
+	DepthWeights    screen.DepthWeighting `json:"depth_weights,omitempty"`
+	SampleCV        float64               `json:"sample_cv,omitempty"`
+	// Cost, when set, prices the budget and each pair's optimal lanes.
+	Cost *CostSchedule `json:"cost,omitempty"`
 }
 
 func (b SequencingBudget) EffectiveReadPairsPerLane() float64 {
@@ -61,6 +92,55 @@ type DesignTarget struct {
 	TargetGenomePct      float64   `json:"target_genome_pct"`
 	CoverageTolerancePct float64   `json:"coverage_tolerance_pct"`
 	Objective            Objective `json:"objective"`
+	// TargetSNPs, when > 0, replaces TargetGenomePct: candidates must predict
+	// a SNP count within SNPTolerancePct percent of it.
+	TargetSNPs      float64   `json:"target_snps,omitempty"`
+	SNPTolerancePct float64   `json:"snp_tolerance_pct,omitempty"`
+	SNPModel        *SNPModel `json:"snp_model,omitempty"`
+	// PresentPct, when > 0, reports the loci called in at least that
+	// percentage of samples. Candidates with fewer than MinPresentLoci such
+	// loci are infeasible.
+	PresentPct     float64 `json:"present_pct,omitempty"`
+	MinPresentLoci float64 `json:"min_present_loci,omitempty"`
+	// MinCallableLoci, when > 0, marks candidates with fewer callable loci
+	// infeasible. It needs a budget MinCallDepth.
+	MinCallableLoci float64 `json:"min_callable_loci,omitempty"`
+}
+
+// SNPModel predicts the SNPs in sequenced bases of recovered loci. Pair
+// summaries scored with known SNPs use those counts; otherwise Theta, the
+// per-base nucleotide diversity, is scaled by Watterson's a_n for the
+// budget's diploid samples.
+type SNPModel struct {
+	Theta    float64 `json:"theta,omitempty"`
+	KnownVCF string  `json:"known_vcf,omitempty"`
+}
+
+// PredictSNPs returns the expected SNP count in the sequenced bases of
+// summary, or 0 when the summary was scored without sequenced bases.
+func (m SNPModel) PredictSNPs(summary screen.PairSummary, samples int) float64 {
+	seq := summary.Sequenced
+	if seq == nil {
+		return 0
+	}
+	if seq.KnownSNPs {
+		return seq.WeightedKnownSNPs
+	}
+	return m.Theta * WattersonA(2*samples) * seq.WeightedBases
+}
+
+// WattersonA returns a_n = sum 1/i for i in 1..n-1, the expected number of
+// segregating sites per unit theta in a sample of n chromosomes. It is 1 for
+// n < 2 so a single haplotype still yields theta per base.
+func WattersonA(n int) float64 {
+	if n < 2 {
+		return 1
+	}
+	a := 0.0
+	for i := 1; i < n; i++ {
+		a += 1 / float64(i)
+	}
+	return a
 }
 
 type ScoreWeights struct {
@@ -89,6 +169,17 @@ type Candidate struct {
 
 	FitScore float64 `json:"fit_score"`
 	FitLoss  float64 `json:"fit_loss"`
+	// SizeMin, SizeMax, and SizeMean are the size window, and the model mean
+	// when the model has one, the pair was scored with. WindowsSearched is the
+	// number of windows tried when the window was searched.
+	SizeMin         int     `json:"size_min"`
+	SizeMax         int     `json:"size_max"`
+	SizeMean        float64 `json:"size_mean,omitempty"`
+	WindowsSearched int     `json:"windows_searched,omitempty"`
+
+	// ParetoRank is the candidate's non-dominated front, 1 being best; see
+	// AssignParetoRanks.
+	ParetoRank int `json:"pareto_rank"`
 
 	TargetGenomePct              float64 `json:"target_genome_pct"`
 	PredictedWeightedGenomePct   float64 `json:"predicted_weighted_genome_pct"`
@@ -103,8 +194,35 @@ type Candidate struct {
 	ReadPairsPerSample           float64 `json:"read_pairs_per_sample"`
 	RequiredPairsPerSampleTarget float64 `json:"required_pairs_per_sample_full_target"`
 
-	WeightedBases        float64 `json:"weighted_bases"`
-	WeightedFragments    float64 `json:"weighted_fragments"`
+	WeightedBases       float64 `json:"weighted_bases"`
+	WeightedFragments   float64 `json:"weighted_fragments"`
+	EffectiveUniqueLoci float64 `json:"effective_unique_loci,omitempty"`
+	DuplicateClusters   int     `json:"duplicate_clusters,omitempty"`
+	MappableLoci        float64 `json:"mappable_loci,omitempty"`
+	// AmplifiedFragments and AmplifiedBases weight WeightedFragments and
+	// WeightedBases by PCR amplification, when it is modeled.
+	AmplifiedFragments float64 `json:"amplified_fragments,omitempty"`
+	AmplifiedBases     float64 `json:"amplified_bases,omitempty"`
+	// SequencedBases and PredictedSNPs are set when the target carries a
+	// SNP model. With a SNP target, the *_rel coverage fields compare
+	// PredictedSNPs with TargetSNPs instead of genome percentages.
+	SequencedBases float64 `json:"sequenced_bases,omitempty"`
+	PredictedSNPs  float64 `json:"predicted_snps"`
+	TargetSNPs     float64 `json:"target_snps,omitempty"`
+	DepthLoci      float64 `json:"depth_loci"`
+	// CallableFraction is the fraction of DepthLoci expected to reach
+	// MinCallDepth read pairs per sample, and CallableLoci the expected
+	// number that do.
+	MinCallDepth     int     `json:"min_call_depth,omitempty"`
+	CallableFraction float64 `json:"callable_fraction,omitempty"`
+	CallableLoci     float64 `json:"callable_loci,omitempty"`
+	MinCallableLoci  float64 `json:"min_callable_loci,omitempty"`
+	// PresentFraction is the fraction of DepthLoci expected to be called in
+	// at least PresentPct percent of samples, and PresentLoci their number.
+	PresentPct           float64 `json:"present_pct,omitempty"`
+	PresentFraction      float64 `json:"present_fraction,omitempty"`
+	PresentLoci          float64 `json:"present_loci,omitempty"`
+	MinPresentLoci       float64 `json:"min_present_loci,omitempty"`
 	MeanWeightedLength   float64 `json:"mean_weighted_length"`
 	RawBasesInWindow     int64   `json:"raw_bases_in_window"`
 	RawFragmentsInWindow int     `json:"raw_fragments_in_window"`
@@ -114,13 +232,26 @@ type Candidate struct {
 	MaxSamplesPerLaneFullTarget  int     `json:"max_samples_per_lane_full_target"`
 	MaxSamplesTotalFullTarget    int     `json:"max_samples_total_full_target"`
 	LanesRequiredFullTarget      int     `json:"lanes_required_full_target"`
-	AdapterThresholdBP           int     `json:"adapter_threshold_bp"`
-	OverlapThresholdBP           int     `json:"overlap_threshold_bp,omitempty"`
-	MeanInsertCategory           string  `json:"mean_insert_category"`
-	InsertPenalty                float64 `json:"insert_penalty"`
-	Records                      int     `json:"records"`
-	CachedCutSites               int     `json:"cached_cut_sites"`
-	CacheMemoryEstimateBytes     int64   `json:"cache_memory_estimate_bytes"`
+	// With a cost schedule, TotalCost prices the budget's lanes and samples
+	// and CostPerCallableLocus divides it by CallableLoci, staying 0 when no
+	// locus is callable or the budget has no MinCallDepth. OptimalLanes and
+	// OptimalSamplesPerLane are the cheapest configuration OptimizeLanes
+	// finds, or 0 if none meets the targets; OptimalFeasible also requires
+	// the coverage target.
+	TotalCost                float64 `json:"total_cost,omitempty"`
+	CostPerSample            float64 `json:"cost_per_sample,omitempty"`
+	CostPerCallableLocus     float64 `json:"cost_per_callable_locus,omitempty"`
+	OptimalLanes             int     `json:"optimal_lanes,omitempty"`
+	OptimalSamplesPerLane    int     `json:"optimal_samples_per_lane,omitempty"`
+	OptimalCost              float64 `json:"optimal_cost,omitempty"`
+	OptimalFeasible          bool    `json:"optimal_feasible,omitempty"`
+	AdapterThresholdBP       int     `json:"adapter_threshold_bp"`
+	OverlapThresholdBP       int     `json:"overlap_threshold_bp,omitempty"`
+	MeanInsertCategory       string  `json:"mean_insert_category"`
+	InsertPenalty            float64 `json:"insert_penalty"`
+	Records                  int     `json:"records"`
+	CachedCutSites           int     `json:"cached_cut_sites"`
+	CacheMemoryEstimateBytes int64   `json:"cache_memory_estimate_bytes"`
 }
 
 func CountReferenceBases(path string) (GenomeBases, error) {
@@ -151,13 +282,34 @@ func CountReferenceBases(path string) (GenomeBases, error) {
 func ValidateObjective(value string) (Objective, error) {
 	obj := Objective(strings.ToLower(strings.TrimSpace(value)))
 	switch obj {
-	case ObjectiveBalanced, ObjectiveClosestCoverage, ObjectiveDepthFirst, ObjectiveFeasibleLowestCoverage, ObjectiveMaxDepth:
+	case ObjectiveBalanced, ObjectiveClosestCoverage, ObjectiveDepthFirst, ObjectiveFeasibleLowestCoverage, ObjectiveMaxDepth, ObjectiveCallableLoci, ObjectiveMinCost:
 		return obj, nil
 	default:
-		return "", fmt.Errorf("invalid objective %q; use balanced, closest-coverage, depth-first, feasible-lowest-coverage, or max-depth", value)
+		return "", fmt.Errorf("invalid objective %q; use balanced, closest-coverage, depth-first, feasible-lowest-coverage, max-depth, callable-loci, or min-cost", value)
+	}
+}
+
+func ValidateDepthDenominator(value string) (DepthDenominator, error) {
+	d := DepthDenominator(strings.ToLower(strings.TrimSpace(value)))
+	switch d {
+	case "":
+		return DepthWeightedFragments, nil
+	case DepthWeightedFragments, DepthEffectiveUniqueLoci:
+		return d, nil
+	default:
+		return "", fmt.Errorf("invalid depth denominator %q; use weighted-fragments or effective-unique-loci", value)
 	}
 }
 
+// DepthLoci returns the locus count used as the depth denominator for
+// summary under budget.
+func DepthLoci(summary screen.PairSummary, budget SequencingBudget) float64 {
+	if budget.DepthDenominator == DepthEffectiveUniqueLoci && summary.Duplicates != nil {
+		return summary.Duplicates.EffectiveUniqueLoci
+	}
+	return summary.SizeSelection.WeightedFragments
+}
+
 func EvaluateSummary(summary screen.PairSummary, genomeBases int64, budget SequencingBudget, target DesignTarget, weights ScoreWeights) Candidate {
 	weightedBases := summary.SizeSelection.WeightedBases
 	weightedFragments := summary.SizeSelection.WeightedFragments
@@ -167,14 +319,28 @@ func EvaluateSummary(summary screen.PairSummary, genomeBases int64, budget Seque
 	}
 
 	readPairsPerSample := budget.ReadPairsPerSample()
-	expectedDepth := safeDiv(readPairsPerSample, weightedFragments)
-	requiredPairsPerSample := budget.TargetMeanLocusDepth * weightedFragments
+	depthLoci := DepthLoci(summary, budget)
+	expectedDepth := safeDiv(readPairsPerSample, depthLoci)
+	requiredPairsPerSample := budget.TargetMeanLocusDepth * depthLoci
 
 	coverageDelta := weightedGenomePct - target.TargetGenomePct
 	coverageErrorPctPoints := math.Abs(coverageDelta)
 	coverageErrorRel := safeDiv(coverageErrorPctPoints, target.TargetGenomePct)
 	overcoverageRel := safeDiv(math.Max(0, coverageDelta), target.TargetGenomePct)
 	undercoverageRel := safeDiv(math.Max(0, -coverageDelta), target.TargetGenomePct)
+	coverageCloseEnough := coverageErrorPctPoints <= target.CoverageTolerancePct
+
+	predictedSNPs := 0.0
+	if target.SNPModel != nil {
+		predictedSNPs = target.SNPModel.PredictSNPs(summary, budget.Samples)
+	}
+	if target.TargetSNPs > 0 {
+		snpDelta := predictedSNPs - target.TargetSNPs
+		coverageErrorRel = math.Abs(snpDelta) / target.TargetSNPs
+		overcoverageRel = math.Max(0, snpDelta) / target.TargetSNPs
+		undercoverageRel = math.Max(0, -snpDelta) / target.TargetSNPs
+		coverageCloseEnough = 100*coverageErrorRel <= target.SNPTolerancePct
+	}
 	depthMargin := expectedDepth - budget.TargetMeanLocusDepth
 	depthShortfallRel := safeDiv(math.Max(0, -depthMargin), budget.TargetMeanLocusDepth)
 
@@ -200,9 +366,12 @@ func EvaluateSummary(summary screen.PairSummary, genomeBases int64, budget Seque
 		lanesRequired = ceilNonnegative(float64(budget.Samples) * requiredPairsPerSample / budget.EffectiveReadPairsPerLane())
 	}
 
-	coverageCloseEnough := coverageErrorPctPoints <= target.CoverageTolerancePct
 	depthSufficient := expectedDepth >= budget.TargetMeanLocusDepth
-	feasible := weightedFragments > 0 && coverageCloseEnough && depthSufficient
+	presentFraction := PresentFraction(summary, expectedDepth, budget, target.PresentPct)
+	presentLoci := presentFraction * depthLoci
+	callableFraction := CallableFraction(summary, expectedDepth, budget)
+	callableLoci := callableFraction * depthLoci
+	feasible := weightedFragments > 0 && coverageCloseEnough && depthSufficient && presentLoci >= target.MinPresentLoci && callableLoci >= target.MinCallableLoci
 
 	loss := weights.Coverage*coverageErrorRel +
 		weights.Depth*depthShortfallRel +
@@ -217,6 +386,9 @@ func EvaluateSummary(summary screen.PairSummary, genomeBases int64, budget Seque
 	candidate := Candidate{
 		Enzymes:                      append([]string(nil), summary.Enzymes...),
 		Feasible:                     feasible,
+		SizeMin:                      summary.MinLength,
+		SizeMax:                      summary.MaxLength,
+		SizeMean:                     summary.SizeSelection.Mean,
 		FitScore:                     score,
 		FitLoss:                      loss,
 		TargetGenomePct:              target.TargetGenomePct,
@@ -233,9 +405,12 @@ func EvaluateSummary(summary screen.PairSummary, genomeBases int64, budget Seque
 		RequiredPairsPerSampleTarget: requiredPairsPerSample,
 		WeightedBases:                weightedBases,
 		WeightedFragments:            weightedFragments,
+		DepthLoci:                    depthLoci,
 		MeanWeightedLength:           summary.SizeSelection.MeanWeightedLength,
 		RawBasesInWindow:             summary.SizeSelection.RawBasesInWindow,
 		RawFragmentsInWindow:         summary.SizeSelection.RawFragmentsInWindow,
+		AmplifiedFragments:           summary.SizeSelection.AmplifiedFragments,
+		AmplifiedBases:               summary.SizeSelection.AmplifiedBases,
 		BudgetSupportedGenomePct:     budgetSupportedGenomePct,
 		BudgetSupportedWeightedBases: budgetSupportedWeightedBases,
 		MaxSamplesPerLaneFullTarget:  maxSamplesPerLane,
@@ -249,6 +424,42 @@ func EvaluateSummary(summary screen.PairSummary, genomeBases int64, budget Seque
 		CachedCutSites:               summary.Screening.CachedCutSites,
 		CacheMemoryEstimateBytes:     summary.Screening.CacheMemoryEstimateBytes,
 	}
+	if summary.Duplicates != nil {
+		candidate.EffectiveUniqueLoci = summary.Duplicates.EffectiveUniqueLoci
+		candidate.DuplicateClusters = summary.Duplicates.Clusters
+	}
+	if summary.Mappability != nil {
+		candidate.MappableLoci = summary.Mappability.MappableLoci
+	}
+	if budget.MinCallDepth > 0 {
+		candidate.MinCallDepth = budget.MinCallDepth
+		candidate.CallableFraction = callableFraction
+		candidate.CallableLoci = callableLoci
+		candidate.MinCallableLoci = target.MinCallableLoci
+	}
+	if target.PresentPct > 0 {
+		candidate.PresentPct = target.PresentPct
+		candidate.PresentFraction = presentFraction
+		candidate.PresentLoci = presentLoci
+		candidate.MinPresentLoci = target.MinPresentLoci
+	}
+	if cost := budget.Cost; cost != nil && budget.Samples > 0 {
+		enzymes := len(summary.Enzymes)
+		candidate.TotalCost = cost.Total(budget.Lanes, budget.Samples, enzymes)
+		candidate.CostPerSample = candidate.TotalCost / float64(budget.Samples)
+		candidate.CostPerCallableLocus = safeDiv(candidate.TotalCost, candidate.CallableLoci)
+		if lanes, perLane := OptimizeLanes(summary, budget, target); lanes > 0 {
+			candidate.OptimalLanes = lanes
+			candidate.OptimalSamplesPerLane = perLane
+			candidate.OptimalCost = cost.Total(lanes, budget.Samples, enzymes)
+			candidate.OptimalFeasible = weightedFragments > 0 && coverageCloseEnough
+		}
+	}
+	if target.SNPModel != nil && summary.Sequenced != nil {
+		candidate.SequencedBases = summary.Sequenced.WeightedBases
+	}
+	candidate.PredictedSNPs = predictedSNPs
+	candidate.TargetSNPs = target.TargetSNPs
 	if len(summary.Enzymes) > 0 {
 		candidate.EnzymeA = summary.Enzymes[0]
 	}
@@ -303,7 +514,16 @@ func DecisionReason(c Candidate, target DesignTarget) string {
 		return "no recovered weighted fragments"
 	}
 	parts := make([]string, 0, 4)
-	if c.CoverageErrorPctPoints <= target.CoverageTolerancePct {
+	if target.TargetSNPs > 0 {
+		switch {
+		case 100*c.CoverageErrorRel <= target.SNPTolerancePct:
+			parts = append(parts, "matches target SNPs")
+		case c.PredictedSNPs < c.TargetSNPs:
+			parts = append(parts, fmt.Sprintf("under target SNPs by %.6f", c.TargetSNPs-c.PredictedSNPs))
+		default:
+			parts = append(parts, fmt.Sprintf("over target SNPs by %.6f", c.PredictedSNPs-c.TargetSNPs))
+		}
+	} else if c.CoverageErrorPctPoints <= target.CoverageTolerancePct {
 		parts = append(parts, "matches target coverage")
 	} else if c.PredictedWeightedGenomePct < c.TargetGenomePct {
 		parts = append(parts, fmt.Sprintf("under target coverage by %.6f pct-points", c.TargetGenomePct-c.PredictedWeightedGenomePct))
@@ -315,6 +535,20 @@ func DecisionReason(c Candidate, target DesignTarget) string {
 	} else {
 		parts = append(parts, fmt.Sprintf("depth shortfall %.6f", c.TargetMeanLocusDepth-c.PredictedMeanLocusDepth))
 	}
+	if c.MinPresentLoci > 0 {
+		if c.PresentLoci >= c.MinPresentLoci {
+			parts = append(parts, "meets present loci")
+		} else {
+			parts = append(parts, fmt.Sprintf("present loci shortfall %.6f", c.MinPresentLoci-c.PresentLoci))
+		}
+	}
+	if c.MinCallableLoci > 0 {
+		if c.CallableLoci >= c.MinCallableLoci {
+			parts = append(parts, "meets callable loci")
+		} else {
+			parts = append(parts, fmt.Sprintf("callable loci shortfall %.6f", c.MinCallableLoci-c.CallableLoci))
+		}
+	}
 	if c.MeanInsertCategory == "mean_lt_read_length_adapter_risk" || c.MeanInsertCategory == "mean_lt_2_read_lengths_overlap_risk" {
 		parts = append(parts, c.MeanInsertCategory)
 	}
@@ -347,6 +581,23 @@ func SortCandidates(candidates []Candidate, objective Objective) {
 			if a.PredictedWeightedGenomePct != b.PredictedWeightedGenomePct {
 				return a.PredictedWeightedGenomePct < b.PredictedWeightedGenomePct
 			}
+		case ObjectiveCallableLoci:
+			if a.CallableLoci != b.CallableLoci {
+				return a.CallableLoci > b.CallableLoci
+			}
+			if a.CoverageErrorRel != b.CoverageErrorRel {
+				return a.CoverageErrorRel < b.CoverageErrorRel
+			}
+		case ObjectiveMinCost:
+			if a.OptimalFeasible != b.OptimalFeasible {
+				return a.OptimalFeasible
+			}
+			if a.OptimalCost != b.OptimalCost {
+				return a.OptimalCost < b.OptimalCost
+			}
+			if a.CoverageErrorRel != b.CoverageErrorRel {
+				return a.CoverageErrorRel < b.CoverageErrorRel
+			}
 		case ObjectiveMaxDepth:
 			if a.PredictedMeanLocusDepth != b.PredictedMeanLocusDepth {
 				return a.PredictedMeanLocusDepth > b.PredictedMeanLocusDepth
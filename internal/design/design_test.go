@@ -1,10 +1,12 @@
 package design
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/ericksamera/radigest/internal/paralog"
 	"github.com/ericksamera/radigest/internal/screen"
 	"github.com/ericksamera/radigest/internal/sizeselect"
 )
@@ -56,6 +58,56 @@ func TestEvaluateSummaryDepthShortfall(t *testing.T) {
 	}
 }
 
+func TestEvaluateSummaryEffectiveUniqueLociDenominator(t *testing.T) {
+	summary := screen.PairSummary{
+		Enzymes:       []string{"EcoRI", "MseI"},
+		SizeSelection: sizeselect.Stats{WeightedBases: 2500, WeightedFragments: 100, MeanWeightedLength: 400},
+		Duplicates:    &paralog.Summary{Clusters: 5, EffectiveUniqueLoci: 80},
+	}
+	budget := SequencingBudget{ReadLayout: "pe", ReadLength: 150, LaneReadPairs: 800, Lanes: 1, UsableReadFraction: 1, Samples: 1, TargetMeanLocusDepth: 10}
+	target := DesignTarget{TargetGenomePct: 2.5, CoverageTolerancePct: 0.01, Objective: ObjectiveBalanced}
+
+	cand := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	if cand.PredictedMeanLocusDepth != 8 || cand.DepthLoci != 100 {
+		t.Fatalf("default denominator depth = %g over %g loci, want 8 over 100", cand.PredictedMeanLocusDepth, cand.DepthLoci)
+	}
+	if cand.EffectiveUniqueLoci != 80 || cand.DuplicateClusters != 5 {
+		t.Fatalf("duplicate fields not copied: %+v", cand)
+	}
+
+	budget.DepthDenominator = DepthEffectiveUniqueLoci
+	cand = EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	if cand.PredictedMeanLocusDepth != 10 || cand.RequiredPairsPerSampleTarget != 800 || !cand.Feasible {
+		t.Fatalf("effective-loci denominator not applied: %+v", cand)
+	}
+}
+
+func TestEvaluateSummarySNPTarget(t *testing.T) {
+	summary := screen.PairSummary{
+		Enzymes:       []string{"EcoRI", "MseI"},
+		SizeSelection: sizeselect.Stats{WeightedBases: 2500, WeightedFragments: 100, MeanWeightedLength: 400},
+		Sequenced:     &screen.SequencedSummary{ReadLength: 150, Paired: true, WeightedBases: 3000},
+	}
+	budget := SequencingBudget{ReadLayout: "pe", ReadLength: 150, LaneReadPairs: 4000, Lanes: 1, UsableReadFraction: 1, Samples: 2, TargetMeanLocusDepth: 10}
+	target := DesignTarget{TargetSNPs: 55, SNPTolerancePct: 5, Objective: ObjectiveBalanced, SNPModel: &SNPModel{Theta: 0.01}}
+
+	// Two diploid samples: a_4 = 1 + 1/2 + 1/3.
+	cand := EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	if math.Abs(cand.PredictedSNPs-55) > 1e-9 || cand.SequencedBases != 3000 || !cand.Feasible {
+		t.Fatalf("theta prediction wrong: %+v", cand)
+	}
+	if cand.CoverageErrorRel > 1e-9 || cand.DecisionReason != "matches target SNPs; meets target mean locus depth" {
+		t.Fatalf("SNP target not used for fit: rel=%g reason=%q", cand.CoverageErrorRel, cand.DecisionReason)
+	}
+
+	summary.Sequenced.KnownSNPs = true
+	summary.Sequenced.WeightedKnownSNPs = 44
+	cand = EvaluateSummary(summary, 100000, budget, target, DefaultScoreWeights())
+	if cand.PredictedSNPs != 44 || cand.Feasible || math.Abs(cand.UndercoverageRel-0.2) > 1e-9 {
+		t.Fatalf("known SNPs should override theta: %+v", cand)
+	}
+}
+
 func TestSortCandidatesBalancedPrefersFeasibleThenLoss(t *testing.T) {
 	candidates := []Candidate{
 		{EnzymeA: "B", EnzymeB: "C", Feasible: false, FitLoss: 0.01},
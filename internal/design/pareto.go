@@ -0,0 +1,118 @@
+package design
+
+import "math"
+
+// ParetoOptions adds optional criteria to the Pareto comparison of
+// candidates. Coverage error, depth margin, and insert penalty are always
+// compared.
+type ParetoOptions struct {
+	// Cost compares OptimalCost; pairs with no optimal lane count lose.
+	Cost bool
+	// MappableLoci compares MappableLoci, higher being better.
+	MappableLoci bool
+}
+
+// Criteria returns the compared criteria as design.tsv column names.
+func (o ParetoOptions) Criteria() []string {
+	names := []string{"coverage_error_rel", "depth_margin", "insert_penalty"}
+	if o.Cost {
+		names = append(names, "optimal_cost")
+	}
+	if o.MappableLoci {
+		names = append(names, "mappable_loci")
+	}
+	return names
+}
+
+// Values returns c's criteria in Criteria order.
+func (o ParetoOptions) Values(c Candidate) []float64 {
+	v := []float64{c.CoverageErrorRel, c.DepthMargin, c.InsertPenalty}
+	if o.Cost {
+		v = append(v, c.OptimalCost)
+	}
+	if o.MappableLoci {
+		v = append(v, c.MappableLoci)
+	}
+	return v
+}
+
+// costs returns c's criteria, each oriented so that lower is better.
+func (o ParetoOptions) costs(c Candidate) []float64 {
+	v := o.Values(c)
+	v[1] = -v[1]
+	if o.Cost && c.OptimalLanes <= 0 {
+		v[3] = math.Inf(1)
+	}
+	if o.MappableLoci {
+		v[len(v)-1] = -v[len(v)-1]
+	}
+	for i, x := range v {
+		if math.IsNaN(x) {
+			v[i] = math.Inf(1)
+		}
+	}
+	return v
+}
+
+// AssignParetoRanks sets ParetoRank on every candidate by non-dominated
+// sorting: rank 1 is the set no other candidate beats on every criterion,
+// rank 2 the set left non-dominated once rank 1 is removed, and so on. It
+// returns the number of fronts.
+func AssignParetoRanks(candidates []Candidate, opt ParetoOptions) int {
+	n := len(candidates)
+	costs := make([][]float64, n)
+	for i, c := range candidates {
+		costs[i] = opt.costs(c)
+	}
+	dominatedBy := make([]int, n)
+	dominates := make([][]int, n)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			switch {
+			case paretoDominates(costs[i], costs[j]):
+				dominates[i] = append(dominates[i], j)
+				dominatedBy[j]++
+			case paretoDominates(costs[j], costs[i]):
+				dominates[j] = append(dominates[j], i)
+				dominatedBy[i]++
+			}
+		}
+	}
+	front := make([]int, 0, n)
+	for i := range candidates {
+		if dominatedBy[i] == 0 {
+			front = append(front, i)
+		}
+	}
+	rank := 0
+	for len(front) > 0 {
+		rank++
+		var next []int
+		for _, i := range front {
+			candidates[i].ParetoRank = rank
+			for _, j := range dominates[i] {
+				dominatedBy[j]--
+				if dominatedBy[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		front = next
+	}
+	return rank
+}
+
+// paretoDominates reports whether a is no worse than b on every criterion
+// and better on at least one.
+func paretoDominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] > b[i] {
+			return false
+		}
+		if a[i] < b[i] {
+			better = true
+		}
+	}
+	return better
+}
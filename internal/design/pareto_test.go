@@ -0,0 +1,38 @@
+package design
+
+import "testing"
+
+func TestAssignParetoRanks(t *testing.T) {
+	candidates := []Candidate{
+		{EnzymeA: "closest", CoverageErrorRel: 0.01, DepthMargin: 1, InsertPenalty: 0.25},
+		{EnzymeA: "deepest", CoverageErrorRel: 0.30, DepthMargin: 9, InsertPenalty: 0.25},
+		{EnzymeA: "dominated", CoverageErrorRel: 0.30, DepthMargin: 1, InsertPenalty: 0.25},
+		{EnzymeA: "worst", CoverageErrorRel: 0.50, DepthMargin: 0, InsertPenalty: 1},
+		{EnzymeA: "clean-insert", CoverageErrorRel: 0.40, DepthMargin: 0, InsertPenalty: 0},
+	}
+	if fronts := AssignParetoRanks(candidates, ParetoOptions{}); fronts != 3 {
+		t.Fatalf("fronts = %d, want 3", fronts)
+	}
+	want := map[string]int{"closest": 1, "deepest": 1, "clean-insert": 1, "dominated": 2, "worst": 3}
+	for _, c := range candidates {
+		if c.ParetoRank != want[c.EnzymeA] {
+			t.Fatalf("%s rank = %d, want %d", c.EnzymeA, c.ParetoRank, want[c.EnzymeA])
+		}
+	}
+
+	// Cost breaks the tie in favor of the pair with a cheap optimum; a pair
+	// with no optimum is worst on cost.
+	candidates[2].OptimalLanes, candidates[2].OptimalCost = 1, 100
+	candidates[0].OptimalLanes, candidates[0].OptimalCost = 2, 200
+	opt := ParetoOptions{Cost: true}
+	AssignParetoRanks(candidates, opt)
+	if candidates[2].ParetoRank != 1 || candidates[1].ParetoRank != 1 {
+		t.Fatalf("cost ranks: dominated=%d deepest=%d, want 1 and 1", candidates[2].ParetoRank, candidates[1].ParetoRank)
+	}
+	if got := opt.Criteria(); len(got) != 4 || got[3] != "optimal_cost" {
+		t.Fatalf("criteria = %v", got)
+	}
+	if v := opt.Values(candidates[0]); v[1] != 1 || v[3] != 200 {
+		t.Fatalf("values = %v, want raw depth margin and cost", v)
+	}
+}
@@ -0,0 +1,217 @@
+package design
+
+import (
+	"math"
+	"sort"
+)
+
+// SweepGrid lists the settings a sensitivity sweep visits. An empty list
+// keeps the baseline setting.
+type SweepGrid struct {
+	SizeMean           []float64 `json:"size_mean,omitempty"`
+	SizeSD             []float64 `json:"size_sd,omitempty"`
+	UsableReadFraction []float64 `json:"usable_read_fraction,omitempty"`
+	Samples            []int     `json:"samples,omitempty"`
+}
+
+// Enabled reports whether any setting is swept.
+func (g SweepGrid) Enabled() bool {
+	return len(g.SizeMean) > 0 || len(g.SizeSD) > 0 || len(g.UsableReadFraction) > 0 || len(g.Samples) > 0
+}
+
+// SweepPoint is one combination of swept settings. A zero SizeMean keeps
+// each pair's own mean, and a zero SizeSD a model without one.
+type SweepPoint struct {
+	SizeMean           float64 `json:"size_mean"`
+	SizeSD             float64 `json:"size_sd"`
+	UsableReadFraction float64 `json:"usable_read_fraction"`
+	Samples            int     `json:"samples"`
+}
+
+// Points returns base with every combination of grid settings applied, in
+// size mean, size SD, usable fraction, samples order.
+func (g SweepGrid) Points(base SweepPoint) []SweepPoint {
+	means, sds, usable, samples := g.SizeMean, g.SizeSD, g.UsableReadFraction, g.Samples
+	if len(means) == 0 {
+		means = []float64{base.SizeMean}
+	}
+	if len(sds) == 0 {
+		sds = []float64{base.SizeSD}
+	}
+	if len(usable) == 0 {
+		usable = []float64{base.UsableReadFraction}
+	}
+	if len(samples) == 0 {
+		samples = []int{base.Samples}
+	}
+	points := make([]SweepPoint, 0, len(means)*len(sds)*len(usable)*len(samples))
+	for _, mean := range means {
+		for _, sd := range sds {
+			for _, u := range usable {
+				for _, n := range samples {
+					points = append(points, SweepPoint{SizeMean: mean, SizeSD: sd, UsableReadFraction: u, Samples: n})
+				}
+			}
+		}
+	}
+	return points
+}
+
+// Budget returns budget with p's usable read fraction and sample count.
+func (p SweepPoint) Budget(budget SequencingBudget) SequencingBudget {
+	budget.UsableReadFraction = p.UsableReadFraction
+	budget.Samples = p.Samples
+	return budget
+}
+
+// SweepResult is one pair re-evaluated at one sweep point. BaseRank is the
+// pair's rank in the baseline run and Rank its rank among the swept pairs
+// at Point. Skipped, when set, says why the pair could not be evaluated at
+// Point; such a pair is infeasible there and ranks after every evaluated
+// pair.
+type SweepResult struct {
+	Point     SweepPoint
+	BaseRank  int
+	Rank      int
+	Skipped   string
+	Candidate Candidate
+}
+
+// RankSweepPoint ranks the swept pairs at one point by objective. Each
+// candidate is one pair re-evaluated at point, with Rank still holding its
+// baseline rank. A non-empty skipped[i] marks candidates[i] as not
+// evaluated; skipped may be nil. Results keep the order of candidates, and
+// record each pair's own size mean when point keeps it.
+func RankSweepPoint(point SweepPoint, candidates []Candidate, skipped []string, objective Objective) []SweepResult {
+	reason := func(i int) string {
+		if i < len(skipped) {
+			return skipped[i]
+		}
+		return ""
+	}
+	var sorted, unevaluated []Candidate
+	for i, c := range candidates {
+		if reason(i) != "" {
+			unevaluated = append(unevaluated, c)
+		} else {
+			sorted = append(sorted, c)
+		}
+	}
+	SortCandidates(sorted, objective)
+	sort.SliceStable(unevaluated, func(i, j int) bool { return unevaluated[i].Rank < unevaluated[j].Rank })
+	ranks := make(map[[2]string]int, len(candidates))
+	for i, c := range append(sorted, unevaluated...) {
+		ranks[[2]string{c.EnzymeA, c.EnzymeB}] = i + 1
+	}
+	results := make([]SweepResult, len(candidates))
+	for i, c := range candidates {
+		rank := ranks[[2]string{c.EnzymeA, c.EnzymeB}]
+		if why := reason(i); why != "" {
+			c.Feasible = false
+			c.DecisionReason = why
+		}
+		results[i] = SweepResult{Point: point, BaseRank: c.Rank, Rank: rank, Skipped: reason(i), Candidate: c}
+		results[i].Candidate.Rank = rank
+		if point.SizeMean == 0 {
+			results[i].Point.SizeMean = c.SizeMean
+		}
+	}
+	return results
+}
+
+// SweepRegion bounds the sweep settings at which a pair stays feasible:
+// Min and Max hold the lowest and highest value of each setting over its
+// feasible points.
+type SweepRegion struct {
+	Min SweepPoint `json:"min"`
+	Max SweepPoint `json:"max"`
+}
+
+// SweepStability summarizes one pair over every point of a sweep.
+type SweepStability struct {
+	Enzymes        []string `json:"enzymes"`
+	BaseRank       int      `json:"base_rank"`
+	Points         int      `json:"points"`
+	FeasiblePoints int      `json:"feasible_points"`
+	// SkippedPoints counts points where the pair could not be evaluated,
+	// such as a size mean outside its window.
+	SkippedPoints int `json:"skipped_points,omitempty"`
+	// SameRankPoints counts points where the pair keeps its baseline rank,
+	// and TopPoints those where it ranks first.
+	SameRankPoints int     `json:"same_rank_points"`
+	TopPoints      int     `json:"top_points"`
+	MeanRank       float64 `json:"mean_rank"`
+	BestRank       int     `json:"best_rank"`
+	WorstRank      int     `json:"worst_rank"`
+	// MaxRankShift is the largest distance from the baseline rank.
+	MaxRankShift int `json:"max_rank_shift"`
+	// FeasibleRegion is nil when the pair is feasible at no point.
+	FeasibleRegion *SweepRegion `json:"feasible_region,omitempty"`
+}
+
+// SummarizeSweep returns the stability of each pair in results, in
+// baseline rank order.
+func SummarizeSweep(results []SweepResult) []SweepStability {
+	index := make(map[int]int)
+	var out []SweepStability
+	for _, r := range results {
+		i, ok := index[r.BaseRank]
+		if !ok {
+			i = len(out)
+			index[r.BaseRank] = i
+			out = append(out, SweepStability{
+				Enzymes:   append([]string(nil), r.Candidate.Enzymes...),
+				BaseRank:  r.BaseRank,
+				BestRank:  r.Rank,
+				WorstRank: r.Rank,
+			})
+		}
+		s := &out[i]
+		s.Points++
+		s.MeanRank += float64(r.Rank)
+		s.BestRank = min(s.BestRank, r.Rank)
+		s.WorstRank = max(s.WorstRank, r.Rank)
+		shift := r.Rank - r.BaseRank
+		if shift < 0 {
+			shift = -shift
+		}
+		s.MaxRankShift = max(s.MaxRankShift, shift)
+		if r.Skipped != "" {
+			s.SkippedPoints++
+		}
+		if r.Rank == r.BaseRank {
+			s.SameRankPoints++
+		}
+		if r.Rank == 1 {
+			s.TopPoints++
+		}
+		if r.Candidate.Feasible {
+			s.FeasiblePoints++
+			s.FeasibleRegion = s.FeasibleRegion.extend(r.Point)
+		}
+	}
+	for i := range out {
+		out[i].MeanRank /= float64(out[i].Points)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].BaseRank < out[j].BaseRank })
+	return out
+}
+
+func (r *SweepRegion) extend(p SweepPoint) *SweepRegion {
+	if r == nil {
+		return &SweepRegion{Min: p, Max: p}
+	}
+	r.Min = SweepPoint{
+		SizeMean:           math.Min(r.Min.SizeMean, p.SizeMean),
+		SizeSD:             math.Min(r.Min.SizeSD, p.SizeSD),
+		UsableReadFraction: math.Min(r.Min.UsableReadFraction, p.UsableReadFraction),
+		Samples:            min(r.Min.Samples, p.Samples),
+	}
+	r.Max = SweepPoint{
+		SizeMean:           math.Max(r.Max.SizeMean, p.SizeMean),
+		SizeSD:             math.Max(r.Max.SizeSD, p.SizeSD),
+		UsableReadFraction: math.Max(r.Max.UsableReadFraction, p.UsableReadFraction),
+		Samples:            max(r.Max.Samples, p.Samples),
+	}
+	return r
+}
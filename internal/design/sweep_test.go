@@ -0,0 +1,79 @@
+package design
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSweepGridPoints(t *testing.T) {
+	grid := SweepGrid{UsableReadFraction: []float64{0.5, 1}, Samples: []int{10, 20}}
+	if !grid.Enabled() || (SweepGrid{}).Enabled() {
+		t.Fatal("Enabled should report whether any setting is swept")
+	}
+	points := grid.Points(SweepPoint{SizeSD: 85, UsableReadFraction: 0.9, Samples: 96})
+	want := []SweepPoint{
+		{SizeSD: 85, UsableReadFraction: 0.5, Samples: 10},
+		{SizeSD: 85, UsableReadFraction: 0.5, Samples: 20},
+		{SizeSD: 85, UsableReadFraction: 1, Samples: 10},
+		{SizeSD: 85, UsableReadFraction: 1, Samples: 20},
+	}
+	if !reflect.DeepEqual(points, want) {
+		t.Fatalf("points = %+v, want %+v", points, want)
+	}
+	budget := points[1].Budget(SequencingBudget{LaneReadPairs: 1000, UsableReadFraction: 0.9, Samples: 96})
+	if budget.LaneReadPairs != 1000 || budget.UsableReadFraction != 0.5 || budget.Samples != 20 {
+		t.Fatalf("budget = %+v", budget)
+	}
+}
+
+func TestRankSweepPointAndSummarize(t *testing.T) {
+	low := SweepPoint{SizeMean: 300, Samples: 10}
+	high := SweepPoint{Samples: 20}
+	var results []SweepResult
+	// EcoRI-MseI ranks first at the low point and second at the high one.
+	results = append(results, RankSweepPoint(low, []Candidate{
+		{EnzymeA: "EcoRI", EnzymeB: "MseI", Enzymes: []string{"EcoRI", "MseI"}, Rank: 1, SizeMean: 275, Feasible: true, CoverageErrorRel: 0.1},
+		{EnzymeA: "PstI", EnzymeB: "MspI", Enzymes: []string{"PstI", "MspI"}, Rank: 2, SizeMean: 250, Feasible: false, CoverageErrorRel: 0.2},
+	}, nil, ObjectiveClosestCoverage)...)
+	results = append(results, RankSweepPoint(high, []Candidate{
+		{EnzymeA: "EcoRI", EnzymeB: "MseI", Enzymes: []string{"EcoRI", "MseI"}, Rank: 1, SizeMean: 275, Feasible: true, CoverageErrorRel: 0.3},
+		{EnzymeA: "PstI", EnzymeB: "MspI", Enzymes: []string{"PstI", "MspI"}, Rank: 2, SizeMean: 250, Feasible: true, CoverageErrorRel: 0.2},
+	}, nil, ObjectiveClosestCoverage)...)
+
+	if r := results[2]; r.BaseRank != 1 || r.Rank != 2 || r.Candidate.Rank != 2 || r.Point.SizeMean != 275 {
+		t.Fatalf("high-point result = %+v, want base rank 1, rank 2 at the pair's own mean", r)
+	}
+
+	stability := SummarizeSweep(results)
+	if len(stability) != 2 {
+		t.Fatalf("stability = %+v", stability)
+	}
+	first := stability[0]
+	if first.BaseRank != 1 || first.Points != 2 || first.FeasiblePoints != 2 || first.SameRankPoints != 1 || first.TopPoints != 1 || first.MeanRank != 1.5 || first.WorstRank != 2 || first.MaxRankShift != 1 {
+		t.Fatalf("first pair stability = %+v", first)
+	}
+	if want := (SweepRegion{Min: SweepPoint{SizeMean: 275, Samples: 10}, Max: SweepPoint{SizeMean: 300, Samples: 20}}); first.FeasibleRegion == nil || *first.FeasibleRegion != want {
+		t.Fatalf("first pair region = %+v, want %+v", first.FeasibleRegion, want)
+	}
+	second := stability[1]
+	if second.FeasiblePoints != 1 || second.BestRank != 1 || second.FeasibleRegion == nil || second.FeasibleRegion.Min.Samples != 20 {
+		t.Fatalf("second pair stability = %+v", second)
+	}
+}
+
+func TestRankSweepPointRanksSkippedPairsLast(t *testing.T) {
+	results := RankSweepPoint(SweepPoint{SizeMean: 900}, []Candidate{
+		{EnzymeA: "EcoRI", EnzymeB: "MseI", Rank: 1, Feasible: true},
+		{EnzymeA: "PstI", EnzymeB: "MspI", Rank: 2, Feasible: true, CoverageErrorRel: 0.5},
+	}, []string{"size mean 900 outside window", ""}, ObjectiveClosestCoverage)
+	skipped := results[0]
+	if skipped.Rank != 2 || skipped.Candidate.Feasible || skipped.Candidate.DecisionReason != "size mean 900 outside window" {
+		t.Fatalf("skipped result = %+v, want an infeasible last-ranked pair", skipped)
+	}
+	if results[1].Rank != 1 {
+		t.Fatalf("evaluated pair rank = %d, want 1", results[1].Rank)
+	}
+	if st := SummarizeSweep(results); st[0].SkippedPoints != 1 || st[0].FeasiblePoints != 0 || st[0].FeasibleRegion != nil {
+		t.Fatalf("skipped pair stability = %+v", st[0])
+	}
+}
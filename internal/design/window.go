@@ -0,0 +1,135 @@
+package design
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+// WindowGrid lists the size-selection settings searched for each pair. An
+// empty list keeps the base setting.
+type WindowGrid struct {
+	Min  []int     `json:"min,omitempty"`
+	Max  []int     `json:"max,omitempty"`
+	Mean []float64 `json:"mean,omitempty"`
+}
+
+// maxGridPoints bounds the values one grid flag may expand to.
+const maxGridPoints = 10000
+
+// Enabled reports whether any setting is searched.
+func (g WindowGrid) Enabled() bool {
+	return len(g.Min) > 0 || len(g.Max) > 0 || len(g.Mean) > 0
+}
+
+// Configs returns base with every combination of grid settings applied, in
+// min, max, mean order. Combinations with Max < Min are skipped. A zero base
+// Mean stays zero so each window defaults to its own midpoint.
+func (g WindowGrid) Configs(base sizeselect.Config) []sizeselect.Config {
+	mins, maxs, means := g.Min, g.Max, g.Mean
+	if len(mins) == 0 {
+		mins = []int{base.Min}
+	}
+	if len(maxs) == 0 {
+		maxs = []int{base.Max}
+	}
+	if len(means) == 0 {
+		means = []float64{base.Mean}
+	}
+	configs := make([]sizeselect.Config, 0, len(mins)*len(maxs)*len(means))
+	for _, lo := range mins {
+		for _, hi := range maxs {
+			if hi < lo {
+				continue
+			}
+			for _, mean := range means {
+				cfg := base
+				cfg.Min, cfg.Max, cfg.Mean = lo, hi, mean
+				configs = append(configs, cfg)
+			}
+		}
+	}
+	return configs
+}
+
+// ParseGrid parses a comma-separated list of values and LO:HI:STEP ranges,
+// which include LO and every STEP up to HI.
+func ParseGrid(value string) ([]float64, error) {
+	var out []float64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ":")
+		nums := make([]float64, len(fields))
+		for i, f := range fields {
+			v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("grid value %q must be a finite number", f)
+			}
+			nums[i] = v
+		}
+		switch len(nums) {
+		case 1:
+			out = append(out, nums[0])
+		case 3:
+			lo, hi, step := nums[0], nums[1], nums[2]
+			if !(step > 0) || hi < lo {
+				return nil, fmt.Errorf("grid range %q needs LO <= HI and STEP > 0", part)
+			}
+			if (hi-lo)/step >= maxGridPoints {
+				return nil, fmt.Errorf("grid range %q has more than %d points", part, maxGridPoints)
+			}
+			for i := 0; ; i++ {
+				v := lo + float64(i)*step
+				if v > hi+1e-9*step {
+					break
+				}
+				out = append(out, v)
+			}
+		default:
+			return nil, fmt.Errorf("grid entry %q must be a value or LO:HI:STEP", part)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("grid %q has no values", value)
+	}
+	if len(out) > maxGridPoints {
+		return nil, fmt.Errorf("grid %q has more than %d points", value, maxGridPoints)
+	}
+	return out, nil
+}
+
+// ParseIntGrid is ParseGrid for whole-number settings such as window bounds.
+func ParseIntGrid(value string) ([]int, error) {
+	values, err := ParseGrid(value)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int, len(values))
+	for i, v := range values {
+		if v != math.Trunc(v) || v < 0 {
+			return nil, fmt.Errorf("grid value %g must be a whole number >= 0", v)
+		}
+		out[i] = int(v)
+	}
+	return out, nil
+}
+
+// BestWindow returns the candidate objective ranks first among one pair's
+// window candidates, with WindowsSearched set to their number. candidates is
+// reordered.
+func BestWindow(candidates []Candidate, objective Objective) Candidate {
+	if len(candidates) == 0 {
+		return Candidate{}
+	}
+	SortCandidates(candidates, objective)
+	best := candidates[0]
+	best.Rank = 0
+	best.WindowsSearched = len(candidates)
+	return best
+}
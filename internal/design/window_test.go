@@ -0,0 +1,59 @@
+package design
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+func TestParseGrid(t *testing.T) {
+	got, err := ParseGrid("200:300:50, 425")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{200, 250, 300, 425}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseGrid = %v, want %v", got, want)
+	}
+	if got, err := ParseGrid("0.1:0.3:0.1"); err != nil || len(got) != 3 {
+		t.Fatalf("fractional steps should include HI: %v %v", got, err)
+	}
+	for _, bad := range []string{"", "300:200:10", "1:2:0", "1:2", "x", "0:1e9:1"} {
+		if _, err := ParseGrid(bad); err == nil {
+			t.Fatalf("ParseGrid(%q) returned nil error", bad)
+		}
+	}
+	if _, err := ParseIntGrid("100:200:12.5"); err == nil {
+		t.Fatal("ParseIntGrid accepted a fractional window bound")
+	}
+}
+
+func TestWindowGridConfigs(t *testing.T) {
+	base := sizeselect.Config{Model: sizeselect.ModelNormal, Min: 100, Max: 500, SD: 30}
+	configs := WindowGrid{Min: []int{200, 300}, Max: []int{250, 400}}.Configs(base)
+	var windows [][2]int
+	for _, c := range configs {
+		if c.Mean != 0 || c.SD != 30 {
+			t.Fatalf("grid changed unsearched settings: %+v", c)
+		}
+		windows = append(windows, [2]int{c.Min, c.Max})
+	}
+	if want := [][2]int{{200, 250}, {200, 400}, {300, 400}}; !reflect.DeepEqual(windows, want) {
+		t.Fatalf("windows = %v, want %v", windows, want)
+	}
+	if got := (WindowGrid{Mean: []float64{250, 350}}).Configs(base); len(got) != 2 || got[1].Mean != 350 || got[1].Min != 100 {
+		t.Fatalf("mean grid configs = %+v", got)
+	}
+}
+
+func TestBestWindow(t *testing.T) {
+	windows := []Candidate{
+		{EnzymeA: "EcoRI", SizeMin: 100, Feasible: false, FitLoss: 0.1},
+		{EnzymeA: "EcoRI", SizeMin: 200, Feasible: true, FitLoss: 0.5},
+		{EnzymeA: "EcoRI", SizeMin: 300, Feasible: true, FitLoss: 0.2},
+	}
+	best := BestWindow(windows, ObjectiveBalanced)
+	if best.SizeMin != 300 || best.WindowsSearched != 3 {
+		t.Fatalf("best window = %+v, want the feasible 300 bp window of 3", best)
+	}
+}
@@ -3,6 +3,7 @@ package digest
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/ericksamera/radigest/internal/enzyme"
@@ -12,26 +13,109 @@ import (
 type Fragment struct {
 	Start int
 	End   int
+	// Possible flags ends cut at a possible site, one that matches only
+	// through a reference ambiguity code: PossibleStart and PossibleEnd.
+	// It is always zero unless Options.Ambiguity is set.
+	Possible uint8
+}
+
+// Fragment.Possible bits.
+const (
+	PossibleStart uint8 = 1 << iota
+	PossibleEnd
+)
+
+// PossibleEnds returns how many ends of f are cut at possible sites.
+func (f Fragment) PossibleEnds() int {
+	return int(f.Possible&PossibleStart) + int(f.Possible&PossibleEnd)>>1
+}
+
+// Ambiguity selects how reference IUPAC ambiguity codes are matched.
+type Ambiguity int
+
+const (
+	// AmbiguityOff never matches a site across an ambiguity code.
+	AmbiguityOff Ambiguity = iota
+	// AmbiguityCut treats possible sites as cuts.
+	AmbiguityCut
+	// AmbiguityNoCut counts possible sites but does not cut at them.
+	AmbiguityNoCut
+	// AmbiguityHalf cuts at possible sites and weights each fragment by 0.5
+	// per possible end, as if the site were heterozygous.
+	AmbiguityHalf
+)
+
+var ambiguityNames = []string{"off", "cut", "nocut", "half"}
+
+func (a Ambiguity) String() string {
+	if a < 0 || int(a) >= len(ambiguityNames) {
+		return fmt.Sprintf("Ambiguity(%d)", int(a))
+	}
+	return ambiguityNames[a]
+}
+
+// ParseAmbiguity parses off, cut, nocut, or half.
+func ParseAmbiguity(s string) (Ambiguity, error) {
+	for i, name := range ambiguityNames {
+		if strings.EqualFold(s, name) {
+			return Ambiguity(i), nil
+		}
+	}
+	return AmbiguityOff, fmt.Errorf("unknown ambiguity mode %q (want off, cut, nocut, or half)", s)
 }
 
 // Stats summarizes kept digest fragments without materializing Fragment values.
 type Stats struct {
 	Fragments int
 	Bases     int
+	// Possible counts kept fragments with at least one end at a possible
+	// site. PossibleSites counts every possible site scanned, including
+	// those AmbiguityNoCut does not cut.
+	Possible      int
+	PossibleSites int
+	// Weighted counts kept fragments at 0.5 per possible end under
+	// AmbiguityHalf, and at 1 otherwise.
+	Weighted float64
+}
+
+// possibleMarks records the cut coordinates of possible sites so fragments
+// can be flagged as they are emitted. A nil set marks nothing.
+type possibleMarks struct {
+	cuts map[int]bool
+	half bool
 }
 
-func (s *Stats) addIfKept(start, end, min, max int) {
+func (m possibleMarks) flags(start, end int) uint8 {
+	var f uint8
+	if m.cuts[start] {
+		f |= PossibleStart
+	}
+	if m.cuts[end] {
+		f |= PossibleEnd
+	}
+	return f
+}
+
+func (s *Stats) addIfKept(start, end, min, max int, marks possibleMarks) {
 	if ln := end - start; ln >= min && ln <= max {
 		s.Fragments++
 		s.Bases += ln
+		weight := 1.0
+		if f := (Fragment{Possible: marks.flags(start, end)}); f.Possible != 0 {
+			s.Possible++
+			if marks.half {
+				weight = math.Pow(0.5, float64(f.PossibleEnds()))
+			}
+		}
+		s.Weighted += weight
 	}
 }
 
-func (s *Stats) addTerminalIfKept(start, end, min, max int) {
+func (s *Stats) addTerminalIfKept(start, end, min, max int, marks possibleMarks) {
 	if end <= start {
 		return
 	}
-	s.addIfKept(start, end, min, max)
+	s.addIfKept(start, end, min, max, marks)
 }
 
 type matcher struct {
@@ -42,9 +126,10 @@ type matcher struct {
 }
 
 type Options struct {
-	AllowSame   bool // keep AA/BB neighbors in double digest
-	StrictCuts  bool // error if site has no caret and CutIndex==0 (mid-site fallback)
-	IncludeEnds bool // also emit terminal chromosome/contig-end fragments
+	AllowSame   bool      // keep AA/BB neighbors in double digest
+	StrictCuts  bool      // error if site has no caret and CutIndex==0 (mid-site fallback)
+	IncludeEnds bool      // also emit terminal chromosome/contig-end fragments
+	Ambiguity   Ambiguity // match sites across reference IUPAC ambiguity codes
 }
 
 // Plan precompiles up to two enzymes (A,B) for fast reuse.
@@ -52,6 +137,7 @@ type Plan struct {
 	m           [2]matcher // m[0] = A (required), m[1] = B (optional)
 	allowSame   bool
 	includeEnds bool
+	ambiguity   Ambiguity
 }
 
 func NewPlanWithOptions(ens []enzyme.Enzyme, opt Options) Plan {
@@ -66,6 +152,7 @@ func TryNewPlanWithOptions(ens []enzyme.Enzyme, opt Options) (Plan, error) {
 	var p Plan
 	p.allowSame = opt.AllowSame
 	p.includeEnds = opt.IncludeEnds
+	p.ambiguity = opt.Ambiguity
 
 	n := 2
 	if len(ens) < n {
@@ -101,7 +188,8 @@ func TryNewPlanWithOptions(ens []enzyme.Enzyme, opt Options) (Plan, error) {
 			anchor: enzyme.BestMaskAnchor(mask),
 			offset: offset,
 		}
-		if enzyme.IsExactACGT(site) {
+		// The exact fast path cannot see ambiguity codes in the reference.
+		if enzyme.IsExactACGT(site) && opt.Ambiguity == AmbiguityOff {
 			mat.exact = []byte(strings.ToUpper(site))
 		}
 		p.m[i] = mat
@@ -116,12 +204,50 @@ type cutScanner struct {
 	mat matcher
 	seq []byte
 	pos int
+
+	// Ambiguity matching: possible is nil when off. Possible cuts are added
+	// to possible unless noCut skips them; possibleSites counts both.
+	possible      map[int]bool
+	noCut         bool
+	possibleSites int
 }
 
 func newCutScanner(mat matcher, seq []byte) cutScanner {
 	return cutScanner{mat: mat, seq: seq}
 }
 
+// scanner returns a scanner for enzyme i that records possible sites in
+// marks when the plan matches ambiguity codes.
+func (p Plan) scanner(i int, seq []byte, marks possibleMarks) cutScanner {
+	s := newCutScanner(p.m[i], seq)
+	s.possible = marks.cuts
+	s.noCut = p.ambiguity == AmbiguityNoCut
+	return s
+}
+
+// marks returns an empty possible-site set when the plan matches ambiguity
+// codes, and a nil one otherwise.
+func (p Plan) marks() possibleMarks {
+	if p.ambiguity == AmbiguityOff {
+		return possibleMarks{}
+	}
+	return possibleMarks{cuts: make(map[int]bool), half: p.ambiguity == AmbiguityHalf}
+}
+
+// Ambiguity returns the plan's ambiguity mode.
+func (p Plan) Ambiguity() Ambiguity {
+	return p.ambiguity
+}
+
+// Weight returns the ambiguity weight of fr: 0.5 per possible end under
+// AmbiguityHalf, and 1 otherwise.
+func (p Plan) Weight(fr Fragment) float64 {
+	if p.ambiguity != AmbiguityHalf || fr.Possible == 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(fr.PossibleEnds()))
+}
+
 func (s *cutScanner) next() (int, bool) {
 	if len(s.mat.exact) > 0 {
 		return s.nextExact()
@@ -137,9 +263,24 @@ func (s *cutScanner) nextMask() (int, bool) {
 	for s.pos <= len(s.seq)-n {
 		pos := s.pos
 		s.pos++
-		if enzyme.MatchMaskAt(s.mat.mask, s.mat.anchor, s.seq[pos:pos+n]) {
-			return pos + s.mat.offset, true
+		if s.possible == nil {
+			if enzyme.MatchMaskAt(s.mat.mask, s.mat.anchor, s.seq[pos:pos+n]) {
+				return pos + s.mat.offset, true
+			}
+			continue
 		}
+		match, possible := enzyme.MatchMaskAmbiguousAt(s.mat.mask, s.mat.anchor, s.seq[pos:pos+n])
+		if !match {
+			continue
+		}
+		if possible {
+			s.possibleSites++
+			if s.noCut {
+				continue
+			}
+			s.possible[pos+s.mat.offset] = true
+		}
+		return pos + s.mat.offset, true
 	}
 	return 0, false
 }
@@ -179,14 +320,56 @@ func emitTerminalIfKept(start, end, min, max int, emit func(Fragment) error) err
 // deterministic genomic cut-coordinate order. If emit returns an error,
 // scanning stops and that error is returned.
 func (p Plan) CutsEach(seq []byte, emit func(int) error) error {
-	if p.m[0].mask == nil { // no enzymes compiled
+	return p.EnzymeCutsEach(0, seq, emit)
+}
+
+// Enzymes returns the number of compiled enzymes: 0, 1, or 2.
+func (p Plan) Enzymes() int {
+	n := 0
+	for _, m := range p.m {
+		if m.mask != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Site returns the recognition-site length and cut offset of enzyme i
+// (0 = A, 1 = B). Both are zero for an enzyme the plan does not compile.
+func (p Plan) Site(i int) (length, offset int) {
+	if i < 0 || i >= len(p.m) {
+		return 0, 0
+	}
+	return len(p.m[i].mask), p.m[i].offset
+}
+
+// SiteAt returns the bounds of a recognition site in seq whose top-strand
+// cut falls at cut, trying enzyme A first. ok is false when no site of the
+// plan cuts there, as at a contig end.
+func (p Plan) SiteAt(seq []byte, cut int) (start, end int, ok bool) {
+	for _, m := range p.m {
+		start := cut - m.offset
+		if m.mask == nil || start < 0 || start+len(m.mask) > len(seq) {
+			continue
+		}
+		if enzyme.MatchMaskAt(m.mask, m.anchor, seq[start:start+len(m.mask)]) {
+			return start, start + len(m.mask), true
+		}
+	}
+	return 0, 0, false
+}
+
+// EnzymeCutsEach is like CutsEach, but streams the cuts of enzyme i
+// (0 = A, 1 = B). An enzyme the plan does not compile has no cuts.
+func (p Plan) EnzymeCutsEach(i int, seq []byte, emit func(int) error) error {
+	if i < 0 || i >= len(p.m) || p.m[i].mask == nil {
 		return nil
 	}
 	if emit == nil {
 		return fmt.Errorf("digest cut emit callback is nil")
 	}
 
-	scan := newCutScanner(p.m[0], seq)
+	scan := p.scanner(i, seq, p.marks())
 	for {
 		cut, ok := scan.next()
 		if !ok {
@@ -198,6 +381,29 @@ func (p Plan) CutsEach(seq []byte, emit func(int) error) error {
 	}
 }
 
+// PossibleSites counts the sites of every enzyme in seq that match only
+// through a reference ambiguity code. It is zero when ambiguity matching is
+// off.
+func (p Plan) PossibleSites(seq []byte) int {
+	if p.ambiguity == AmbiguityOff {
+		return 0
+	}
+	n := 0
+	for i := range p.m {
+		if p.m[i].mask == nil {
+			continue
+		}
+		scan := p.scanner(i, seq, p.marks())
+		for {
+			if _, ok := scan.next(); !ok {
+				break
+			}
+		}
+		n += scan.possibleSites
+	}
+	return n
+}
+
 // Cuts returns sorted cut coordinates for the first enzyme in the plan.
 func (p Plan) Cuts(seq []byte) []int {
 	if p.m[0].mask == nil {
@@ -226,8 +432,16 @@ func (p Plan) DigestEach(seq []byte, min, max int, emit func(Fragment) error) er
 	if emit == nil {
 		return fmt.Errorf("digest emit callback is nil")
 	}
+	marks := p.marks()
+	if marks.cuts != nil {
+		inner := emit
+		emit = func(fr Fragment) error {
+			fr.Possible = marks.flags(fr.Start, fr.End)
+			return inner(fr)
+		}
+	}
 
-	aScan := newCutScanner(p.m[0], seq)
+	aScan := p.scanner(0, seq, marks)
 	aPos, aOK := aScan.next()
 
 	// Single-enzyme mode: only the previous cut coordinate is needed.
@@ -260,7 +474,7 @@ func (p Plan) DigestEach(seq []byte, min, max int, emit func(Fragment) error) er
 	}
 
 	// Double-enzyme mode: merge the two naturally sorted cut-coordinate streams.
-	bScan := newCutScanner(p.m[1], seq)
+	bScan := p.scanner(1, seq, marks)
 	bPos, bOK := bScan.next()
 	prevType := -1 // 0=A, 1=B
 	prevPos := 0
@@ -453,36 +667,39 @@ func (p Plan) DigestStats(seq []byte, min, max int) Stats {
 		return stats
 	}
 
-	aScan := newCutScanner(p.m[0], seq)
+	marks := p.marks()
+	aScan := p.scanner(0, seq, marks)
 	aPos, aOK := aScan.next()
 
 	// Single-enzyme mode: only the previous cut coordinate is needed.
 	if p.m[1].mask == nil {
 		if !aOK {
 			if p.includeEnds {
-				stats.addTerminalIfKept(0, len(seq), min, max)
+				stats.addTerminalIfKept(0, len(seq), min, max, marks)
 			}
+			stats.PossibleSites = aScan.possibleSites
 			return stats
 		}
 		if p.includeEnds {
-			stats.addTerminalIfKept(0, aPos, min, max)
+			stats.addTerminalIfKept(0, aPos, min, max, marks)
 		}
 		prevPos := aPos
 		for {
 			pos, ok := aScan.next()
 			if !ok {
 				if p.includeEnds {
-					stats.addTerminalIfKept(prevPos, len(seq), min, max)
+					stats.addTerminalIfKept(prevPos, len(seq), min, max, marks)
 				}
+				stats.PossibleSites = aScan.possibleSites
 				return stats
 			}
-			stats.addIfKept(prevPos, pos, min, max)
+			stats.addIfKept(prevPos, pos, min, max, marks)
 			prevPos = pos
 		}
 	}
 
 	// Double-enzyme mode: merge the two naturally sorted cut-coordinate streams.
-	bScan := newCutScanner(p.m[1], seq)
+	bScan := p.scanner(1, seq, marks)
 	bPos, bOK := bScan.next()
 	prevType := -1 // 0=A, 1=B
 	prevPos := 0
@@ -500,7 +717,7 @@ func (p Plan) DigestStats(seq []byte, min, max int) Stats {
 		hasB := bOK && bPos == pos
 
 		if p.includeEnds && !sawCut {
-			stats.addTerminalIfKept(0, pos, min, max)
+			stats.addTerminalIfKept(0, pos, min, max, marks)
 		}
 		sawCut = true
 		lastPos = pos
@@ -509,7 +726,7 @@ func (p Plan) DigestStats(seq []byte, min, max int) Stats {
 			// Coincident cuts are barriers. Count one zero-length fragment for
 			// the site if the caller's size range allows it, then reset
 			// adjacency so no fragment bridges across the coincident cut.
-			stats.addIfKept(pos, pos, min, max)
+			stats.addIfKept(pos, pos, min, max, marks)
 			aPos, aOK = aScan.next()
 			bPos, bOK = bScan.next()
 			prevType = -1
@@ -526,16 +743,17 @@ func (p Plan) DigestStats(seq []byte, min, max int) Stats {
 		}
 
 		if prevType != -1 && (p.allowSame || prevType != curType) {
-			stats.addIfKept(prevPos, pos, min, max)
+			stats.addIfKept(prevPos, pos, min, max, marks)
 		}
 		prevType, prevPos = curType, pos
 	}
+	stats.PossibleSites = aScan.possibleSites + bScan.possibleSites
 	if p.includeEnds {
 		if !sawCut {
-			stats.addTerminalIfKept(0, len(seq), min, max)
+			stats.addTerminalIfKept(0, len(seq), min, max, marks)
 			return stats
 		}
-		stats.addTerminalIfKept(lastPos, len(seq), min, max)
+		stats.addTerminalIfKept(lastPos, len(seq), min, max, marks)
 	}
 	return stats
 }
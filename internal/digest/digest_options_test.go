@@ -84,3 +84,57 @@ func TestTryNewPlanWithOptions_ValidEnzymesCompile(t *testing.T) {
 		t.Fatalf("valid plan produced no fragments")
 	}
 }
+
+func TestAmbiguityModes(t *testing.T) {
+	eA := enzyme.DB["EcoRI"]
+	// EcoRI sites at 4 and 36; GARTTC at 20 is a possible site; GANTTC never matches.
+	seq := []byte("AAAAGAATTCAAAAAAAAAAGARTTCAAAAAAAAAAGAATTCAAAAGANTTCAAA")
+
+	cases := []struct {
+		mode     Ambiguity
+		want     []Fragment
+		weighted float64
+	}{
+		{AmbiguityOff, []Fragment{{Start: 5, End: 37}}, 1},
+		{AmbiguityNoCut, []Fragment{{Start: 5, End: 37}}, 1},
+		{AmbiguityCut, []Fragment{{Start: 5, End: 21, Possible: PossibleEnd}, {Start: 21, End: 37, Possible: PossibleStart}}, 2},
+		{AmbiguityHalf, []Fragment{{Start: 5, End: 21, Possible: PossibleEnd}, {Start: 21, End: 37, Possible: PossibleStart}}, 1},
+	}
+	for _, tc := range cases {
+		p := NewPlanWithOptions([]enzyme.Enzyme{eA}, Options{Ambiguity: tc.mode})
+		got := p.Digest(seq, 1, 1<<30)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: fragments = %#v, want %#v", tc.mode, got, tc.want)
+		}
+		stats := p.DigestStats(seq, 1, 1<<30)
+		wantSites := 1
+		if tc.mode == AmbiguityOff {
+			wantSites = 0
+		}
+		if stats.Fragments != len(tc.want) || stats.Weighted != tc.weighted || stats.PossibleSites != wantSites || p.PossibleSites(seq) != wantSites {
+			t.Fatalf("%s: stats = %+v, PossibleSites = %d", tc.mode, stats, p.PossibleSites(seq))
+		}
+		weight := 0.0
+		for _, fr := range got {
+			weight += p.Weight(fr)
+		}
+		if weight != tc.weighted {
+			t.Fatalf("%s: summed Weight = %g, want %g", tc.mode, weight, tc.weighted)
+		}
+	}
+}
+
+func TestAmbiguityInsideDegenerateMotifIsDefinite(t *testing.T) {
+	// ApeKI is G^CWGC, so a reference W at the W position always matches.
+	p := NewPlanWithOptions([]enzyme.Enzyme{enzyme.DB["ApeKI"]}, Options{Ambiguity: AmbiguityCut})
+	got := p.Digest([]byte("AAGCWGCAAAAGCAGCAA"), 1, 1<<30)
+	if want := []Fragment{{Start: 3, End: 12}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("fragments = %#v, want %#v", got, want)
+	}
+	if _, err := ParseAmbiguity("maybe"); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+	if m, err := ParseAmbiguity("NoCut"); err != nil || m != AmbiguityNoCut {
+		t.Fatalf("ParseAmbiguity(NoCut) = %v, %v", m, err)
+	}
+}
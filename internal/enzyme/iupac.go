@@ -30,6 +30,11 @@ var motifMaskTable [256]uint8
 // so no recognition site is inferred across assembly gaps or unknown bases.
 var refMaskTable [256]uint8
 
+// ambiguousRefMaskTable is refMaskTable extended with the two- and
+// three-base IUPAC codes, for matching consensus references whose ambiguity
+// codes mark heterozygous positions. N stays zero.
+var ambiguousRefMaskTable [256]uint8
+
 func init() {
 	for b, m := range codeMap {
 		setMaskBothCases(&motifMaskTable, b, m)
@@ -43,6 +48,12 @@ func init() {
 	// Reference N remains zero by design.
 	refMaskTable['N'] = 0
 	refMaskTable['n'] = 0
+
+	for b, m := range codeMap {
+		if b != 'N' {
+			setMaskBothCases(&ambiguousRefMaskTable, b, m)
+		}
+	}
 }
 
 func setMaskBothCases(table *[256]uint8, b byte, mask uint8) {
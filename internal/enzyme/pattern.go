@@ -89,6 +89,34 @@ func MatchMaskAt(mask []uint8, anchor int, window []byte) bool {
 	return true
 }
 
+// MatchMaskAmbiguousAt is like MatchMaskAt, but also accepts reference IUPAC
+// ambiguity codes other than N wherever they share a base with the motif.
+// possible reports that the match relies on at least one such code that also
+// allows a non-matching base, i.e. the site is present on only some
+// haplotypes.
+func MatchMaskAmbiguousAt(mask []uint8, anchor int, window []byte) (match, possible bool) {
+	n := len(mask)
+	if n == 0 || len(window) < n {
+		return false, false
+	}
+	if anchor < 0 || anchor >= n {
+		anchor = n - 1
+	}
+	if ambiguousRefMaskTable[window[anchor]]&mask[anchor] == 0 {
+		return false, false
+	}
+	for i := 0; i < n; i++ {
+		ref := ambiguousRefMaskTable[window[i]]
+		if ref&mask[i] == 0 {
+			return false, false
+		}
+		if ref&^mask[i] != 0 {
+			possible = true
+		}
+	}
+	return true, possible
+}
+
 // IsExactACGT reports whether site contains only unambiguous A/C/G/T bases.
 func IsExactACGT(site string) bool {
 	if site == "" {
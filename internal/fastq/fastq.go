@@ -0,0 +1,85 @@
+package fastq
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Writer emits four-line FASTQ records. A Writer created with an empty path is
+// a no-op, which lets callers keep FASTQ output disabled without nil checks.
+type Writer struct {
+	bw       *bufio.Writer
+	close    func() error
+	disabled bool
+}
+
+// New opens path for FASTQ output. Use an empty path to disable output. Use "-"
+// to write to stdout.
+func New(path string) (*Writer, error) {
+	return NewTo(path, os.Stdout)
+}
+
+// NewTo is like New, but writes "-" to stdout instead of os.Stdout.
+func NewTo(path string, stdout io.Writer) (*Writer, error) {
+	if path == "" {
+		return &Writer{disabled: true}, nil
+	}
+
+	var sink io.Writer
+	var close func() error
+	if path == "-" {
+		if stdout == nil {
+			return nil, fmt.Errorf("stdout writer is nil")
+		}
+		sink = stdout
+	} else {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		sink = f
+		close = f.Close
+	}
+	return &Writer{bw: bufio.NewWriter(sink), close: close}, nil
+}
+
+// Write emits one record. name is written after "@" verbatim and may carry a
+// space-separated comment. seq and qual must have equal length.
+func (w *Writer) Write(name string, seq, qual []byte) error {
+	if w == nil || w.disabled {
+		return nil
+	}
+	if len(seq) != len(qual) {
+		return fmt.Errorf("fastq: record %q has %d bases but %d quality scores", name, len(seq), len(qual))
+	}
+	if _, err := fmt.Fprintf(w.bw, "@%s\n", name); err != nil {
+		return err
+	}
+	if _, err := w.bw.Write(seq); err != nil {
+		return err
+	}
+	if _, err := w.bw.WriteString("\n+\n"); err != nil {
+		return err
+	}
+	if _, err := w.bw.Write(qual); err != nil {
+		return err
+	}
+	return w.bw.WriteByte('\n')
+}
+
+// Close flushes pending output and closes owned files. Stdout is flushed but
+// not closed. Disabled writers are no-ops.
+func (w *Writer) Close() error {
+	if w == nil || w.disabled {
+		return nil
+	}
+	err := w.bw.Flush()
+	if w.close != nil {
+		if closeErr := w.close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package fastq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewTo("-", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("r1 chrom=chr1", []byte("ACGT"), []byte("IIII")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "@r1 chrom=chr1\nACGT\n+\nIIII\n"; got != want {
+		t.Fatalf("record = %q, want %q", got, want)
+	}
+	if err := w.Write("bad", []byte("ACGT"), []byte("II")); err == nil {
+		t.Fatal("expected length mismatch error")
+	}
+}
+
+func TestDisabledWriterIsNoOp(t *testing.T) {
+	w, err := NewTo("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("r", []byte("A"), []byte("I")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
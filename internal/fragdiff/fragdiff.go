@@ -0,0 +1,483 @@
+// Package fragdiff compares two sets of digest fragments, such as the outputs
+// of two radigest runs or digests of two assemblies, and reports which loci
+// are shared, lost, or gained.
+//
+// Fragments are matched either by reciprocal coordinate overlap on the same
+// chromosome, or by the sequence of their flanking cut-site ends, which
+// survives coordinate changes between assemblies.
+package fragdiff
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/fasta"
+	"github.com/ericksamera/radigest/internal/library"
+)
+
+// Locus is one fragment in 0-based half-open coordinates.
+type Locus struct {
+	Chr   string
+	Start int
+	End   int
+	// Key is the canonical flanking-sequence key set by AddKeys or Digest;
+	// it is empty until then.
+	Key string
+}
+
+// Len returns the fragment length.
+func (l Locus) Len() int {
+	return l.End - l.Start
+}
+
+// Mode selects how fragments are matched.
+type Mode string
+
+const (
+	// ModeOverlap matches fragments on the same chromosome whose reciprocal
+	// overlap reaches Options.MinOverlap.
+	ModeOverlap Mode = "overlap"
+	// ModeFlank matches fragments whose flanking end sequences are identical
+	// on either strand.
+	ModeFlank Mode = "flank"
+)
+
+// ParseMode parses overlap or flank.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case ModeOverlap, ModeFlank:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown match mode %q (want overlap or flank)", s)
+}
+
+// Options configures Compare.
+type Options struct {
+	Mode Mode
+	// MinOverlap is the smallest overlap, as a fraction of the longer
+	// fragment, that ModeOverlap accepts.
+	MinOverlap float64
+}
+
+// DefaultMinOverlap is the reciprocal overlap used when Options.MinOverlap
+// is zero.
+const DefaultMinOverlap = 0.5
+
+// Pair is one locus present in both sets.
+type Pair struct {
+	A, B Locus
+	// Overlap is the overlap as a fraction of the longer fragment, or 0 when
+	// the fragments lie on different chromosomes.
+	Overlap float64
+}
+
+// LengthChange returns the length of B minus the length of A.
+func (p Pair) LengthChange() int {
+	return p.B.Len() - p.A.Len()
+}
+
+// Result lists shared loci in A order, lost loci (only in A) in A order, and
+// gained loci (only in B) in B order.
+type Result struct {
+	Shared []Pair
+	Lost   []Locus
+	Gained []Locus
+}
+
+// Compare matches a against b one-to-one. Both slices are sorted in place by
+// chromosome and start.
+func Compare(a, b []Locus, opt Options) Result {
+	sortLoci(a)
+	sortLoci(b)
+	var match []int // match[i] is the b index paired with a[i], or -1
+	if opt.Mode == ModeFlank {
+		match = matchFlank(a, b)
+	} else {
+		minOverlap := opt.MinOverlap
+		if minOverlap <= 0 {
+			minOverlap = DefaultMinOverlap
+		}
+		match = matchOverlap(a, b, minOverlap)
+	}
+
+	var r Result
+	used := make([]bool, len(b))
+	for i, j := range match {
+		if j < 0 {
+			r.Lost = append(r.Lost, a[i])
+			continue
+		}
+		used[j] = true
+		r.Shared = append(r.Shared, Pair{A: a[i], B: b[j], Overlap: overlap(a[i], b[j])})
+	}
+	for j, ok := range used {
+		if !ok {
+			r.Gained = append(r.Gained, b[j])
+		}
+	}
+	return r
+}
+
+func sortLoci(loci []Locus) {
+	sort.SliceStable(loci, func(i, j int) bool {
+		if loci[i].Chr != loci[j].Chr {
+			return loci[i].Chr < loci[j].Chr
+		}
+		if loci[i].Start != loci[j].Start {
+			return loci[i].Start < loci[j].Start
+		}
+		return loci[i].End < loci[j].End
+	})
+}
+
+// overlap returns the shared bases of a and b as a fraction of the longer.
+func overlap(a, b Locus) float64 {
+	if a.Chr != b.Chr {
+		return 0
+	}
+	shared := min(a.End, b.End) - max(a.Start, b.Start)
+	longer := max(a.Len(), b.Len())
+	if shared <= 0 || longer <= 0 {
+		return 0
+	}
+	return float64(shared) / float64(longer)
+}
+
+// matchOverlap pairs each a locus with the unused b locus of greatest
+// reciprocal overlap. Fragments from one digest do not overlap, so b sorted by
+// start is also sorted by end.
+func matchOverlap(a, b []Locus, minOverlap float64) []int {
+	match := make([]int, len(a))
+	used := make([]bool, len(b))
+	for i, la := range a {
+		match[i] = -1
+		j := sort.Search(len(b), func(j int) bool {
+			return b[j].Chr > la.Chr || b[j].Chr == la.Chr && b[j].End > la.Start
+		})
+		best, bestOverlap := -1, 0.0
+		for ; j < len(b) && b[j].Chr == la.Chr && b[j].Start < la.End; j++ {
+			if ov := overlap(la, b[j]); !used[j] && ov >= minOverlap && ov > bestOverlap {
+				best, bestOverlap = j, ov
+			}
+		}
+		if best >= 0 {
+			match[i] = best
+			used[best] = true
+		}
+	}
+	return match
+}
+
+// matchFlank pairs loci with equal non-empty keys in order of appearance.
+func matchFlank(a, b []Locus) []int {
+	byKey := make(map[string][]int)
+	for j, lb := range b {
+		if lb.Key != "" {
+			byKey[lb.Key] = append(byKey[lb.Key], j)
+		}
+	}
+	match := make([]int, len(a))
+	for i, la := range a {
+		match[i] = -1
+		if queue := byKey[la.Key]; la.Key != "" && len(queue) > 0 {
+			match[i] = queue[0]
+			byKey[la.Key] = queue[1:]
+		}
+	}
+	return match
+}
+
+// FlankKey returns the canonical key of fr: flank bases reading inward from
+// the outer edge of the recognition site at each end, upper-cased, choosing
+// the smaller of the forward and reverse-complement forms so inverted contigs
+// still match. Anchoring on the site rather than the top-strand cut keeps the
+// key independent of strand for sticky-end enzymes. An end that plan cannot
+// place on a site, such as a contig end, is read from the cut itself.
+func FlankKey(seq []byte, fr digest.Fragment, flank int, plan digest.Plan) string {
+	left := fr.Start
+	if start, _, ok := plan.SiteAt(seq, fr.Start); ok {
+		left = start
+	}
+	right := fr.End
+	if _, end, ok := plan.SiteAt(seq, fr.End); ok {
+		right = end
+	}
+	l := bytes.ToUpper(seq[left:min(right, left+flank)])
+	r := bytes.ToUpper(seq[max(left, right-flank):right])
+	fwd := string(l) + "|" + string(r)
+	rev := string(library.ReverseComplement(r)) + "|" + string(library.ReverseComplement(l))
+	return min(fwd, rev)
+}
+
+// Summary totals a Result.
+type Summary struct {
+	LociA         int `json:"loci_a"`
+	LociB         int `json:"loci_b"`
+	Shared        int `json:"shared"`
+	Lost          int `json:"lost"`
+	Gained        int `json:"gained"`
+	LengthChanged int `json:"length_changed"`
+	// MeanAbsLengthChange averages |LengthChange| over shared loci.
+	MeanAbsLengthChange float64 `json:"mean_abs_length_change"`
+	// SharedFraction is Shared over LociA.
+	SharedFraction float64 `json:"shared_fraction"`
+}
+
+// Summary returns the totals of r.
+func (r Result) Summary() Summary {
+	s := Summary{
+		Shared: len(r.Shared),
+		Lost:   len(r.Lost),
+		Gained: len(r.Gained),
+	}
+	s.LociA = s.Shared + s.Lost
+	s.LociB = s.Shared + s.Gained
+	total := 0
+	for _, p := range r.Shared {
+		if d := p.LengthChange(); d != 0 {
+			s.LengthChanged++
+			total += max(d, -d)
+		}
+	}
+	if s.Shared > 0 {
+		s.MeanAbsLengthChange = float64(total) / float64(s.Shared)
+	}
+	if s.LociA > 0 {
+		s.SharedFraction = float64(s.Shared) / float64(s.LociA)
+	}
+	return s
+}
+
+// Read loads loci from a radigest fragment TSV or a BED file, plain or gzip.
+// Fragment TSVs are recognized by their chrom/start0/end0 header; their rows
+// with hard_kept=false are skipped so both formats describe hard-kept loci.
+func Read(path string) ([]Locus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	loci, err := Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return loci, nil
+}
+
+// Parse reads fragment TSV or BED text from r.
+func Parse(r io.Reader) ([]Locus, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	var loci []Locus
+	hardKeptCol := -1
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser") {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if lineNo == 1 && len(cols) >= 3 && cols[0] == "chrom" && cols[1] == "start0" {
+			for i, name := range cols {
+				if name == "hard_kept" {
+					hardKeptCol = i
+				}
+			}
+			continue
+		}
+		if len(cols) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least 3 columns, got %d", lineNo, len(cols))
+		}
+		start, err := strconv.Atoi(cols[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid start %q", lineNo, cols[1])
+		}
+		end, err := strconv.Atoi(cols[2])
+		if err != nil || end < start || start < 0 {
+			return nil, fmt.Errorf("line %d: invalid end %q", lineNo, cols[2])
+		}
+		if hardKeptCol >= 0 && hardKeptCol < len(cols) && cols[hardKeptCol] == "false" {
+			continue
+		}
+		loci = append(loci, Locus{Chr: cols[0], Start: start, End: end})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return loci, nil
+}
+
+// AddKeys sets the flanking-sequence key of every locus from the reference
+// FASTA at path, placing recognition sites with plan. It fails if a locus lies
+// on a missing chromosome or beyond a chromosome's end.
+func AddKeys(loci []Locus, path string, flank int, plan digest.Plan) error {
+	byChr := make(map[string][]int)
+	for i, l := range loci {
+		byChr[l.Chr] = append(byChr[l.Chr], i)
+	}
+	found := 0
+	err := streamFASTA(path, func(rec fasta.Record) error {
+		idx, ok := byChr[rec.ID]
+		if !ok {
+			return nil
+		}
+		found += len(idx)
+		for _, i := range idx {
+			l := &loci[i]
+			if l.End > len(rec.Seq) {
+				return fmt.Errorf("fragment %s:%d-%d extends past the end of %s (%d bp)", l.Chr, l.Start, l.End, rec.ID, len(rec.Seq))
+			}
+			l.Key = FlankKey(rec.Seq, digest.Fragment{Start: l.Start, End: l.End}, flank, plan)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if missing := len(loci) - found; missing > 0 {
+		return fmt.Errorf("%d fragments lie on chromosomes missing from %s", missing, path)
+	}
+	return nil
+}
+
+// Digest digests the FASTA at path with plan and returns the fragments whose
+// length lies in [minLen, maxLen]. When flank > 0 each locus is also keyed.
+func Digest(path string, plan digest.Plan, minLen, maxLen, flank int) ([]Locus, error) {
+	var loci []Locus
+	err := streamFASTA(path, func(rec fasta.Record) error {
+		return plan.DigestEach(rec.Seq, minLen, maxLen, func(fr digest.Fragment) error {
+			l := Locus{Chr: rec.ID, Start: fr.Start, End: fr.End}
+			if flank > 0 {
+				l.Key = FlankKey(rec.Seq, fr, flank, plan)
+			}
+			loci = append(loci, l)
+			return nil
+		})
+	})
+	return loci, err
+}
+
+func streamFASTA(path string, each func(fasta.Record) error) error {
+	ch := make(chan fasta.Record)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fasta.Stream(path, ch)
+	}()
+	var firstErr error
+	for rec := range ch {
+		if firstErr == nil {
+			firstErr = each(rec)
+		}
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// Writer emits a per-locus comparison TSV. A Writer created with an empty
+// path is a no-op.
+type Writer struct {
+	bw       *bufio.Writer
+	close    func() error
+	disabled bool
+}
+
+var header = []string{"status", "a_chrom", "a_start0", "a_end0", "a_length", "b_chrom", "b_start0", "b_end0", "b_length", "length_change", "overlap"}
+
+// NewTo opens path, or writes "-" to stdout, and writes the header. Use an
+// empty path to disable output.
+func NewTo(path string, stdout io.Writer) (*Writer, error) {
+	if path == "" {
+		return &Writer{disabled: true}, nil
+	}
+	var sink io.Writer
+	var close func() error
+	if path == "-" {
+		if stdout == nil {
+			return nil, fmt.Errorf("stdout writer is nil")
+		}
+		sink = stdout
+	} else {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		sink = f
+		close = f.Close
+	}
+	w := &Writer{bw: bufio.NewWriter(sink), close: close}
+	if _, err := w.bw.WriteString(strings.Join(header, "\t") + "\n"); err != nil {
+		if close != nil {
+			_ = close()
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteResult emits shared, then lost, then gained rows. Columns of the
+// missing side are '.'.
+func (w *Writer) WriteResult(r Result) error {
+	if w == nil || w.disabled {
+		return nil
+	}
+	for _, p := range r.Shared {
+		if _, err := fmt.Fprintf(w.bw, "shared\t%s\t%s\t%d\t%s\n", locusCols(&p.A), locusCols(&p.B), p.LengthChange(), strconv.FormatFloat(math.Round(p.Overlap*1e6)/1e6, 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+	for i := range r.Lost {
+		if _, err := fmt.Fprintf(w.bw, "lost\t%s\t%s\t.\t.\n", locusCols(&r.Lost[i]), locusCols(nil)); err != nil {
+			return err
+		}
+	}
+	for i := range r.Gained {
+		if _, err := fmt.Fprintf(w.bw, "gained\t%s\t%s\t.\t.\n", locusCols(nil), locusCols(&r.Gained[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func locusCols(l *Locus) string {
+	if l == nil {
+		return ".\t.\t.\t."
+	}
+	return fmt.Sprintf("%s\t%d\t%d\t%d", l.Chr, l.Start, l.End, l.Len())
+}
+
+// Close flushes pending output and closes owned files. Disabled writers are
+// no-ops.
+func (w *Writer) Close() error {
+	if w == nil || w.disabled {
+		return nil
+	}
+	err := w.bw.Flush()
+	if w.close != nil {
+		if closeErr := w.close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
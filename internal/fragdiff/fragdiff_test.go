@@ -0,0 +1,77 @@
+package fragdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/enzyme"
+	"github.com/ericksamera/radigest/internal/library"
+)
+
+func TestCompareOverlapReportsSharedLostGained(t *testing.T) {
+	a := []Locus{
+		{Chr: "chr1", Start: 100, End: 200},
+		{Chr: "chr1", Start: 500, End: 600},
+		{Chr: "chr2", Start: 0, End: 50},
+	}
+	b := []Locus{
+		{Chr: "chr2", Start: 10, End: 50},
+		{Chr: "chr1", Start: 110, End: 200},
+		{Chr: "chr1", Start: 540, End: 700},
+	}
+	r := Compare(a, b, Options{Mode: ModeOverlap})
+	if len(r.Shared) != 2 || len(r.Lost) != 1 || len(r.Gained) != 1 {
+		t.Fatalf("shared/lost/gained = %d/%d/%d, want 2/1/1", len(r.Shared), len(r.Lost), len(r.Gained))
+	}
+	if got := r.Shared[0]; got.B.Start != 110 || got.LengthChange() != -10 || got.Overlap != 0.9 {
+		t.Fatalf("first shared = %+v (change %d)", got, got.LengthChange())
+	}
+	if r.Lost[0].Start != 500 || r.Gained[0].Start != 540 {
+		t.Fatalf("lost = %+v gained = %+v; 60/160 overlap should not match", r.Lost, r.Gained)
+	}
+	s := r.Summary()
+	if s.LociA != 3 || s.LociB != 3 || s.LengthChanged != 2 || s.MeanAbsLengthChange != 10 {
+		t.Fatalf("summary = %+v", s)
+	}
+}
+
+func TestFlankKeyMatchesReverseComplement(t *testing.T) {
+	// The EcoRI 5' overhang moves the top-strand cut by four bases between
+	// orientations; anchoring on the site absorbs the shift.
+	plan := digest.NewPlan([]enzyme.Enzyme{enzyme.DB["EcoRI"], enzyme.DB["MseI"]})
+	seq := []byte("CCGAATTCGGATCCTTAAGGCCTACC")
+	rc := library.ReverseComplement(seq)
+	frs, rcFrs := plan.Digest(seq, 1, 100), plan.Digest(rc, 1, 100)
+	if len(frs) != 1 || len(rcFrs) != 1 || frs[0].End-frs[0].Start == rcFrs[0].End-rcFrs[0].Start {
+		t.Fatalf("fragments = %+v / %+v, want one per strand differing by overhang", frs, rcFrs)
+	}
+	fr, rcFr := frs[0], rcFrs[0]
+	if got, want := FlankKey(rc, rcFr, 5, plan), FlankKey(seq, fr, 5, plan); got != want {
+		t.Fatalf("reverse-complement key = %q, want %q", got, want)
+	}
+
+	a := []Locus{{Chr: "old", Start: fr.Start, End: fr.End, Key: FlankKey(seq, fr, 5, plan)}}
+	b := []Locus{{Chr: "new", Start: rcFr.Start, End: rcFr.End, Key: FlankKey(rc, rcFr, 5, plan)}}
+	if r := Compare(a, b, Options{Mode: ModeFlank}); len(r.Shared) != 1 || r.Shared[0].Overlap != 0 {
+		t.Fatalf("flank result = %+v", r)
+	}
+}
+
+func TestParseFragmentTSVSkipsSoftOnlyRows(t *testing.T) {
+	in := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\n" +
+		"chr1\t10\t20\t10\ttrue\t1\n" +
+		"chr1\t30\t90\t60\tfalse\t0.2\n"
+	loci, err := Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loci) != 1 || loci[0] != (Locus{Chr: "chr1", Start: 10, End: 20}) {
+		t.Fatalf("loci = %+v", loci)
+	}
+
+	bed := "track name=x\nchr1\t10\t20\tchr1_1\t0\t+\n"
+	if loci, err := Parse(strings.NewReader(bed)); err != nil || len(loci) != 1 || loci[0].End != 20 {
+		t.Fatalf("BED loci = %+v, err = %v", loci, err)
+	}
+}
@@ -4,11 +4,57 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/ericksamera/radigest/internal/agp"
+	"github.com/ericksamera/radigest/internal/composition"
 	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/mappability"
+	"github.com/ericksamera/radigest/internal/varsite"
 )
 
+// Options selects optional TSV column groups. The zero value writes the
+// original six columns.
+type Options struct {
+	// Composition appends gc_fraction, cpg_count, n_count, homopolymer_max,
+	// and low_complexity columns.
+	Composition bool
+	// Mappability appends left_end_copies, right_end_copies, and unique_ends
+	// columns. Copy counts are NA for ends that could not be evaluated.
+	Mappability bool
+	// Variants appends variant_alleles, variant_afs, and dropout_risk
+	// columns. Allele frequencies are comma-separated, NA when unknown, and
+	// "." when no allele disrupts the fragment.
+	Variants bool
+	// Components appends component, component_start0, component_end0,
+	// component_strand, and crosses_component columns from an AGP. The
+	// component fields are "." for fragments that cross a component
+	// boundary or lie on sequences the AGP does not describe.
+	Components bool
+	// Amplification appends an amplification_weight column: the PCR
+	// amplification weight from fragment length and GC, to be multiplied
+	// by size_weight.
+	Amplification bool
+}
+
+// Row is one scored fragment. Optional fields are written only when the
+// matching Options column group is enabled.
+type Row struct {
+	Chr         string
+	Fragment    digest.Fragment
+	HardKept    bool
+	SizeWeight  float64
+	Composition composition.Metrics
+	Mappability mappability.Ends
+	Variants    varsite.Fragment
+	Component   agp.Placement
+	// AmplificationWeight is the PCR amplification weight.
+	AmplificationWeight float64
+}
+
 // Writer emits per-fragment TSV rows for downstream modeling. A Writer created
 // with an empty path is a no-op, which lets callers keep TSV output disabled
 // without nil checks.
@@ -16,6 +62,7 @@ type Writer struct {
 	bw       *bufio.Writer
 	close    func() error
 	disabled bool
+	opt      Options
 }
 
 // New opens path and writes the TSV header. Use an empty path to disable TSV
@@ -26,6 +73,12 @@ func New(path string) (*Writer, error) {
 
 // NewTo is like New, but writes "-" to stdout instead of os.Stdout.
 func NewTo(path string, stdout io.Writer) (*Writer, error) {
+	return NewToWithOptions(path, stdout, Options{})
+}
+
+// NewToWithOptions is like NewTo, but also writes the optional column groups
+// selected by opt.
+func NewToWithOptions(path string, stdout io.Writer, opt Options) (*Writer, error) {
 	if path == "" {
 		return &Writer{disabled: true}, nil
 	}
@@ -45,8 +98,8 @@ func NewTo(path string, stdout io.Writer) (*Writer, error) {
 		sink = f
 		close = f.Close
 	}
-	w := &Writer{bw: bufio.NewWriter(sink), close: close}
-	if _, err := w.bw.WriteString("chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\n"); err != nil {
+	w := &Writer{bw: bufio.NewWriter(sink), close: close, opt: opt}
+	if _, err := w.bw.WriteString(strings.Join(Header(opt), "\t") + "\n"); err != nil {
 		if close != nil {
 			_ = close()
 		}
@@ -55,14 +108,101 @@ func NewTo(path string, stdout io.Writer) (*Writer, error) {
 	return w, nil
 }
 
+// Header returns the TSV column names written for opt.
+func Header(opt Options) []string {
+	cols := []string{"chrom", "start0", "end0", "length", "hard_kept", "size_weight"}
+	if opt.Composition {
+		cols = append(cols, "gc_fraction", "cpg_count", "n_count", "homopolymer_max", "low_complexity")
+	}
+	if opt.Mappability {
+		cols = append(cols, "left_end_copies", "right_end_copies", "unique_ends")
+	}
+	if opt.Variants {
+		cols = append(cols, "variant_alleles", "variant_afs", "dropout_risk")
+	}
+	if opt.Components {
+		cols = append(cols, "component", "component_start0", "component_end0", "component_strand", "crosses_component")
+	}
+	if opt.Amplification {
+		cols = append(cols, "amplification_weight")
+	}
+	return cols
+}
+
 // Write emits one scored fragment row. Coordinates are 0-based half-open.
 func (w *Writer) Write(chr string, fr digest.Fragment, hardKept bool, sizeWeight float64) error {
+	return w.WriteRow(Row{Chr: chr, Fragment: fr, HardKept: hardKept, SizeWeight: sizeWeight})
+}
+
+// WriteRow emits one scored fragment row, including any optional column
+// groups enabled when the Writer was created.
+func (w *Writer) WriteRow(r Row) error {
 	if w == nil || w.disabled {
 		return nil
 	}
+	fr := r.Fragment
 	length := fr.End - fr.Start
-	_, err := fmt.Fprintf(w.bw, "%s\t%d\t%d\t%d\t%t\t%.8g\n", chr, fr.Start, fr.End, length, hardKept, sizeWeight)
-	return err
+	if _, err := fmt.Fprintf(w.bw, "%s\t%d\t%d\t%d\t%t\t%.8g", r.Chr, fr.Start, fr.End, length, r.HardKept, r.SizeWeight); err != nil {
+		return err
+	}
+	if w.opt.Composition {
+		m := r.Composition
+		if _, err := fmt.Fprintf(w.bw, "\t%.6f\t%d\t%d\t%d\t%.6g", m.GC, m.CpG, m.N, m.Homopolymer, m.LowComplexity); err != nil {
+			return err
+		}
+	}
+	if w.opt.Mappability {
+		e := r.Mappability
+		if _, err := fmt.Fprintf(w.bw, "\t%s\t%s\t%t", copiesField(e.Left), copiesField(e.Right), e.Unique()); err != nil {
+			return err
+		}
+	}
+	if w.opt.Variants {
+		v := r.Variants
+		if _, err := fmt.Fprintf(w.bw, "\t%d\t%s\t%.6g", v.Alleles(), afsField(v.AFs), v.Risk); err != nil {
+			return err
+		}
+	}
+	if w.opt.Components {
+		if _, err := w.bw.WriteString(componentFields(r.Component)); err != nil {
+			return err
+		}
+	}
+	if w.opt.Amplification {
+		if _, err := fmt.Fprintf(w.bw, "\t%.8g", r.AmplificationWeight); err != nil {
+			return err
+		}
+	}
+	return w.bw.WriteByte('\n')
+}
+
+func componentFields(p agp.Placement) string {
+	if !p.Placed || p.Crosses {
+		return fmt.Sprintf("\t.\t.\t.\t.\t%t", p.Crosses)
+	}
+	return fmt.Sprintf("\t%s\t%d\t%d\t%c\tfalse", p.Component, p.Start, p.End, p.Strand)
+}
+
+func afsField(afs []float64) string {
+	if len(afs) == 0 {
+		return "."
+	}
+	fields := make([]string, len(afs))
+	for i, af := range afs {
+		if math.IsNaN(af) {
+			fields[i] = "NA"
+		} else {
+			fields[i] = strconv.FormatFloat(af, 'g', 6, 64)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func copiesField(n int) string {
+	if n == mappability.NotEvaluated {
+		return "NA"
+	}
+	return strconv.Itoa(n)
 }
 
 // Close flushes pending TSV output and closes owned files. Stdout is flushed but
@@ -1,11 +1,16 @@
 package fragmenttsv
 
 import (
+	"math"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/ericksamera/radigest/internal/agp"
+	"github.com/ericksamera/radigest/internal/composition"
 	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/mappability"
+	"github.com/ericksamera/radigest/internal/varsite"
 )
 
 func TestWriter(t *testing.T) {
@@ -63,3 +68,107 @@ func TestNilWriterNoops(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestWriterCompositionColumns(t *testing.T) {
+	var buf strings.Builder
+	w, err := NewToWithOptions("-", &buf, Options{Composition: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := Row{
+		Chr:         "chr1",
+		Fragment:    digest.Fragment{Start: 0, End: 8},
+		HardKept:    true,
+		SizeWeight:  1,
+		Composition: composition.Metrics{GC: 0.5, CpG: 2, N: 1, Homopolymer: 3, LowComplexity: 1.5},
+	}
+	if err := w.WriteRow(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\tgc_fraction\tcpg_count\tn_count\thomopolymer_max\tlow_complexity\n" +
+		"chr1\t0\t8\t8\ttrue\t1\t0.500000\t2\t1\t3\t1.5\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected TSV\nwant:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriterMappabilityColumns(t *testing.T) {
+	var buf strings.Builder
+	w, err := NewToWithOptions("-", &buf, Options{Mappability: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []Row{
+		{Chr: "chr1", Fragment: digest.Fragment{Start: 0, End: 8}, SizeWeight: 1, Mappability: mappability.Ends{Left: 0, Right: 0}},
+		{Chr: "chr1", Fragment: digest.Fragment{Start: 8, End: 20}, SizeWeight: 1, Mappability: mappability.Ends{Left: 3, Right: mappability.NotEvaluated}},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\tleft_end_copies\tright_end_copies\tunique_ends\n" +
+		"chr1\t0\t8\t8\tfalse\t1\t0\t0\ttrue\n" +
+		"chr1\t8\t20\t12\tfalse\t1\t3\tNA\tfalse\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected TSV\nwant:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriterVariantColumns(t *testing.T) {
+	var buf strings.Builder
+	w, err := NewToWithOptions("-", &buf, Options{Variants: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []Row{
+		{Chr: "chr1", Fragment: digest.Fragment{Start: 0, End: 8}, SizeWeight: 1},
+		{Chr: "chr1", Fragment: digest.Fragment{Start: 8, End: 20}, SizeWeight: 1, Variants: varsite.Fragment{AFs: []float64{0.25, math.NaN()}, Risk: 0.25}},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\tvariant_alleles\tvariant_afs\tdropout_risk\n" +
+		"chr1\t0\t8\t8\tfalse\t1\t0\t.\t0\n" +
+		"chr1\t8\t20\t12\tfalse\t1\t2\t0.25,NA\t0.25\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected TSV\nwant:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestWriterComponentColumns(t *testing.T) {
+	var buf strings.Builder
+	w, err := NewToWithOptions("-", &buf, Options{Components: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []Row{
+		{Chr: "scaf1", Fragment: digest.Fragment{Start: 0, End: 8}, SizeWeight: 1, Component: agp.Placement{Placed: true, Component: "ctg1", Start: 92, End: 100, Strand: '-'}},
+		{Chr: "scaf1", Fragment: digest.Fragment{Start: 8, End: 20}, SizeWeight: 1, Component: agp.Placement{Placed: true, Crosses: true}},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\tcomponent\tcomponent_start0\tcomponent_end0\tcomponent_strand\tcrosses_component\n" +
+		"scaf1\t0\t8\t8\tfalse\t1\tctg1\t92\t100\t-\tfalse\n" +
+		"scaf1\t8\t20\t12\tfalse\t1\t.\t.\t.\t.\ttrue\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected TSV\nwant:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
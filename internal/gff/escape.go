@@ -1,6 +1,11 @@
 package gff
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ericksamera/radigest/internal/composition"
+)
 
 const upperHex = "0123456789ABCDEF"
 
@@ -27,6 +32,16 @@ func FragmentAttributes(chr string, ordinal, length int) string {
 	return fmt.Sprintf("ID=%s;Length=%d", fragmentID(chr, ordinal), length)
 }
 
+// CompositionAttributes builds lower-case GFF3 attributes for fragment
+// composition metrics. The names match the fragment TSV column names.
+func CompositionAttributes(m composition.Metrics) string {
+	return "gc_fraction=" + strconv.FormatFloat(m.GC, 'f', 6, 64) +
+		";cpg_count=" + strconv.Itoa(m.CpG) +
+		";n_count=" + strconv.Itoa(m.N) +
+		";homopolymer_max=" + strconv.Itoa(m.Homopolymer) +
+		";low_complexity=" + strconv.FormatFloat(m.LowComplexity, 'g', 6, 64)
+}
+
 func fragmentID(chr string, ordinal int) string {
 	if chr == "" {
 		return fmt.Sprintf("frag%d", ordinal)
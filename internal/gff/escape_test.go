@@ -1,6 +1,10 @@
 package gff
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/composition"
+)
 
 func TestEscapeSeqID(t *testing.T) {
 	got := EscapeSeqID("chr 1;bad=2,50%\t")
@@ -25,3 +29,11 @@ func TestFragmentAttributesEscapesID(t *testing.T) {
 		t.Fatalf("FragmentAttributes mismatch: got %q want %q", got, want)
 	}
 }
+
+func TestCompositionAttributes(t *testing.T) {
+	got := CompositionAttributes(composition.Metrics{GC: 0.25, CpG: 3, N: 0, Homopolymer: 4, LowComplexity: 2.5})
+	want := "gc_fraction=0.250000;cpg_count=3;n_count=0;homopolymer_max=4;low_complexity=2.5"
+	if got != want {
+		t.Fatalf("CompositionAttributes mismatch: got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,370 @@
+// Package haplotype digests per-sample haplotypes built by applying phased
+// VCF genotypes to a reference sequence, and writes the resulting
+// locus-by-sample matrix.
+//
+// Loci are reference fragments. A haplotype carries a locus when its own
+// digest yields a fragment whose ends map back to the same reference cuts;
+// SNPs and indels that abolish or create cuts remove the locus, and indels
+// inside it change its length.
+package haplotype
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/vcf"
+)
+
+// Ploidy is the number of haplotypes built per sample. Haploid calls are
+// applied to both.
+const Ploidy = 2
+
+// edit records one applied allele in haplotype coordinates.
+type edit struct {
+	hapStart, hapEnd int // ALT span in the haplotype
+	refStart, refEnd int // REF span in the reference
+}
+
+// Haplotype is a reference sequence with one sample haplotype's alleles
+// applied.
+type Haplotype struct {
+	Seq   []byte
+	edits []edit
+}
+
+// Applied counts the alleles Apply used or skipped.
+type Applied struct {
+	Alleles     int // ALT alleles applied
+	Overlapping int // ALT alleles skipped because they overlap an applied one
+	Unphased    int // heterozygous calls applied in listed order without phase
+}
+
+// Apply builds haplotype h of sample from seq and the records of one
+// chromosome. Symbolic alleles and records whose REF does not match seq are
+// left as reference.
+func Apply(seq []byte, recs []vcf.Record, sample, h int) (Haplotype, Applied) {
+	var out Haplotype
+	var n Applied
+	var buf []byte
+	refPos := 0
+	for _, rec := range recs {
+		if sample >= len(rec.Calls) {
+			continue
+		}
+		call := rec.Calls[sample]
+		a := call.Allele(h)
+		if a == 0 || a > len(rec.Alt) || !vcf.IsSequence(rec.Alt[a-1]) {
+			continue
+		}
+		start, end := rec.Start(), rec.End()
+		if end > len(seq) || !bytes.Equal(seq[start:end], []byte(rec.Ref)) {
+			continue
+		}
+		if start < refPos {
+			n.Overlapping++
+			continue
+		}
+		if buf == nil {
+			buf = make([]byte, 0, len(seq))
+		}
+		buf = append(buf, seq[refPos:start]...)
+		alt := rec.Alt[a-1]
+		out.edits = append(out.edits, edit{hapStart: len(buf), hapEnd: len(buf) + len(alt), refStart: start, refEnd: end})
+		buf = append(buf, alt...)
+		refPos = end
+		n.Alleles++
+		if !call.Phased && call.Heterozygous() {
+			n.Unphased++
+		}
+	}
+	if buf == nil {
+		out.Seq = seq
+		return out, n
+	}
+	out.Seq = append(buf, seq[refPos:]...)
+	return out, n
+}
+
+// ToRef maps a haplotype coordinate to the reference. Coordinates inside an
+// inserted allele map into the REF span, clamped to its end.
+func (h Haplotype) ToRef(pos int) int {
+	i := sort.Search(len(h.edits), func(i int) bool { return h.edits[i].hapStart > pos }) - 1
+	if i < 0 {
+		return pos
+	}
+	e := h.edits[i]
+	if pos >= e.hapEnd {
+		return e.refEnd + pos - e.hapEnd
+	}
+	return e.refStart + min(pos-e.hapStart, e.refEnd-e.refStart)
+}
+
+// FromRef maps a reference coordinate to the haplotype. Coordinates inside
+// a replaced REF span map into the ALT allele, clamped to its end.
+func (h Haplotype) FromRef(pos int) int {
+	i := sort.Search(len(h.edits), func(i int) bool { return h.edits[i].refStart > pos }) - 1
+	if i < 0 {
+		return pos
+	}
+	e := h.edits[i]
+	if pos >= e.refEnd {
+		return e.hapEnd + pos - e.refEnd
+	}
+	return e.hapStart + min(pos-e.refStart, e.hapEnd-e.hapStart)
+}
+
+// Absent marks a haplotype that does not carry a locus.
+const Absent = -1
+
+// Chromosome holds the haplotype fragment lengths of one chromosome's loci.
+type Chromosome struct {
+	Haplotypes int
+	Applied    Applied
+	lengths    map[digest.Fragment][]int
+}
+
+// Digest digests every haplotype of samples samples on one chromosome and
+// indexes the fragments whose ends map back to a reference locus: a
+// reference fragment for which locus returns true, or any reference fragment
+// when locus is nil. Other haplotype fragments are dropped, so memory grows
+// with the loci kept rather than with every fragment. A cut inside an
+// inserted allele maps into its REF span, so such fragments seldom match a
+// locus.
+//
+// segments, when non-nil, lists the [start, end) reference stretches to
+// digest as separate contigs, as for AGP gap ends; each haplotype digests
+// the same stretches mapped through its alleles.
+func Digest(plan digest.Plan, seq []byte, recs []vcf.Record, samples int, segments [][2]int, locus func(digest.Fragment) bool) Chromosome {
+	c := Chromosome{Haplotypes: samples * Ploidy, lengths: make(map[digest.Fragment][]int)}
+	if segments == nil {
+		segments = [][2]int{{0, len(seq)}}
+	}
+	digestSegments(plan, seq, segments, func(fr digest.Fragment) {
+		if locus == nil || locus(fr) {
+			lengths := make([]int, c.Haplotypes)
+			for i := range lengths {
+				lengths[i] = Absent
+			}
+			c.lengths[digest.Fragment{Start: fr.Start, End: fr.End}] = lengths
+		}
+	})
+	hapSegments := make([][2]int, len(segments))
+	for s := 0; s < samples; s++ {
+		for h := 0; h < Ploidy; h++ {
+			hap, applied := Apply(seq, recs, s, h)
+			c.Applied.Alleles += applied.Alleles
+			c.Applied.Overlapping += applied.Overlapping
+			c.Applied.Unphased += applied.Unphased
+			for i, seg := range segments {
+				hapSegments[i] = [2]int{hap.FromRef(seg[0]), hap.FromRef(seg[1])}
+			}
+			idx := s*Ploidy + h
+			digestSegments(plan, hap.Seq, hapSegments, func(fr digest.Fragment) {
+				if lengths, ok := c.lengths[digest.Fragment{Start: hap.ToRef(fr.Start), End: hap.ToRef(fr.End)}]; ok {
+					lengths[idx] = fr.End - fr.Start
+				}
+			})
+		}
+	}
+	return c
+}
+
+// digestSegments digests each [start, end) segment of seq as its own contig
+// and emits fragments in seq coordinates.
+func digestSegments(plan digest.Plan, seq []byte, segments [][2]int, emit func(digest.Fragment)) {
+	for _, seg := range segments {
+		_ = plan.DigestEach(seq[seg[0]:seg[1]], 1, seg[1]-seg[0], func(fr digest.Fragment) error {
+			fr.Start += seg[0]
+			fr.End += seg[0]
+			emit(fr)
+			return nil
+		})
+	}
+}
+
+// Lengths returns the fragment length each haplotype gives reference
+// fragment fr, indexed sample*Ploidy+haplotype, with Absent where the
+// haplotype lacks it.
+func (c Chromosome) Lengths(fr digest.Fragment) []int {
+	if lengths, ok := c.lengths[digest.Fragment{Start: fr.Start, End: fr.End}]; ok {
+		return lengths
+	}
+	lengths := make([]int, c.Haplotypes)
+	for i := range lengths {
+		lengths[i] = Absent
+	}
+	return lengths
+}
+
+// SampleSummary counts the loci one sample carries on at least one
+// size-selected haplotype.
+type SampleSummary struct {
+	Name    string `json:"name"`
+	Present int    `json:"present"`
+	Missing int    `json:"missing"`
+}
+
+// Summary totals the locus-by-sample matrix.
+type Summary struct {
+	Loci            int     `json:"loci"`
+	Samples         int     `json:"samples"`
+	AllelesApplied  int     `json:"alleles_applied"`
+	Overlapping     int     `json:"overlapping_alleles_skipped"`
+	Unphased        int     `json:"unphased_heterozygous_calls"`
+	MissingCells    int     `json:"missing_cells"`
+	MissingFraction float64 `json:"missing_fraction"`
+	// LengthShifted counts present locus-by-haplotype cells whose length
+	// differs from the reference fragment, i.e. indels inside the locus.
+	LengthShifted int             `json:"length_shifted"`
+	CompleteLoci  int             `json:"complete_loci"`
+	PerSample     []SampleSummary `json:"per_sample"`
+}
+
+// NewSummary returns an empty summary for the named samples.
+func NewSummary(samples []string) Summary {
+	s := Summary{Samples: len(samples), PerSample: make([]SampleSummary, len(samples))}
+	for i, name := range samples {
+		s.PerSample[i].Name = name
+	}
+	return s
+}
+
+// AddChromosome adds the allele totals of c.
+func (s *Summary) AddChromosome(c Chromosome) {
+	s.AllelesApplied += c.Applied.Alleles
+	s.Overlapping += c.Applied.Overlapping
+	s.Unphased += c.Applied.Unphased
+}
+
+// AddLocus adds one locus with reference length refLen. lengths and kept are
+// indexed sample*Ploidy+haplotype; kept marks haplotypes whose fragment is
+// size-selected.
+func (s *Summary) AddLocus(refLen int, lengths []int, kept []bool) {
+	s.Loci++
+	complete := true
+	for i := range s.PerSample {
+		present := false
+		for h := 0; h < Ploidy; h++ {
+			k := i*Ploidy + h
+			if kept[k] {
+				present = true
+				if lengths[k] != refLen {
+					s.LengthShifted++
+				}
+			}
+		}
+		if present {
+			s.PerSample[i].Present++
+		} else {
+			s.PerSample[i].Missing++
+			s.MissingCells++
+			complete = false
+		}
+	}
+	if complete {
+		s.CompleteLoci++
+	}
+	if cells := s.Loci * s.Samples; cells > 0 {
+		s.MissingFraction = float64(s.MissingCells) / float64(cells)
+	}
+}
+
+// Writer emits the locus-by-sample matrix as TSV. Each sample cell lists its
+// haplotypes separated by '|': the fragment length when size-selected, the
+// length prefixed with '!' when the fragment exists but falls outside the
+// size window, or '.' when the haplotype lacks the locus. A Writer created
+// with an empty path is a no-op.
+type Writer struct {
+	bw       *bufio.Writer
+	close    func() error
+	disabled bool
+}
+
+// NewTo opens path, or writes "-" to stdout, and writes the header. Use an
+// empty path to disable output.
+func NewTo(path string, stdout io.Writer, samples []string) (*Writer, error) {
+	if path == "" {
+		return &Writer{disabled: true}, nil
+	}
+	var sink io.Writer
+	var close func() error
+	if path == "-" {
+		if stdout == nil {
+			return nil, fmt.Errorf("stdout writer is nil")
+		}
+		sink = stdout
+	} else {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		sink = f
+		close = f.Close
+	}
+	w := &Writer{bw: bufio.NewWriter(sink), close: close}
+	header := append([]string{"chrom", "start0", "end0", "ref_length", "present"}, samples...)
+	if _, err := w.bw.WriteString(strings.Join(header, "\t") + "\n"); err != nil {
+		if close != nil {
+			_ = close()
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write emits one locus row. lengths and kept are indexed
+// sample*Ploidy+haplotype.
+func (w *Writer) Write(chr string, fr digest.Fragment, lengths []int, kept []bool) error {
+	if w == nil || w.disabled {
+		return nil
+	}
+	var cells strings.Builder
+	present := 0
+	for s := 0; s*Ploidy < len(lengths); s++ {
+		cells.WriteByte('\t')
+		carried := false
+		for h := 0; h < Ploidy; h++ {
+			k := s*Ploidy + h
+			if h > 0 {
+				cells.WriteByte('|')
+			}
+			switch {
+			case lengths[k] == Absent:
+				cells.WriteByte('.')
+			case kept[k]:
+				carried = true
+				cells.WriteString(strconv.Itoa(lengths[k]))
+			default:
+				cells.WriteByte('!')
+				cells.WriteString(strconv.Itoa(lengths[k]))
+			}
+		}
+		if carried {
+			present++
+		}
+	}
+	_, err := fmt.Fprintf(w.bw, "%s\t%d\t%d\t%d\t%d%s\n", chr, fr.Start, fr.End, fr.End-fr.Start, present, cells.String())
+	return err
+}
+
+// Close flushes pending output and closes owned files. Disabled writers are
+// no-ops.
+func (w *Writer) Close() error {
+	if w == nil || w.disabled {
+		return nil
+	}
+	err := w.bw.Flush()
+	if w.close != nil {
+		if closeErr := w.close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
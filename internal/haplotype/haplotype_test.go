@@ -0,0 +1,148 @@
+package haplotype
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/enzyme"
+	"github.com/ericksamera/radigest/internal/vcf"
+)
+
+func TestDigestBuildsLocusBySampleLengths(t *testing.T) {
+	e, ok := enzyme.Get("EcoRI")
+	if !ok {
+		t.Fatal("missing EcoRI")
+	}
+	plan, err := digest.TryNewPlanWithOptions([]enzyme.Enzyme{e}, digest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// EcoRI cuts at 5, 25, and 45.
+	seq := []byte("CCCCGAATTCCCCCCCCCCCCCCCGAATTCCCCCCCCCCCCCCCGAATTCCCCC")
+	recs := []vcf.Record{
+		// 3 bp deletion inside the first locus.
+		{Pos: 12, Ref: "CCCC", Alt: []string{"C"}, Calls: []vcf.Call{vcf.ParseCall("0|0"), vcf.ParseCall("1|1"), vcf.ParseCall(".|.")}},
+		// SNP abolishing the cut at 25 on one haplotype of sample 0.
+		{Pos: 27, Ref: "A", Alt: []string{"C"}, Calls: []vcf.Call{vcf.ParseCall("0|1"), vcf.ParseCall("0/0"), vcf.ParseCall(".|.")}},
+	}
+	chr := Digest(plan, seq, recs, 3, nil, nil)
+	if chr.Applied.Alleles != 3 {
+		t.Fatalf("applied = %+v, want 3 alleles", chr.Applied)
+	}
+
+	first := chr.Lengths(digest.Fragment{Start: 5, End: 25})
+	if want := []int{20, Absent, 17, 17, 20, 20}; !equal(first, want) {
+		t.Fatalf("first locus lengths = %v, want %v", first, want)
+	}
+	second := chr.Lengths(digest.Fragment{Start: 25, End: 45})
+	if want := []int{20, Absent, 20, 20, 20, 20}; !equal(second, want) {
+		t.Fatalf("second locus lengths = %v, want %v", second, want)
+	}
+
+	// Only the loci asked for are indexed.
+	only := Digest(plan, seq, recs, 3, nil, func(fr digest.Fragment) bool { return fr.Start == 5 })
+	if len(only.lengths) != 1 || !equal(only.Lengths(digest.Fragment{Start: 5, End: 25}), first) {
+		t.Fatalf("indexed %d loci, want only the first with lengths %v", len(only.lengths), first)
+	}
+	if got := only.Lengths(digest.Fragment{Start: 25, End: 45}); !equal(got, []int{Absent, Absent, Absent, Absent, Absent, Absent}) {
+		t.Fatalf("unlisted locus lengths = %v, want all absent", got)
+	}
+
+	sum := NewSummary([]string{"s0", "s1", "s2"})
+	sum.AddChromosome(chr)
+	var buf strings.Builder
+	w, err := NewTo("-", &buf, []string{"s0", "s1", "s2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fr := range []digest.Fragment{{Start: 5, End: 25}, {Start: 25, End: 45}} {
+		lengths := chr.Lengths(fr)
+		kept := make([]bool, len(lengths))
+		for i, n := range lengths {
+			kept[i] = n >= 18
+		}
+		sum.AddLocus(fr.End-fr.Start, lengths, kept)
+		if err := w.Write("chr1", fr, lengths, kept); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tref_length\tpresent\ts0\ts1\ts2\n" +
+		"chr1\t5\t25\t20\t2\t20|.\t!17|!17\t20|20\n" +
+		"chr1\t25\t45\t20\t3\t20|.\t20|20\t20|20\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected matrix\nwant:\n%s\ngot:\n%s", want, buf.String())
+	}
+	if sum.Loci != 2 || sum.MissingCells != 1 || sum.CompleteLoci != 1 || sum.PerSample[1].Missing != 1 || sum.LengthShifted != 0 {
+		t.Fatalf("summary = %+v", sum)
+	}
+}
+
+func TestApplySkipsOverlappingAlleles(t *testing.T) {
+	seq := []byte("ACGTACGTAC")
+	recs := []vcf.Record{
+		{Pos: 2, Ref: "CGT", Alt: []string{"C"}, Calls: []vcf.Call{vcf.ParseCall("1/0")}},
+		{Pos: 3, Ref: "G", Alt: []string{"A"}, Calls: []vcf.Call{vcf.ParseCall("1/1")}},
+		{Pos: 6, Ref: "C", Alt: []string{"CTT"}, Calls: []vcf.Call{vcf.ParseCall("1/1")}},
+	}
+	hap, n := Apply(seq, recs, 0, 0)
+	if string(hap.Seq) != "ACACTTGTAC" || n.Alleles != 2 || n.Overlapping != 1 || n.Unphased != 1 {
+		t.Fatalf("hap = %q applied = %+v", hap.Seq, n)
+	}
+	for hapPos, refPos := range map[int]int{0: 0, 2: 4, 4: 6, 6: 6, 9: 9} {
+		if got := hap.ToRef(hapPos); got != refPos {
+			t.Fatalf("ToRef(%d) = %d, want %d", hapPos, got, refPos)
+		}
+	}
+	for refPos, hapPos := range map[int]int{0: 0, 2: 2, 4: 2, 5: 3, 6: 6, 9: 9} {
+		if got := hap.FromRef(refPos); got != hapPos {
+			t.Fatalf("FromRef(%d) = %d, want %d", refPos, got, hapPos)
+		}
+	}
+}
+
+func TestDigestTreatsSegmentsAsContigEnds(t *testing.T) {
+	e, ok := enzyme.Get("EcoRI")
+	if !ok {
+		t.Fatal("missing EcoRI")
+	}
+	plan, err := digest.TryNewPlanWithOptions([]enzyme.Enzyme{e}, digest.Options{IncludeEnds: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// EcoRI cuts at 5 and 35 either side of a gap at [20, 30).
+	seq := []byte("AAAAGAATTCAAAAAAAAAANNNNNNNNNNAAAAGAATTCAAAAA")
+	recs := []vcf.Record{
+		// 2 bp deletion between the first cut and the gap edge.
+		{Pos: 12, Ref: "AAA", Alt: []string{"A"}, Calls: []vcf.Call{vcf.ParseCall("0|1")}},
+	}
+	chr := Digest(plan, seq, recs, 1, [][2]int{{0, 20}, {30, 45}}, nil)
+	for fr, want := range map[digest.Fragment][]int{
+		{Start: 0, End: 5}:   {5, 5},
+		{Start: 5, End: 20}:  {15, 13},
+		{Start: 30, End: 35}: {5, 5},
+		{Start: 35, End: 45}: {10, 10},
+	} {
+		if got := chr.Lengths(fr); !equal(got, want) {
+			t.Fatalf("lengths of %d-%d = %v, want %v", fr.Start, fr.End, got, want)
+		}
+	}
+	if len(chr.lengths) != 4 {
+		t.Fatalf("indexed %d loci, want 4 gap-bounded loci", len(chr.lengths))
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
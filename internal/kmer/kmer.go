@@ -0,0 +1,265 @@
+// Package kmer provides strand-aware hashes of short DNA windows. Hashes are
+// defined only for A/C/G/T sequence; windows containing any other base are
+// reported as unhashable so ambiguous reference stretches never collide.
+package kmer
+
+import "sort"
+
+// MaxK is the largest k supported by BottomSketch, which packs k-mers into
+// 64-bit words.
+const MaxK = 32
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+var (
+	code       [256]int8
+	complement [256]byte
+)
+
+func init() {
+	for i := range code {
+		code[i] = -1
+	}
+	for c, b := range []byte("ACGT") {
+		code[b] = int8(c)
+		code[b+'a'-'A'] = int8(c)
+	}
+	for _, pair := range [][2]byte{{'A', 'T'}, {'C', 'G'}, {'G', 'C'}, {'T', 'A'}} {
+		complement[pair[0]] = pair[1]
+		complement[pair[0]+'a'-'A'] = pair[1]
+	}
+}
+
+// Forward hashes seq as read on the forward strand. The boolean is false when
+// seq is empty or contains a base other than A/C/G/T.
+func Forward(seq []byte) (uint64, bool) {
+	if len(seq) == 0 {
+		return 0, false
+	}
+	h := uint64(fnvOffset)
+	for _, b := range seq {
+		c := code[b]
+		if c < 0 {
+			return 0, false
+		}
+		h ^= uint64(c) + 1
+		h *= fnvPrime
+	}
+	return h, true
+}
+
+// Reverse hashes the reverse complement of seq. Reverse(x) equals Forward(y)
+// whenever y is the reverse complement of x.
+func Reverse(seq []byte) (uint64, bool) {
+	if len(seq) == 0 {
+		return 0, false
+	}
+	h := uint64(fnvOffset)
+	for i := len(seq) - 1; i >= 0; i-- {
+		c := code[complement[seq[i]]]
+		if c < 0 {
+			return 0, false
+		}
+		h ^= uint64(c) + 1
+		h *= fnvPrime
+	}
+	return h, true
+}
+
+// Canonical returns the smaller of the forward and reverse-complement hashes,
+// so a window and its reverse complement hash identically.
+func Canonical(seq []byte) (uint64, bool) {
+	fwd, ok := Forward(seq)
+	if !ok {
+		return 0, false
+	}
+	rev, _ := Reverse(seq)
+	if rev < fwd {
+		return rev, true
+	}
+	return fwd, true
+}
+
+// Sketch is a bottom-s MinHash sketch: the smallest distinct mixed
+// canonical k-mer hashes of a window, in ascending order.
+type Sketch []uint64
+
+// BottomSketch returns the sketch of the s smallest distinct mixed canonical
+// k-mer hashes in seq. A window with fewer than s distinct k-mers keeps all
+// of them. The boolean is false when k is outside [1,MaxK], s < 1, or seq has
+// no A/C/G/T-only k-mer.
+func BottomSketch(seq []byte, k, s int) (Sketch, bool) {
+	if k < 1 || k > MaxK || s < 1 || len(seq) < k {
+		return nil, false
+	}
+	shift := uint(2 * (k - 1))
+	mask := ^uint64(0)
+	if k < MaxK {
+		mask = (uint64(1) << uint(2*k)) - 1
+	}
+
+	var fwd, rev uint64
+	valid := 0
+	sketch := make(Sketch, 0, s)
+	for _, b := range seq {
+		c := code[b]
+		if c < 0 {
+			valid = 0
+			fwd, rev = 0, 0
+			continue
+		}
+		fwd = (fwd<<2 | uint64(c)) & mask
+		rev = rev>>2 | uint64(3-c)<<shift
+		valid++
+		if valid < k {
+			continue
+		}
+		h := mix64(minUint64(fwd, rev))
+		if len(sketch) == s && h >= sketch[s-1] {
+			continue
+		}
+		i := sort.Search(len(sketch), func(i int) bool { return sketch[i] >= h })
+		if i < len(sketch) && sketch[i] == h {
+			continue
+		}
+		if len(sketch) < s {
+			sketch = append(sketch, 0)
+		}
+		copy(sketch[i+1:], sketch[i:])
+		sketch[i] = h
+	}
+	return sketch, len(sketch) > 0
+}
+
+// Similarity estimates the Jaccard similarity of the k-mer sets behind two
+// sketches of size s as the fraction of the s smallest hashes of their union
+// that both hold. The estimate is exact when the union has at most s hashes,
+// and its standard error is about sqrt(J(1-J)/s) otherwise. Empty sketches
+// have similarity 0.
+func Similarity(a, b Sketch, s int) float64 {
+	taken, shared := 0, 0
+	for i, j := 0, 0; taken < s && (i < len(a) || j < len(b)); taken++ {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			i++
+		case i == len(a) || b[j] < a[i]:
+			j++
+		default:
+			shared++
+			i++
+			j++
+		}
+	}
+	if taken == 0 {
+		return 0
+	}
+	return float64(shared) / float64(taken)
+}
+
+// mix64 is the splitmix64 finalizer. It spreads packed k-mers so the smallest
+// hashes are not biased toward A-rich sequence.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// rollBase is the odd multiplier of the polynomial rolling hash. Being odd, it
+// is invertible modulo 2^64, which lets the reverse-complement hash roll too.
+const rollBase = 0x100000001b3
+
+// Roller computes canonical polynomial hashes of fixed-length windows. Unlike
+// Canonical, whose cost grows with the window, Each advances in constant time
+// per base, so it suits indexing every read-length window of a genome.
+type Roller struct {
+	l    int
+	top  uint64 // rollBase^(l-1)
+	binv uint64 // rollBase^-1 mod 2^64
+}
+
+// NewRoller returns a Roller for windows of l bases. l must be >= 1.
+func NewRoller(l int) Roller {
+	if l < 1 {
+		l = 1
+	}
+	top := uint64(1)
+	for i := 1; i < l; i++ {
+		top *= rollBase
+	}
+	// Newton's iteration doubles the number of correct low bits each step.
+	inv := uint64(rollBase)
+	for i := 0; i < 6; i++ {
+		inv *= 2 - rollBase*inv
+	}
+	return Roller{l: l, top: top, binv: inv}
+}
+
+// Len returns the window length.
+func (r Roller) Len() int {
+	return r.l
+}
+
+// Window returns the canonical hash of window, which must be exactly Len
+// bases. The boolean is false for a wrong length or a non-ACGT base.
+func (r Roller) Window(window []byte) (uint64, bool) {
+	if len(window) != r.l {
+		return 0, false
+	}
+	var fwd, rev uint64
+	pow := uint64(1)
+	for i, b := range window {
+		c := code[b]
+		if c < 0 {
+			return 0, false
+		}
+		fwd = fwd*rollBase + uint64(c) + 1
+		rev += uint64(4-c) * pow
+		if i+1 < r.l {
+			pow *= rollBase
+		}
+	}
+	return minUint64(fwd, rev), true
+}
+
+// Each calls fn with the start position and canonical hash of every window of
+// seq that contains only A/C/G/T bases. Hashes equal those from Window.
+func (r Roller) Each(seq []byte, fn func(pos int, h uint64)) {
+	var fwd, rev uint64
+	pow := uint64(1) // rollBase^valid, the reverse-hash power of the next base
+	valid := 0
+	for i, b := range seq {
+		c := code[b]
+		if c < 0 {
+			valid = 0
+			fwd, rev, pow = 0, 0, 1
+			continue
+		}
+		if valid == r.l {
+			out := uint64(code[seq[i-r.l]])
+			fwd -= (out + 1) * r.top
+			rev = (rev - (4 - out)) * r.binv
+			valid--
+		}
+		fwd = fwd*rollBase + uint64(c) + 1
+		rev += uint64(4-c) * pow
+		valid++
+		if valid < r.l {
+			pow *= rollBase
+		} else {
+			fn(i-r.l+1, minUint64(fwd, rev))
+		}
+	}
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
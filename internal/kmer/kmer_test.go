@@ -0,0 +1,97 @@
+package kmer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func reverseComplement(seq string) string {
+	out := make([]byte, len(seq))
+	for i := range seq {
+		out[len(seq)-1-i] = complement[seq[i]]
+	}
+	return string(out)
+}
+
+func TestReverseMatchesForwardOfReverseComplement(t *testing.T) {
+	seq := "ACGGTTACCAGT"
+	rev, ok := Reverse([]byte(seq))
+	if !ok {
+		t.Fatal("Reverse reported unhashable sequence")
+	}
+	fwd, ok := Forward([]byte(reverseComplement(seq)))
+	if !ok || fwd != rev {
+		t.Fatalf("Reverse(%s)=%d, Forward(revcomp)=%d", seq, rev, fwd)
+	}
+}
+
+func TestCanonicalIsStrandIndependent(t *testing.T) {
+	seq := "GATTACAGGC"
+	a, _ := Canonical([]byte(seq))
+	b, _ := Canonical([]byte(reverseComplement(seq)))
+	if a != b {
+		t.Fatalf("canonical hashes differ: %d vs %d", a, b)
+	}
+	if _, ok := Forward([]byte("ACNGT")); ok {
+		t.Fatal("sequence with N should be unhashable")
+	}
+}
+
+func TestBottomSketchIsStrandIndependent(t *testing.T) {
+	seq := "ACGTTGCAAGCTTCGATCGGATCCATGCAGTACGATGACTGCATGCTAGC"
+	a, ok := BottomSketch([]byte(seq), 11, 8)
+	if !ok || len(a) != 8 {
+		t.Fatalf("BottomSketch = %v, %v; want 8 hashes", a, ok)
+	}
+	for i := 1; i < len(a); i++ {
+		if a[i] <= a[i-1] {
+			t.Fatalf("sketch not strictly ascending: %v", a)
+		}
+	}
+	b, _ := BottomSketch([]byte(reverseComplement(seq)), 11, 8)
+	if !reflect.DeepEqual(a, b) || Similarity(a, b, 8) != 1 {
+		t.Fatalf("reverse complement changed sketch: %v vs %v", a, b)
+	}
+	if _, ok := BottomSketch([]byte("ACGT"), 5, 8); ok {
+		t.Fatal("sequence shorter than k should have no sketch")
+	}
+	if _, ok := BottomSketch([]byte(seq), MaxK+1, 8); ok {
+		t.Fatal("k above MaxK should be rejected")
+	}
+	if _, ok := BottomSketch([]byte(seq), 11, 0); ok {
+		t.Fatal("empty sketch size should be rejected")
+	}
+}
+
+func TestSimilarityIsExactForUnfilledSketches(t *testing.T) {
+	// Five 11-mers each; changing the last base replaces one of them.
+	a, _ := BottomSketch([]byte("ACGTTGCAAGCTTCG"), 11, 8)
+	b, _ := BottomSketch([]byte("ACGTTGCAAGCTTCA"), 11, 8)
+	if got, want := Similarity(a, b, 8), 4.0/6; got != want {
+		t.Fatalf("Similarity = %g, want %g", got, want)
+	}
+	if Similarity(nil, a, 8) != 0 || Similarity(nil, nil, 8) != 0 {
+		t.Fatal("empty sketches should share nothing")
+	}
+}
+
+func TestRollerEachMatchesWindow(t *testing.T) {
+	seq := []byte("ACGTTGCAAGCTNCGATCGGATCCATGCAGTACGATGAC")
+	r := NewRoller(7)
+	seen := 0
+	r.Each(seq, func(pos int, h uint64) {
+		seen++
+		want, ok := r.Window(seq[pos : pos+7])
+		if !ok || want != h {
+			t.Fatalf("pos %d: rolling hash %d, direct %d (ok=%v)", pos, h, want, ok)
+		}
+		rc, _ := r.Window([]byte(reverseComplement(string(seq[pos : pos+7]))))
+		if rc != h {
+			t.Fatalf("pos %d: reverse complement hash %d differs from %d", pos, rc, h)
+		}
+	})
+	// 12 bases before the N give 6 windows; 26 after it give 20.
+	if seen != 26 {
+		t.Fatalf("saw %d windows, want 26", seen)
+	}
+}
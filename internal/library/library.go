@@ -0,0 +1,364 @@
+// Package library models sequencing-library molecules built from digest
+// fragments. A fragment's ligatable insert runs between the outer edges of its
+// two sticky ends; the P1 adapter and inline barcode 1 are ligated to the
+// first (A) enzyme's end and the P2 adapter and barcode 2 to the other end:
+//
+//	P1 + barcode1 + insert + revcomp(barcode2) + revcomp(P2)
+//
+// Adapters and barcodes are written 5'→3' toward the insert, so read 1 begins
+// with barcode 1 and read 2 with barcode 2.
+package library
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/enzyme"
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+// Default adapters are the Illumina TruSeq universal (P1) and index (P2)
+// adapters without an index, written 5'→3' toward the insert.
+const (
+	DefaultP1 = "AATGATACGGCGACCACCGAGATCTACACTCTTTCCCTACACGACGCTCTTCCGATCT"
+	DefaultP2 = "CAAGCAGAAGACGGCATACGAGATGTGACTGGAGTTCAGACGTGTGCTCTTCCGATCT"
+)
+
+// StickyEnd describes the end an enzyme leaves. Cut is the top-strand cut
+// offset within the site; Outer and Inner are the single-strand boundaries, so
+// a 5' overhang spans [Outer,Inner) and a 3' overhang is filled the same way.
+type StickyEnd struct {
+	Name  string
+	Site  string
+	Cut   int
+	Outer int
+	Inner int
+	mask  []uint8
+}
+
+// LeftRemnant is the top-strand sequence a read starts with when it is
+// sequenced from this end into the fragment on its right.
+func (e StickyEnd) LeftRemnant() string {
+	return e.Site[e.Outer:]
+}
+
+// RightRemnant is the top-strand sequence that ends a fragment at this end,
+// through the inner edge of the overhang.
+func (e StickyEnd) RightRemnant() string {
+	return e.Site[:e.Inner]
+}
+
+// StickyEnds identifies which enzyme cut each fragment end.
+type StickyEnds []StickyEnd
+
+// NewStickyEnds compiles the one or two enzymes of a digest plan, in order.
+func NewStickyEnds(enzymes []enzyme.Enzyme) (StickyEnds, error) {
+	out := make(StickyEnds, 0, len(enzymes))
+	for _, e := range enzymes {
+		site, cut := enzyme.StripCaret(e.Recognition)
+		if !strings.Contains(e.Recognition, "^") && e.CutIndex != 0 {
+			cut = e.CutIndex
+		}
+		mask, err := enzyme.CompileMaskChecked(site)
+		if err != nil {
+			return nil, fmt.Errorf("enzyme %s recognition %q: %w", e.Name, e.Recognition, err)
+		}
+		bottom := len(site) - cut
+		out = append(out, StickyEnd{
+			Name:  e.Name,
+			Site:  strings.ToUpper(site),
+			Cut:   cut,
+			Outer: min(cut, bottom),
+			Inner: max(cut, bottom),
+			mask:  mask,
+		})
+	}
+	return out, nil
+}
+
+// Insert is the ligatable span of a fragment in top-strand coordinates. Left
+// and Right index the enzyme that cut each end, or -1 for a contig end.
+type Insert struct {
+	Start int
+	End   int
+	Left  int
+	Right int
+}
+
+// Len returns the insert length.
+func (i Insert) Len() int {
+	return i.End - i.Start
+}
+
+// Insert extends fr to the outer edges of its sticky ends.
+func (ends StickyEnds) Insert(seq []byte, fr digest.Fragment) Insert {
+	ins := Insert{Start: fr.Start, End: fr.End, Left: ends.at(seq, fr.Start), Right: ends.at(seq, fr.End)}
+	if ins.Left >= 0 {
+		ins.Start = max(0, ins.Start-(ends[ins.Left].Cut-ends[ins.Left].Outer))
+	}
+	if ins.Right >= 0 {
+		ins.End = min(len(seq), ins.End+(ends[ins.Right].Inner-ends[ins.Right].Cut))
+	}
+	return ins
+}
+
+// at reports which enzyme's site leaves a top-strand cut at pos.
+func (ends StickyEnds) at(seq []byte, pos int) int {
+	for i, e := range ends {
+		siteStart := pos - e.Cut
+		if siteStart < 0 || siteStart+len(e.mask) > len(seq) {
+			continue
+		}
+		if enzyme.MatchMask(e.mask, seq[siteStart:siteStart+len(e.mask)]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// contains reports the first enzyme whose site occurs in seq.
+func (ends StickyEnds) contains(seq []byte) (StickyEnd, string, bool) {
+	for _, e := range ends {
+		for i := 0; i+len(e.mask) <= len(seq); i++ {
+			if window := seq[i : i+len(e.mask)]; enzyme.MatchMask(e.mask, window) {
+				return e, string(window), true
+			}
+		}
+	}
+	return StickyEnd{}, "", false
+}
+
+// Sample is one barcode-sheet row. Barcode2 may be empty.
+type Sample struct {
+	Name     string `json:"name"`
+	Barcode1 string `json:"barcode1"`
+	Barcode2 string `json:"barcode2,omitempty"`
+}
+
+// Model holds the adapters and per-sample barcodes of a pooled library.
+type Model struct {
+	P1      string
+	P2      string
+	Samples []Sample
+}
+
+// ReadSheet reads a barcode sheet from path. See ParseSheet.
+func ReadSheet(path string) ([]Sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSheet(f)
+}
+
+// ParseSheet reads whitespace-separated rows of sample, barcode1, and an
+// optional barcode2. Blank lines, '#' comments, and a header row whose first
+// field is "sample" are skipped.
+func ParseSheet(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(samples) == 0 && strings.EqualFold(fields[0], "sample") {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("barcode sheet line %d: want sample, barcode1, and optional barcode2 (got %d fields)", line, len(fields))
+		}
+		s := Sample{Name: fields[0], Barcode1: strings.ToUpper(fields[1])}
+		if len(fields) == 3 {
+			s.Barcode2 = strings.ToUpper(fields[2])
+		}
+		samples = append(samples, s)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// Validate checks that the model has unique samples and A/C/G/T-only
+// adapters and barcodes.
+func (m Model) Validate() error {
+	if len(m.Samples) == 0 {
+		return fmt.Errorf("barcode sheet has no samples")
+	}
+	for _, seq := range []struct{ name, value string }{{"P1 adapter", m.P1}, {"P2 adapter", m.P2}} {
+		if !acgt(seq.value) {
+			return fmt.Errorf("%s %q must contain only A/C/G/T", seq.name, seq.value)
+		}
+	}
+	seen := make(map[string]bool, len(m.Samples))
+	for _, s := range m.Samples {
+		if seen[s.Name] {
+			return fmt.Errorf("duplicate sample %q in barcode sheet", s.Name)
+		}
+		seen[s.Name] = true
+		if s.Barcode1 == "" || !acgt(s.Barcode1) || !acgt(s.Barcode2) {
+			return fmt.Errorf("sample %q: barcodes must be non-empty A/C/G/T sequences", s.Name)
+		}
+	}
+	return nil
+}
+
+func acgt(seq string) bool {
+	return strings.Trim(strings.ToUpper(seq), "ACGT") == ""
+}
+
+// Flank returns the bases sample i adds to every insert.
+func (m Model) Flank(i int) int {
+	s := m.Samples[i]
+	return len(m.P1) + len(s.Barcode1) + len(s.Barcode2) + len(m.P2)
+}
+
+// FlankRange returns the smallest and largest per-sample flank.
+func (m Model) FlankRange() (int, int) {
+	lo, hi := m.Flank(0), m.Flank(0)
+	for i := range m.Samples {
+		f := m.Flank(i)
+		lo, hi = min(lo, f), max(hi, f)
+	}
+	return lo, hi
+}
+
+// Molecule returns the full library molecule of sample i for insert, given in
+// read-1 orientation.
+func (m Model) Molecule(i int, insert []byte) []byte {
+	s := m.Samples[i]
+	out := make([]byte, 0, len(insert)+m.Flank(i))
+	out = append(out, m.P1...)
+	out = append(out, s.Barcode1...)
+	out = append(out, insert...)
+	out = append(out, ReverseComplement([]byte(s.Barcode2))...)
+	out = append(out, ReverseComplement([]byte(m.P2))...)
+	return out
+}
+
+// ReadThrough returns the sequence read 1 and read 2 of sample i continue into
+// after the insert: the opposite barcode and adapter.
+func (m Model) ReadThrough(i int) (read1, read2 []byte) {
+	s := m.Samples[i]
+	read1 = append(ReverseComplement([]byte(s.Barcode2)), ReverseComplement([]byte(m.P2))...)
+	read2 = append(ReverseComplement([]byte(s.Barcode1)), ReverseComplement([]byte(m.P1))...)
+	return read1, read2
+}
+
+// Selection is the size-selection outcome for one insert across the pool.
+type Selection struct {
+	HardKept     bool
+	InScoreRange bool
+	Weight       float64
+}
+
+// Select applies sel to the molecule length of every sample, since a pool is
+// size-selected after ligation. A fragment is kept or scored when any sample's
+// molecule is, and its weight is the mean over samples.
+func (m Model) Select(sel sizeselect.Selector, insertLen int) Selection {
+	var out Selection
+	for i := range m.Samples {
+		length := insertLen + m.Flank(i)
+		if sel.InHardWindow(length) {
+			out.HardKept = true
+		}
+		if sel.InScoreRange(length) {
+			out.InScoreRange = true
+			out.Weight += sel.Weight(length)
+		}
+	}
+	out.Weight /= float64(len(m.Samples))
+	return out
+}
+
+// RecreatedSite records an adapter junction that contains a recognition site
+// after ligation, so the ligated product would be recut.
+type RecreatedSite struct {
+	Sample   string `json:"sample"`
+	Adapter  string `json:"adapter"`
+	LigateTo string `json:"ligated_to"`
+	Enzyme   string `json:"enzyme"`
+	Site     string `json:"site"`
+}
+
+// RecreatedSites checks each sample's P1 junction against the first enzyme's
+// end and its P2 junction against the second (or only) enzyme's end, searching
+// the adapter, barcode, and ligated remnant for any enzyme's site.
+func (m Model) RecreatedSites(ends StickyEnds) []RecreatedSite {
+	if len(ends) == 0 {
+		return nil
+	}
+	p1End, p2End := ends[0], ends[len(ends)-1]
+	var out []RecreatedSite
+	for _, s := range m.Samples {
+		left := []byte(m.P1 + s.Barcode1 + p1End.LeftRemnant())
+		if e, site, ok := ends.contains(left); ok {
+			out = append(out, RecreatedSite{Sample: s.Name, Adapter: "p1", LigateTo: p1End.Name, Enzyme: e.Name, Site: site})
+		}
+		right := append([]byte(p2End.RightRemnant()), ReverseComplement([]byte(s.Barcode2))...)
+		right = append(right, ReverseComplement([]byte(m.P2))...)
+		if e, site, ok := ends.contains(right); ok {
+			out = append(out, RecreatedSite{Sample: s.Name, Adapter: "p2", LigateTo: p2End.Name, Enzyme: e.Name, Site: site})
+		}
+	}
+	return out
+}
+
+// Summary describes the library model for run reports.
+type Summary struct {
+	Samples        int             `json:"samples"`
+	P1Length       int             `json:"p1_length"`
+	P2Length       int             `json:"p2_length"`
+	MinFlank       int             `json:"min_flank"`
+	MaxFlank       int             `json:"max_flank"`
+	RecreatedSites []RecreatedSite `json:"recreated_sites"`
+}
+
+// Summary reports the model and its recreated sites for ends.
+func (m Model) Summary(ends StickyEnds) Summary {
+	lo, hi := m.FlankRange()
+	sites := m.RecreatedSites(ends)
+	if sites == nil {
+		sites = []RecreatedSite{}
+	}
+	return Summary{
+		Samples:        len(m.Samples),
+		P1Length:       len(m.P1),
+		P2Length:       len(m.P2),
+		MinFlank:       lo,
+		MaxFlank:       hi,
+		RecreatedSites: sites,
+	}
+}
+
+// ReverseComplement returns the reverse complement of seq. Bases other than
+// A/C/G/T become N.
+func ReverseComplement(seq []byte) []byte {
+	out := make([]byte, len(seq))
+	for i, b := range seq {
+		var c byte
+		switch b {
+		case 'A', 'a':
+			c = 'T'
+		case 'C', 'c':
+			c = 'G'
+		case 'G', 'g':
+			c = 'C'
+		case 'T', 't':
+			c = 'A'
+		default:
+			c = 'N'
+		}
+		out[len(seq)-1-i] = c
+	}
+	return out
+}
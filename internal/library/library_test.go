@@ -0,0 +1,110 @@
+package library
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/enzyme"
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+// testP2 avoids EcoRI and MseI sites at its junction.
+const testP2 = "GTTCAGAGTTCTACAGTCCGACGATCG"
+
+func stickyEnds(t *testing.T, names ...string) StickyEnds {
+	t.Helper()
+	ens := make([]enzyme.Enzyme, 0, len(names))
+	for _, name := range names {
+		e, ok := enzyme.Get(name)
+		if !ok {
+			t.Fatalf("missing enzyme %s", name)
+		}
+		ens = append(ens, e)
+	}
+	ends, err := NewStickyEnds(ens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ends
+}
+
+func TestParseSheet(t *testing.T) {
+	samples, err := ParseSheet(strings.NewReader("sample\tbarcode1\tbarcode2\n# pool A\nS1 acgtc\nS2\tTGCATGA\tGGT\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Sample{{Name: "S1", Barcode1: "ACGTC"}, {Name: "S2", Barcode1: "TGCATGA", Barcode2: "GGT"}}
+	if len(samples) != len(want) || samples[0] != want[0] || samples[1] != want[1] {
+		t.Fatalf("samples = %+v, want %+v", samples, want)
+	}
+	if _, err := ParseSheet(strings.NewReader("S1\n")); err == nil {
+		t.Fatal("expected error for a row without a barcode")
+	}
+	if err := (Model{P1: DefaultP1, P2: DefaultP2, Samples: []Sample{{Name: "S1", Barcode1: "ACNT"}}}).Validate(); err == nil {
+		t.Fatal("expected error for a non-ACGT barcode")
+	}
+}
+
+func TestInsertExtendsToOuterOverhangEdges(t *testing.T) {
+	ends := stickyEnds(t, "EcoRI", "MseI")
+	seq := []byte("CCCCGAATTCAGGTTAACCCC")
+	// EcoRI cuts at 5, MseI at 14.
+	ins := ends.Insert(seq, digest.Fragment{Start: 5, End: 14})
+	if ins.Left != 0 || ins.Right != 1 {
+		t.Fatalf("ends = %d,%d, want EcoRI then MseI", ins.Left, ins.Right)
+	}
+	if got := string(seq[ins.Start:ins.End]); got != "AATTCAGGTTA" {
+		t.Fatalf("insert = %q, want AATTCAGGTTA", got)
+	}
+}
+
+func TestRecreatedSites(t *testing.T) {
+	ends := stickyEnds(t, "EcoRI", "MseI")
+	m := Model{P1: DefaultP1, P2: testP2, Samples: []Sample{
+		{Name: "recut", Barcode1: "ACGTG"},
+		{Name: "safe", Barcode1: "ACGTC"},
+		{Name: "p2-recut", Barcode1: "ACGTC", Barcode2: "GGT"},
+	}}
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	got := m.RecreatedSites(ends)
+	if len(got) != 2 {
+		t.Fatalf("recreated sites = %+v, want two", got)
+	}
+	if got[0] != (RecreatedSite{Sample: "recut", Adapter: "p1", LigateTo: "EcoRI", Enzyme: "EcoRI", Site: "GAATTC"}) {
+		t.Fatalf("unexpected P1 recreation: %+v", got[0])
+	}
+	// rc(GGT) = ACC, so the MseI end reads TTA + ACC and recreates TTAA.
+	if got[1] != (RecreatedSite{Sample: "p2-recut", Adapter: "p2", LigateTo: "MseI", Enzyme: "MseI", Site: "TTAA"}) {
+		t.Fatalf("unexpected P2 recreation: %+v", got[1])
+	}
+}
+
+func TestSelectUsesMoleculeLength(t *testing.T) {
+	sel, err := sizeselect.New(sizeselect.Config{Model: sizeselect.ModelHard, Min: 200, Max: 300, ScoreMin: 1, ScoreMax: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Model{P1: strings.Repeat("A", 58), P2: strings.Repeat("C", 27), Samples: []Sample{
+		{Name: "short", Barcode1: "ACGTA"},
+		{Name: "long", Barcode1: "ACGTACGTA"},
+	}}
+	if lo, hi := m.FlankRange(); lo != 90 || hi != 94 {
+		t.Fatalf("flank range = %d,%d, want 90,94", lo, hi)
+	}
+	if got := m.Select(sel, 115); !got.HardKept || got.Weight != 1 {
+		t.Fatalf("insert 115: %+v", got)
+	}
+	if got := m.Select(sel, 108); !got.HardKept || math.Abs(got.Weight-0.5) > 1e-12 {
+		t.Fatalf("insert 108 should be kept for one of two samples: %+v", got)
+	}
+	if got := m.Select(sel, 100); got.HardKept || got.Weight != 0 || !got.InScoreRange {
+		t.Fatalf("insert 100 should be scored but not kept: %+v", got)
+	}
+	if got := string(m.Molecule(0, []byte("GG"))); got != m.P1+"ACGTA"+"GG"+strings.Repeat("G", 27) {
+		t.Fatalf("molecule = %q", got)
+	}
+}
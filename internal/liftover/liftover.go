@@ -0,0 +1,317 @@
+// Package liftover maps fragment coordinates between assemblies through a UCSC
+// chain file and rewrites radigest BED, GFF3, and fragment TSV outputs.
+//
+// A fragment maps when one chain aligns at least the minimum fraction of its
+// bases; the lifted interval runs from the first to the last aligned base.
+// Fragments that do not map are reported with a Reason.
+package liftover
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Block is one gapless aligned block. QStart is on the chain's query strand.
+type Block struct {
+	TStart int
+	QStart int
+	Size   int
+}
+
+// Chain is one alignment chain from the source (target, t) assembly to the
+// destination (query, q) assembly.
+type Chain struct {
+	ID      string
+	TName   string
+	TStart  int
+	TEnd    int
+	QName   string
+	QSize   int
+	QStrand byte
+	Blocks  []Block
+}
+
+// Reason explains why a fragment did not lift.
+type Reason string
+
+const (
+	// NoChain means no chain covers the fragment's source interval.
+	NoChain Reason = "no_chain"
+	// Deleted means chains span the fragment but align too few of its bases.
+	Deleted Reason = "deleted"
+	// Split means the fragment's aligned bases reach the minimum match only
+	// across two or more chains, typically across a rearrangement.
+	Split Reason = "split"
+)
+
+// DefaultMinMatch is the smallest fraction of a fragment's bases one chain
+// must align, matching UCSC liftOver's default.
+const DefaultMinMatch = 0.95
+
+// Map indexes chains by source chromosome.
+type Map struct {
+	byChr map[string]*chrChains
+}
+
+type chrChains struct {
+	chains []*Chain // sorted by TStart
+	maxEnd []int    // maxEnd[i] is the largest TEnd of chains[:i+1]
+}
+
+// Read loads a chain file, plain or gzip.
+func Read(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := maybeGzip(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	m, err := Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Open opens path for reading, decompressing gzip input, or returns stdin for
+// "-". The caller closes the returned closer.
+func Open(path string, stdin io.Reader) (io.Reader, io.Closer, error) {
+	if path == "-" {
+		r, err := maybeGzip(stdin)
+		return r, io.NopCloser(stdin), err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := maybeGzip(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, f, nil
+}
+
+func maybeGzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// Parse reads chain-format text.
+func Parse(r io.Reader) (*Map, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	m := &Map{byChr: make(map[string]*chrChains)}
+	var cur *Chain
+	var t, q int
+	lineNo := 0
+	finish := func() error {
+		if cur == nil {
+			return nil
+		}
+		if t != cur.TEnd {
+			return fmt.Errorf("chain %s: blocks end at %d, header says %d", cur.ID, t, cur.TEnd)
+		}
+		cc := m.byChr[cur.TName]
+		if cc == nil {
+			cc = &chrChains{}
+			m.byChr[cur.TName] = cc
+		}
+		cc.chains = append(cc.chains, cur)
+		cur = nil
+		return nil
+	}
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if fields[0] == "chain" {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			c, qStart, err := parseHeader(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			cur, t, q = c, c.TStart, qStart
+			continue
+		}
+		if cur == nil {
+			return nil, fmt.Errorf("line %d: alignment data before chain header", lineNo)
+		}
+		if len(fields) != 1 && len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected size [dt dq], got %d fields", lineNo, len(fields))
+		}
+		nums := make([]int, len(fields))
+		for i, f := range fields {
+			n, err := strconv.Atoi(f)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("line %d: invalid number %q", lineNo, f)
+			}
+			nums[i] = n
+		}
+		cur.Blocks = append(cur.Blocks, Block{TStart: t, QStart: q, Size: nums[0]})
+		t += nums[0]
+		q += nums[0]
+		if len(nums) == 3 {
+			t += nums[1]
+			q += nums[2]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	for _, cc := range m.byChr {
+		sort.SliceStable(cc.chains, func(i, j int) bool { return cc.chains[i].TStart < cc.chains[j].TStart })
+		cc.maxEnd = make([]int, len(cc.chains))
+		end := 0
+		for i, c := range cc.chains {
+			end = max(end, c.TEnd)
+			cc.maxEnd[i] = end
+		}
+	}
+	return m, nil
+}
+
+// parseHeader returns the chain and its query start.
+func parseHeader(fields []string) (*Chain, int, error) {
+	// chain score tName tSize tStrand tStart tEnd qName qSize qStrand qStart qEnd [id]
+	if len(fields) < 12 {
+		return nil, 0, fmt.Errorf("chain header has %d fields, want at least 12", len(fields))
+	}
+	ints := make([]int, 0, 6)
+	for _, i := range []int{3, 5, 6, 8, 10, 11} {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || n < 0 {
+			return nil, 0, fmt.Errorf("invalid chain header number %q", fields[i])
+		}
+		ints = append(ints, n)
+	}
+	if fields[4] != "+" {
+		return nil, 0, fmt.Errorf("source strand must be + (got %s)", fields[4])
+	}
+	if fields[9] != "+" && fields[9] != "-" {
+		return nil, 0, fmt.Errorf("invalid query strand %q", fields[9])
+	}
+	c := &Chain{
+		TName:   fields[2],
+		TStart:  ints[1],
+		TEnd:    ints[2],
+		QName:   fields[7],
+		QSize:   ints[3],
+		QStrand: fields[9][0],
+	}
+	if len(fields) > 12 {
+		c.ID = fields[12]
+	}
+	if c.TEnd < c.TStart || ints[5] < ints[4] || ints[5] > c.QSize {
+		return nil, 0, fmt.Errorf("invalid chain coordinates")
+	}
+	return c, ints[4], nil
+}
+
+// Lifted is a fragment mapped to the destination assembly. Strand is '-' when
+// the chain reverses the fragment.
+type Lifted struct {
+	Chr    string
+	Start  int
+	End    int
+	Strand byte
+	// Matched is the fraction of source bases the chain aligns.
+	Matched float64
+}
+
+// Lift maps the source interval [start, end) on chr. When the fragment does
+// not map, reason says why and matched is the best single-chain fraction.
+func (m *Map) Lift(chr string, start, end int, minMatch float64) (out Lifted, reason Reason) {
+	if minMatch <= 0 {
+		minMatch = DefaultMinMatch
+	}
+	cc := m.byChr[chr]
+	if cc == nil || end <= start {
+		return Lifted{}, NoChain
+	}
+	length := float64(end - start)
+	i := sort.Search(len(cc.maxEnd), func(i int) bool { return cc.maxEnd[i] > start })
+	overlapping, aligning, total := 0, 0, 0
+	var best *Chain
+	bestAligned := 0
+	for ; i < len(cc.chains) && cc.chains[i].TStart < end; i++ {
+		c := cc.chains[i]
+		if c.TEnd <= start {
+			continue
+		}
+		overlapping++
+		n := c.aligned(start, end)
+		if n == 0 {
+			continue
+		}
+		aligning++
+		total += n
+		if n > bestAligned {
+			best, bestAligned = c, n
+		}
+	}
+	switch {
+	case overlapping == 0:
+		return Lifted{}, NoChain
+	case best != nil && float64(bestAligned)/length >= minMatch:
+		out = best.lift(start, end)
+		out.Matched = float64(bestAligned) / length
+		return out, ""
+	case aligning > 1 && float64(total)/length >= minMatch:
+		return Lifted{Matched: float64(bestAligned) / length}, Split
+	}
+	return Lifted{Matched: float64(bestAligned) / length}, Deleted
+}
+
+// blocksFrom returns the index of the first block ending after start.
+func (c *Chain) blocksFrom(start int) int {
+	return sort.Search(len(c.Blocks), func(i int) bool { return c.Blocks[i].TStart+c.Blocks[i].Size > start })
+}
+
+// aligned counts the bases of [start, end) inside c's blocks.
+func (c *Chain) aligned(start, end int) int {
+	n := 0
+	for i := c.blocksFrom(start); i < len(c.Blocks) && c.Blocks[i].TStart < end; i++ {
+		b := c.Blocks[i]
+		n += min(end, b.TStart+b.Size) - max(start, b.TStart)
+	}
+	return n
+}
+
+// lift maps the first and last aligned bases of [start, end).
+func (c *Chain) lift(start, end int) Lifted {
+	qs, qe := -1, -1
+	for i := c.blocksFrom(start); i < len(c.Blocks) && c.Blocks[i].TStart < end; i++ {
+		b := c.Blocks[i]
+		s, e := max(start, b.TStart), min(end, b.TStart+b.Size)
+		if qs < 0 {
+			qs = b.QStart + s - b.TStart
+		}
+		qe = b.QStart + e - b.TStart
+	}
+	out := Lifted{Chr: c.QName, Start: qs, End: qe, Strand: '+'}
+	if c.QStrand == '-' {
+		out.Start, out.End, out.Strand = c.QSize-qe, c.QSize-qs, '-'
+	}
+	return out
+}
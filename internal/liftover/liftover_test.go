@@ -0,0 +1,88 @@
+package liftover
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testChains maps old:0-100 to new:1000-1098 with a 2-base deletion at
+// old:50-52, old:100-150 to other:0-50, and old:200-300 onto the reverse
+// strand of rev (size 500) at 400-300.
+const testChains = `chain 100 old 1000 + 0 100 new 5000 + 1000 1098 1
+50	2	0
+48
+
+chain 50 old 1000 + 100 150 other 50 + 0 50 2
+50
+
+chain 90 old 1000 + 200 300 rev 500 - 100 200 3
+100
+`
+
+func TestLiftMapsAndReportsReasons(t *testing.T) {
+	m, err := Parse(strings.NewReader(testChains))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		start, end int
+		want       Lifted
+		reason     Reason
+	}{
+		{10, 40, Lifted{Chr: "new", Start: 1010, End: 1040, Strand: '+', Matched: 1}, ""},
+		{40, 90, Lifted{Chr: "new", Start: 1040, End: 1088, Strand: '+', Matched: 0.96}, ""},
+		{210, 230, Lifted{Chr: "rev", Start: 370, End: 390, Strand: '-', Matched: 1}, ""},
+		{60, 140, Lifted{Matched: 0.5}, Split},
+		{45, 55, Lifted{Matched: 0.8}, Deleted},
+		{160, 190, Lifted{}, NoChain},
+	}
+	for _, tc := range cases {
+		got, reason := m.Lift("old", tc.start, tc.end, 0)
+		if reason != tc.reason || got != tc.want {
+			t.Fatalf("Lift(old:%d-%d) = %+v, %q; want %+v, %q", tc.start, tc.end, got, reason, tc.want, tc.reason)
+		}
+	}
+}
+
+func TestRewriteKeepsFragmentIDs(t *testing.T) {
+	m, err := Parse(strings.NewReader(testChains))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\n" +
+		"old\t10\t40\t30\ttrue\t1\n" +
+		"old\t60\t140\t80\ttrue\t1\n" +
+		"old\t150\t210\t60\tfalse\t0.1\n" +
+		"old\t210\t230\t20\ttrue\t1\n"
+	var out bytes.Buffer
+	var lost []Unmapped
+	stats, err := Rewrite(strings.NewReader(in), &out, FormatAuto, m, 0, func(u Unmapped) error {
+		lost = append(lost, u)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\tsource_id\tstrand\n" +
+		"new\t1010\t1040\t30\ttrue\t1\told_1\t+\n" +
+		"rev\t370\t390\t20\ttrue\t1\told_3\t-\n"
+	if out.String() != want {
+		t.Fatalf("TSV =\n%s\nwant\n%s", out.String(), want)
+	}
+	if stats.Format != FormatTSV || stats.Lifted != 2 || stats.Unmapped[Split] != 1 || stats.Unmapped[Deleted] != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if len(lost) != 2 || lost[0].ID != "old_2" || lost[1].ID != "." {
+		t.Fatalf("unmapped = %+v", lost)
+	}
+
+	gffIn := "##gff-version 3\nold\tradigest\tfragment\t11\t40\t.\t+\t.\tID=old_1;Length=30\n"
+	out.Reset()
+	if _, err := Rewrite(strings.NewReader(gffIn), &out, FormatAuto, m, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "##gff-version 3\nnew\tradigest\tfragment\t1011\t1040\t.\t+\t.\tID=old_1;Length=30\n"; out.String() != want {
+		t.Fatalf("GFF =\n%s\nwant\n%s", out.String(), want)
+	}
+}
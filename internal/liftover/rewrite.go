@@ -0,0 +1,321 @@
+package liftover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/gff"
+)
+
+// Format is a radigest fragment output format.
+type Format string
+
+const (
+	// FormatAuto detects the format from the first line.
+	FormatAuto Format = "auto"
+	FormatBED  Format = "bed"
+	FormatGFF  Format = "gff"
+	FormatTSV  Format = "tsv"
+)
+
+// ParseFormat parses auto, bed, gff, or tsv.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatAuto, FormatBED, FormatGFF, FormatTSV:
+		return f, nil
+	case "gff3":
+		return FormatGFF, nil
+	}
+	return "", fmt.Errorf("unknown format %q (want auto, bed, gff, or tsv)", s)
+}
+
+// Unmapped is one fragment that did not lift.
+type Unmapped struct {
+	ID      string
+	Chr     string
+	Start   int
+	End     int
+	Reason  Reason
+	Matched float64
+}
+
+// Stats counts the fragments Rewrite processed.
+type Stats struct {
+	Format   Format         `json:"format"`
+	Lifted   int            `json:"lifted"`
+	Unmapped map[Reason]int `json:"unmapped"`
+}
+
+// Rewrite lifts every fragment row read from r and writes it to w in the same
+// format, keeping fragment IDs so chr_N identifiers trace across builds. BED
+// names and GFF3 attributes are kept as is; fragment TSVs gain source_id and
+// strand columns, with source_id empty ('.') for rows that were not hard-kept.
+// Rows that do not lift are passed to unmapped instead. Output rows keep the
+// input order, which may no longer be sorted by destination coordinate.
+func Rewrite(r io.Reader, w io.Writer, format Format, m *Map, minMatch float64, unmapped func(Unmapped) error) (Stats, error) {
+	stats := Stats{Format: format, Unmapped: make(map[Reason]int)}
+	if unmapped == nil {
+		unmapped = func(Unmapped) error { return nil }
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	bw := bufio.NewWriter(w)
+	tsv := tsvState{hardKeptCol: -1, ordinals: make(map[string]int)}
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimRight(sc.Text(), "\r")
+		if stats.Format == FormatAuto {
+			stats.Format = detect(line)
+		}
+		out, skip, err := "", false, error(nil)
+		switch stats.Format {
+		case FormatGFF:
+			out, skip, err = liftGFF(line, m, minMatch, &stats, unmapped)
+		case FormatTSV:
+			out, skip, err = tsv.lift(line, lineNo, m, minMatch, &stats, unmapped)
+		default:
+			out, skip, err = liftBED(line, m, minMatch, &stats, unmapped)
+		}
+		if err != nil {
+			return stats, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if skip {
+			continue
+		}
+		if _, err := bw.WriteString(out + "\n"); err != nil {
+			return stats, err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return stats, err
+	}
+	if stats.Format == FormatAuto {
+		stats.Format = FormatBED
+	}
+	return stats, bw.Flush()
+}
+
+func detect(line string) Format {
+	switch {
+	case strings.HasPrefix(line, "##gff-version"):
+		return FormatGFF
+	case strings.HasPrefix(line, "chrom\tstart0\t"):
+		return FormatTSV
+	}
+	return FormatBED
+}
+
+// lift maps one fragment and reports it through unmapped when it fails.
+func lift(id, chr string, start, end int, m *Map, minMatch float64, stats *Stats, unmapped func(Unmapped) error) (Lifted, bool, error) {
+	out, reason := m.Lift(chr, start, end, minMatch)
+	if reason != "" {
+		stats.Unmapped[reason]++
+		return out, false, unmapped(Unmapped{ID: id, Chr: chr, Start: start, End: end, Reason: reason, Matched: out.Matched})
+	}
+	stats.Lifted++
+	return out, true, nil
+}
+
+func flipStrand(strand string, lifted Lifted) string {
+	if lifted.Strand != '-' {
+		return strand
+	}
+	switch strand {
+	case "+":
+		return "-"
+	case "-":
+		return "+"
+	}
+	return strand
+}
+
+func liftBED(line string, m *Map, minMatch float64, stats *Stats, unmapped func(Unmapped) error) (string, bool, error) {
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser") {
+		return line, false, nil
+	}
+	cols := strings.Split(line, "\t")
+	if len(cols) < 3 {
+		return "", false, fmt.Errorf("expected at least 3 BED columns, got %d", len(cols))
+	}
+	start, end, err := parseSpan(cols[1], cols[2])
+	if err != nil {
+		return "", false, err
+	}
+	id := fmt.Sprintf("%s:%d-%d", cols[0], start, end)
+	if len(cols) > 3 {
+		id = cols[3]
+	}
+	lifted, ok, err := lift(id, cols[0], start, end, m, minMatch, stats, unmapped)
+	if !ok {
+		return "", true, err
+	}
+	cols[0], cols[1], cols[2] = lifted.Chr, strconv.Itoa(lifted.Start), strconv.Itoa(lifted.End)
+	if len(cols) > 5 {
+		cols[5] = flipStrand(cols[5], lifted)
+	}
+	return strings.Join(cols, "\t"), false, nil
+}
+
+func liftGFF(line string, m *Map, minMatch float64, stats *Stats, unmapped func(Unmapped) error) (string, bool, error) {
+	if strings.HasPrefix(line, "##sequence-region") {
+		// Source sequence regions do not describe the destination assembly.
+		return "", true, nil
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return line, false, nil
+	}
+	cols := strings.Split(line, "\t")
+	if len(cols) != 9 {
+		return "", false, fmt.Errorf("expected 9 GFF3 columns, got %d", len(cols))
+	}
+	chr, err := url.PathUnescape(cols[0])
+	if err != nil {
+		return "", false, fmt.Errorf("invalid seqid %q", cols[0])
+	}
+	start1, end, err := parseSpan(cols[3], cols[4])
+	if err != nil || start1 < 1 {
+		return "", false, fmt.Errorf("invalid GFF3 span %s-%s", cols[3], cols[4])
+	}
+	id := fmt.Sprintf("%s:%d-%d", chr, start1, end)
+	for _, attr := range strings.Split(cols[8], ";") {
+		if v, ok := strings.CutPrefix(attr, "ID="); ok {
+			id = v
+		}
+	}
+	lifted, ok, err := lift(id, chr, start1-1, end, m, minMatch, stats, unmapped)
+	if !ok {
+		return "", true, err
+	}
+	cols[0] = gff.EscapeSeqID(lifted.Chr)
+	cols[3], cols[4] = strconv.Itoa(lifted.Start+1), strconv.Itoa(lifted.End)
+	cols[6] = flipStrand(cols[6], lifted)
+	return strings.Join(cols, "\t"), false, nil
+}
+
+// tsvState tracks the fragment TSV header and per-chromosome ordinals, which
+// match the BED/GFF3 IDs radigest assigns to hard-kept fragments.
+type tsvState struct {
+	hardKeptCol int
+	lengthCol   int
+	ordinals    map[string]int
+}
+
+func (t *tsvState) lift(line string, lineNo int, m *Map, minMatch float64, stats *Stats, unmapped func(Unmapped) error) (string, bool, error) {
+	if lineNo == 1 && strings.HasPrefix(line, "chrom\t") {
+		for i, name := range strings.Split(line, "\t") {
+			switch name {
+			case "hard_kept":
+				t.hardKeptCol = i
+			case "length":
+				t.lengthCol = i
+			}
+		}
+		return line + "\tsource_id\tstrand", false, nil
+	}
+	if line == "" {
+		return line, false, nil
+	}
+	cols := strings.Split(line, "\t")
+	if len(cols) < 3 {
+		return "", false, fmt.Errorf("expected at least 3 TSV columns, got %d", len(cols))
+	}
+	start, end, err := parseSpan(cols[1], cols[2])
+	if err != nil {
+		return "", false, err
+	}
+	id := "."
+	if t.hardKeptCol < 0 || t.hardKeptCol < len(cols) && cols[t.hardKeptCol] == "true" {
+		t.ordinals[cols[0]]++
+		id = fmt.Sprintf("%s_%d", gff.EscapeAttributeValue(cols[0]), t.ordinals[cols[0]])
+	}
+	lifted, ok, err := lift(id, cols[0], start, end, m, minMatch, stats, unmapped)
+	if !ok {
+		return "", true, err
+	}
+	cols[0], cols[1], cols[2] = lifted.Chr, strconv.Itoa(lifted.Start), strconv.Itoa(lifted.End)
+	if t.lengthCol > 0 && t.lengthCol < len(cols) {
+		cols[t.lengthCol] = strconv.Itoa(lifted.End - lifted.Start)
+	}
+	return strings.Join(cols, "\t") + "\t" + id + "\t" + string(lifted.Strand), false, nil
+}
+
+func parseSpan(startText, endText string) (int, int, error) {
+	start, err := strconv.Atoi(startText)
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("invalid start %q", startText)
+	}
+	end, err := strconv.Atoi(endText)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid end %q", endText)
+	}
+	return start, end, nil
+}
+
+// UnmappedWriter emits a TSV of fragments that did not lift. A UnmappedWriter
+// created with an empty path is a no-op.
+type UnmappedWriter struct {
+	bw       *bufio.Writer
+	close    func() error
+	disabled bool
+}
+
+// NewUnmappedTo opens path, or writes "-" to stdout, and writes the header.
+// Use an empty path to disable output.
+func NewUnmappedTo(path string, stdout io.Writer) (*UnmappedWriter, error) {
+	if path == "" {
+		return &UnmappedWriter{disabled: true}, nil
+	}
+	var sink io.Writer
+	var close func() error
+	if path == "-" {
+		if stdout == nil {
+			return nil, fmt.Errorf("stdout writer is nil")
+		}
+		sink = stdout
+	} else {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		sink = f
+		close = f.Close
+	}
+	w := &UnmappedWriter{bw: bufio.NewWriter(sink), close: close}
+	if _, err := w.bw.WriteString("id\tchrom\tstart0\tend0\treason\tmatched_fraction\n"); err != nil {
+		if close != nil {
+			_ = close()
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write emits one unmapped fragment.
+func (w *UnmappedWriter) Write(u Unmapped) error {
+	if w == nil || w.disabled {
+		return nil
+	}
+	_, err := fmt.Fprintf(w.bw, "%s\t%s\t%d\t%d\t%s\t%.6g\n", u.ID, u.Chr, u.Start, u.End, u.Reason, u.Matched)
+	return err
+}
+
+// Close flushes pending output and closes owned files. Disabled writers are
+// no-ops.
+func (w *UnmappedWriter) Close() error {
+	if w == nil || w.disabled {
+		return nil
+	}
+	err := w.bw.Flush()
+	if w.close != nil {
+		if closeErr := w.close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
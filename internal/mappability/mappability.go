@@ -0,0 +1,189 @@
+// Package mappability scores how uniquely fragment read ends occur in a
+// reference. An Index counts every canonical read-length window in the
+// reference; a fragment end is unique when its window occurs exactly once, so
+// reads from it map to a single place regardless of strand.
+package mappability
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/kmer"
+)
+
+// NotEvaluated marks an end whose window runs off the sequence or contains a
+// non-ACGT base.
+const NotEvaluated = -1
+
+// Index counts canonical read-length windows. Build it with Add, call Freeze
+// once every record is added, then query it. Queries are safe for concurrent
+// use after Freeze; Add and Freeze are not.
+type Index struct {
+	roller kmer.Roller
+	hashes []uint64
+	frozen bool
+}
+
+// NewIndex returns an empty index for windows of readLength bases.
+func NewIndex(readLength int) (*Index, error) {
+	if readLength < 1 {
+		return nil, fmt.Errorf("mappability read length must be >= 1 (got %d)", readLength)
+	}
+	return &Index{roller: kmer.NewRoller(readLength)}, nil
+}
+
+// ReadLength returns the indexed window length.
+func (x *Index) ReadLength() int {
+	return x.roller.Len()
+}
+
+// Windows returns the number of indexed windows.
+func (x *Index) Windows() int {
+	return len(x.hashes)
+}
+
+// Add indexes every A/C/G/T-only window of seq. It panics after Freeze.
+func (x *Index) Add(seq []byte) {
+	if x.frozen {
+		panic("mappability: Add after Freeze")
+	}
+	x.roller.Each(seq, func(_ int, h uint64) {
+		x.hashes = append(x.hashes, h)
+	})
+}
+
+// Freeze sorts the index for counting. It is idempotent.
+func (x *Index) Freeze() {
+	if x.frozen {
+		return
+	}
+	sort.Slice(x.hashes, func(i, j int) bool { return x.hashes[i] < x.hashes[j] })
+	x.frozen = true
+}
+
+// Count returns how many indexed windows have canonical hash h.
+func (x *Index) Count(h uint64) int {
+	if !x.frozen {
+		panic("mappability: Count before Freeze")
+	}
+	lo := sort.Search(len(x.hashes), func(i int) bool { return x.hashes[i] >= h })
+	hi := lo
+	for hi < len(x.hashes) && x.hashes[hi] == h {
+		hi++
+	}
+	return hi - lo
+}
+
+// Hash returns the canonical hash of the window starting at pos, as used by
+// the index. The boolean is false when the window is not evaluable.
+func (x *Index) Hash(seq []byte, pos int) (uint64, bool) {
+	l := x.roller.Len()
+	if pos < 0 || pos+l > len(seq) {
+		return 0, false
+	}
+	return x.roller.Window(seq[pos : pos+l])
+}
+
+// Copies returns how many other times the window with hash h occurs in the
+// reference, or NotEvaluated when ok is false. The window is assumed to be one
+// of the indexed occurrences.
+func (x *Index) Copies(h uint64, ok bool) int {
+	if !ok {
+		return NotEvaluated
+	}
+	if n := x.Count(h); n > 0 {
+		return n - 1
+	}
+	return 0
+}
+
+// Ends holds the number of other reference copies of each fragment end.
+type Ends struct {
+	Left  int
+	Right int
+}
+
+// Evaluated reports whether both end windows could be scored.
+func (e Ends) Evaluated() bool {
+	return e.Left != NotEvaluated && e.Right != NotEvaluated
+}
+
+// Unique reports whether both ends occur nowhere else in the reference.
+func (e Ends) Unique() bool {
+	return e.Left == 0 && e.Right == 0
+}
+
+// Fragment scores the read-length windows at each end of fr: the window
+// starting at fr.Start and the window ending at fr.End. For fragments shorter
+// than the read length these windows extend past the far cut into flanking
+// reference sequence.
+func (x *Index) Fragment(seq []byte, fr digest.Fragment) Ends {
+	return Ends{
+		Left:  x.Copies(x.Hash(seq, fr.Start)),
+		Right: x.Copies(x.Hash(seq, fr.End-x.ReadLength())),
+	}
+}
+
+// Summary aggregates fragment end uniqueness. MappableLoci is the
+// size-selection weighted count of fragments whose ends are both unique.
+type Summary struct {
+	ReadLength           int     `json:"read_length"`
+	IndexedWindows       int     `json:"indexed_windows"`
+	Fragments            int     `json:"fragments"`
+	UnevaluatedFragments int     `json:"unevaluated_fragments"`
+	UniqueFragments      int     `json:"unique_fragments"`
+	WeightedFragments    float64 `json:"weighted_fragments"`
+	MappableLoci         float64 `json:"mappable_loci"`
+}
+
+// NewSummary returns an empty summary describing x.
+func NewSummary(x *Index) Summary {
+	return Summary{ReadLength: x.ReadLength(), IndexedWindows: x.Windows()}
+}
+
+// Add records one fragment with its size-selection weight.
+func (s *Summary) Add(e Ends, weight float64) {
+	s.Fragments++
+	s.WeightedFragments += weight
+	if !e.Evaluated() {
+		s.UnevaluatedFragments++
+		return
+	}
+	if e.Unique() {
+		s.UniqueFragments++
+		s.MappableLoci += weight
+	}
+}
+
+// CutHashes holds the canonical hashes of the windows on both sides of one
+// cut: Down starts at the cut and Up ends at it. Index builders that discard
+// sequence can record these while indexing and resolve them after Freeze.
+type CutHashes struct {
+	Down   uint64
+	Up     uint64
+	DownOK bool
+	UpOK   bool
+}
+
+// CutHashes returns the window hashes anchored at pos.
+func (x *Index) CutHashes(seq []byte, pos int) CutHashes {
+	var c CutHashes
+	c.Down, c.DownOK = x.Hash(seq, pos)
+	c.Up, c.UpOK = x.Hash(seq, pos-x.ReadLength())
+	return c
+}
+
+// CutEnds holds the copy counts of the windows on both sides of one cut.
+type CutEnds struct {
+	Down int32
+	Up   int32
+}
+
+// Resolve converts cut hashes to copy counts. The index must be frozen.
+func (x *Index) Resolve(c CutHashes) CutEnds {
+	return CutEnds{
+		Down: int32(x.Copies(c.Down, c.DownOK)),
+		Up:   int32(x.Copies(c.Up, c.UpOK)),
+	}
+}
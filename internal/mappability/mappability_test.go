@@ -0,0 +1,51 @@
+package mappability
+
+import (
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/digest"
+)
+
+func TestFragmentCountsRepeatedAndReverseComplementEnds(t *testing.T) {
+	// "ACGTTGCAAG" reverse-complemented is "CTTGCAACGT".
+	seq := []byte("ACGTTGCAAGTCCGATGGCATTACTTGCAACGT")
+	x, err := NewIndex(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.Add(seq)
+	x.Freeze()
+	if x.Windows() != len(seq)-9 {
+		t.Fatalf("indexed %d windows, want %d", x.Windows(), len(seq)-9)
+	}
+
+	ends := x.Fragment(seq, digest.Fragment{Start: 0, End: 20})
+	if ends.Left != 1 || ends.Right != 0 {
+		t.Fatalf("ends = %+v, want left 1 copy (reverse-complement repeat), right unique", ends)
+	}
+	if ends.Unique() || !ends.Evaluated() {
+		t.Fatalf("fragment with a repeated end should be evaluated but not unique: %+v", ends)
+	}
+
+	var s Summary
+	s.Add(ends, 0.5)
+	s.Add(x.Fragment(seq, digest.Fragment{Start: 5, End: 22}), 1)
+	s.Add(Ends{Left: NotEvaluated, Right: 0}, 1)
+	if s.Fragments != 3 || s.UniqueFragments != 1 || s.UnevaluatedFragments != 1 || s.MappableLoci != 1 || s.WeightedFragments != 2.5 {
+		t.Fatalf("summary wrong: %+v", s)
+	}
+}
+
+func TestFragmentEndsWithNAreNotEvaluated(t *testing.T) {
+	seq := []byte("ACGTNACGTACGGT")
+	x, _ := NewIndex(4)
+	x.Add(seq)
+	x.Freeze()
+	ends := x.Fragment(seq, digest.Fragment{Start: 2, End: 14})
+	if ends.Left != NotEvaluated || ends.Right == NotEvaluated {
+		t.Fatalf("ends = %+v, want left not evaluated", ends)
+	}
+	if _, err := NewIndex(0); err == nil {
+		t.Fatal("NewIndex(0) should fail")
+	}
+}
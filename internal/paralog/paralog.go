@@ -0,0 +1,423 @@
+// Package paralog detects predicted fragments that are likely to collapse into
+// one locus after read mapping because their read ends are identical or nearly
+// identical, as happens in repeats and segmental duplications.
+//
+// A fragment is described by the unordered pair of hashes of its two read
+// ends: the first ReadLength bases sequenced from each cut. Each end is hashed
+// in the orientation a read would observe it, so the pair does not depend on
+// which strand a copy lies on. In ModeExact, fragments whose pairs are equal
+// form one cluster. In ModeKmer, each end is a bottom-SketchSize MinHash
+// sketch of its k-mers, and a fragment joins the earliest cluster whose
+// founding fragment has both ends, in either orientation, at an estimated
+// k-mer Jaccard similarity of at least MinSimilarity. A cluster contributes a
+// single effective locus.
+//
+// Coordinates follow the top-strand cut positions, so an inverted copy cut by
+// an enzyme with a staggered overhang is offset by the overhang length and
+// matches only in ModeKmer.
+package paralog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/kmer"
+)
+
+// Mode selects how read ends are compared.
+type Mode string
+
+const (
+	// ModeOff disables duplicate detection.
+	ModeOff Mode = "off"
+	// ModeExact clusters fragments whose read ends are identical.
+	ModeExact Mode = "exact"
+	// ModeKmer clusters fragments whose read ends have similar canonical
+	// k-mer sets, which tolerates scattered mismatches.
+	ModeKmer Mode = "kmer"
+)
+
+// DefaultK is the default k-mer length for ModeKmer.
+const DefaultK = 21
+
+// SketchSize is the number of k-mer hashes kept per read end in ModeKmer.
+const SketchSize = 16
+
+// MinSimilarity is the estimated k-mer Jaccard similarity at which two read
+// ends match in ModeKmer. A mismatch inside a read end replaces up to k of
+// its k-mers, so for 150 bp ends and k = 21 one mismatch leaves a similarity
+// of about 0.72 and two well-separated mismatches about 0.51: the threshold
+// tolerates roughly two mismatches per end. Shorter ends or larger k tolerate
+// fewer. Estimates from SketchSize hashes carry a standard error near 0.12 at
+// the threshold, so pairs close to it may fall either way.
+const MinSimilarity = 0.5
+
+// indexedHashes is the number of smallest hashes of each founding read end
+// indexed to find candidate clusters. A matching end holds most of them, so
+// a fragment at MinSimilarity is seldom missed, while the index stays small.
+const indexedHashes = 4
+
+// ParseMode normalizes and validates a duplicate-detection mode name.
+func ParseMode(value string) (Mode, error) {
+	mode := Mode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case "":
+		return ModeOff, nil
+	case ModeOff, ModeExact, ModeKmer:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid duplicate mode %q; use off, exact, or kmer", value)
+	}
+}
+
+// Hasher computes read-end hashes. The zero value, and any Hasher with Mode
+// ModeOff, is disabled.
+type Hasher struct {
+	Mode       Mode
+	ReadLength int
+	K          int
+}
+
+// Enabled reports whether h computes hashes.
+func (h Hasher) Enabled() bool {
+	return h.Mode == ModeExact || h.Mode == ModeKmer
+}
+
+// Validate checks that the read length and k-mer length are usable for the
+// selected mode.
+func (h Hasher) Validate() error {
+	switch h.Mode {
+	case "", ModeOff:
+		return nil
+	case ModeExact:
+		if h.ReadLength < 1 {
+			return fmt.Errorf("duplicate detection read length must be >= 1 (got %d)", h.ReadLength)
+		}
+	case ModeKmer:
+		if h.ReadLength < 1 {
+			return fmt.Errorf("duplicate detection read length must be >= 1 (got %d)", h.ReadLength)
+		}
+		if h.K < 1 || h.K > kmer.MaxK {
+			return fmt.Errorf("duplicate k-mer length must be in [1,%d] (got %d)", kmer.MaxK, h.K)
+		}
+		if h.K > h.ReadLength {
+			return fmt.Errorf("duplicate k-mer length %d exceeds read length %d", h.K, h.ReadLength)
+		}
+	default:
+		return fmt.Errorf("invalid duplicate mode %q; use off, exact, or kmer", h.Mode)
+	}
+	return nil
+}
+
+// End is the hash of one read end: a single sequence hash in ModeExact, or
+// a bottom-SketchSize k-mer sketch in ModeKmer. A nil End marks a window that
+// runs off the sequence or has nothing to hash: a non-ACGT base in ModeExact,
+// or no A/C/G/T-only k-mer in ModeKmer.
+type End []uint64
+
+// EndHashes holds the two read-end hashes anchored at one cut coordinate.
+// Down is the read sequenced from the cut into the fragment on its right; Up
+// is the read sequenced from the cut into the fragment on its left.
+type EndHashes struct {
+	Down End
+	Up   End
+}
+
+// Down hashes the ReadLength bases starting at pos on the forward strand.
+func (h Hasher) Down(seq []byte, pos int) End {
+	if pos < 0 || pos+h.ReadLength > len(seq) {
+		return nil
+	}
+	window := seq[pos : pos+h.ReadLength]
+	if h.Mode == ModeKmer {
+		sketch, _ := kmer.BottomSketch(window, h.K, SketchSize)
+		return End(sketch)
+	}
+	return single(kmer.Forward(window))
+}
+
+// Up hashes the ReadLength bases ending at pos on the reverse strand.
+func (h Hasher) Up(seq []byte, pos int) End {
+	if pos > len(seq) || pos-h.ReadLength < 0 {
+		return nil
+	}
+	window := seq[pos-h.ReadLength : pos]
+	if h.Mode == ModeKmer {
+		sketch, _ := kmer.BottomSketch(window, h.K, SketchSize)
+		return End(sketch)
+	}
+	return single(kmer.Reverse(window))
+}
+
+// At returns both end hashes anchored at pos.
+func (h Hasher) At(seq []byte, pos int) EndHashes {
+	return EndHashes{Down: h.Down(seq, pos), Up: h.Up(seq, pos)}
+}
+
+// CutEnds returns end hashes for every cut coordinate, in cut order.
+func (h Hasher) CutEnds(seq []byte, cuts []int) []EndHashes {
+	out := make([]EndHashes, len(cuts))
+	for i, pos := range cuts {
+		out[i] = h.At(seq, pos)
+	}
+	return out
+}
+
+func single(v uint64, ok bool) End {
+	if !ok {
+		return nil
+	}
+	return End{v}
+}
+
+// FragmentEnds holds the read ends of one fragment: the left cut's Down hash
+// and the right cut's Up hash.
+type FragmentEnds struct {
+	Left  End
+	Right End
+}
+
+// NewFragmentEnds pairs the left and right read ends of a fragment. It
+// returns false when either end is unhashable.
+func NewFragmentEnds(left, right End) (FragmentEnds, bool) {
+	if left == nil || right == nil {
+		return FragmentEnds{}, false
+	}
+	return FragmentEnds{Left: left, Right: right}, true
+}
+
+// FragmentEnds returns the read ends of fr. Fragments shorter than
+// ReadLength have overlapping reads that run into adapter, so they are
+// reported as unhashable and treated as unique loci.
+func (h Hasher) FragmentEnds(seq []byte, fr digest.Fragment) (FragmentEnds, bool) {
+	if !h.Enabled() || fr.End-fr.Start < h.ReadLength {
+		return FragmentEnds{}, false
+	}
+	return NewFragmentEnds(h.Down(seq, fr.Start), h.Up(seq, fr.End))
+}
+
+// key identifies a ModeExact fragment by its unordered pair of end hashes.
+type key struct {
+	a, b uint64
+}
+
+func exactKey(ends FragmentEnds) key {
+	left, right := ends.Left[0], ends.Right[0]
+	if right < left {
+		left, right = right, left
+	}
+	return key{a: left, b: right}
+}
+
+// similar reports whether two ModeKmer fragments match end to end in either
+// orientation.
+func similar(x, y FragmentEnds) bool {
+	match := func(a, b End) bool {
+		return kmer.Similarity(kmer.Sketch(a), kmer.Sketch(b), SketchSize) >= MinSimilarity
+	}
+	return (match(x.Left, y.Left) && match(x.Right, y.Right)) ||
+		(match(x.Left, y.Right) && match(x.Right, y.Left))
+}
+
+// Member is one fragment in a duplicate cluster.
+type Member struct {
+	Chr    string
+	Start  int
+	End    int
+	Weight float64
+}
+
+type cluster struct {
+	size      int
+	maxWeight float64
+	members   []Member
+	founder   FragmentEnds // ModeKmer only
+}
+
+// Detector accumulates fragment read ends and reports duplicate clusters. A
+// Detector is not safe for concurrent use.
+type Detector struct {
+	hasher      Hasher
+	keepMembers bool
+	exact       map[key]*cluster
+	// founders maps the smallest hashes of each ModeKmer founding end to the
+	// first cluster indexed under them.
+	founders map[uint64]int
+	order    []*cluster
+
+	fragments         int
+	weightedFragments float64
+	unhashed          int
+	unhashedWeight    float64
+}
+
+// NewDetector returns an empty detector. When keepMembers is true, fragment
+// coordinates are retained so WriteTSV can list cluster members.
+func NewDetector(h Hasher, keepMembers bool) *Detector {
+	return &Detector{
+		hasher:      h,
+		keepMembers: keepMembers,
+		exact:       make(map[key]*cluster),
+		founders:    make(map[uint64]int),
+	}
+}
+
+// Add hashes fr from seq and records it with its size-selection weight.
+func (d *Detector) Add(chr string, seq []byte, fr digest.Fragment, weight float64) {
+	ends, ok := d.hasher.FragmentEnds(seq, fr)
+	d.AddEnds(ends, ok, Member{Chr: chr, Start: fr.Start, End: fr.End, Weight: weight})
+}
+
+// AddEnds records a fragment whose read ends were hashed elsewhere, for
+// example from cached cut-site hashes. Fragments with ok == false count as
+// unique loci.
+func (d *Detector) AddEnds(ends FragmentEnds, ok bool, m Member) {
+	d.fragments++
+	d.weightedFragments += m.Weight
+	if !ok {
+		d.unhashed++
+		d.unhashedWeight += m.Weight
+		return
+	}
+	c := d.cluster(ends)
+	c.size++
+	if m.Weight > c.maxWeight {
+		c.maxWeight = m.Weight
+	}
+	if d.keepMembers {
+		c.members = append(c.members, m)
+	}
+}
+
+// cluster returns the cluster ends belongs to, founding a new one when none
+// matches.
+func (d *Detector) cluster(ends FragmentEnds) *cluster {
+	if d.hasher.Mode != ModeKmer {
+		k := exactKey(ends)
+		c := d.exact[k]
+		if c == nil {
+			c = &cluster{}
+			d.exact[k] = c
+			d.order = append(d.order, c)
+		}
+		return c
+	}
+
+	var candidates []int
+	for _, end := range []End{ends.Left, ends.Right} {
+		for _, v := range end {
+			if id, ok := d.founders[v]; ok && !slices.Contains(candidates, id) {
+				candidates = append(candidates, id)
+			}
+		}
+	}
+	slices.Sort(candidates)
+	for _, id := range candidates {
+		if c := d.order[id]; similar(c.founder, ends) {
+			return c
+		}
+	}
+
+	c := &cluster{founder: ends}
+	id := len(d.order)
+	d.order = append(d.order, c)
+	for _, end := range []End{ends.Left, ends.Right} {
+		for _, v := range end[:min(len(end), indexedHashes)] {
+			if _, ok := d.founders[v]; !ok {
+				d.founders[v] = id
+			}
+		}
+	}
+	return c
+}
+
+// Summary reports duplicate-cluster totals. WeightedFragments and
+// EffectiveUniqueLoci use size-selection weights; each cluster contributes its
+// largest member weight once.
+type Summary struct {
+	Mode                Mode    `json:"mode"`
+	ReadLength          int     `json:"read_length"`
+	K                   int     `json:"k,omitempty"`
+	SketchSize          int     `json:"sketch_size,omitempty"`
+	MinSimilarity       float64 `json:"min_similarity,omitempty"`
+	Fragments           int     `json:"fragments"`
+	WeightedFragments   float64 `json:"weighted_fragments"`
+	UnhashedFragments   int     `json:"unhashed_fragments"`
+	Clusters            int     `json:"clusters"`
+	DuplicatedFragments int     `json:"duplicated_fragments"`
+	LargestCluster      int     `json:"largest_cluster"`
+	UniqueLoci          int     `json:"unique_loci"`
+	EffectiveUniqueLoci float64 `json:"effective_unique_loci"`
+	CollapsedWeight     float64 `json:"collapsed_weight"`
+}
+
+// Summary returns the current totals.
+func (d *Detector) Summary() Summary {
+	s := Summary{
+		Mode:              d.hasher.Mode,
+		ReadLength:        d.hasher.ReadLength,
+		Fragments:         d.fragments,
+		WeightedFragments: d.weightedFragments,
+		UnhashedFragments: d.unhashed,
+		UniqueLoci:        d.unhashed + len(d.order),
+	}
+	if d.hasher.Mode == ModeKmer {
+		s.K = d.hasher.K
+		s.SketchSize = SketchSize
+		s.MinSimilarity = MinSimilarity
+	}
+	effective := d.unhashedWeight
+	for _, c := range d.order {
+		effective += c.maxWeight
+		if c.size > 1 {
+			s.Clusters++
+			s.DuplicatedFragments += c.size
+		}
+		if c.size > s.LargestCluster {
+			s.LargestCluster = c.size
+		}
+	}
+	if s.LargestCluster == 0 && d.unhashed > 0 {
+		s.LargestCluster = 1
+	}
+	s.EffectiveUniqueLoci = effective
+	s.CollapsedWeight = d.weightedFragments - effective
+	if s.CollapsedWeight < 0 {
+		s.CollapsedWeight = 0
+	}
+	return s
+}
+
+// TSVHeader is the column layout written by WriteTSV. Coordinates are 0-based
+// half-open.
+var TSVHeader = []string{"cluster_id", "cluster_size", "chr", "start", "end", "length", "size_weight"}
+
+// WriteTSV lists every member of each multi-fragment cluster. Clusters are
+// numbered in first-seen order and members keep their input order. It
+// requires a detector created with keepMembers.
+func (d *Detector) WriteTSV(w io.Writer) error {
+	if !d.keepMembers {
+		return fmt.Errorf("paralog: detector was created without member retention")
+	}
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintln(bw, strings.Join(TSVHeader, "\t")); err != nil {
+		return err
+	}
+	id := 0
+	for _, c := range d.order {
+		if c.size < 2 {
+			continue
+		}
+		id++
+		for _, m := range c.members {
+			if _, err := fmt.Fprintf(bw, "%d\t%d\t%s\t%d\t%d\t%d\t%.6g\n", id, c.size, m.Chr, m.Start, m.End, m.End-m.Start, m.Weight); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
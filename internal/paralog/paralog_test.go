@@ -0,0 +1,134 @@
+package paralog
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/kmer"
+)
+
+const unit = "ACGTTGCAAGCTTCGATCGGATCCATGCAG"
+
+func revcomp(s string) string {
+	pairs := map[byte]byte{'A': 'T', 'C': 'G', 'G': 'C', 'T': 'A'}
+	out := make([]byte, len(s))
+	for i := range s {
+		out[len(s)-1-i] = pairs[s[i]]
+	}
+	return string(out)
+}
+
+func TestExactModeClustersReverseOrientedCopies(t *testing.T) {
+	unique := "TTAGGCATCGAAGTCCGATTACGGATCAAC"
+	seq := []byte(unit + unique + revcomp(unit))
+	d := NewDetector(Hasher{Mode: ModeExact, ReadLength: 10}, true)
+	d.Add("chr1", seq, digest.Fragment{Start: 0, End: 30}, 1)
+	d.Add("chr1", seq, digest.Fragment{Start: 30, End: 60}, 0.5)
+	d.Add("chr1", seq, digest.Fragment{Start: 60, End: 90}, 0.25)
+
+	s := d.Summary()
+	if s.Clusters != 1 || s.DuplicatedFragments != 2 || s.LargestCluster != 2 {
+		t.Fatalf("cluster totals wrong: %+v", s)
+	}
+	if s.UniqueLoci != 2 {
+		t.Fatalf("unique loci = %d, want 2", s.UniqueLoci)
+	}
+	if math.Abs(s.EffectiveUniqueLoci-1.5) > 1e-12 || math.Abs(s.CollapsedWeight-0.25) > 1e-12 {
+		t.Fatalf("effective loci = %g collapsed = %g, want 1.5 and 0.25", s.EffectiveUniqueLoci, s.CollapsedWeight)
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteTSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[1] != "1\t2\tchr1\t0\t30\t30\t1" || lines[2] != "1\t2\tchr1\t60\t90\t30\t0.25" {
+		t.Fatalf("unexpected TSV:\n%s", buf.String())
+	}
+}
+
+func TestShortAndAmbiguousFragmentsCountAsUnique(t *testing.T) {
+	seq := []byte(unit + "NNNNNNNNNN" + unit)
+	d := NewDetector(Hasher{Mode: ModeExact, ReadLength: 20}, false)
+	d.Add("chr1", seq, digest.Fragment{Start: 0, End: 15}, 1)
+	d.Add("chr1", seq, digest.Fragment{Start: 10, End: 40}, 1)
+
+	s := d.Summary()
+	if s.UnhashedFragments != 2 || s.Clusters != 0 || s.EffectiveUniqueLoci != 2 {
+		t.Fatalf("unhashed fragments should be unique loci: %+v", s)
+	}
+	if err := d.WriteTSV(&bytes.Buffer{}); err == nil {
+		t.Fatal("WriteTSV should fail without member retention")
+	}
+}
+
+// pseudoRandom returns n bases from a fixed linear congruential generator.
+func pseudoRandom(n int, seed uint32) string {
+	out := make([]byte, n)
+	for i := range out {
+		seed = seed*1664525 + 1013904223
+		out[i] = "ACGT"[seed>>30]
+	}
+	return string(out)
+}
+
+func mutate(s string, positions ...int) string {
+	out := []byte(s)
+	for _, i := range positions {
+		out[i] = "CGTA"[strings.IndexByte("ACGT", out[i])]
+	}
+	return string(out)
+}
+
+func TestKmerModeClustersEndsAboveMinSimilarity(t *testing.T) {
+	h := Hasher{Mode: ModeKmer, ReadLength: 60, K: 11}
+	left, right := pseudoRandom(60, 1), pseudoRandom(60, 2)
+	copies := []string{
+		left + right,
+		// One mismatch per end leaves 49 of 61 k-mers shared.
+		mutate(left, 30) + mutate(right, 20),
+		// The same copy on the other strand.
+		revcomp(mutate(left, 30) + mutate(right, 20)),
+		// A mismatch every 10 bases leaves no 11-mer shared.
+		mutate(left, 5, 15, 25, 35, 45, 55) + right,
+	}
+	d := NewDetector(h, true)
+	for i, seq := range copies {
+		d.Add("chr1", []byte(seq), digest.Fragment{Start: 0, End: len(seq)}, float64(i+1))
+	}
+	s := d.Summary()
+	if s.Clusters != 1 || s.DuplicatedFragments != 3 || s.UniqueLoci != 2 || s.SketchSize != SketchSize || s.MinSimilarity != MinSimilarity {
+		t.Fatalf("kmer clusters wrong: %+v", s)
+	}
+
+	one, _ := kmer.BottomSketch([]byte(left), 11, SketchSize)
+	two, _ := kmer.BottomSketch([]byte(mutate(left, 30)), 11, SketchSize)
+	if sim := kmer.Similarity(one, two, SketchSize); sim < MinSimilarity {
+		t.Fatalf("one mismatch gave similarity %g below %g", sim, MinSimilarity)
+	}
+	if exact := (Hasher{Mode: ModeExact, ReadLength: 30}); exact.Down([]byte(unit), 0)[0] == exact.Down([]byte("C"+unit[1:]), 0)[0] {
+		t.Fatal("exact mode should distinguish a single mismatch")
+	}
+}
+
+func TestHasherValidate(t *testing.T) {
+	for _, h := range []Hasher{
+		{Mode: ModeExact},
+		{Mode: ModeKmer, ReadLength: 10, K: 11},
+		{Mode: ModeKmer, ReadLength: 100, K: 40},
+		{Mode: "fuzzy", ReadLength: 100},
+	} {
+		if err := h.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) should fail", h)
+		}
+	}
+	if err := (Hasher{}).Validate(); err != nil {
+		t.Fatalf("zero hasher should be valid: %v", err)
+	}
+	if _, err := ParseMode("KMER"); err != nil {
+		t.Fatalf("ParseMode should be case-insensitive: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package pcrbias
+
+import "math/bits"
+
+// GCIndex answers the G+C fraction of any span of a sequence after the
+// sequence is discarded. It keeps one G/C bit and one A/C/G/T bit per base
+// plus prefix counts at every 64-base word, about 3/8 byte per base.
+type GCIndex struct {
+	Length    int
+	strong    []uint64
+	acgt      []uint64
+	strongCum []uint32
+	acgtCum   []uint32
+}
+
+// NewGCIndex builds the index for seq.
+func NewGCIndex(seq []byte) GCIndex {
+	words := (len(seq) + 63) / 64
+	idx := GCIndex{
+		Length:    len(seq),
+		strong:    make([]uint64, words),
+		acgt:      make([]uint64, words),
+		strongCum: make([]uint32, words+1),
+		acgtCum:   make([]uint32, words+1),
+	}
+	for i, b := range seq {
+		bit := uint64(1) << (i % 64)
+		switch b {
+		case 'G', 'C', 'g', 'c':
+			idx.strong[i/64] |= bit
+			idx.acgt[i/64] |= bit
+		case 'A', 'T', 'a', 't':
+			idx.acgt[i/64] |= bit
+		}
+	}
+	for w := 0; w < words; w++ {
+		idx.strongCum[w+1] = idx.strongCum[w] + uint32(bits.OnesCount64(idx.strong[w]))
+		idx.acgtCum[w+1] = idx.acgtCum[w] + uint32(bits.OnesCount64(idx.acgt[w]))
+	}
+	return idx
+}
+
+// Bytes estimates the memory held by the index.
+func (x GCIndex) Bytes() int64 {
+	return int64(8*(len(x.strong)+len(x.acgt)) + 4*(len(x.strongCum)+len(x.acgtCum)))
+}
+
+// GC returns the G+C fraction among the A/C/G/T bases of [start, end); ok is
+// false when there are none.
+func (x GCIndex) GC(start, end int) (gc float64, ok bool) {
+	start, end = min(max(start, 0), x.Length), min(max(end, 0), x.Length)
+	if end <= start {
+		return 0, false
+	}
+	acgt := count(x.acgt, x.acgtCum, end) - count(x.acgt, x.acgtCum, start)
+	if acgt == 0 {
+		return 0, false
+	}
+	strong := count(x.strong, x.strongCum, end) - count(x.strong, x.strongCum, start)
+	return float64(strong) / float64(acgt), true
+}
+
+// count returns the set bits before pos.
+func count(words []uint64, cum []uint32, pos int) int {
+	w, r := pos/64, pos%64
+	n := int(cum[w])
+	if r > 0 {
+		n += bits.OnesCount64(words[w] & (uint64(1)<<r - 1))
+	}
+	return n
+}
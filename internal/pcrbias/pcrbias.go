@@ -0,0 +1,101 @@
+// Package pcrbias models PCR amplification bias. The per-cycle efficiency of
+// a fragment falls with its length and with GC content away from an
+// optimum, and the shortfall compounds over the cycles of library PCR:
+//
+//	e(L, gc) = Efficiency · exp(-L/LengthScale) · exp(-½((gc-GCOptimum)/GCWidth)²)
+//	weight   = ((1+e) / (1+Efficiency))^Cycles
+//
+// so a short fragment at the optimum GC has weight near 1 and every other
+// fragment is amplified relatively less.
+package pcrbias
+
+import (
+	"fmt"
+	"math"
+)
+
+// Default parameters for a typical ddRAD library PCR.
+const (
+	DefaultEfficiency  = 0.95
+	DefaultLengthScale = 5000
+	DefaultGCOptimum   = 0.5
+	DefaultGCWidth     = 0.25
+)
+
+// Model holds the amplification parameters. A Model with zero Cycles is
+// disabled.
+type Model struct {
+	Cycles      int     `json:"cycles"`
+	Efficiency  float64 `json:"efficiency"`
+	LengthScale float64 `json:"length_scale"`
+	GCOptimum   float64 `json:"gc_optimum"`
+	GCWidth     float64 `json:"gc_width"`
+}
+
+// Enabled reports whether amplification weighting is on.
+func (m Model) Enabled() bool {
+	return m.Cycles > 0
+}
+
+// Validate checks the parameters of an enabled model.
+func (m Model) Validate() error {
+	if m.Cycles < 0 {
+		return fmt.Errorf("PCR cycles must be >= 0 (got %d)", m.Cycles)
+	}
+	if !m.Enabled() {
+		return nil
+	}
+	if !(m.Efficiency > 0 && m.Efficiency <= 1) {
+		return fmt.Errorf("PCR efficiency must be in (0,1] (got %g)", m.Efficiency)
+	}
+	if !(m.LengthScale > 0) || math.IsInf(m.LengthScale, 0) {
+		return fmt.Errorf("PCR length scale must be a finite value > 0 (got %g)", m.LengthScale)
+	}
+	if !(m.GCOptimum >= 0 && m.GCOptimum <= 1) {
+		return fmt.Errorf("PCR GC optimum must be in [0,1] (got %g)", m.GCOptimum)
+	}
+	if !(m.GCWidth > 0) || math.IsInf(m.GCWidth, 0) {
+		return fmt.Errorf("PCR GC width must be a finite value > 0 (got %g)", m.GCWidth)
+	}
+	return nil
+}
+
+// Weight returns the relative amplification of a fragment of length bp with
+// G+C fraction gc.
+func (m Model) Weight(length int, gc float64) float64 {
+	if !m.Enabled() {
+		return 1
+	}
+	z := (gc - m.GCOptimum) / m.GCWidth
+	e := m.Efficiency * math.Exp(-float64(length)/m.LengthScale) * math.Exp(-z*z/2)
+	return math.Pow((1+e)/(1+m.Efficiency), float64(m.Cycles))
+}
+
+// SeqWeight returns Weight for the fragment sequence seq. Fragments with no
+// A/C/G/T bases are taken to be at the GC optimum.
+func (m Model) SeqWeight(seq []byte) float64 {
+	gc, ok := GC(seq)
+	if !ok {
+		gc = m.GCOptimum
+	}
+	return m.Weight(len(seq), gc)
+}
+
+// GC returns the G+C fraction among the A/C/G/T bases of seq; ok is false
+// when there are none.
+func GC(seq []byte) (gc float64, ok bool) {
+	strong, acgt := 0, 0
+	for _, b := range seq {
+		switch b {
+		case 'G', 'C', 'g', 'c':
+			strong++
+			acgt++
+		case 'A', 'T', 'a', 't':
+			acgt++
+		}
+	}
+	if acgt == 0 {
+		return 0, false
+	}
+	return float64(strong) / float64(acgt), true
+}
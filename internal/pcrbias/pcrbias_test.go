@@ -0,0 +1,67 @@
+package pcrbias
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func testModel() Model {
+	return Model{Cycles: 12, Efficiency: DefaultEfficiency, LengthScale: DefaultLengthScale, GCOptimum: DefaultGCOptimum, GCWidth: DefaultGCWidth}
+}
+
+func TestWeightFallsWithLengthAndGCExtremes(t *testing.T) {
+	m := testModel()
+	if w := m.Weight(0, 0.5); math.Abs(w-1) > 1e-12 {
+		t.Fatalf("Weight(0, optimum) = %g, want 1", w)
+	}
+	short, long := m.Weight(200, 0.5), m.Weight(800, 0.5)
+	if !(short < 1 && long < short) {
+		t.Fatalf("weights should fall with length: 200 bp %g, 800 bp %g", short, long)
+	}
+	if lo, hi := m.Weight(300, 0.25), m.Weight(300, 0.75); math.Abs(lo-hi) > 1e-12 || lo >= m.Weight(300, 0.5) {
+		t.Fatalf("GC extremes should be symmetric and below the optimum: %g %g", lo, hi)
+	}
+	if w := (Model{}).Weight(800, 0.9); w != 1 {
+		t.Fatalf("disabled model weight = %g, want 1", w)
+	}
+	if w, want := m.SeqWeight([]byte("NNNN")), m.Weight(4, 0.5); w != want {
+		t.Fatalf("all-N fragment weight = %g, want the GC-optimum %g", w, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := []Model{
+		{Cycles: -1},
+		{Cycles: 10, Efficiency: 1.5, LengthScale: 1, GCOptimum: 0.5, GCWidth: 0.2},
+		{Cycles: 10, Efficiency: 0.9, LengthScale: 0, GCOptimum: 0.5, GCWidth: 0.2},
+		{Cycles: 10, Efficiency: 0.9, LengthScale: 1, GCOptimum: 1.5, GCWidth: 0.2},
+		{Cycles: 10, Efficiency: 0.9, LengthScale: 1, GCOptimum: 0.5, GCWidth: 0},
+	}
+	for _, m := range bad {
+		if err := m.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) returned nil error", m)
+		}
+	}
+	if err := testModel().Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGCIndexMatchesDirectCount(t *testing.T) {
+	seq := []byte(strings.Repeat("AT", 40) + strings.Repeat("GGCN", 25) + "acgtac")
+	idx := NewGCIndex(seq)
+	for _, span := range [][2]int{{0, len(seq)}, {0, 64}, {3, 90}, {63, 65}, {80, 181}, {150, 186}} {
+		want, _ := GC(seq[span[0]:span[1]])
+		got, ok := idx.GC(span[0], span[1])
+		if !ok || math.Abs(got-want) > 1e-12 {
+			t.Fatalf("GC%v = %g, want %g", span, got, want)
+		}
+	}
+	if _, ok := idx.GC(83, 84); ok {
+		t.Fatal("a span of N reported a GC fraction")
+	}
+	if _, ok := (GCIndex{}).GC(0, 10); ok {
+		t.Fatal("empty index reported a GC fraction")
+	}
+}
@@ -0,0 +1,261 @@
+// Package readsim simulates RAD-seq reads from predicted digest fragments.
+//
+// Each score-range fragment is sampled a Poisson number of times with mean
+// Depth × size weight. A simulated insert runs between the outer edges of the
+// two sticky ends, so read 1 starts with the cut-site remnant left by the
+// enzyme at its end and read 2 starts with the remnant of the opposite enzyme,
+// as after adapter ligation and fill-in. In a double digest read 1 always
+// comes from the first (A) enzyme's end, matching barcoded-adapter designs;
+// otherwise the orientation is drawn at random. Inserts shorter than the read
+// length run through into the adapter.
+//
+// With a library model each read is assigned a random sample: read 1 starts
+// with its barcode 1, read 2 with its barcode 2, and read-through continues
+// into the opposite barcode and adapter instead of Adapter1/Adapter2.
+package readsim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/enzyme"
+	"github.com/ericksamera/radigest/internal/library"
+)
+
+// Default adapters are the Illumina TruSeq read-through sequences.
+const (
+	DefaultAdapter1 = "AGATCGGAAGAGCACACGTCTGAACTCCAGTCA"
+	DefaultAdapter2 = "AGATCGGAAGAGCGTCGTGTAGGGAAAGAGTGT"
+)
+
+// maxQuality caps the Phred score written for error-free simulations.
+const maxQuality = 40
+
+// Config controls read simulation.
+type Config struct {
+	ReadLength int
+	Paired     bool
+	// Depth is the mean number of reads (pairs when Paired) drawn from a
+	// fragment with size weight 1.
+	Depth     float64
+	ErrorRate float64
+	Adapter1  string
+	Adapter2  string
+	// Library, when set, adds per-sample inline barcodes and adapters.
+	Library *library.Model
+}
+
+// Validate checks that the configuration can simulate reads.
+func (c Config) Validate() error {
+	if c.ReadLength < 1 {
+		return fmt.Errorf("read length must be >= 1 (got %d)", c.ReadLength)
+	}
+	if math.IsNaN(c.Depth) || math.IsInf(c.Depth, 0) || c.Depth <= 0 {
+		return fmt.Errorf("read depth must be finite and > 0 (got %g)", c.Depth)
+	}
+	if math.IsNaN(c.ErrorRate) || c.ErrorRate < 0 || c.ErrorRate > 0.75 {
+		return fmt.Errorf("read error rate must be in [0,0.75] (got %g)", c.ErrorRate)
+	}
+	for _, adapter := range []string{c.Adapter1, c.Adapter2} {
+		if strings.Trim(strings.ToUpper(adapter), "ACGTN") != "" {
+			return fmt.Errorf("adapter %q must contain only A/C/G/T/N", adapter)
+		}
+	}
+	if c.Library != nil {
+		if err := c.Library.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sink receives simulated reads. *fastq.Writer satisfies Sink.
+type Sink interface {
+	Write(name string, seq, qual []byte) error
+}
+
+// Stats summarizes a simulation.
+type Stats struct {
+	Layout           string  `json:"layout"`
+	ReadLength       int     `json:"read_length"`
+	Depth            float64 `json:"depth"`
+	ErrorRate        float64 `json:"error_rate"`
+	Seed             int64   `json:"seed"`
+	FragmentsSampled int     `json:"fragments_sampled"`
+	Reads            int     `json:"reads"`
+	ReadThrough      int     `json:"read_through"`
+	Substitutions    int     `json:"substitutions"`
+}
+
+// Simulator draws reads from fragments. It is not safe for concurrent use;
+// feed fragments in a fixed order for reproducible output.
+type Simulator struct {
+	cfg      Config
+	ends     library.StickyEnds
+	double   bool
+	rng      *rand.Rand
+	r1, r2   Sink
+	adapter1 []byte
+	adapter2 []byte
+	qual     []byte
+	stats    Stats
+}
+
+// New returns a simulator for fragments cut by enzymes, the same one or two
+// enzymes used to build the digest plan. r2 is used only when cfg.Paired.
+func New(cfg Config, enzymes []enzyme.Enzyme, seed int64, r1, r2 Sink) (*Simulator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if len(enzymes) == 0 || len(enzymes) > 2 {
+		return nil, fmt.Errorf("read simulation needs one or two enzymes (got %d)", len(enzymes))
+	}
+	if r1 == nil || (cfg.Paired && r2 == nil) {
+		return nil, fmt.Errorf("read simulation output is nil")
+	}
+	s := &Simulator{
+		cfg:      cfg,
+		double:   len(enzymes) == 2,
+		rng:      rand.New(rand.NewSource(seed)),
+		r1:       r1,
+		r2:       r2,
+		adapter1: []byte(strings.ToUpper(cfg.Adapter1)),
+		adapter2: []byte(strings.ToUpper(cfg.Adapter2)),
+		qual:     make([]byte, cfg.ReadLength),
+	}
+	ends, err := library.NewStickyEnds(enzymes)
+	if err != nil {
+		return nil, err
+	}
+	s.ends = ends
+	q := byte(maxQuality)
+	if cfg.ErrorRate > 0 {
+		q = byte(math.Max(2, math.Min(maxQuality, math.Round(-10*math.Log10(cfg.ErrorRate)))))
+	}
+	for i := range s.qual {
+		s.qual[i] = q + 33
+	}
+	s.stats = Stats{Layout: "se", ReadLength: cfg.ReadLength, Depth: cfg.Depth, ErrorRate: cfg.ErrorRate, Seed: seed}
+	if cfg.Paired {
+		s.stats.Layout = "pe"
+	}
+	return s, nil
+}
+
+// Stats returns the totals so far.
+func (s *Simulator) Stats() Stats {
+	return s.stats
+}
+
+// Fragment draws reads from fr, a fragment of seq with the given size weight.
+func (s *Simulator) Fragment(chr string, seq []byte, fr digest.Fragment, weight float64) error {
+	n := poisson(s.rng, s.cfg.Depth*weight)
+	if n == 0 {
+		return nil
+	}
+	if fr.Start < 0 || fr.End < fr.Start || fr.End > len(seq) {
+		return fmt.Errorf("read simulation: invalid fragment %s:%d-%d (sequence length %d)", chr, fr.Start, fr.End, len(seq))
+	}
+	ins := s.ends.Insert(seq, fr)
+	insert := seq[ins.Start:ins.End]
+	forward := insert
+	reverse := library.ReverseComplement(insert)
+
+	s.stats.FragmentsSampled++
+	for i := 0; i < n; i++ {
+		flip := s.rng.Intn(2) == 1
+		if s.double && ins.Left >= 0 && ins.Right >= 0 && ins.Left != ins.Right {
+			flip = ins.Left == 1
+		}
+		first, second := forward, reverse
+		if flip {
+			first, second = reverse, forward
+		}
+		var barcode1, barcode2 []byte
+		adapter1, adapter2 := s.adapter1, s.adapter2
+		sample := ""
+		if lib := s.cfg.Library; lib != nil {
+			k := s.rng.Intn(len(lib.Samples))
+			sample = " sample=" + lib.Samples[k].Name
+			barcode1, barcode2 = []byte(lib.Samples[k].Barcode1), []byte(lib.Samples[k].Barcode2)
+			adapter1, adapter2 = lib.ReadThrough(k)
+		}
+		s.stats.Reads++
+		if len(barcode1)+len(insert) < s.cfg.ReadLength {
+			s.stats.ReadThrough++
+		}
+		name := fmt.Sprintf("%s:%d-%d_%d insert=%d strand=%s%s", chr, fr.Start, fr.End, i+1, len(insert), strandLabel(flip), sample)
+		if err := s.r1.Write(name, s.read(barcode1, first, adapter1), s.qual); err != nil {
+			return err
+		}
+		if s.cfg.Paired {
+			if err := s.r2.Write(name, s.read(barcode2, second, adapter2), s.qual); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// read takes the first ReadLength bases of barcode + template, continues into
+// adapter and then poly-A when template is short, and applies substitution
+// errors.
+func (s *Simulator) read(barcode, template, adapter []byte) []byte {
+	out := make([]byte, s.cfg.ReadLength)
+	n := copy(out, barcode)
+	n += copy(out[n:], template)
+	n += copy(out[n:], adapter)
+	for i := n; i < len(out); i++ {
+		out[i] = 'A'
+	}
+	if s.cfg.ErrorRate > 0 {
+		for i, b := range out {
+			if s.rng.Float64() >= s.cfg.ErrorRate {
+				continue
+			}
+			out[i] = substitute(s.rng, b)
+			s.stats.Substitutions++
+		}
+	}
+	return out
+}
+
+func substitute(rng *rand.Rand, b byte) byte {
+	const bases = "ACGT"
+	i := strings.IndexByte(bases, b)
+	if i < 0 {
+		return bases[rng.Intn(4)]
+	}
+	return bases[(i+1+rng.Intn(3))%4]
+}
+
+func strandLabel(flip bool) string {
+	if flip {
+		return "-"
+	}
+	return "+"
+}
+
+// poisson draws a Poisson variate, using Knuth's method for small means and a
+// rounded normal approximation for large ones.
+func poisson(rng *rand.Rand, lambda float64) int {
+	if lambda <= 0 {
+		return 0
+	}
+	if lambda >= 30 {
+		x := math.Round(lambda + math.Sqrt(lambda)*rng.NormFloat64())
+		if x < 0 {
+			return 0
+		}
+		return int(x)
+	}
+	limit := math.Exp(-lambda)
+	k := 0
+	for p := rng.Float64(); p > limit; p *= rng.Float64() {
+		k++
+	}
+	return k
+}
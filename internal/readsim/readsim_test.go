@@ -0,0 +1,180 @@
+package readsim
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/enzyme"
+)
+
+type record struct {
+	name string
+	seq  string
+	qual string
+}
+
+type memorySink struct{ records []record }
+
+func (m *memorySink) Write(name string, seq, qual []byte) error {
+	m.records = append(m.records, record{name: name, seq: string(seq), qual: string(qual)})
+	return nil
+}
+
+func testEnzymes(t *testing.T, names ...string) []enzyme.Enzyme {
+	t.Helper()
+	out := make([]enzyme.Enzyme, 0, len(names))
+	for _, name := range names {
+		e, ok := enzyme.Get(name)
+		if !ok {
+			t.Fatalf("missing enzyme %s", name)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func digestOne(t *testing.T, seq []byte, ens []enzyme.Enzyme) []digest.Fragment {
+	t.Helper()
+	plan, err := digest.TryNewPlanWithOptions(ens, digest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan.Digest(seq, 1, len(seq))
+}
+
+func TestPairedReadsStartWithCutSiteRemnants(t *testing.T) {
+	// MseI (T^TAA) on the left, EcoRI (G^AATTC) on the right: a BA fragment,
+	// so read 1 must come from the EcoRI end.
+	seq := []byte("CCCTTAAGCATGCCAGTCAGGATCCAGTACGATCGGAATTCCCC")
+	ens := testEnzymes(t, "EcoRI", "MseI")
+	frags := digestOne(t, seq, ens)
+	if len(frags) != 1 {
+		t.Fatalf("expected one fragment, got %v", frags)
+	}
+
+	var r1, r2 memorySink
+	sim, err := New(Config{ReadLength: 20, Paired: true, Depth: 5, Adapter1: DefaultAdapter1, Adapter2: DefaultAdapter2}, ens, 7, &r1, &r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.Fragment("chr1", seq, frags[0], 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(r1.records) == 0 || len(r1.records) != len(r2.records) {
+		t.Fatalf("expected matching non-empty read pairs, got %d and %d", len(r1.records), len(r2.records))
+	}
+	for i := range r1.records {
+		if !strings.HasPrefix(r1.records[i].seq, "AATTC") {
+			t.Fatalf("read 1 should start with the EcoRI remnant: %q", r1.records[i].seq)
+		}
+		if !strings.HasPrefix(r2.records[i].seq, "TAAGC") {
+			t.Fatalf("read 2 should start with the MseI remnant: %q", r2.records[i].seq)
+		}
+		if r1.records[i].name != r2.records[i].name {
+			t.Fatalf("mate names differ: %q vs %q", r1.records[i].name, r2.records[i].name)
+		}
+		if r1.records[i].qual != strings.Repeat("I", 20) {
+			t.Fatalf("error-free reads should carry Q40: %q", r1.records[i].qual)
+		}
+	}
+	if got := sim.Stats(); got.Reads != len(r1.records) || got.FragmentsSampled != 1 || got.ReadThrough != 0 || got.Layout != "pe" {
+		t.Fatalf("unexpected stats: %+v", got)
+	}
+}
+
+func TestShortInsertReadsRunIntoAdapter(t *testing.T) {
+	seq := []byte("CCCCGAATTCAGGTTAACCCC")
+	ens := testEnzymes(t, "EcoRI", "MseI")
+	frags := digestOne(t, seq, ens)
+	if len(frags) != 1 {
+		t.Fatalf("expected one fragment, got %v", frags)
+	}
+
+	var r1 memorySink
+	sim, err := New(Config{ReadLength: 30, Depth: 3, Adapter1: DefaultAdapter1, Adapter2: DefaultAdapter2}, ens, 1, &r1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.Fragment("chr1", seq, frags[0], 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(r1.records) == 0 {
+		t.Fatal("expected reads")
+	}
+	// Insert spans the EcoRI outer edge through the MseI inner edge: AATTCAGGTTA.
+	want := "AATTCAGGTTA" + DefaultAdapter1[:19]
+	for _, rec := range r1.records {
+		if rec.seq != want {
+			t.Fatalf("read = %q, want %q", rec.seq, want)
+		}
+	}
+	if got := sim.Stats(); got.ReadThrough != got.Reads {
+		t.Fatalf("every read should be read-through: %+v", got)
+	}
+}
+
+func TestErrorRateAndSeedAreReproducible(t *testing.T) {
+	seq := []byte("CCCTTAAGCATGCCAGTCAGGATCCAGTACGATCGGAATTCCCC")
+	ens := testEnzymes(t, "EcoRI", "MseI")
+	frags := digestOne(t, seq, ens)
+	cfg := Config{ReadLength: 30, Depth: 400, ErrorRate: 0.05}
+
+	runOnce := func(seed int64) ([]record, Stats) {
+		var r1 memorySink
+		sim, err := New(cfg, ens, seed, &r1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sim.Fragment("chr1", seq, frags[0], 1); err != nil {
+			t.Fatal(err)
+		}
+		return r1.records, sim.Stats()
+	}
+	a, stats := runOnce(42)
+	b, _ := runOnce(42)
+	if len(a) != len(b) {
+		t.Fatalf("same seed gave %d and %d reads", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("same seed diverged at read %d", i)
+		}
+	}
+	rate := float64(stats.Substitutions) / float64(stats.Reads*cfg.ReadLength)
+	if math.Abs(rate-cfg.ErrorRate) > 0.01 {
+		t.Fatalf("observed substitution rate %g, want about %g", rate, cfg.ErrorRate)
+	}
+	if a[0].qual[0] != 13+33 {
+		t.Fatalf("quality for 5%% error should be Q13, got %q", a[0].qual[0])
+	}
+}
+
+func TestPoissonMean(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for _, lambda := range []float64{0.5, 8, 60} {
+		total := 0
+		const draws = 20000
+		for i := 0; i < draws; i++ {
+			total += poisson(rng, lambda)
+		}
+		if mean := float64(total) / draws; math.Abs(mean-lambda) > 0.05*lambda+0.02 {
+			t.Fatalf("poisson(%g) mean = %g", lambda, mean)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, cfg := range []Config{
+		{ReadLength: 0, Depth: 1},
+		{ReadLength: 10, Depth: 0},
+		{ReadLength: 10, Depth: 1, ErrorRate: 0.9},
+		{ReadLength: 10, Depth: 1, Adapter1: "ACGU"},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) should fail", cfg)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package screen
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DepthWeighting selects the relative read share of each scored fragment
+// recorded in PairSummary.DepthWeights.
+type DepthWeighting string
+
+const (
+	// DepthWeightsUniform gives every scored fragment the same share.
+	DepthWeightsUniform DepthWeighting = "uniform"
+	// DepthWeightsSize scales the share by the fragment's size weight.
+	DepthWeightsSize DepthWeighting = "size"
+	// DepthWeightsPCR scales the share by the PCR amplification weight.
+	DepthWeightsPCR DepthWeighting = "pcr"
+	// DepthWeightsSizePCR scales the share by both.
+	DepthWeightsSizePCR DepthWeighting = "size-pcr"
+)
+
+// ParseDepthWeighting validates a depth weighting name; empty means uniform.
+func ParseDepthWeighting(value string) (DepthWeighting, error) {
+	w := DepthWeighting(strings.ToLower(strings.TrimSpace(value)))
+	switch w {
+	case "":
+		return DepthWeightsUniform, nil
+	case DepthWeightsUniform, DepthWeightsSize, DepthWeightsPCR, DepthWeightsSizePCR:
+		return w, nil
+	default:
+		return "", fmt.Errorf("invalid depth weighting %q; use uniform, size, pcr, or size-pcr", value)
+	}
+}
+
+// UsesPCR reports whether w needs PCR amplification weights.
+func (w DepthWeighting) UsesPCR() bool {
+	return w == DepthWeightsPCR || w == DepthWeightsSizePCR
+}
+
+// DepthWeightBin holds the scored fragments whose relative read shares fall
+// in one bin. Loci is their total size weight and RelativeWeight their
+// size-weighted mean share.
+type DepthWeightBin struct {
+	RelativeWeight float64 `json:"relative_weight"`
+	Loci           float64 `json:"loci"`
+}
+
+// DepthWeightSummary is the distribution of relative read shares over the
+// size-weighted scored fragments, in increasing share order. Bins are
+// 1/depthWeightBinsPerDoubling of a doubling wide.
+type DepthWeightSummary struct {
+	Weighting DepthWeighting   `json:"weighting"`
+	Bins      []DepthWeightBin `json:"bins"`
+}
+
+// MeanRelativeWeight returns the size-weighted mean share, or 0 when there
+// are no loci.
+func (s DepthWeightSummary) MeanRelativeWeight() float64 {
+	loci, total := 0.0, 0.0
+	for _, b := range s.Bins {
+		loci += b.Loci
+		total += b.Loci * b.RelativeWeight
+	}
+	if loci <= 0 {
+		return 0
+	}
+	return total / loci
+}
+
+const depthWeightBinsPerDoubling = 16
+
+// depthWeights accumulates a DepthWeightSummary.
+type depthWeights struct {
+	weighting DepthWeighting
+	bins      map[int][2]float64
+}
+
+func newDepthWeights(weighting DepthWeighting) *depthWeights {
+	return &depthWeights{weighting: weighting, bins: make(map[int][2]float64)}
+}
+
+// share returns the relative read share of a fragment with size weight
+// weight and amplification weight amp.
+func (d *depthWeights) share(weight, amp float64) float64 {
+	switch d.weighting {
+	case DepthWeightsSize:
+		return weight
+	case DepthWeightsPCR:
+		return amp
+	case DepthWeightsSizePCR:
+		return weight * amp
+	default:
+		return 1
+	}
+}
+
+func (d *depthWeights) add(weight, share float64) {
+	if !(weight > 0) || !(share > 0) {
+		return
+	}
+	key := int(math.Round(depthWeightBinsPerDoubling * math.Log2(share)))
+	b := d.bins[key]
+	b[0] += weight
+	b[1] += weight * share
+	d.bins[key] = b
+}
+
+func (d *depthWeights) summary() *DepthWeightSummary {
+	keys := make([]int, 0, len(d.bins))
+	for k := range d.bins {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	s := &DepthWeightSummary{Weighting: d.weighting, Bins: make([]DepthWeightBin, 0, len(keys))}
+	for _, k := range keys {
+		b := d.bins[k]
+		s.Bins = append(s.Bins, DepthWeightBin{RelativeWeight: b[1] / b[0], Loci: b[0]})
+	}
+	return s
+}
@@ -13,29 +13,124 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"runtime"
+	"sort"
 	"strconv"
 	"sync"
 
 	"github.com/ericksamera/radigest/internal/digest"
 	"github.com/ericksamera/radigest/internal/enzyme"
 	"github.com/ericksamera/radigest/internal/fasta"
+	"github.com/ericksamera/radigest/internal/mappability"
+	"github.com/ericksamera/radigest/internal/paralog"
+	"github.com/ericksamera/radigest/internal/pcrbias"
 	"github.com/ericksamera/radigest/internal/sizeselect"
 )
 
 const EngineCachedCutIndex = "cached-cut-index"
 
 // RecordCuts stores cached cut coordinates for one FASTA record.
+//
+// Ends and Bounds are populated only when the index was built with read-end
+// hashing enabled. Ends[name][i] holds the hashes anchored at Cuts[name][i];
+// Bounds holds the Down hash at the record start and the Up hash at the record
+// end so terminal fragments can be keyed too.
+//
+// Copies and CopyBounds follow the same layout for read-end mappability and
+// are populated only when the index was built with a mappability read length.
+// GC holds GC prefix sums when the index was built with BuildOptions.GC.
 type RecordCuts struct {
-	ID     string
-	Length int
-	Cuts   map[string][]int
+	ID         string
+	Length     int
+	Cuts       map[string][]int
+	Ends       map[string][]paralog.EndHashes
+	Bounds     paralog.EndHashes
+	Copies     map[string][]mappability.CutEnds
+	CopyBounds mappability.CutEnds
+	GC         pcrbias.GCIndex
 }
 
-// CutIndex stores per-record, per-enzyme sorted cut coordinates.
+// CutIndex stores per-record, per-enzyme sorted cut coordinates. EndHasher
+// records the read-end hashing settings used at build time; it is disabled
+// when no end hashes were stored.
+//
+// MappabilityReadLength and IndexedWindows describe the reference window index
+// used to fill RecordCuts.Copies; both are zero when mappability is disabled.
+// GC is set when RecordCuts.GC holds GC prefix sums.
 type CutIndex struct {
-	Records     []RecordCuts
-	EnzymeNames []string
+	Records               []RecordCuts
+	EnzymeNames           []string
+	EndHasher             paralog.Hasher
+	MappabilityReadLength int
+	IndexedWindows        int
+	GC                    bool
+}
+
+// BuildOptions configures optional data captured while building a CutIndex.
+type BuildOptions struct {
+	// Workers bounds concurrent enzyme scans per record. A value <= 0 uses
+	// runtime.NumCPU().
+	Workers int
+	// Ends, when enabled, stores read-end hashes at every cut so ScorePair
+	// can report duplicate-locus clusters.
+	Ends paralog.Hasher
+	// MappabilityReadLength, when > 0, indexes every reference window of this
+	// length and stores how often the windows beside each cut recur, so
+	// ScorePair can report mappable loci. The index is held only while the
+	// cut index is built.
+	MappabilityReadLength int
+	// GC stores GC prefix sums of every record so ScorePairWithOptions can
+	// apply PCR amplification weights.
+	GC bool
+}
+
+// ScoreOptions configures optional per-fragment totals computed by
+// ScorePairWithOptions.
+type ScoreOptions struct {
+	// ReadLength, when > 0, totals the bases read from each score-range
+	// fragment: ReadLength bases from the first enzyme's end, or from both
+	// ends when Paired, counting overlapping bases once.
+	ReadLength int
+	Paired     bool
+	// KnownSNPs maps record IDs to sorted, distinct 0-based SNP positions.
+	// When set, SNPs inside the sequenced bases are counted too. It requires
+	// ReadLength.
+	KnownSNPs map[string][]int
+	// Amplification, when enabled, weights each scored fragment by PCR
+	// amplification from its length and GC and totals the result in
+	// SizeSelection.AmplifiedFragments and AmplifiedBases. It requires a cut
+	// index built with GC.
+	Amplification pcrbias.Model
+	// DepthWeights, when set, records the distribution of relative read
+	// shares over scored fragments in PairSummary.DepthWeights. The pcr
+	// weightings require Amplification.
+	DepthWeights DepthWeighting
+	// LengthHistogram records the length of every fragment of the pair, not
+	// only those in the selector's insert range, in
+	// PairSummary.LengthHistogram so other size models can be evaluated with
+	// Rescore. Bins are 1 bp wide up to LengthHistogramMax, or the end of
+	// the selector's insert range when that is larger.
+	LengthHistogram    bool
+	LengthHistogramMax int
+}
+
+// Rescorable reports whether summaries scored from idx with o can be
+// re-evaluated by Rescore: none of their optional totals depend on more
+// than a fragment's length.
+func (o ScoreOptions) Rescorable(idx CutIndex) bool {
+	return o.ReadLength <= 0 && !o.Amplification.Enabled() && o.DepthWeights == "" &&
+		!idx.EndHasher.Enabled() && idx.MappabilityReadLength == 0
+}
+
+// SequencedSummary totals the bases read from score-range fragments and the
+// known SNPs inside them, each scaled by the fragment's size weight.
+type SequencedSummary struct {
+	ReadLength        int     `json:"read_length"`
+	Paired            bool    `json:"paired"`
+	WeightedBases     float64 `json:"weighted_bases"`
+	KnownSNPs         bool    `json:"known_snps"`
+	WeightedKnownSNPs float64 `json:"weighted_known_snps"`
 }
 
 // RecordStats summarizes hard-window fragments for one record.
@@ -57,15 +152,20 @@ type ScreeningStats struct {
 // It intentionally preserves fields consumed by scripts/radigest-rank-pairs:
 // enzymes and size_selection.raw/weighted fields.
 type PairSummary struct {
-	SchemaVersion  int                    `json:"schema_version"`
-	Enzymes        []string               `json:"enzymes"`
-	MinLength      int                    `json:"min_length"`
-	MaxLength      int                    `json:"max_length"`
-	TotalFragments int                    `json:"total_fragments"`
-	TotalBases     int                    `json:"total_bases"`
-	PerChromosome  map[string]RecordStats `json:"per_chromosome"`
-	SizeSelection  sizeselect.Stats       `json:"size_selection"`
-	Screening      ScreeningStats         `json:"screening"`
+	SchemaVersion   int                    `json:"schema_version"`
+	Enzymes         []string               `json:"enzymes"`
+	MinLength       int                    `json:"min_length"`
+	MaxLength       int                    `json:"max_length"`
+	TotalFragments  int                    `json:"total_fragments"`
+	TotalBases      int                    `json:"total_bases"`
+	PerChromosome   map[string]RecordStats `json:"per_chromosome"`
+	SizeSelection   sizeselect.Stats       `json:"size_selection"`
+	Duplicates      *paralog.Summary       `json:"duplicates,omitempty"`
+	Mappability     *mappability.Summary   `json:"mappability,omitempty"`
+	Sequenced       *SequencedSummary      `json:"sequenced,omitempty"`
+	DepthWeights    *DepthWeightSummary    `json:"depth_weights,omitempty"`
+	LengthHistogram *sizeselect.Histogram  `json:"length_histogram,omitempty"`
+	Screening       ScreeningStats         `json:"screening"`
 }
 
 // Pair identifies one unique enzyme pair.
@@ -102,7 +202,7 @@ func BuildCutIndexParallel(records []fasta.Record, enzymes []enzyme.Enzyme, opt
 	}
 
 	for _, rec := range records {
-		rc, err := scanRecordCutsWithWorkers(rec, names, plans, workers)
+		rc, err := scanRecordCutsWithWorkers(rec, names, plans, workers, paralog.Hasher{})
 		if err != nil {
 			return CutIndex{}, err
 		}
@@ -124,29 +224,66 @@ func BuildCutIndexFromRecords(records <-chan fasta.Record, enzymes []enzyme.Enzy
 // the returned index remains the same as input FASTA order. A workers value <= 0
 // uses runtime.NumCPU().
 func BuildCutIndexFromRecordsParallel(records <-chan fasta.Record, enzymes []enzyme.Enzyme, opt digest.Options, workers int) (CutIndex, error) {
+	return BuildCutIndexFromRecordsWithOptions(records, enzymes, opt, BuildOptions{Workers: workers})
+}
+
+// BuildCutIndexFromRecordsWithOptions is like BuildCutIndexFromRecordsParallel,
+// but can also store read-end hashes and mappability copy counts at every cut,
+// and GC prefix sums of every record, as selected by build.
+func BuildCutIndexFromRecordsWithOptions(records <-chan fasta.Record, enzymes []enzyme.Enzyme, opt digest.Options, build BuildOptions) (CutIndex, error) {
 	if records == nil {
 		return CutIndex{}, fmt.Errorf("screen cut index: records channel is nil")
 	}
+	if err := build.Ends.Validate(); err != nil {
+		return CutIndex{}, fmt.Errorf("screen cut index: %w", err)
+	}
 
 	names, plans, err := compileCutPlans(enzymes, opt)
 	if err != nil {
 		return CutIndex{}, err
 	}
-	workers = normalizeBuildWorkers(workers, len(plans))
+	workers := normalizeBuildWorkers(build.Workers, len(plans))
 
 	idx := CutIndex{
 		Records:     make([]RecordCuts, 0),
 		EnzymeNames: names,
+		GC:          build.GC,
+	}
+	if build.Ends.Enabled() {
+		idx.EndHasher = build.Ends
+	}
+	var windows *mappability.Index
+	var pending []recordCutHashes
+	if build.MappabilityReadLength > 0 {
+		windows, err = mappability.NewIndex(build.MappabilityReadLength)
+		if err != nil {
+			return CutIndex{}, fmt.Errorf("screen cut index: %w", err)
+		}
 	}
 
 	for rec := range records {
-		rc, err := scanRecordCutsWithWorkers(rec, names, plans, workers)
+		rc, err := scanRecordCutsWithWorkers(rec, names, plans, workers, idx.EndHasher)
 		if err != nil {
 			return CutIndex{}, err
 		}
+		if build.GC {
+			rc.GC = pcrbias.NewGCIndex(rec.Seq)
+		}
+		if windows != nil {
+			windows.Add(rec.Seq)
+			pending = append(pending, collectCutHashes(windows, rec.Seq, rc))
+		}
 		idx.Records = append(idx.Records, rc)
 	}
 
+	if windows != nil {
+		windows.Freeze()
+		for i := range idx.Records {
+			pending[i].resolve(windows, &idx.Records[i])
+		}
+		idx.MappabilityReadLength = windows.ReadLength()
+		idx.IndexedWindows = windows.Windows()
+	}
 	return idx, nil
 }
 
@@ -161,13 +298,20 @@ func BuildCutIndexFromFASTA(path string, enzymes []enzyme.Enzyme, opt digest.Opt
 // record's candidate enzymes with up to workers goroutines. A workers value <= 0
 // uses runtime.NumCPU().
 func BuildCutIndexFromFASTAParallel(path string, enzymes []enzyme.Enzyme, opt digest.Options, workers int) (CutIndex, error) {
+	return BuildCutIndexFromFASTAWithOptions(path, enzymes, opt, BuildOptions{Workers: workers})
+}
+
+// BuildCutIndexFromFASTAWithOptions is like BuildCutIndexFromFASTAParallel,
+// but can also store read-end hashes and mappability copy counts at every cut,
+// and GC prefix sums of every record, as selected by build.
+func BuildCutIndexFromFASTAWithOptions(path string, enzymes []enzyme.Enzyme, opt digest.Options, build BuildOptions) (CutIndex, error) {
 	ch := make(chan fasta.Record)
 	errCh := make(chan error, 1)
 	go func() {
 		errCh <- fasta.Stream(path, ch)
 	}()
 
-	idx, buildErr := BuildCutIndexFromRecordsParallel(ch, enzymes, opt, workers)
+	idx, buildErr := BuildCutIndexFromRecordsWithOptions(ch, enzymes, opt, build)
 	if buildErr != nil {
 		for range ch {
 			// Drain the FASTA stream so fasta.Stream can return its error and the
@@ -213,7 +357,7 @@ func compileCutPlans(enzymes []enzyme.Enzyme, opt digest.Options) ([]string, []d
 	return names, plans, nil
 }
 
-func scanRecordCutsWithWorkers(rec fasta.Record, names []string, plans []digest.Plan, workers int) (RecordCuts, error) {
+func scanRecordCutsWithWorkers(rec fasta.Record, names []string, plans []digest.Plan, workers int, ends paralog.Hasher) (RecordCuts, error) {
 	if rec.ID == "" {
 		return RecordCuts{}, fmt.Errorf("screen cut index: record with empty ID")
 	}
@@ -222,10 +366,18 @@ func scanRecordCutsWithWorkers(rec fasta.Record, names []string, plans []digest.
 		Length: len(rec.Seq),
 		Cuts:   make(map[string][]int, len(names)),
 	}
+	if ends.Enabled() {
+		rc.Ends = make(map[string][]paralog.EndHashes, len(names))
+		rc.Bounds = paralog.EndHashes{Down: ends.Down(rec.Seq, 0), Up: ends.Up(rec.Seq, len(rec.Seq))}
+	}
 	workers = normalizeBuildWorkers(workers, len(plans))
 	if workers == 1 || len(plans) <= 1 {
 		for i, plan := range plans {
-			rc.Cuts[names[i]] = plan.Cuts(rec.Seq)
+			cuts := plan.Cuts(rec.Seq)
+			rc.Cuts[names[i]] = cuts
+			if rc.Ends != nil {
+				rc.Ends[names[i]] = ends.CutEnds(rec.Seq, cuts)
+			}
 		}
 		return rc, nil
 	}
@@ -233,6 +385,7 @@ func scanRecordCutsWithWorkers(rec fasta.Record, names []string, plans []digest.
 	type result struct {
 		idx  int
 		cuts []int
+		ends []paralog.EndHashes
 	}
 	jobs := make(chan int)
 	results := make(chan result, len(plans))
@@ -242,7 +395,11 @@ func scanRecordCutsWithWorkers(rec fasta.Record, names []string, plans []digest.
 		go func() {
 			defer wg.Done()
 			for i := range jobs {
-				results <- result{idx: i, cuts: plans[i].Cuts(rec.Seq)}
+				res := result{idx: i, cuts: plans[i].Cuts(rec.Seq)}
+				if rc.Ends != nil {
+					res.ends = ends.CutEnds(rec.Seq, res.cuts)
+				}
+				results <- res
 			}
 		}()
 	}
@@ -255,6 +412,9 @@ func scanRecordCutsWithWorkers(rec fasta.Record, names []string, plans []digest.
 
 	for res := range results {
 		rc.Cuts[names[res.idx]] = res.cuts
+		if rc.Ends != nil {
+			rc.Ends[names[res.idx]] = res.ends
+		}
 	}
 	return rc, nil
 }
@@ -308,13 +468,159 @@ func (idx CutIndex) CachedCutSites() int {
 }
 
 // CacheMemoryEstimateBytes returns an approximate in-memory size for cached cut
-// coordinates only. It intentionally excludes map, slice, and string overhead.
+// coordinates and, when present, their read-end hashes, copy counts, and GC
+// prefix sums. It intentionally excludes map, slice, and string overhead.
 func (idx CutIndex) CacheMemoryEstimateBytes() int64 {
-	return int64(idx.CachedCutSites()) * int64(strconv.IntSize/8)
+	perSite := int64(strconv.IntSize / 8)
+	if idx.EndHasher.Enabled() {
+		perSite += 16
+	}
+	if idx.MappabilityReadLength > 0 {
+		perSite += 8
+	}
+	total := int64(idx.CachedCutSites()) * perSite
+	for _, rec := range idx.Records {
+		total += rec.GC.Bytes()
+	}
+	return total
+}
+
+// recordCutHashes holds mappability window hashes for one record until the
+// window index is frozen and they can be resolved to copy counts.
+type recordCutHashes struct {
+	cuts   map[string][]mappability.CutHashes
+	bounds mappability.CutHashes
+}
+
+func collectCutHashes(windows *mappability.Index, seq []byte, rc RecordCuts) recordCutHashes {
+	out := recordCutHashes{cuts: make(map[string][]mappability.CutHashes, len(rc.Cuts))}
+	for name, cuts := range rc.Cuts {
+		hashes := make([]mappability.CutHashes, len(cuts))
+		for i, pos := range cuts {
+			hashes[i] = windows.CutHashes(seq, pos)
+		}
+		out.cuts[name] = hashes
+	}
+	start := windows.CutHashes(seq, 0)
+	end := windows.CutHashes(seq, len(seq))
+	out.bounds = mappability.CutHashes{Down: start.Down, DownOK: start.DownOK, Up: end.Up, UpOK: end.UpOK}
+	return out
+}
+
+func (p recordCutHashes) resolve(windows *mappability.Index, rc *RecordCuts) {
+	rc.Copies = make(map[string][]mappability.CutEnds, len(p.cuts))
+	for name, hashes := range p.cuts {
+		copies := make([]mappability.CutEnds, len(hashes))
+		for i, h := range hashes {
+			copies[i] = windows.Resolve(h)
+		}
+		rc.Copies[name] = copies
+	}
+	rc.CopyBounds = windows.Resolve(p.bounds)
+}
+
+// cutAt reports which named enzyme cuts at pos and the cut's position in that
+// enzyme's sorted cut list.
+func (rec RecordCuts) cutAt(pos int, names ...string) (string, int, bool) {
+	for _, name := range names {
+		cuts := rec.Cuts[name]
+		i := sort.SearchInts(cuts, pos)
+		if i < len(cuts) && cuts[i] == pos {
+			return name, i, true
+		}
+	}
+	return "", 0, false
+}
+
+// endsAt returns the read-end hashes anchored at pos, looking first at the
+// cuts of each named enzyme and then at the record boundaries.
+func (rec RecordCuts) endsAt(pos int, names ...string) (paralog.EndHashes, bool) {
+	if name, i, ok := rec.cutAt(pos, names...); ok {
+		if ends := rec.Ends[name]; i < len(ends) {
+			return ends[i], true
+		}
+	}
+	switch pos {
+	case 0:
+		return paralog.EndHashes{Down: rec.Bounds.Down}, true
+	case rec.Length:
+		return paralog.EndHashes{Up: rec.Bounds.Up}, true
+	}
+	return paralog.EndHashes{}, false
+}
+
+// copiesAt is like endsAt for cached mappability copy counts.
+func (rec RecordCuts) copiesAt(pos int, names ...string) mappability.CutEnds {
+	if name, i, ok := rec.cutAt(pos, names...); ok {
+		if copies := rec.Copies[name]; i < len(copies) {
+			return copies[i]
+		}
+	}
+	switch pos {
+	case 0:
+		return mappability.CutEnds{Down: rec.CopyBounds.Down, Up: mappability.NotEvaluated}
+	case rec.Length:
+		return mappability.CutEnds{Down: mappability.NotEvaluated, Up: rec.CopyBounds.Up}
+	}
+	return mappability.CutEnds{Down: mappability.NotEvaluated, Up: mappability.NotEvaluated}
+}
+
+// fragmentCopies mirrors mappability.Index.Fragment using cached counts.
+func (rec RecordCuts) fragmentCopies(fr digest.Fragment, enzymeA, enzymeB string) mappability.Ends {
+	return mappability.Ends{
+		Left:  int(rec.copiesAt(fr.Start, enzymeA, enzymeB).Down),
+		Right: int(rec.copiesAt(fr.End, enzymeA, enzymeB).Up),
+	}
+}
+
+// fragmentEnds returns the read ends of fr from cached end hashes,
+// mirroring paralog.Hasher.FragmentEnds on the original sequence.
+func (rec RecordCuts) fragmentEnds(h paralog.Hasher, fr digest.Fragment, enzymeA, enzymeB string) (paralog.FragmentEnds, bool) {
+	if fr.End-fr.Start < h.ReadLength {
+		return paralog.FragmentEnds{}, false
+	}
+	left, ok := rec.endsAt(fr.Start, enzymeA, enzymeB)
+	if !ok {
+		return paralog.FragmentEnds{}, false
+	}
+	right, ok := rec.endsAt(fr.End, enzymeA, enzymeB)
+	if !ok {
+		return paralog.FragmentEnds{}, false
+	}
+	return paralog.NewFragmentEnds(left.Down, right.Up)
+}
+
+// sequencedSpans returns the intervals of fr read under score: ReadLength
+// bases from the enzymeA end, or from both ends when paired. The second
+// interval is empty unless paired reads do not overlap.
+func (rec RecordCuts) sequencedSpans(fr digest.Fragment, enzymeA string, score ScoreOptions) ([2]int, [2]int) {
+	n := score.ReadLength
+	if score.Paired {
+		if fr.End-fr.Start <= 2*n {
+			return [2]int{fr.Start, fr.End}, [2]int{}
+		}
+		return [2]int{fr.Start, fr.Start + n}, [2]int{fr.End - n, fr.End}
+	}
+	_, _, startA := rec.cutAt(fr.Start, enzymeA)
+	if _, _, endA := rec.cutAt(fr.End, enzymeA); endA && !startA {
+		return [2]int{maxInt(fr.Start, fr.End-n), fr.End}, [2]int{}
+	}
+	return [2]int{fr.Start, minInt(fr.End, fr.Start+n)}, [2]int{}
+}
+
+// countIn returns how many sorted positions fall in [span[0], span[1]).
+func countIn(positions []int, span [2]int) int {
+	return sort.SearchInts(positions, span[1]) - sort.SearchInts(positions, span[0])
 }
 
 // ScorePair scores one enzyme pair from cached cut-coordinate streams.
 func ScorePair(idx CutIndex, enzymeA, enzymeB string, selector sizeselect.Selector, opt digest.Options) (PairSummary, error) {
+	return ScorePairWithOptions(idx, enzymeA, enzymeB, selector, opt, ScoreOptions{})
+}
+
+// ScorePairWithOptions is like ScorePair, but can also total sequenced bases
+// and known SNPs, as selected by score.
+func ScorePairWithOptions(idx CutIndex, enzymeA, enzymeB string, selector sizeselect.Selector, opt digest.Options, score ScoreOptions) (PairSummary, error) {
 	if enzymeA == "" || enzymeB == "" {
 		return PairSummary{}, fmt.Errorf("screen score pair: enzyme names must be non-empty")
 	}
@@ -329,21 +635,61 @@ func ScorePair(idx CutIndex, enzymeA, enzymeB string, selector sizeselect.Select
 	}
 
 	cfg := selector.Config()
-	digestMin := minInt(cfg.Min, cfg.ScoreMin)
-	digestMax := maxInt(cfg.Max, cfg.ScoreMax)
+	digestMin, digestMax := selector.InsertRange()
 
 	sizeStats := sizeselect.NewStats(selector)
 	perChromosome := make(map[string]RecordStats, len(idx.Records))
 	totalFragments := 0
 	totalBases := 0
+	var duplicates *paralog.Detector
+	if idx.EndHasher.Enabled() {
+		duplicates = paralog.NewDetector(idx.EndHasher, false)
+	}
+	var mappable *mappability.Summary
+	if idx.MappabilityReadLength > 0 {
+		mappable = &mappability.Summary{ReadLength: idx.MappabilityReadLength, IndexedWindows: idx.IndexedWindows}
+	}
+	if score.KnownSNPs != nil && score.ReadLength <= 0 {
+		return PairSummary{}, fmt.Errorf("screen score pair: known SNPs require a read length")
+	}
+	if err := score.Amplification.Validate(); err != nil {
+		return PairSummary{}, fmt.Errorf("screen score pair: %w", err)
+	}
+	if score.Amplification.Enabled() && !idx.GC {
+		return PairSummary{}, fmt.Errorf("screen score pair: amplification weighting requires a cut index built with GC")
+	}
+	if score.DepthWeights.UsesPCR() && !score.Amplification.Enabled() {
+		return PairSummary{}, fmt.Errorf("screen score pair: %s depth weights require amplification weighting", score.DepthWeights)
+	}
+	var depths *depthWeights
+	if score.DepthWeights != "" {
+		depths = newDepthWeights(score.DepthWeights)
+	}
+	var sequenced *SequencedSummary
+	if score.ReadLength > 0 {
+		sequenced = &SequencedSummary{ReadLength: score.ReadLength, Paired: score.Paired, KnownSNPs: score.KnownSNPs != nil}
+	}
+	var histogram *sizeselect.Histogram
+	scanMin, scanMax := digestMin, digestMax
+	if score.LengthHistogram {
+		histogram = sizeselect.NewHistogram(maxInt(digestMax, score.LengthHistogramMax))
+		scanMin, scanMax = minInt(digestMin, 1), math.MaxInt
+	}
 
 	for _, rec := range idx.Records {
 		cutsA := rec.Cuts[enzymeA]
 		cutsB := rec.Cuts[enzymeB]
 		local := RecordStats{}
+		snps := score.KnownSNPs[rec.ID]
 
-		err := digest.DigestCutsEach(cutsA, cutsB, rec.Length, digestMin, digestMax, opt, func(fr digest.Fragment) error {
+		err := digest.DigestCutsEach(cutsA, cutsB, rec.Length, scanMin, scanMax, opt, func(fr digest.Fragment) error {
 			length := fr.End - fr.Start
+			if histogram != nil {
+				histogram.Add(length)
+				if length < digestMin || length > digestMax {
+					return nil
+				}
+			}
 			hardKept := selector.InHardWindow(length)
 			if hardKept {
 				sizeStats.AddHardKept(length)
@@ -353,7 +699,34 @@ func ScorePair(idx CutIndex, enzymeA, enzymeB string, selector sizeselect.Select
 				totalBases += length
 			}
 			if selector.InScoreRange(length) {
-				sizeStats.AddScored(length, selector.Weight(length))
+				weight := selector.Weight(length)
+				sizeStats.AddScored(length, weight)
+				amp := 1.0
+				if score.Amplification.Enabled() {
+					gc, ok := rec.GC.GC(fr.Start, fr.End)
+					if !ok {
+						gc = score.Amplification.GCOptimum
+					}
+					amp = score.Amplification.Weight(length, gc)
+					sizeStats.AddAmplified(length, weight*amp)
+				}
+				if depths != nil {
+					depths.add(weight, depths.share(weight, amp))
+				}
+				if duplicates != nil {
+					ends, ok := rec.fragmentEnds(idx.EndHasher, fr, enzymeA, enzymeB)
+					duplicates.AddEnds(ends, ok, paralog.Member{Chr: rec.ID, Start: fr.Start, End: fr.End, Weight: weight})
+				}
+				if mappable != nil {
+					mappable.Add(rec.fragmentCopies(fr, enzymeA, enzymeB), weight)
+				}
+				if sequenced != nil {
+					first, second := rec.sequencedSpans(fr, enzymeA, score)
+					sequenced.WeightedBases += weight * float64(first[1]-first[0]+second[1]-second[0])
+					if len(snps) > 0 {
+						sequenced.WeightedKnownSNPs += weight * float64(countIn(snps, first)+countIn(snps, second))
+					}
+				}
 			}
 			return nil
 		})
@@ -363,7 +736,7 @@ func ScorePair(idx CutIndex, enzymeA, enzymeB string, selector sizeselect.Select
 		perChromosome[rec.ID] = local
 	}
 
-	return PairSummary{
+	summary := PairSummary{
 		SchemaVersion:  1,
 		Enzymes:        []string{enzymeA, enzymeB},
 		MinLength:      cfg.Min,
@@ -379,7 +752,42 @@ func ScorePair(idx CutIndex, enzymeA, enzymeB string, selector sizeselect.Select
 			CachedCutSites:           idx.CachedCutSites(),
 			CacheMemoryEstimateBytes: idx.CacheMemoryEstimateBytes(),
 		},
-	}, nil
+	}
+	if duplicates != nil {
+		dup := duplicates.Summary()
+		summary.Duplicates = &dup
+	}
+	summary.Mappability = mappable
+	summary.Sequenced = sequenced
+	if depths != nil {
+		summary.DepthWeights = depths.summary()
+	}
+	summary.LengthHistogram = histogram
+	return summary, nil
+}
+
+// Rescore re-evaluates summary under selector from its length histogram
+// without re-merging cut streams. Size-selection totals are exact for
+// lengths in the histogram's 1 bp bins. PerChromosome is not kept, since the
+// histogram pools records. Summaries without a histogram, or with totals a
+// histogram cannot reproduce (see ScoreOptions.Rescorable), are rejected.
+func Rescore(summary PairSummary, selector sizeselect.Selector) (PairSummary, error) {
+	if summary.LengthHistogram == nil {
+		return PairSummary{}, fmt.Errorf("screen rescore: pair summary has no length histogram")
+	}
+	if summary.Duplicates != nil || summary.Mappability != nil || summary.Sequenced != nil || summary.DepthWeights != nil || summary.SizeSelection.AmplifiedFragments != 0 {
+		return PairSummary{}, fmt.Errorf("screen rescore: pair summary has per-fragment totals a length histogram cannot reproduce")
+	}
+	cfg := selector.Config()
+	stats := summary.LengthHistogram.Stats(selector)
+	summary.Enzymes = append([]string(nil), summary.Enzymes...)
+	summary.MinLength = cfg.Min
+	summary.MaxLength = cfg.Max
+	summary.TotalFragments = stats.RawFragmentsInWindow
+	summary.TotalBases = int(stats.RawBasesInWindow)
+	summary.PerChromosome = nil
+	summary.SizeSelection = stats
+	return summary, nil
 }
 
 // ScoreAllPairs scores all unique enzyme pairs in cut-index order.
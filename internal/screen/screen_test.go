@@ -12,6 +12,9 @@ import (
 	"github.com/ericksamera/radigest/internal/digest"
 	"github.com/ericksamera/radigest/internal/enzyme"
 	"github.com/ericksamera/radigest/internal/fasta"
+	"github.com/ericksamera/radigest/internal/mappability"
+	"github.com/ericksamera/radigest/internal/paralog"
+	"github.com/ericksamera/radigest/internal/pcrbias"
 	"github.com/ericksamera/radigest/internal/sizeselect"
 )
 
@@ -53,9 +56,7 @@ func expectedFromPlan(t *testing.T, records []fasta.Record, ens []enzyme.Enzyme,
 		t.Fatalf("TryNewPlanWithOptions returned error: %v", err)
 	}
 
-	cfg := selector.Config()
-	digestMin := minInt(cfg.Min, cfg.ScoreMin)
-	digestMax := maxInt(cfg.Max, cfg.ScoreMax)
+	digestMin, digestMax := selector.InsertRange()
 	sizeStats := sizeselect.NewStats(selector)
 	perChromosome := make(map[string]RecordStats, len(records))
 	totalFragments := 0
@@ -84,7 +85,7 @@ func expectedFromPlan(t *testing.T, records []fasta.Record, ens []enzyme.Enzyme,
 		perChromosome[rec.ID] = local
 	}
 
-	cfg = selector.Config()
+	cfg := selector.Config()
 	return PairSummary{
 		Enzymes:        []string{ens[0].Name, ens[1].Name},
 		MinLength:      cfg.Min,
@@ -312,6 +313,40 @@ func TestScorePairMatchesPlanDigestEach(t *testing.T) {
 	}
 }
 
+func TestScorePairSizeSelectsLibraryLength(t *testing.T) {
+	idx, err := BuildCutIndex(testRecords(), testEnzymes(), digest.Options{})
+	if err != nil {
+		t.Fatalf("BuildCutIndex returned error: %v", err)
+	}
+	insertSel, err := sizeselect.New(sizeselect.Config{Min: 1, Max: 5, ScoreMin: 1, ScoreMax: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	librarySel, err := sizeselect.New(sizeselect.Config{Min: 121, Max: 125, ScoreMin: 121, ScoreMax: 125, AdapterP1: 60, AdapterP2: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ScorePair(idx, "EcoRI", "MseI", insertSel, digest.Options{})
+	if err != nil {
+		t.Fatalf("ScorePair returned error: %v", err)
+	}
+	got, err := ScorePair(idx, "EcoRI", "MseI", librarySel, digest.Options{})
+	if err != nil {
+		t.Fatalf("ScorePair returned error: %v", err)
+	}
+	if want.TotalFragments != 1 || want.TotalBases != 5 {
+		t.Fatalf("insert-length totals got fragments=%d bases=%d, want 1 fragment of 5 bp", want.TotalFragments, want.TotalBases)
+	}
+	if got.TotalFragments != want.TotalFragments || got.TotalBases != want.TotalBases ||
+		got.SizeSelection.RawBasesScored != want.SizeSelection.RawBasesScored {
+		t.Fatalf("library-length summary got %#v want %#v", got.SizeSelection, want.SizeSelection)
+	}
+	if got.SizeSelection.AdapterP1 != 60 || got.SizeSelection.AdapterP2 != 60 {
+		t.Fatalf("adapter lengths not recorded: %#v", got.SizeSelection)
+	}
+}
+
 func TestScoreAllPairs(t *testing.T) {
 	idx, err := BuildCutIndex(testRecords(), testEnzymes(), digest.Options{})
 	if err != nil {
@@ -415,3 +450,275 @@ func TestWritePairSummaryJSON(t *testing.T) {
 		t.Fatalf("decoded engine got %q", decoded.Screening.Engine)
 	}
 }
+
+func TestScorePairReportsDuplicatesFromCachedEnds(t *testing.T) {
+	repeat := "GAATTCAGCGCGATCAGTCCAGCATGCAGGTCAGCACGCTAGGTTAA"
+	records := []fasta.Record{
+		{ID: "chr1", Seq: []byte("CCGCACCAGT" + repeat + "GCAGCGCCAGGCA" + repeat + "GGCAGCCAGC")},
+		{ID: "chr2", Seq: []byte("GACCGCAGGC" + repeat + "CAGCAC")},
+	}
+	hasher := paralog.Hasher{Mode: paralog.ModeExact, ReadLength: 12}
+	ch := make(chan fasta.Record, len(records))
+	for _, rec := range records {
+		ch <- rec
+	}
+	close(ch)
+	idx, err := BuildCutIndexFromRecordsWithOptions(ch, testEnzymes(), digest.Options{}, BuildOptions{Workers: 2, Ends: hasher})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector := testSelector(t)
+	opt := digest.Options{IncludeEnds: true}
+	got, err := ScorePair(idx, "EcoRI", "MseI", selector, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Duplicates == nil {
+		t.Fatal("expected duplicate summary when end hashes are cached")
+	}
+
+	plan, err := digest.TryNewPlanWithOptions(testEnzymes()[:2], opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector := paralog.NewDetector(hasher, false)
+	for _, rec := range records {
+		err := plan.DigestEach(rec.Seq, 1, 100, func(fr digest.Fragment) error {
+			if length := fr.End - fr.Start; selector.InScoreRange(length) {
+				detector.Add(rec.ID, rec.Seq, fr, selector.Weight(length))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := detector.Summary()
+	if !reflect.DeepEqual(*got.Duplicates, want) {
+		t.Fatalf("cached duplicates = %+v, want %+v", *got.Duplicates, want)
+	}
+	if want.Clusters != 1 || want.LargestCluster != 3 {
+		t.Fatalf("expected the repeat to form one 3-member cluster: %+v", want)
+	}
+	if want.EffectiveUniqueLoci >= want.WeightedFragments {
+		t.Fatalf("effective loci %g should be below weighted fragments %g", want.EffectiveUniqueLoci, want.WeightedFragments)
+	}
+}
+
+func TestScorePairReportsMappabilityFromCachedCopies(t *testing.T) {
+	repeat := "GAATTCAGCGCGATCAGTCCAGCATGCAGGTCAGCACGCTAGGTTAA"
+	records := []fasta.Record{
+		{ID: "chr1", Seq: []byte("CCGCACCAGT" + repeat + "GCAGCGCCAGGCATGACCTTAAGGCACCAGTGCATCGGATC" + "GGCAGCCAGC")},
+		{ID: "chr2", Seq: []byte("GACCGCAGGC" + repeat + "CAGCAC")},
+	}
+	ch := make(chan fasta.Record, len(records))
+	for _, rec := range records {
+		ch <- rec
+	}
+	close(ch)
+	idx, err := BuildCutIndexFromRecordsWithOptions(ch, testEnzymes(), digest.Options{}, BuildOptions{Workers: 2, MappabilityReadLength: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector := testSelector(t)
+	opt := digest.Options{IncludeEnds: true}
+	got, err := ScorePair(idx, "EcoRI", "MseI", selector, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Mappability == nil {
+		t.Fatal("expected mappability summary when copy counts are cached")
+	}
+
+	windows, err := mappability.NewIndex(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range records {
+		windows.Add(rec.Seq)
+	}
+	windows.Freeze()
+	want := mappability.NewSummary(windows)
+	plan, err := digest.TryNewPlanWithOptions(testEnzymes()[:2], opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range records {
+		err := plan.DigestEach(rec.Seq, 1, 100, func(fr digest.Fragment) error {
+			if length := fr.End - fr.Start; selector.InScoreRange(length) {
+				want.Add(windows.Fragment(rec.Seq, fr), selector.Weight(length))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(*got.Mappability, want) {
+		t.Fatalf("cached mappability = %+v, want %+v", *got.Mappability, want)
+	}
+	if want.Fragments == 0 || want.UniqueFragments == want.Fragments {
+		t.Fatalf("expected repeat fragments to have non-unique ends: %+v", want)
+	}
+}
+
+func TestScorePairWithOptionsCountsSequencedBasesAndKnownSNPs(t *testing.T) {
+	// EcoRI cuts at 5 and MseI at 31, giving one 26 bp AB fragment.
+	records := []fasta.Record{{ID: "chr1", Seq: []byte("AAAAGAATTCCCCCCCCCCCCCCCCCCCCCTTAACCCC")}}
+	idx, err := BuildCutIndex(records, testEnzymes(), digest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector, err := sizeselect.New(sizeselect.Config{Model: sizeselect.ModelHard, Min: 1, Max: 100, ScoreMin: 1, ScoreMax: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snps := map[string][]int{"chr1": {6, 14, 15, 20, 25}}
+
+	cases := []struct {
+		score       ScoreOptions
+		bases, snps float64
+	}{
+		{ScoreOptions{ReadLength: 10, KnownSNPs: snps}, 10, 2},
+		{ScoreOptions{ReadLength: 10, Paired: true, KnownSNPs: snps}, 20, 3},
+		{ScoreOptions{ReadLength: 20, Paired: true, KnownSNPs: snps}, 26, 5},
+	}
+	for _, tc := range cases {
+		got, err := ScorePairWithOptions(idx, "EcoRI", "MseI", selector, digest.Options{}, tc.score)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Sequenced == nil || got.Sequenced.WeightedBases != tc.bases || got.Sequenced.WeightedKnownSNPs != tc.snps || !got.Sequenced.KnownSNPs {
+			t.Fatalf("%+v: sequenced = %+v, want %g bases and %g SNPs", tc.score, got.Sequenced, tc.bases, tc.snps)
+		}
+	}
+
+	// Reading from the MseI end instead swaps which SNPs are covered.
+	got, err := ScorePairWithOptions(idx, "MseI", "EcoRI", selector, digest.Options{}, ScoreOptions{ReadLength: 10, KnownSNPs: snps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Sequenced.WeightedKnownSNPs != 1 {
+		t.Fatalf("MseI-end SNPs = %g, want 1", got.Sequenced.WeightedKnownSNPs)
+	}
+	if plain, _ := ScorePair(idx, "EcoRI", "MseI", selector, digest.Options{}); plain.Sequenced != nil {
+		t.Fatalf("ScorePair should not total sequenced bases: %+v", plain.Sequenced)
+	}
+	if _, err := ScorePairWithOptions(idx, "EcoRI", "MseI", selector, digest.Options{}, ScoreOptions{KnownSNPs: snps}); err == nil {
+		t.Fatal("expected error for known SNPs without a read length")
+	}
+}
+
+func TestScorePairWithOptionsTotalsAmplifiedWeights(t *testing.T) {
+	records := testRecords()
+	ch := make(chan fasta.Record, len(records))
+	for _, rec := range records {
+		ch <- rec
+	}
+	close(ch)
+	idx, err := BuildCutIndexFromRecordsWithOptions(ch, testEnzymes(), digest.Options{}, BuildOptions{Workers: 1, GC: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector := testSelector(t)
+	amp := pcrbias.Model{Cycles: 12, Efficiency: 0.9, LengthScale: 100, GCOptimum: 0.5, GCWidth: 0.2}
+
+	got, err := ScorePairWithOptions(idx, "EcoRI", "MseI", selector, digest.Options{}, ScoreOptions{Amplification: amp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// EcoRI-MseI fragments [5,11) and [11,16) of the toy record.
+	seq := records[0].Seq
+	wantFragments, wantBases := 0.0, 0.0
+	for _, fr := range [][2]int{{5, 11}, {11, 16}} {
+		w := selector.Weight(fr[1]-fr[0]) * amp.SeqWeight(seq[fr[0]:fr[1]])
+		wantFragments += w
+		wantBases += w * float64(fr[1]-fr[0])
+	}
+	ss := got.SizeSelection
+	assertFloatNear(t, "amplified fragments", ss.AmplifiedFragments, wantFragments)
+	assertFloatNear(t, "amplified bases", ss.AmplifiedBases, wantBases)
+	if ss.AmplifiedFragments >= ss.WeightedFragments {
+		t.Fatalf("amplified fragments %g should fall below size-weighted %g", ss.AmplifiedFragments, ss.WeightedFragments)
+	}
+
+	plain, err := BuildCutIndex(records, testEnzymes(), digest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ScorePairWithOptions(plain, "EcoRI", "MseI", selector, digest.Options{}, ScoreOptions{Amplification: amp}); err == nil {
+		t.Fatal("amplification without GC prefix sums returned nil error")
+	}
+
+	weighted, err := ScorePairWithOptions(idx, "EcoRI", "MseI", selector, digest.Options{}, ScoreOptions{Amplification: amp, DepthWeights: DepthWeightsPCR})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dw := weighted.DepthWeights
+	if dw == nil || dw.Weighting != DepthWeightsPCR {
+		t.Fatalf("depth weights = %+v, want pcr", dw)
+	}
+	loci := 0.0
+	for _, b := range dw.Bins {
+		loci += b.Loci
+	}
+	assertFloatNear(t, "depth-weight loci", loci, ss.WeightedFragments)
+	assertFloatNear(t, "mean relative weight", dw.MeanRelativeWeight(), ss.AmplifiedFragments/ss.WeightedFragments)
+	if _, err := ScorePairWithOptions(idx, "EcoRI", "MseI", selector, digest.Options{}, ScoreOptions{DepthWeights: DepthWeightsSizePCR}); err == nil {
+		t.Fatal("pcr depth weights without amplification returned nil error")
+	}
+}
+
+func TestRescoreFromLengthHistogramMatchesScorePair(t *testing.T) {
+	idx, err := BuildCutIndex(testRecords(), testEnzymes(), digest.Options{})
+	if err != nil {
+		t.Fatalf("BuildCutIndex returned error: %v", err)
+	}
+	narrow, err := sizeselect.New(sizeselect.Config{Model: sizeselect.ModelHard, Min: 5, Max: 5, ScoreMin: 5, ScoreMax: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	score := ScoreOptions{LengthHistogram: true, LengthHistogramMax: 100}
+	if !score.Rescorable(idx) {
+		t.Fatal("plain score options should be rescorable")
+	}
+	base, err := ScorePairWithOptions(idx, "EcoRI", "MseI", narrow, digest.Options{}, score)
+	if err != nil {
+		t.Fatalf("ScorePairWithOptions returned error: %v", err)
+	}
+	if h := base.LengthHistogram; h == nil || h.FineMax != 100 || h.Fragments() <= base.SizeSelection.RawFragmentsScored {
+		t.Fatalf("histogram should count fragments outside the narrow window: %+v", h)
+	}
+	plain, err := ScorePair(idx, "EcoRI", "MseI", narrow, digest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(base.SizeSelection, plain.SizeSelection) || base.TotalFragments != plain.TotalFragments {
+		t.Fatalf("histogram changed the narrow window's totals: %+v vs %+v", base.SizeSelection, plain.SizeSelection)
+	}
+
+	wide := testSelector(t)
+	got, err := Rescore(base, wide)
+	if err != nil {
+		t.Fatalf("Rescore returned error: %v", err)
+	}
+	want, err := ScorePair(idx, "EcoRI", "MseI", wide, digest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, w := got.SizeSelection, want.SizeSelection
+	if g.RawFragmentsScored != w.RawFragmentsScored || g.RawBasesInWindow != w.RawBasesInWindow || got.TotalFragments != want.TotalFragments || got.MaxLength != 100 {
+		t.Fatalf("rescored %+v, want %+v", got, want)
+	}
+	if math.Abs(g.WeightedBases-w.WeightedBases) > 1e-9 || math.Abs(g.WeightedFragments-w.WeightedFragments) > 1e-9 {
+		t.Fatalf("rescored weights %+v, want %+v", g, w)
+	}
+
+	if _, err := Rescore(plain, wide); err == nil {
+		t.Fatal("Rescore accepted a summary without a histogram")
+	}
+	base.Sequenced = &SequencedSummary{ReadLength: 5}
+	if _, err := Rescore(base, wide); err == nil {
+		t.Fatal("Rescore accepted a summary with sequenced totals")
+	}
+}
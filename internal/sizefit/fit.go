@@ -0,0 +1,401 @@
+package sizefit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+// eps keeps lengths the model gives zero weight from making the likelihood
+// -Inf, matching the original grid-search fitter.
+const eps = 1e-12
+
+// z95 is the two-sided 95% standard normal quantile.
+const z95 = 1.959963984540054
+
+// Options controls Fit.
+type Options struct {
+	// Min and Max are the nominal hard window. Hard, triangular, and
+	// soft-window models keep it fixed and fit only their shape.
+	Min int
+	Max int
+	// CurveBin is the bin width (bp) of the fitted empirical curve; 0 skips
+	// the empirical model.
+	CurveBin int
+}
+
+// Param is one fitted parameter, named after its size-model JSON key. CI95 is
+// a Wald interval from the observed information, computed on the scale the
+// optimizer uses (log for positive parameters), and is absent when the
+// likelihood is not locally quadratic at the estimate.
+type Param struct {
+	Name  string      `json:"name"`
+	Value float64     `json:"value"`
+	CI95  *[2]float64 `json:"ci95,omitempty"`
+}
+
+// Result is one fitted model with its goodness of fit.
+type Result struct {
+	Model sizeselect.Model `json:"model"`
+	// Config is the fitted selector configuration.
+	Config sizeselect.Config `json:"-"`
+	Params []Param           `json:"params,omitempty"`
+	// K counts free parameters; an empirical curve of n bins has n-1.
+	K             int     `json:"k"`
+	LogLikelihood float64 `json:"log_likelihood"`
+	AIC           float64 `json:"aic"`
+	DeltaAIC      float64 `json:"delta_aic"`
+	// KS is the Kolmogorov-Smirnov distance between the observed and fitted
+	// insert-length distributions.
+	KS            float64 `json:"ks"`
+	ObservedMean  float64 `json:"observed_mean"`
+	PredictedMean float64 `json:"predicted_mean"`
+	ObservedPairs int     `json:"observed_pairs"`
+}
+
+// Fit fits every size model to d and returns the results ordered by AIC, best
+// first.
+func Fit(d *Data, opt Options) ([]Result, error) {
+	if opt.Min < 0 || opt.Max < opt.Min {
+		return nil, fmt.Errorf("invalid hard window: min=%d max=%d", opt.Min, opt.Max)
+	}
+	if opt.CurveBin < 0 {
+		return nil, fmt.Errorf("curve bin must be >= 0 (got %d)", opt.CurveBin)
+	}
+	if d.ObservedTotal == 0 {
+		return nil, errors.New("no observed inserts in the score range")
+	}
+	predicted := 0.0
+	for _, c := range d.Predicted {
+		predicted += c
+	}
+	if predicted == 0 {
+		return nil, errors.New("no predicted fragments in the score range")
+	}
+
+	base := sizeselect.Config{Model: sizeselect.ModelHard, Min: opt.Min, Max: opt.Max, ScoreMin: d.ScoreMin, ScoreMax: d.ScoreMax}
+	hard, err := d.evaluate(base, 0)
+	if err != nil {
+		return nil, err
+	}
+	results := []Result{hard}
+	for _, s := range shapes(d, opt) {
+		r, err := d.fitShape(base, s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.model, err)
+		}
+		results = append(results, r)
+	}
+	if opt.CurveBin > 0 {
+		if curve, bins := d.empiricalCurve(opt.CurveBin); bins > 0 {
+			cfg := base
+			cfg.Model, cfg.Curve = sizeselect.ModelEmpirical, curve
+			r, err := d.evaluate(cfg, bins-1)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", cfg.Model, err)
+			}
+			results = append(results, r)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].AIC < results[j].AIC })
+	for i := range results {
+		results[i].DeltaAIC = results[i].AIC - results[0].AIC
+	}
+	return results, nil
+}
+
+// probabilities returns P(l) for every length in the score range under sel.
+func (d *Data) probabilities(sel sizeselect.Selector) []float64 {
+	p := make([]float64, len(d.Predicted))
+	total := 0.0
+	for i, c := range d.Predicted {
+		if c > 0 {
+			p[i] = c * sel.Weight(d.ScoreMin+i)
+			total += p[i]
+		}
+	}
+	denom := total + eps*float64(len(p))
+	for i := range p {
+		p[i] = (p[i] + eps) / denom
+	}
+	return p
+}
+
+func (d *Data) logLikelihood(p []float64) float64 {
+	ll := 0.0
+	for i, c := range d.Observed {
+		if c > 0 {
+			ll += c * math.Log(p[i])
+		}
+	}
+	return ll
+}
+
+// negLogLikelihood is the optimizer objective; invalid configurations are
+// +Inf.
+func (d *Data) negLogLikelihood(cfg sizeselect.Config) float64 {
+	sel, err := sizeselect.New(cfg)
+	if err != nil {
+		return math.Inf(1)
+	}
+	return -d.logLikelihood(d.probabilities(sel))
+}
+
+// evaluate scores cfg with k free parameters.
+func (d *Data) evaluate(cfg sizeselect.Config, k int) (Result, error) {
+	sel, err := sizeselect.New(cfg)
+	if err != nil {
+		return Result{}, err
+	}
+	p := d.probabilities(sel)
+	r := Result{
+		Model:         sel.Config().Model,
+		Config:        sel.Config(),
+		K:             k,
+		LogLikelihood: d.logLikelihood(p),
+		ObservedPairs: int(d.ObservedTotal),
+	}
+	r.AIC = 2*float64(k) - 2*r.LogLikelihood
+	cumObs, cumFit := 0.0, 0.0
+	for i, c := range d.Observed {
+		length := float64(d.ScoreMin + i)
+		cumObs += c / d.ObservedTotal
+		cumFit += p[i]
+		r.KS = math.Max(r.KS, math.Abs(cumObs-cumFit))
+		r.ObservedMean += length * c / d.ObservedTotal
+		r.PredictedMean += length * p[i]
+	}
+	return r, nil
+}
+
+// transform maps an unconstrained optimizer coordinate to a parameter value.
+type transform int
+
+const (
+	identity transform = iota
+	positive
+	// interval keeps the value strictly inside (lo, hi).
+	interval
+)
+
+type param struct {
+	name   string
+	kind   transform
+	lo, hi float64
+	set    func(cfg *sizeselect.Config, v float64)
+}
+
+func (p param) value(u float64) float64 {
+	switch p.kind {
+	case positive:
+		return math.Exp(u)
+	case interval:
+		return p.lo + (p.hi-p.lo)/(1+math.Exp(-u))
+	}
+	return u
+}
+
+func (p param) coord(v float64) float64 {
+	switch p.kind {
+	case positive:
+		return math.Log(v)
+	case interval:
+		t := (v - p.lo) / (p.hi - p.lo)
+		return math.Log(t / (1 - t))
+	}
+	return v
+}
+
+// shape is a parametric model: its parameters, starting points in parameter
+// units, and initial simplex steps in optimizer units.
+type shape struct {
+	model  sizeselect.Model
+	params []param
+	starts [][]float64
+	steps  []float64
+}
+
+func setMean(cfg *sizeselect.Config, v float64)   { cfg.Mean = v }
+func setSD(cfg *sizeselect.Config, v float64)     { cfg.SD = v }
+func setEdgeSD(cfg *sizeselect.Config, v float64) { cfg.EdgeSD = v }
+func setSkew(cfg *sizeselect.Config, v float64)   { cfg.Skew = v }
+func setMean2(cfg *sizeselect.Config, v float64)  { cfg.Mean2 = v }
+func setSD2(cfg *sizeselect.Config, v float64)    { cfg.SD2 = v }
+func setMix(cfg *sizeselect.Config, v float64)    { cfg.Mix = v }
+
+// shapes lists the parametric models Fit tries.
+func shapes(d *Data, opt Options) []shape {
+	mean, sd := d.observedMoments()
+	lo, hi := float64(d.ScoreMin), float64(opt.Max)
+	normal := shape{
+		model:  sizeselect.ModelNormal,
+		params: []param{{name: "size_mean", set: setMean}, {name: "size_sd", kind: positive, set: setSD}},
+		steps:  []float64{math.Max(sd, 5), 0.5},
+	}
+	for _, m := range []float64{mean, lo + (hi-lo)/4, lo + (hi-lo)/2, lo + 3*(hi-lo)/4, hi} {
+		for _, s := range []float64{sd / 2, sd, 2 * sd} {
+			normal.starts = append(normal.starts, []float64{m, math.Max(s, 1)})
+		}
+	}
+	shapes := []shape{normal}
+
+	// Log-normal and gamma take the same mean/SD starts on positive scales;
+	// gamma starts with SD >= mean are invalid and skipped.
+	for _, model := range []sizeselect.Model{sizeselect.ModelLogNormal, sizeselect.ModelGamma} {
+		shapes = append(shapes, shape{
+			model:  model,
+			params: []param{{name: "size_mean", kind: positive, set: setMean}, {name: "size_sd", kind: positive, set: setSD}},
+			starts: normal.starts,
+			steps:  []float64{0.2, 0.5},
+		})
+	}
+
+	skew := shape{
+		model:  sizeselect.ModelSkewNormal,
+		params: []param{{name: "size_mean", set: setMean}, {name: "size_sd", kind: positive, set: setSD}, {name: "size_skew", set: setSkew}},
+		steps:  []float64{math.Max(sd, 5), 0.5, 1},
+	}
+	for _, a := range []float64{-2, 0, 2, 5} {
+		for _, start := range normal.starts {
+			skew.starts = append(skew.starts, []float64{start[0], start[1], a})
+		}
+	}
+	shapes = append(shapes, skew)
+
+	mixture := shape{
+		model: sizeselect.ModelMixture,
+		params: []param{
+			{name: "size_mean", set: setMean}, {name: "size_sd", kind: positive, set: setSD},
+			{name: "size_mean2", kind: positive, set: setMean2}, {name: "size_sd2", kind: positive, set: setSD2},
+			{name: "size_mix", kind: interval, lo: 0, hi: 1, set: setMix},
+		},
+		steps: []float64{math.Max(sd, 5), 0.5, 0.2, 0.5, 1},
+	}
+	for _, m2 := range []float64{mean - 2*sd, mean - sd, mean + sd, mean + 2*sd} {
+		for _, mix := range []float64{0.1, 0.3} {
+			mixture.starts = append(mixture.starts, []float64{mean, sd, math.Max(m2, 1), math.Max(sd/2, 1), mix})
+		}
+	}
+	shapes = append(shapes, mixture)
+
+	if opt.Max-opt.Min >= 2 {
+		tri := shape{
+			model:  sizeselect.ModelTriangular,
+			params: []param{{name: "size_mean", kind: interval, lo: float64(opt.Min), hi: float64(opt.Max), set: setMean}},
+			steps:  []float64{1},
+		}
+		for i := 1; i < 10; i++ {
+			tri.starts = append(tri.starts, []float64{float64(opt.Min) + float64(i*(opt.Max-opt.Min))/10})
+		}
+		shapes = append(shapes, tri)
+	}
+
+	soft := shape{
+		model:  sizeselect.ModelSoftWindow,
+		params: []param{{name: "size_edge_sd", kind: positive, set: setEdgeSD}},
+		steps:  []float64{0.5},
+	}
+	for _, s := range []float64{5, 10, 25, 50, 100, 200} {
+		soft.starts = append(soft.starts, []float64{s})
+	}
+	return append(shapes, soft)
+}
+
+func (d *Data) observedMoments() (mean, sd float64) {
+	for i, c := range d.Observed {
+		mean += float64(d.ScoreMin+i) * c / d.ObservedTotal
+	}
+	for i, c := range d.Observed {
+		dev := float64(d.ScoreMin+i) - mean
+		sd += dev * dev * c / d.ObservedTotal
+	}
+	return mean, math.Max(math.Sqrt(sd), 1)
+}
+
+// fitShape maximises the likelihood of s from its best starting point and
+// attaches Wald intervals.
+func (d *Data) fitShape(base sizeselect.Config, s shape) (Result, error) {
+	config := func(u []float64) sizeselect.Config {
+		cfg := base
+		cfg.Model = s.model
+		for i, p := range s.params {
+			p.set(&cfg, p.value(u[i]))
+		}
+		return cfg
+	}
+	objective := func(u []float64) float64 { return d.negLogLikelihood(config(u)) }
+
+	var best []float64
+	bestVal := math.Inf(1)
+	for _, start := range s.starts {
+		u := make([]float64, len(start))
+		for i, p := range s.params {
+			u[i] = p.coord(start[i])
+		}
+		if v := objective(u); v < bestVal {
+			best, bestVal = u, v
+		}
+	}
+	if best == nil {
+		return Result{}, errors.New("no valid starting point")
+	}
+	// Restarting from the first optimum guards against a collapsed simplex.
+	best, _ = minimize(objective, best, s.steps)
+	best, _ = minimize(objective, best, s.steps)
+
+	r, err := d.evaluate(config(best), len(s.params))
+	if err != nil {
+		return Result{}, err
+	}
+	cov, ok := invert(hessian(objective, best))
+	for i, p := range s.params {
+		fitted := Param{Name: p.name, Value: p.value(best[i])}
+		if ok && cov[i][i] > 0 && !math.IsInf(cov[i][i], 0) {
+			se := math.Sqrt(cov[i][i])
+			a, b := p.value(best[i]-z95*se), p.value(best[i]+z95*se)
+			fitted.CI95 = &[2]float64{math.Min(a, b), math.Max(a, b)}
+		}
+		r.Params = append(r.Params, fitted)
+	}
+	return r, nil
+}
+
+// empiricalCurve is the piecewise maximum-likelihood recovery curve: in each
+// bin with predicted fragments, the weight is observed / predicted, scaled to
+// a peak of 1. Points sit at bin centres, with the edge values extended to
+// the ends of the score range. bins counts the fitted bins.
+func (d *Data) empiricalCurve(bin int) (curve []sizeselect.CurvePoint, bins int) {
+	peak := 0.0
+	for start := 0; start < len(d.Predicted); start += bin {
+		end := min(start+bin, len(d.Predicted))
+		pred, obs := 0.0, 0.0
+		for i := start; i < end; i++ {
+			pred += d.Predicted[i]
+			obs += d.Observed[i]
+		}
+		if pred == 0 {
+			continue
+		}
+		w := obs / pred
+		peak = math.Max(peak, w)
+		curve = append(curve, sizeselect.CurvePoint{Length: float64(d.ScoreMin) + float64(start+end-1)/2, Weight: w})
+	}
+	if len(curve) == 0 || peak == 0 {
+		return nil, 0
+	}
+	bins = len(curve)
+	for i := range curve {
+		curve[i].Weight /= peak
+	}
+	if first := curve[0]; first.Length > float64(d.ScoreMin) {
+		curve = append([]sizeselect.CurvePoint{{Length: float64(d.ScoreMin), Weight: first.Weight}}, curve...)
+	}
+	if last := curve[len(curve)-1]; last.Length < float64(d.ScoreMax) {
+		curve = append(curve, sizeselect.CurvePoint{Length: float64(d.ScoreMax), Weight: last.Weight})
+	}
+	return curve, bins
+}
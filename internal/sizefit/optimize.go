@@ -0,0 +1,154 @@
+package sizefit
+
+import (
+	"math"
+	"sort"
+)
+
+// minimize runs Nelder-Mead from x0 with the given initial simplex steps and
+// returns the best point found and its value.
+func minimize(f func([]float64) float64, x0, steps []float64) ([]float64, float64) {
+	n := len(x0)
+	pts := make([][]float64, n+1)
+	vals := make([]float64, n+1)
+	for i := range pts {
+		pts[i] = append([]float64(nil), x0...)
+		if i > 0 {
+			pts[i][i-1] += steps[i-1]
+		}
+		vals[i] = f(pts[i])
+	}
+	point := func(from, to []float64, t float64) []float64 {
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = from[i] + t*(to[i]-from[i])
+		}
+		return out
+	}
+	order := make([]int, n+1)
+	for iter := 0; iter < 500*n; iter++ {
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return vals[order[a]] < vals[order[b]] })
+		sortedPts, sortedVals := make([][]float64, n+1), make([]float64, n+1)
+		for i, j := range order {
+			sortedPts[i], sortedVals[i] = pts[j], vals[j]
+		}
+		pts, vals = sortedPts, sortedVals
+		if math.Abs(vals[n]-vals[0]) <= 1e-10*(1+math.Abs(vals[0])) {
+			break
+		}
+
+		centroid := make([]float64, n)
+		for _, p := range pts[:n] {
+			for i := range centroid {
+				centroid[i] += p[i] / float64(n)
+			}
+		}
+		worst := pts[n]
+		reflected := point(centroid, worst, -1)
+		fr := f(reflected)
+		switch {
+		case fr < vals[0]:
+			expanded := point(centroid, worst, -2)
+			if fe := f(expanded); fe < fr {
+				pts[n], vals[n] = expanded, fe
+			} else {
+				pts[n], vals[n] = reflected, fr
+			}
+		case fr < vals[n-1]:
+			pts[n], vals[n] = reflected, fr
+		default:
+			contracted := point(centroid, worst, 0.5)
+			if fr < vals[n] {
+				contracted = point(centroid, reflected, 0.5)
+			}
+			if fc := f(contracted); fc < math.Min(fr, vals[n]) {
+				pts[n], vals[n] = contracted, fc
+				continue
+			}
+			for i := 1; i <= n; i++ {
+				pts[i] = point(pts[0], pts[i], 0.5)
+				vals[i] = f(pts[i])
+			}
+		}
+	}
+	best := 0
+	for i := range vals {
+		if vals[i] < vals[best] {
+			best = i
+		}
+	}
+	return pts[best], vals[best]
+}
+
+// hessian estimates the second derivatives of f at x by central differences.
+func hessian(f func([]float64) float64, x []float64) [][]float64 {
+	n := len(x)
+	h := make([]float64, n)
+	for i := range h {
+		h[i] = 1e-3 * math.Max(1, math.Abs(x[i]))
+	}
+	eval := func(i int, si float64, j int, sj float64) float64 {
+		p := append([]float64(nil), x...)
+		p[i] += si * h[i]
+		p[j] += sj * h[j]
+		return f(p)
+	}
+	f0 := f(x)
+	out := make([][]float64, n)
+	for i := range out {
+		out[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		out[i][i] = (eval(i, 1, i, 0) - 2*f0 + eval(i, -1, i, 0)) / (h[i] * h[i])
+		for j := 0; j < i; j++ {
+			v := (eval(i, 1, j, 1) - eval(i, 1, j, -1) - eval(i, -1, j, 1) + eval(i, -1, j, -1)) / (4 * h[i] * h[j])
+			out[i][j], out[j][i] = v, v
+		}
+	}
+	return out
+}
+
+// invert returns the inverse of a by Gauss-Jordan elimination, or false when
+// a is singular or not finite.
+func invert(a [][]float64) ([][]float64, bool) {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, 2*n)
+		copy(m[i], a[i])
+		m[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if v := m[pivot][col]; v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		scale := m[col][col]
+		for k := range m[col] {
+			m[col][k] /= scale
+		}
+		for r := 0; r < n; r++ {
+			if r == col || m[r][col] == 0 {
+				continue
+			}
+			factor := m[r][col]
+			for k := range m[r] {
+				m[r][k] -= factor * m[col][k]
+			}
+		}
+	}
+	out := make([][]float64, n)
+	for i := range out {
+		out[i] = m[i][n:]
+	}
+	return out, true
+}
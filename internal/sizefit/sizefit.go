@@ -0,0 +1,173 @@
+// Package sizefit fits size-selection models to observed insert lengths by
+// maximum likelihood.
+//
+// An observed insert of length l is modelled as a draw from the predicted
+// fragment lengths weighted by the model, so P(l) is proportional to
+// predicted(l) * weight(l) over the score range. A fitted model is therefore
+// an empirical recovery profile: it folds in PCR, sequencing, and mapping
+// effects and is not a pure wet-lab size-selection probability.
+package sizefit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Data holds predicted fragment and observed insert counts by length over the
+// score range. Lengths outside the range are ignored.
+type Data struct {
+	ScoreMin int
+	ScoreMax int
+	// Predicted and Observed are indexed by length - ScoreMin.
+	Predicted []float64
+	Observed  []float64
+	// ObservedTotal is the number of observed inserts in the range.
+	ObservedTotal float64
+}
+
+// NewData returns empty counts for lengths in [scoreMin, scoreMax].
+func NewData(scoreMin, scoreMax int) (*Data, error) {
+	if scoreMin < 1 || scoreMax < scoreMin {
+		return nil, fmt.Errorf("invalid score range %d-%d", scoreMin, scoreMax)
+	}
+	n := scoreMax - scoreMin + 1
+	return &Data{ScoreMin: scoreMin, ScoreMax: scoreMax, Predicted: make([]float64, n), Observed: make([]float64, n)}, nil
+}
+
+// AddPredicted counts one predicted fragment.
+func (d *Data) AddPredicted(length int) {
+	if length >= d.ScoreMin && length <= d.ScoreMax {
+		d.Predicted[length-d.ScoreMin]++
+	}
+}
+
+// AddObserved counts one observed insert.
+func (d *Data) AddObserved(length int) {
+	if length >= d.ScoreMin && length <= d.ScoreMax {
+		d.Observed[length-d.ScoreMin]++
+		d.ObservedTotal++
+	}
+}
+
+// ReadFragments counts the lengths of a radigest fragment TSV, plain or gzip.
+// Every row is counted whether or not it was hard-kept, so write the TSV with
+// a score range at least as wide as the one being fitted.
+func ReadFragments(path string, d *Data) error {
+	r, closer, err := open(path, nil)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	if err := ParseFragments(r, d); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// ParseFragments counts the lengths of fragment TSV text.
+func ParseFragments(r io.Reader, d *Data) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	lengthCol := -1
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if lengthCol < 0 {
+			for i, name := range cols {
+				if name == "length" {
+					lengthCol = i
+				}
+			}
+			if lengthCol < 0 {
+				return fmt.Errorf("not a radigest fragment TSV: missing 'length' column")
+			}
+			continue
+		}
+		if lengthCol >= len(cols) {
+			return fmt.Errorf("line %d: missing length column", lineNo)
+		}
+		length, err := strconv.Atoi(cols[lengthCol])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid length %q", lineNo, cols[lengthCol])
+		}
+		d.AddPredicted(length)
+	}
+	return sc.Err()
+}
+
+// ReadTLENs counts observed insert lengths, one per line, from path, plain or
+// gzip, or from stdin for "-".
+func ReadTLENs(path string, stdin io.Reader, d *Data) error {
+	r, closer, err := open(path, stdin)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	if err := ParseTLENs(r, d); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// ParseTLENs counts insert lengths from the first field of each line. SAM
+// TLEN signs are dropped, so column 9 can be used as is; zero lengths,
+// blank lines, and '#' comments are skipped.
+func ParseTLENs(r io.Reader, d *Data) error {
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		tlen, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid TLEN %q", lineNo, fields[0])
+		}
+		if tlen < 0 {
+			tlen = -tlen
+		}
+		if tlen > 0 {
+			d.AddObserved(tlen)
+		}
+	}
+	return sc.Err()
+}
+
+func open(path string, stdin io.Reader) (io.Reader, io.Closer, error) {
+	var src io.Reader
+	closer := io.NopCloser(nil)
+	if path == "-" {
+		if stdin == nil {
+			return nil, nil, fmt.Errorf("stdin reader is nil")
+		}
+		src = stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		src, closer = f, f
+	}
+	br := bufio.NewReader(src)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			_ = closer.Close()
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return gz, closer, nil
+	}
+	return br, closer, nil
+}
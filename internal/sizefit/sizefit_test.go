@@ -0,0 +1,172 @@
+package sizefit
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+// normalData predicts 20 fragments at every length and observes inserts in
+// proportion to a normal(mean, sd) recovery curve.
+func normalData(t *testing.T, mean, sd, pairs float64) *Data {
+	t.Helper()
+	return simulatedData(t, func(l float64) float64 {
+		z := (l - mean) / sd
+		return math.Exp(-0.5 * z * z)
+	}, pairs)
+}
+
+// simulatedData predicts 20 fragments at every length and observes inserts in
+// proportion to weight.
+func simulatedData(t *testing.T, weight func(float64) float64, pairs float64) *Data {
+	t.Helper()
+	d, err := NewData(1, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tsv, tlens strings.Builder
+	tsv.WriteString("chrom\tstart0\tend0\tlength\thard_kept\tsize_weight\n")
+	total := 0.0
+	for l := 1; l <= 1000; l++ {
+		total += weight(float64(l))
+	}
+	for l := 1; l <= 1000; l++ {
+		for i := 0; i < 20; i++ {
+			tsv.WriteString("chr1\t0\t0\t" + strconv.Itoa(l) + "\tfalse\t0\n")
+		}
+		for i := 0; i < int(math.Round(pairs*weight(float64(l))/total)); i++ {
+			if i%2 == 1 {
+				tlens.WriteString("-")
+			}
+			tlens.WriteString(strconv.Itoa(l) + "\n")
+		}
+	}
+	if err := ParseFragments(strings.NewReader(tsv.String()), d); err != nil {
+		t.Fatal(err)
+	}
+	if err := ParseTLENs(strings.NewReader("# samtools view | cut -f9\n0\n"+tlens.String()), d); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestFitRecoversNormalWithIntervals(t *testing.T) {
+	d := normalData(t, 420, 60, 50000)
+	results, err := Fit(d, Options{Min: 300, Max: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 8 {
+		t.Fatalf("got %d results, want every model but empirical", len(results))
+	}
+	best := results[0]
+	if best.Model != sizeselect.ModelNormal || best.DeltaAIC != 0 || best.K != 2 {
+		t.Fatalf("best = %+v, want normal with 2 parameters", best)
+	}
+	for i, want := range []float64{420, 60} {
+		p := best.Params[i]
+		if math.Abs(p.Value-want) > 1 {
+			t.Fatalf("%s = %g, want about %g", p.Name, p.Value, want)
+		}
+		if p.CI95 == nil || p.CI95[0] > p.Value || p.CI95[1] < p.Value || p.CI95[1]-p.CI95[0] > 5 {
+			t.Fatalf("%s CI95 = %v around %g", p.Name, p.CI95, p.Value)
+		}
+	}
+	if best.KS > 0.01 || math.Abs(best.PredictedMean-best.ObservedMean) > 1 {
+		t.Fatalf("normal fit KS=%g pred_mean=%g obs_mean=%g", best.KS, best.PredictedMean, best.ObservedMean)
+	}
+	for _, r := range results[1:] {
+		if r.DeltaAIC <= 0 || r.AIC != best.AIC+r.DeltaAIC {
+			t.Fatalf("%s delta AIC = %g (AIC %g, best %g)", r.Model, r.DeltaAIC, r.AIC, best.AIC)
+		}
+	}
+
+	var table bytes.Buffer
+	if err := WriteTable(&table, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 9 || !strings.HasPrefix(lines[1], "1\tnormal\tsize_mean=420.") || !strings.Contains(lines[1], "\tsize_mean=4") {
+		t.Fatalf("unexpected table:\n%s", table.String())
+	}
+}
+
+func TestFitPrefersSkewedShapesForSkewedInserts(t *testing.T) {
+	// Gamma with mean 400 and SD 100: shape 16, scale 25.
+	d := simulatedData(t, func(l float64) float64 { return math.Exp(15*math.Log(l) - l/25 - 15*math.Log(375) + 15) }, 50000)
+	results, err := Fit(d, Options{Min: 300, Max: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aic := make(map[sizeselect.Model]Result)
+	for _, r := range results {
+		aic[r.Model] = r
+	}
+	gamma := aic[sizeselect.ModelGamma]
+	if gamma.AIC >= aic[sizeselect.ModelNormal].AIC || aic[sizeselect.ModelSkewNormal].AIC >= aic[sizeselect.ModelNormal].AIC {
+		t.Fatalf("skewed shapes should beat normal: gamma=%g skew-normal=%g normal=%g", gamma.AIC, aic[sizeselect.ModelSkewNormal].AIC, aic[sizeselect.ModelNormal].AIC)
+	}
+	if math.Abs(gamma.Params[0].Value-400) > 2 || math.Abs(gamma.Params[1].Value-100) > 2 {
+		t.Fatalf("gamma params = %+v, want mean 400 sd 100", gamma.Params)
+	}
+}
+
+func TestModelFileLoadsInSelector(t *testing.T) {
+	d := normalData(t, 420, 60, 20000)
+	results, err := Fit(d, Options{Min: 300, Max: 600, CurveBin: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, r := range results {
+		var buf bytes.Buffer
+		if err := WriteModelFile(&buf, r); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, string(r.Model)+".json")
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := sizeselect.Config{Model: sizeselect.ModelHard, Min: 300, Max: 600, ScoreMin: 1, ScoreMax: 1000}.WithModelFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", r.Model, err)
+		}
+		sel, err := sizeselect.New(cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", r.Model, err)
+		}
+		fitted, err := sizeselect.New(r.Config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range []int{150, 300, 420, 555, 900} {
+			if got, want := sel.Weight(l), fitted.Weight(l); math.Abs(got-want) > 1e-9 {
+				t.Fatalf("%s weight(%d) = %g after reload, want %g", r.Model, l, got, want)
+			}
+		}
+		if st := sizeselect.NewStats(sel); st.ConfigPath != path {
+			t.Fatalf("%s stats config path = %q", r.Model, st.ConfigPath)
+		}
+	}
+
+	var empirical *Result
+	for i := range results {
+		if results[i].Model == sizeselect.ModelEmpirical {
+			empirical = &results[i]
+		}
+	}
+	if empirical == nil || empirical.K != 19 || empirical.KS > 0.03 {
+		t.Fatalf("empirical fit missing or poor: %+v", empirical)
+	}
+
+	path := filepath.Join(dir, string(sizeselect.ModelSoftWindow)+".json")
+	if _, err := (sizeselect.Config{Min: 250, Max: 600}).WithModelFile(path); err == nil || !strings.Contains(err.Error(), "fitted for -min 300 -max 600") {
+		t.Fatalf("window mismatch error = %v", err)
+	}
+}
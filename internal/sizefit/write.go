@@ -0,0 +1,82 @@
+package sizefit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ericksamera/radigest/internal/sizeselect"
+)
+
+// WriteTable writes the ranked fit results as TSV.
+func WriteTable(w io.Writer, results []Result) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString("rank\tmodel\tparams\tci95\tk\tlog_likelihood\taic\tdelta_aic\tks\tobs_mean\tpred_mean\tobs_pairs\n"); err != nil {
+		return err
+	}
+	for i, r := range results {
+		params, cis := make([]string, 0, len(r.Params)), make([]string, 0, len(r.Params))
+		for _, p := range r.Params {
+			params = append(params, p.Name+"="+formatFloat(p.Value))
+			if p.CI95 != nil {
+				cis = append(cis, p.Name+"="+formatFloat(p.CI95[0])+".."+formatFloat(p.CI95[1]))
+			}
+		}
+		if r.Model == sizeselect.ModelEmpirical {
+			params = append(params, "size_curve_points="+strconv.Itoa(len(r.Config.Curve)))
+		}
+		if _, err := fmt.Fprintf(bw, "%d\t%s\t%s\t%s\t%d\t%.3f\t%.3f\t%.3f\t%.6f\t%.2f\t%.2f\t%d\n",
+			i+1, r.Model, joinOrDot(params), joinOrDot(cis), r.K, r.LogLikelihood, r.AIC, r.DeltaAIC, r.KS, r.ObservedMean, r.PredictedMean, r.ObservedPairs); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func joinOrDot(fields []string) string {
+	if len(fields) == 0 {
+		return "."
+	}
+	return strings.Join(fields, ",")
+}
+
+// ModelFile returns the size-model file for r.
+func ModelFile(r Result) sizeselect.ModelFile {
+	f := sizeselect.ModelFile{Model: r.Model, Min: r.Config.Min, Max: r.Config.Max}
+	switch r.Model {
+	case sizeselect.ModelNormal, sizeselect.ModelLogNormal, sizeselect.ModelGamma:
+		f.Mean, f.SD = r.Config.Mean, r.Config.SD
+	case sizeselect.ModelSkewNormal:
+		f.Mean, f.SD, f.Skew = r.Config.Mean, r.Config.SD, r.Config.Skew
+	case sizeselect.ModelMixture:
+		f.Mean, f.SD = r.Config.Mean, r.Config.SD
+		f.Mean2, f.SD2, f.Mix = r.Config.Mean2, r.Config.SD2, r.Config.Mix
+	case sizeselect.ModelTriangular:
+		f.Mean = r.Config.Mean
+	case sizeselect.ModelSoftWindow:
+		f.EdgeSD = r.Config.EdgeSD
+	case sizeselect.ModelEmpirical:
+		f.Curve = r.Config.Curve
+	}
+	return f
+}
+
+// WriteModelFile writes r as a size-model JSON file that radigest and
+// radigest-design load with -size-config. The fit statistics are recorded
+// under "fit".
+func WriteModelFile(w io.Writer, r Result) error {
+	doc := struct {
+		sizeselect.ModelFile
+		Fit Result `json:"fit"`
+	}{ModelFile(r), r}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
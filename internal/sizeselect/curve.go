@@ -0,0 +1,156 @@
+package sizeselect
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CurvePoint is one length-to-weight point of an empirical size curve, such
+// as a Pippin, BluePippin, or gel calibration.
+type CurvePoint struct {
+	Length float64 `json:"length"`
+	Weight float64 `json:"weight"`
+}
+
+// ReadCurve loads an empirical size curve. JSON input is an array of
+// {"length", "weight"} objects, or an object holding that array under
+// "curve". Any other input is read as whitespace- or tab-separated length and
+// weight columns; '#' comments and a non-numeric header line are skipped.
+func ReadCurve(path string) ([]CurvePoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var points []CurvePoint
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		points, err = parseCurveJSON(trimmed)
+	} else {
+		points, err = parseCurveTable(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return points, nil
+}
+
+func parseCurveJSON(data []byte) ([]CurvePoint, error) {
+	var points []CurvePoint
+	if data[0] == '{' {
+		var doc struct {
+			Curve []CurvePoint `json:"curve"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		points = doc.Curve
+	} else if err := json.Unmarshal(data, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func parseCurveTable(data []byte) ([]CurvePoint, error) {
+	rows, err := parseColumns(data, "length", "weight")
+	if err != nil {
+		return nil, err
+	}
+	points := make([]CurvePoint, len(rows))
+	for i, row := range rows {
+		points[i] = CurvePoint{Length: row[0], Weight: row[1]}
+	}
+	return points, nil
+}
+
+// parseColumns reads the first two numeric columns of a whitespace- or
+// tab-separated table, skipping '#' comments and a non-numeric header line.
+func parseColumns(data []byte, first, second string) ([][2]float64, error) {
+	var rows [][2]float64
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected %s and %s columns", lineNo, first, second)
+		}
+		a, err1 := strconv.ParseFloat(fields[0], 64)
+		b, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 != nil || err2 != nil {
+			if len(rows) == 0 && err1 != nil {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid %s/%s %q %q", lineNo, first, second, fields[0], fields[1])
+		}
+		rows = append(rows, [2]float64{a, b})
+	}
+	return rows, sc.Err()
+}
+
+// validateCurve checks an empirical curve and returns its peak weight.
+func validateCurve(points []CurvePoint) (float64, error) {
+	if len(points) < 2 {
+		return 0, fmt.Errorf("-size-curve needs at least 2 points for -size-model empirical (got %d)", len(points))
+	}
+	peak := 0.0
+	for i, p := range points {
+		if !finite(p.Length) || p.Length < 0 || !finite(p.Weight) || p.Weight < 0 {
+			return 0, fmt.Errorf("-size-curve point %d must have finite, non-negative length and weight (got %g, %g)", i+1, p.Length, p.Weight)
+		}
+		if i > 0 && p.Length <= points[i-1].Length {
+			return 0, fmt.Errorf("-size-curve lengths must be strictly increasing (point %d: %g after %g)", i+1, p.Length, points[i-1].Length)
+		}
+		peak = math.Max(peak, p.Weight)
+	}
+	if peak == 0 {
+		return 0, fmt.Errorf("-size-curve weights are all zero")
+	}
+	return peak, nil
+}
+
+// curveWeight interpolates points linearly at l and scales by peak so the
+// highest point has weight 1. Lengths outside the curve have weight 0.
+func curveWeight(points []CurvePoint, peak, l float64) float64 {
+	i := sort.Search(len(points), func(i int) bool { return points[i].Length >= l })
+	switch {
+	case i == len(points):
+		return 0
+	case points[i].Length == l:
+		return points[i].Weight / peak
+	case i == 0:
+		return 0
+	}
+	a, b := points[i-1], points[i]
+	t := (l - a.Length) / (b.Length - a.Length)
+	return (a.Weight + t*(b.Weight-a.Weight)) / peak
+}
+
+// WithCurve returns cfg with the empirical curve at path loaded. The curve
+// flag and -size-model empirical must be used together.
+func (cfg Config) WithCurve(path string) (Config, error) {
+	empirical := Model(strings.ToLower(strings.TrimSpace(string(cfg.Model)))) == ModelEmpirical
+	switch {
+	case path == "" && empirical:
+		return cfg, fmt.Errorf("-size-model empirical requires -size-curve")
+	case path == "":
+		return cfg, nil
+	case !empirical:
+		return cfg, fmt.Errorf("-size-curve requires -size-model empirical (got %q)", cfg.Model)
+	}
+	points, err := ReadCurve(path)
+	if err != nil {
+		return cfg, fmt.Errorf("-size-curve: %w", err)
+	}
+	cfg.Curve, cfg.CurvePath = points, path
+	return cfg, nil
+}
@@ -0,0 +1,125 @@
+package sizeselect
+
+import (
+	"math"
+	"sort"
+)
+
+// histogramBinsPerDoubling sets the width of coarse histogram bins: each is
+// 1/histogramBinsPerDoubling of a doubling of length wide.
+const histogramBinsPerDoubling = 16
+
+// HistogramBin counts the fragments with lengths in [Start, End).
+type HistogramBin struct {
+	Start     int   `json:"start"`
+	End       int   `json:"end"`
+	Fragments int   `json:"fragments"`
+	Bases     int64 `json:"bases"`
+}
+
+// Histogram counts fragment lengths in 1 bp bins up to FineMax and in
+// log-spaced coarse bins beyond, so any Selector can be evaluated without
+// re-digesting. Counts[l] is the number of fragments l bp long.
+type Histogram struct {
+	FineMax int            `json:"fine_max"`
+	Counts  []int          `json:"counts"`
+	Coarse  []HistogramBin `json:"coarse,omitempty"`
+}
+
+// NewHistogram returns an empty histogram with 1 bp bins up to fineMax.
+func NewHistogram(fineMax int) *Histogram {
+	if fineMax < 0 {
+		fineMax = 0
+	}
+	return &Histogram{FineMax: fineMax, Counts: make([]int, fineMax+1)}
+}
+
+// Add counts one fragment of length bp; negative lengths are ignored.
+func (h *Histogram) Add(length int) {
+	if length < 0 {
+		return
+	}
+	if length <= h.FineMax {
+		h.Counts[length]++
+		return
+	}
+	start, end := h.coarseBounds(length)
+	i := sort.Search(len(h.Coarse), func(i int) bool { return h.Coarse[i].Start >= start })
+	if i == len(h.Coarse) || h.Coarse[i].Start != start {
+		h.Coarse = append(h.Coarse, HistogramBin{})
+		copy(h.Coarse[i+1:], h.Coarse[i:])
+		h.Coarse[i] = HistogramBin{Start: start, End: end}
+	}
+	h.Coarse[i].Fragments++
+	h.Coarse[i].Bases += int64(length)
+}
+
+// coarseBounds returns the coarse bin holding length, which must exceed
+// FineMax.
+func (h *Histogram) coarseBounds(length int) (int, int) {
+	base := float64(h.FineMax + 1)
+	lower := func(k int) int {
+		return int(math.Ceil(base * math.Exp2(float64(k)/histogramBinsPerDoubling)))
+	}
+	k := int(math.Floor(histogramBinsPerDoubling * math.Log2(float64(length)/base)))
+	for lower(k) > length {
+		k--
+	}
+	for lower(k+1) <= length {
+		k++
+	}
+	return lower(k), lower(k + 1)
+}
+
+// Fragments returns the number of fragments counted.
+func (h *Histogram) Fragments() int {
+	total := 0
+	for _, n := range h.Counts {
+		total += n
+	}
+	for _, b := range h.Coarse {
+		total += b.Fragments
+	}
+	return total
+}
+
+// Stats evaluates s over the counted fragments. Lengths up to FineMax are
+// exact; a coarse bin is scored at its mean length, so Stats matches a
+// direct digest only when s's InsertRange ends at or below FineMax.
+// Per-fragment weights a histogram cannot hold, such as amplification and
+// ambiguity weights, are left out, as are selection lengths that depend on
+// a fragment's sequence, such as library molecules built from a barcode
+// sheet.
+func (h *Histogram) Stats(s Selector) Stats {
+	st := NewStats(s)
+	for length, n := range h.Counts {
+		if n > 0 {
+			st.addBin(s, length, n, int64(length)*int64(n))
+		}
+	}
+	for _, b := range h.Coarse {
+		if b.Fragments > 0 {
+			length := int(math.Round(float64(b.Bases) / float64(b.Fragments)))
+			st.addBin(s, length, b.Fragments, b.Bases)
+		}
+	}
+	if st.WeightedFragments > 0 {
+		st.MeanWeightedLength = st.WeightedBases / st.WeightedFragments
+	}
+	return st
+}
+
+// addBin adds n fragments of one length totalling bases.
+func (st *Stats) addBin(s Selector, length, n int, bases int64) {
+	if s.InHardWindow(length) {
+		st.RawFragmentsInWindow += n
+		st.RawBasesInWindow += bases
+	}
+	if s.InScoreRange(length) {
+		weight := s.Weight(length)
+		st.RawFragmentsScored += n
+		st.RawBasesScored += bases
+		st.WeightedFragments += weight * float64(n)
+		st.WeightedBases += weight * float64(bases)
+	}
+}
@@ -0,0 +1,71 @@
+package sizeselect
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ModelFile is a size model saved as JSON, as written by
+// radigest-fit-size-model. Min and Max record the hard window the model was
+// fitted against. Unknown keys, such as the fitter's "fit" summary, are
+// ignored when loading.
+type ModelFile struct {
+	Model  Model        `json:"size_model"`
+	Min    int          `json:"min"`
+	Max    int          `json:"max"`
+	Mean   float64      `json:"size_mean,omitempty"`
+	SD     float64      `json:"size_sd,omitempty"`
+	EdgeSD float64      `json:"size_edge_sd,omitempty"`
+	Skew   float64      `json:"size_skew,omitempty"`
+	Mean2  float64      `json:"size_mean2,omitempty"`
+	SD2    float64      `json:"size_sd2,omitempty"`
+	Mix    float64      `json:"size_mix,omitempty"`
+	Curve  []CurvePoint `json:"size_curve,omitempty"`
+}
+
+// ReadModelFile loads a size-model JSON file.
+func ReadModelFile(path string) (ModelFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ModelFile{}, err
+	}
+	var f ModelFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return ModelFile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	f.Model = Model(strings.ToLower(strings.TrimSpace(string(f.Model))))
+	if f.Model == "" {
+		return ModelFile{}, fmt.Errorf("%s: missing size_model", path)
+	}
+	return f, nil
+}
+
+// WithModelFile returns cfg with its model and shape parameters replaced by
+// the size-model file at path; an empty path leaves cfg unchanged. Hard,
+// triangular, and soft-window weights depend on the hard window, so those
+// models must be used with the -min/-max they were fitted against.
+func (cfg Config) WithModelFile(path string) (Config, error) {
+	if path == "" {
+		return cfg, nil
+	}
+	f, err := ReadModelFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("-size-config: %w", err)
+	}
+	switch f.Model {
+	case ModelHard, ModelTriangular, ModelSoftWindow:
+		if f.Min != cfg.Min || f.Max != cfg.Max {
+			return cfg, fmt.Errorf("-size-config %s holds a %s model fitted for -min %d -max %d (got -min %d -max %d)", path, f.Model, f.Min, f.Max, cfg.Min, cfg.Max)
+		}
+	}
+	cfg.Model, cfg.Mean, cfg.SD, cfg.EdgeSD = f.Model, f.Mean, f.SD, f.EdgeSD
+	cfg.Skew, cfg.Mean2, cfg.SD2, cfg.Mix = f.Skew, f.Mean2, f.SD2, f.Mix
+	cfg.Curve, cfg.CurvePath = f.Curve, ""
+	if f.Model == ModelEmpirical {
+		cfg.CurvePath = path
+	}
+	cfg.ConfigPath = path
+	return cfg, nil
+}
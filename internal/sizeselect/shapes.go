@@ -0,0 +1,151 @@
+package sizeselect
+
+import (
+	"fmt"
+	"math"
+)
+
+// The log-normal, skew-normal, gamma, and mixture models weight lengths by a
+// density scaled so its mode has weight 1.
+//
+//   - log-normal and gamma: Mean and SD are the mean and standard deviation
+//     of the curve itself, so they read like -size-model normal but put the
+//     longer tail on the right.
+//   - skew-normal: Mean is the location, SD the scale, and Skew the shape
+//     alpha; positive Skew leans right, and 0 is the normal model.
+//   - mixture: a normal (Mean, SD) and a second normal (Mean2, SD2) carrying
+//     Mix of the total mass, such as a main peak with a short-insert
+//     shoulder.
+
+func validateShape(cfg Config) error {
+	switch cfg.Model {
+	case ModelLogNormal, ModelGamma:
+		if !finitePositive(cfg.Mean) {
+			return fmt.Errorf("-size-mean must be > 0 for -size-model %s (got %g)", cfg.Model, cfg.Mean)
+		}
+		if !finitePositive(cfg.SD) {
+			return fmt.Errorf("-size-sd must be > 0 for -size-model %s (got %g)", cfg.Model, cfg.SD)
+		}
+		if cfg.Model == ModelGamma && cfg.SD >= cfg.Mean {
+			return fmt.Errorf("-size-sd must be < -size-mean for -size-model gamma so the curve has a peak (got mean=%g sd=%g)", cfg.Mean, cfg.SD)
+		}
+	case ModelSkewNormal:
+		if !finite(cfg.Mean) {
+			return fmt.Errorf("-size-mean must be finite for -size-model skew-normal (got %g)", cfg.Mean)
+		}
+		if !finitePositive(cfg.SD) {
+			return fmt.Errorf("-size-sd must be > 0 for -size-model skew-normal (got %g)", cfg.SD)
+		}
+		if !finite(cfg.Skew) {
+			return fmt.Errorf("-size-skew must be finite for -size-model skew-normal (got %g)", cfg.Skew)
+		}
+	case ModelMixture:
+		if !finite(cfg.Mean) || !finitePositive(cfg.SD) {
+			return fmt.Errorf("-size-mean must be finite and -size-sd > 0 for -size-model mixture (got mean=%g sd=%g)", cfg.Mean, cfg.SD)
+		}
+		if !finitePositive(cfg.Mean2) || !finitePositive(cfg.SD2) {
+			return fmt.Errorf("-size-mean2 and -size-sd2 must be > 0 for -size-model mixture (got mean2=%g sd2=%g)", cfg.Mean2, cfg.SD2)
+		}
+		if !(cfg.Mix > 0 && cfg.Mix < 1) {
+			return fmt.Errorf("-size-mix must be in (0,1) for -size-model mixture (got %g)", cfg.Mix)
+		}
+	}
+	return nil
+}
+
+// logNormalParams converts a mean and SD to the log-scale mu and sigma.
+func logNormalParams(mean, sd float64) (mu, sigma float64) {
+	s2 := math.Log1p(sd * sd / (mean * mean))
+	return math.Log(mean) - s2/2, math.Sqrt(s2)
+}
+
+// gammaParams converts a mean and SD to the shape k and scale theta.
+func gammaParams(mean, sd float64) (k, theta float64) {
+	return mean * mean / (sd * sd), sd * sd / mean
+}
+
+// logDensity is the log of the unnormalized curve of the density shapes.
+func (cfg Config) logDensity(l float64) float64 {
+	switch cfg.Model {
+	case ModelLogNormal:
+		if l <= 0 {
+			return math.Inf(-1)
+		}
+		mu, sigma := logNormalParams(cfg.Mean, cfg.SD)
+		z := (math.Log(l) - mu) / sigma
+		return -math.Log(l) - z*z/2
+	case ModelGamma:
+		if l <= 0 {
+			return math.Inf(-1)
+		}
+		k, theta := gammaParams(cfg.Mean, cfg.SD)
+		return (k-1)*math.Log(l) - l/theta
+	case ModelSkewNormal:
+		z := (l - cfg.Mean) / cfg.SD
+		// log Phi(alpha z), with Phi(x) = erfc(-x/sqrt2)/2.
+		return -z*z/2 + math.Log(math.Erfc(-cfg.Skew*z/math.Sqrt2)/2)
+	case ModelMixture:
+		z1 := (l - cfg.Mean) / cfg.SD
+		z2 := (l - cfg.Mean2) / cfg.SD2
+		return math.Log((1-cfg.Mix)/cfg.SD*math.Exp(-z1*z1/2) + cfg.Mix/cfg.SD2*math.Exp(-z2*z2/2))
+	case ModelSPRI:
+		if l <= 0 {
+			return math.Inf(-1)
+		}
+		lower, upper := cfg.SPRICutoffs()
+		x := math.Log(l)
+		return logSigmoid((x-math.Log(lower))/spriLogScale) + logSigmoid((math.Log(upper)-x)/spriLogScale)
+	}
+	return math.Inf(-1)
+}
+
+// mode returns the length at which logDensity peaks.
+func (cfg Config) mode() float64 {
+	switch cfg.Model {
+	case ModelLogNormal:
+		mu, sigma := logNormalParams(cfg.Mean, cfg.SD)
+		return math.Exp(mu - sigma*sigma)
+	case ModelGamma:
+		k, theta := gammaParams(cfg.Mean, cfg.SD)
+		return (k - 1) * theta
+	case ModelSkewNormal:
+		// The skew-normal density is log-concave with its mode within
+		// two scales of the location.
+		return cfg.argmax(cfg.Mean-2*cfg.SD, cfg.Mean+2*cfg.SD)
+	case ModelMixture:
+		// Every mode of a two-normal mixture lies between the means.
+		return cfg.argmax(math.Min(cfg.Mean, cfg.Mean2), math.Max(cfg.Mean, cfg.Mean2))
+	case ModelSPRI:
+		// Kept × not-removed peaks between the two cutoffs.
+		lower, upper := cfg.SPRICutoffs()
+		return cfg.argmax(lower, upper)
+	}
+	return cfg.Mean
+}
+
+// argmax scans [lo, hi] for the highest logDensity and refines the best
+// bracket by golden-section search.
+func (cfg Config) argmax(lo, hi float64) float64 {
+	const steps = 200
+	step := (hi - lo) / steps
+	best := lo
+	for i := 1; i <= steps; i++ {
+		if x := lo + float64(i)*step; cfg.logDensity(x) > cfg.logDensity(best) {
+			best = x
+		}
+	}
+	a, b := best-step, best+step
+	ratio := (math.Sqrt(5) - 1) / 2
+	for i := 0; i < 60 && b-a > 1e-9; i++ {
+		c, d := b-ratio*(b-a), a+ratio*(b-a)
+		if cfg.logDensity(c) > cfg.logDensity(d) {
+			b = d
+		} else {
+			a = c
+		}
+	}
+	if x := (a + b) / 2; cfg.logDensity(x) > cfg.logDensity(best) {
+		return x
+	}
+	return best
+}
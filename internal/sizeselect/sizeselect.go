@@ -3,6 +3,8 @@ package sizeselect
 import (
 	"fmt"
 	"math"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +15,14 @@ const (
 	ModelNormal     Model = "normal"
 	ModelTriangular Model = "triangular"
 	ModelSoftWindow Model = "soft-window"
+	ModelEmpirical  Model = "empirical"
+	ModelLogNormal  Model = "log-normal"
+	ModelSkewNormal Model = "skew-normal"
+	ModelGamma      Model = "gamma"
+	// ModelMixture is a two-component normal mixture.
+	ModelMixture Model = "mixture"
+	// ModelSPRI is a double-sided SPRI bead cleanup.
+	ModelSPRI Model = "spri"
 )
 
 type Config struct {
@@ -24,10 +34,60 @@ type Config struct {
 	Mean     float64 `json:"mean,omitempty"`
 	SD       float64 `json:"sd,omitempty"`
 	EdgeSD   float64 `json:"edge_sd,omitempty"`
+	// Skew is the skew-normal shape (alpha); Mean2, SD2, and Mix describe
+	// the second mixture component and its share of the mixture.
+	Skew  float64 `json:"skew,omitempty"`
+	Mean2 float64 `json:"mean2,omitempty"`
+	SD2   float64 `json:"sd2,omitempty"`
+	Mix   float64 `json:"mix,omitempty"`
+	// Curve holds the points of -size-model empirical, as loaded, and
+	// CurvePath the file they came from.
+	Curve     []CurvePoint `json:"curve,omitempty"`
+	CurvePath string       `json:"curve_path,omitempty"`
+	// ConfigPath is the -size-config file the model came from, if any.
+	ConfigPath string `json:"config_path,omitempty"`
+	// SPRILeft and SPRIRight are the bead ratios of -size-model spri, and
+	// SPRICalibration maps ratios to cutoffs; empty means
+	// DefaultSPRICalibration.
+	SPRILeft            float64     `json:"spri_left,omitempty"`
+	SPRIRight           float64     `json:"spri_right,omitempty"`
+	SPRICalibration     []SPRIPoint `json:"spri_calibration,omitempty"`
+	SPRICalibrationPath string      `json:"spri_calibration_path,omitempty"`
+	// AdapterP1 and AdapterP2 are the adapter lengths ligated to each end,
+	// and BarcodeLengths the combined inline-barcode lengths of the pooled
+	// samples. When any is set, Min, Max, and the score range are library
+	// molecule lengths, while Selector methods still take insert lengths.
+	AdapterP1      int   `json:"adapter_p1_length,omitempty"`
+	AdapterP2      int   `json:"adapter_p2_length,omitempty"`
+	BarcodeLengths []int `json:"barcode_lengths,omitempty"`
+}
+
+// Flanks returns the bases each library molecule adds to its insert, one per
+// barcode length, or the adapters alone when there are no barcodes.
+func (cfg Config) Flanks() []int {
+	adapters := cfg.AdapterP1 + cfg.AdapterP2
+	if len(cfg.BarcodeLengths) == 0 {
+		return []int{adapters}
+	}
+	out := make([]int, len(cfg.BarcodeLengths))
+	for i, b := range cfg.BarcodeLengths {
+		out[i] = adapters + b
+	}
+	return out
+}
+
+// HasFlanks reports whether size selection runs on library length.
+func (cfg Config) HasFlanks() bool {
+	return cfg.AdapterP1 != 0 || cfg.AdapterP2 != 0 || len(cfg.BarcodeLengths) > 0
 }
 
 type Selector struct {
-	cfg Config
+	cfg    Config
+	flanks []int
+	// peak scales empirical curve weights to a maximum of 1; logPeak does
+	// the same for the log-density shapes.
+	peak    float64
+	logPeak float64
 }
 
 func New(cfg Config) (Selector, error) {
@@ -47,10 +107,19 @@ func New(cfg Config) (Selector, error) {
 	if cfg.ScoreMax < cfg.ScoreMin {
 		return Selector{}, fmt.Errorf("-score-max must be >= -score-min (got score-min=%d score-max=%d)", cfg.ScoreMin, cfg.ScoreMax)
 	}
+	if cfg.AdapterP1 < 0 || cfg.AdapterP2 < 0 {
+		return Selector{}, fmt.Errorf("adapter lengths must be >= 0 (got p1=%d p2=%d)", cfg.AdapterP1, cfg.AdapterP2)
+	}
+	for _, b := range cfg.BarcodeLengths {
+		if b < 0 {
+			return Selector{}, fmt.Errorf("barcode lengths must be >= 0 (got %d)", b)
+		}
+	}
 	if cfg.Mean == 0 {
 		cfg.Mean = float64(cfg.Min+cfg.Max) / 2
 	}
 
+	var peak, logPeak float64
 	switch cfg.Model {
 	case ModelHard:
 		// no additional parameters
@@ -69,11 +138,35 @@ func New(cfg Config) (Selector, error) {
 		if !finitePositive(cfg.EdgeSD) {
 			return Selector{}, fmt.Errorf("-size-edge-sd must be > 0 for -size-model soft-window (got %g)", cfg.EdgeSD)
 		}
+	case ModelEmpirical:
+		var err error
+		if peak, err = validateCurve(cfg.Curve); err != nil {
+			return Selector{}, err
+		}
+	case ModelLogNormal, ModelSkewNormal, ModelGamma, ModelMixture:
+		if err := validateShape(cfg); err != nil {
+			return Selector{}, err
+		}
+		logPeak = cfg.logDensity(cfg.mode())
+	case ModelSPRI:
+		if len(cfg.SPRICalibration) == 0 {
+			cfg.SPRICalibration = DefaultSPRICalibration
+		}
+		if err := validateSPRI(cfg); err != nil {
+			return Selector{}, err
+		}
+		logPeak = cfg.logDensity(cfg.mode())
 	default:
-		return Selector{}, fmt.Errorf("unknown -size-model %q; use hard, normal, triangular, or soft-window", cfg.Model)
+		return Selector{}, fmt.Errorf("unknown -size-model %q; use hard, normal, triangular, soft-window, log-normal, skew-normal, gamma, mixture, spri, or empirical", cfg.Model)
+	}
+	if cfg.Model != ModelEmpirical {
+		cfg.Curve, cfg.CurvePath = nil, ""
+	}
+	if cfg.Model != ModelSPRI {
+		cfg.SPRICalibration, cfg.SPRICalibrationPath = nil, ""
 	}
 
-	return Selector{cfg: cfg}, nil
+	return Selector{cfg: cfg, flanks: cfg.Flanks(), peak: peak, logPeak: logPeak}, nil
 }
 
 func finite(x float64) bool {
@@ -86,19 +179,67 @@ func finitePositive(x float64) bool {
 
 func (s Selector) Config() Config { return s.cfg }
 
+// InsertRange returns the insert lengths that can reach the hard window or
+// the score range once library flanks are added; callers digest this range.
+func (s Selector) InsertRange() (int, int) {
+	lo := min(s.cfg.Min, s.cfg.ScoreMin)
+	hi := max(s.cfg.Max, s.cfg.ScoreMax)
+	if !s.cfg.HasFlanks() {
+		return lo, hi
+	}
+	minFlank, maxFlank := slices.Min(s.flanks), slices.Max(s.flanks)
+	lo = max(1, lo-maxFlank)
+	return lo, max(lo, hi-minFlank)
+}
+
+// InScoreRange reports whether an insert of length bp is scored for any
+// library flank. With several barcode lengths this is looser than Weight,
+// which averages over them: see InHardWindow.
 func (s Selector) InScoreRange(length int) bool {
-	return length >= s.cfg.ScoreMin && length <= s.cfg.ScoreMax
+	for _, f := range s.flanks {
+		if l := length + f; l >= s.cfg.ScoreMin && l <= s.cfg.ScoreMax {
+			return true
+		}
+	}
+	return false
 }
 
+// InHardWindow reports whether an insert of length bp passes the hard window
+// for any library flank. Callers list and count such an insert as one kept
+// locus, since some barcode's molecules of it are kept, while Weight gives
+// it only the share of barcode lengths that pass: under the hard model, an
+// insert kept for one of four barcode lengths is hard-kept with weight 0.25.
 func (s Selector) InHardWindow(length int) bool {
+	for _, f := range s.flanks {
+		if s.inWindow(length + f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Selector) inWindow(length int) bool {
 	return length >= s.cfg.Min && length <= s.cfg.Max
 }
 
+// Weight returns the size-selection weight of an insert of length bp: the
+// model weight at its library length, averaged over barcode lengths.
 func (s Selector) Weight(length int) float64 {
+	if len(s.flanks) == 1 {
+		return s.weight(length + s.flanks[0])
+	}
+	total := 0.0
+	for _, f := range s.flanks {
+		total += s.weight(length + f)
+	}
+	return total / float64(len(s.flanks))
+}
+
+func (s Selector) weight(length int) float64 {
 	l := float64(length)
 	switch s.cfg.Model {
 	case ModelHard:
-		if s.InHardWindow(length) {
+		if s.inWindow(length) {
 			return 1
 		}
 		return 0
@@ -120,6 +261,10 @@ func (s Selector) Weight(length int) float64 {
 		left := sigmoid((l - float64(s.cfg.Min)) / s.cfg.EdgeSD)
 		right := sigmoid((float64(s.cfg.Max) - l) / s.cfg.EdgeSD)
 		return left * right
+	case ModelEmpirical:
+		return curveWeight(s.cfg.Curve, s.peak, l)
+	case ModelLogNormal, ModelSkewNormal, ModelGamma, ModelMixture, ModelSPRI:
+		return math.Min(1, math.Exp(s.cfg.logDensity(l)-s.logPeak))
 	default:
 		return 0
 	}
@@ -152,6 +297,10 @@ type Stats struct {
 	Mean                 float64 `json:"mean,omitempty"`
 	SD                   float64 `json:"sd,omitempty"`
 	EdgeSD               float64 `json:"edge_sd,omitempty"`
+	Skew                 float64 `json:"skew,omitempty"`
+	Mean2                float64 `json:"mean2,omitempty"`
+	SD2                  float64 `json:"sd2,omitempty"`
+	Mix                  float64 `json:"mix,omitempty"`
 	RawFragmentsScored   int     `json:"raw_fragments_scored"`
 	RawBasesScored       int64   `json:"raw_bases_scored"`
 	RawFragmentsInWindow int     `json:"raw_fragments_in_window"`
@@ -159,23 +308,73 @@ type Stats struct {
 	WeightedFragments    float64 `json:"weighted_fragments"`
 	WeightedBases        float64 `json:"weighted_bases"`
 	MeanWeightedLength   float64 `json:"mean_weighted_length"`
+	// AmplifiedFragments and AmplifiedBases total the scored fragments
+	// weighted by size selection times PCR amplification; they are set only
+	// when amplification weighting is on.
+	AmplifiedFragments float64 `json:"amplified_fragments,omitempty"`
+	AmplifiedBases     float64 `json:"amplified_bases,omitempty"`
+	// PossibleFragments counts scored fragments with an end at a possible
+	// (reference ambiguity code) site. Their weights already include any
+	// ambiguity weight.
+	PossibleFragments int `json:"possible_fragments,omitempty"`
+	// Curve and CurvePath record the empirical curve verbatim.
+	Curve     []CurvePoint `json:"curve,omitempty"`
+	CurvePath string       `json:"curve_path,omitempty"`
+	// ConfigPath records the -size-config file, if any.
+	ConfigPath string `json:"config_path,omitempty"`
+	// SPRI fields record the bead ratios, the cutoffs they map to, and any
+	// calibration file.
+	SPRILeft            float64 `json:"spri_left,omitempty"`
+	SPRIRight           float64 `json:"spri_right,omitempty"`
+	SPRILowerCutoff     float64 `json:"spri_lower_cutoff,omitempty"`
+	SPRIUpperCutoff     float64 `json:"spri_upper_cutoff,omitempty"`
+	SPRICalibrationPath string  `json:"spri_calibration_path,omitempty"`
+	// AdapterP1, AdapterP2, and BarcodeLengths record library flanks; the
+	// length totals above stay in insert bases. With several barcode lengths
+	// the Raw counts include an insert that passes for any of them, while
+	// the weighted totals average its weight over all of them.
+	AdapterP1      int   `json:"adapter_p1_length,omitempty"`
+	AdapterP2      int   `json:"adapter_p2_length,omitempty"`
+	BarcodeLengths []int `json:"barcode_lengths,omitempty"`
 }
 
 func NewStats(s Selector) Stats {
 	cfg := s.Config()
 	st := Stats{
-		Model:    cfg.Model,
-		ScoreMin: cfg.ScoreMin,
-		ScoreMax: cfg.ScoreMax,
+		Model:          cfg.Model,
+		ScoreMin:       cfg.ScoreMin,
+		ScoreMax:       cfg.ScoreMax,
+		ConfigPath:     cfg.ConfigPath,
+		AdapterP1:      cfg.AdapterP1,
+		AdapterP2:      cfg.AdapterP2,
+		BarcodeLengths: cfg.BarcodeLengths,
 	}
 	switch cfg.Model {
-	case ModelNormal:
+	case ModelNormal, ModelLogNormal, ModelGamma:
 		st.Mean = cfg.Mean
 		st.SD = cfg.SD
+	case ModelSkewNormal:
+		st.Mean = cfg.Mean
+		st.SD = cfg.SD
+		st.Skew = cfg.Skew
+	case ModelMixture:
+		st.Mean = cfg.Mean
+		st.SD = cfg.SD
+		st.Mean2 = cfg.Mean2
+		st.SD2 = cfg.SD2
+		st.Mix = cfg.Mix
 	case ModelTriangular:
 		st.Mean = cfg.Mean
 	case ModelSoftWindow:
 		st.EdgeSD = cfg.EdgeSD
+	case ModelEmpirical:
+		st.Curve = cfg.Curve
+		st.CurvePath = cfg.CurvePath
+	case ModelSPRI:
+		st.SPRILeft = cfg.SPRILeft
+		st.SPRIRight = cfg.SPRIRight
+		st.SPRILowerCutoff, st.SPRIUpperCutoff = cfg.SPRICutoffs()
+		st.SPRICalibrationPath = cfg.SPRICalibrationPath
 	}
 	return st
 }
@@ -190,6 +389,18 @@ func (s *Stats) AddScored(length int, weight float64) {
 	}
 }
 
+// AddAmplified adds one scored fragment with its size-selection weight
+// already multiplied by its amplification weight.
+func (s *Stats) AddAmplified(length int, weight float64) {
+	s.AmplifiedFragments += weight
+	s.AmplifiedBases += float64(length) * weight
+}
+
+// AddPossible counts one scored fragment cut at a possible site.
+func (s *Stats) AddPossible() {
+	s.PossibleFragments++
+}
+
 func (s *Stats) AddHardKept(length int) {
 	s.RawFragmentsInWindow++
 	s.RawBasesInWindow += int64(length)
@@ -201,3 +412,21 @@ func (s *Stats) Add(length int, hardKept bool, weight float64) {
 		s.AddHardKept(length)
 	}
 }
+
+// ParseBarcodeLengths parses a comma-separated list of barcode lengths; an
+// empty string is no barcodes.
+func ParseBarcodeLengths(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	fields := strings.Split(s, ",")
+	out := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("barcode length %q must be an integer >= 0", strings.TrimSpace(f))
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -2,6 +2,9 @@ package sizeselect
 
 import (
 	"math"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -67,6 +70,176 @@ func TestSoftWindowWeight(t *testing.T) {
 	}
 }
 
+func TestSkewedShapesPeakAtOneAndLeanRight(t *testing.T) {
+	for _, cfg := range []Config{
+		{Model: ModelLogNormal, Mean: 350, SD: 100},
+		{Model: ModelGamma, Mean: 350, SD: 100},
+		{Model: ModelSkewNormal, Mean: 280, SD: 120, Skew: 4},
+	} {
+		cfg.Min, cfg.Max, cfg.ScoreMin, cfg.ScoreMax = 300, 600, 1, 2000
+		sel, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mode, top := 0, 0.0
+		for l := 1; l <= 2000; l++ {
+			if w := sel.Weight(l); w > top {
+				mode, top = l, w
+			}
+		}
+		if math.Abs(top-1) > 1e-4 {
+			t.Fatalf("%s peak weight = %g at %d, want 1", cfg.Model, top, mode)
+		}
+		if left, right := sel.Weight(mode-100), sel.Weight(mode+100); left >= right {
+			t.Fatalf("%s not right-skewed: weight(mode-100)=%g weight(mode+100)=%g", cfg.Model, left, right)
+		}
+		if sel.Weight(0) != 0 && cfg.Model != ModelSkewNormal {
+			t.Fatalf("%s weight at 0 = %g", cfg.Model, sel.Weight(0))
+		}
+	}
+}
+
+func TestMixtureWeightAndStats(t *testing.T) {
+	sel, err := New(Config{Model: ModelMixture, Min: 300, Max: 600, ScoreMin: 1, ScoreMax: 2000, Mean: 400, SD: 50, Mean2: 150, SD2: 25, Mix: 0.2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := sel.Weight(400); math.Abs(w-1) > 1e-3 {
+		t.Fatalf("main peak weight = %g, want about 1", w)
+	}
+	// The shoulder density is 0.2/25 against 0.8/50 at the main peak.
+	if w := sel.Weight(150); math.Abs(w-0.5) > 1e-3 {
+		t.Fatalf("shoulder weight = %g, want about 0.5", w)
+	}
+	if sel.Weight(275) >= sel.Weight(150) {
+		t.Fatalf("mixture should dip between components")
+	}
+	st := NewStats(sel)
+	if st.Mean != 400 || st.SD != 50 || st.Mean2 != 150 || st.SD2 != 25 || st.Mix != 0.2 {
+		t.Fatalf("mixture stats provenance wrong: %+v", st)
+	}
+}
+
+func TestAdapterFlanksShiftToLibraryLength(t *testing.T) {
+	sel, err := New(Config{Model: ModelNormal, Min: 400, Max: 500, ScoreMin: 300, ScoreMax: 700, Mean: 450, SD: 30, AdapterP1: 60, AdapterP2: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(sel.Weight(330)-1) > 1e-12 {
+		t.Fatalf("weight at insert 330 (library 450) = %g, want 1", sel.Weight(330))
+	}
+	if sel.InHardWindow(279) || !sel.InHardWindow(280) || !sel.InHardWindow(380) || sel.InHardWindow(381) {
+		t.Fatalf("hard window not shifted by 120 bp")
+	}
+	if lo, hi := sel.InsertRange(); lo != 180 || hi != 580 {
+		t.Fatalf("InsertRange() = %d-%d, want 180-580", lo, hi)
+	}
+
+	pooled, err := New(Config{Model: ModelHard, Min: 130, Max: 130, ScoreMin: 120, ScoreMax: 140, AdapterP1: 60, AdapterP2: 60, BarcodeLengths: []int{4, 8}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pooled.Weight(2); got != 0.5 {
+		t.Fatalf("pooled weight = %g, want 0.5 from one of two barcode lengths", got)
+	}
+	if !pooled.InHardWindow(6) || pooled.InHardWindow(7) {
+		t.Fatalf("pooled hard window should accept either barcode length")
+	}
+	if lo, hi := pooled.InsertRange(); lo != 1 || hi != 16 {
+		t.Fatalf("pooled InsertRange() = %d-%d, want 1-16", lo, hi)
+	}
+	if st := NewStats(pooled); st.AdapterP1 != 60 || !reflect.DeepEqual(st.BarcodeLengths, []int{4, 8}) {
+		t.Fatalf("stats flanks = %+v", st)
+	}
+}
+
+func TestSPRIWeightFollowsBeadRatios(t *testing.T) {
+	sel, err := New(Config{Model: ModelSPRI, Min: 300, Max: 650, ScoreMin: 1, ScoreMax: 2000, SPRILeft: 0.75, SPRIRight: 0.55})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lower, upper := sel.Config().SPRICutoffs()
+	if lower != 300 || upper != 650 {
+		t.Fatalf("cutoffs = %g-%g, want 300-650 from the built-in calibration", lower, upper)
+	}
+	if w := sel.Weight(442); math.Abs(w-1) > 1e-5 {
+		t.Fatalf("weight midway between cutoffs in log length = %g, want 1", w)
+	}
+	if w300, w650 := sel.Weight(300), sel.Weight(650); math.Abs(w300-0.5) > 0.02 || math.Abs(w650-0.5) > 0.02 {
+		t.Fatalf("weights at cutoffs = %g, %g, want about 0.5", w300, w650)
+	}
+	if sel.Weight(150) > 0.001 || sel.Weight(1300) > 0.001 {
+		t.Fatalf("weights far outside the cutoffs should be ~0")
+	}
+	st := NewStats(sel)
+	if st.SPRILeft != 0.75 || st.SPRIRight != 0.55 || st.SPRILowerCutoff != 300 || st.SPRIUpperCutoff != 650 {
+		t.Fatalf("stats = %+v", st)
+	}
+
+	path := filepath.Join(t.TempDir(), "spri.tsv")
+	if err := os.WriteFile(path, []byte("ratio\tcutoff\n0.5\t1000\n0.8\t400\n1.0\t200\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Config{Model: ModelSPRI, Min: 300, Max: 650, ScoreMin: 1, ScoreMax: 2000, SPRILeft: 0.9, SPRIRight: 0.5}.WithSPRICalibration(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lower, upper := custom.Config().SPRICutoffs(); math.Abs(lower-math.Sqrt(400*200)) > 1e-9 || upper != 1000 {
+		t.Fatalf("custom cutoffs = %g-%g, want log-interpolated %g-1000", lower, upper, math.Sqrt(400*200))
+	}
+	if NewStats(custom).SPRICalibrationPath != path {
+		t.Fatalf("calibration path not recorded")
+	}
+	if _, err := (Config{Model: ModelNormal}).WithSPRICalibration(path); err == nil {
+		t.Fatal("-spri-calibration without -size-model spri returned nil error")
+	}
+}
+
+func TestEmpiricalWeightInterpolatesCurve(t *testing.T) {
+	curve := []CurvePoint{{Length: 200, Weight: 0}, {Length: 300, Weight: 40}, {Length: 400, Weight: 20}}
+	sel, err := New(Config{Model: ModelEmpirical, Min: 200, Max: 400, ScoreMin: 1, ScoreMax: 500, Curve: curve})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for length, want := range map[int]float64{150: 0, 250: 0.5, 300: 1, 350: 0.75, 400: 0.5, 401: 0} {
+		if got := sel.Weight(length); math.Abs(got-want) > 1e-12 {
+			t.Fatalf("Weight(%d) = %g, want %g", length, got, want)
+		}
+	}
+	if st := NewStats(sel); len(st.Curve) != 3 || st.Curve[1].Weight != 40 {
+		t.Fatalf("stats curve = %+v, want the curve verbatim", st.Curve)
+	}
+}
+
+func TestReadCurveTSVAndJSON(t *testing.T) {
+	dir := t.TempDir()
+	tsv := filepath.Join(dir, "pippin.tsv")
+	if err := os.WriteFile(tsv, []byte("# BluePippin run 3\nlength\tweight\n250\t0.1\n300\t0.9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(dir, "gel.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"curve": [{"length": 250, "weight": 0.1}, {"length": 300, "weight": 0.9}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := []CurvePoint{{Length: 250, Weight: 0.1}, {Length: 300, Weight: 0.9}}
+	for _, path := range []string{tsv, jsonPath} {
+		cfg, err := Config{Model: ModelEmpirical}.WithCurve(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cfg.Curve, want) || cfg.CurvePath != path {
+			t.Fatalf("WithCurve(%s) = %+v", path, cfg)
+		}
+	}
+	if _, err := (Config{Model: ModelNormal}).WithCurve(tsv); err == nil {
+		t.Fatal("WithCurve accepted a curve for -size-model normal")
+	}
+}
+
 func TestInvalidConfigs(t *testing.T) {
 	bad := []Config{
 		{Model: "nope", Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10},
@@ -74,6 +247,18 @@ func TestInvalidConfigs(t *testing.T) {
 		{Model: ModelSoftWindow, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, EdgeSD: -1},
 		{Model: ModelTriangular, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, Mean: 10},
 		{Model: ModelHard, Min: 1, Max: 10, ScoreMin: 20, ScoreMax: 10},
+		{Model: ModelEmpirical, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, Curve: []CurvePoint{{Length: 5, Weight: 1}}},
+		{Model: ModelEmpirical, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, Curve: []CurvePoint{{Length: 5, Weight: 1}, {Length: 4, Weight: 1}}},
+		{Model: ModelLogNormal, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, Mean: 5, SD: 0},
+		{Model: ModelGamma, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, Mean: 5, SD: 5},
+		{Model: ModelSkewNormal, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, Mean: 5, SD: 2, Skew: math.Inf(1)},
+		{Model: ModelMixture, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, Mean: 5, SD: 2, Mean2: 8, SD2: 1, Mix: 1},
+		{Model: ModelMixture, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, Mean: 5, SD: 2, Mix: 0.5},
+		{Model: ModelSPRI, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, SPRILeft: 0.55, SPRIRight: 0.75},
+		{Model: ModelSPRI, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, SPRILeft: 2.5, SPRIRight: 0.55},
+		{Model: ModelSPRI, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, SPRILeft: 0.75, SPRIRight: 0.55, SPRICalibration: []SPRIPoint{{Ratio: 0.5, Cutoff: 300}, {Ratio: 0.8, Cutoff: 600}}},
+		{Model: ModelHard, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, AdapterP1: -1},
+		{Model: ModelHard, Min: 1, Max: 10, ScoreMin: 1, ScoreMax: 10, BarcodeLengths: []int{4, -1}},
 	}
 	for _, cfg := range bad {
 		if _, err := New(cfg); err == nil {
@@ -97,3 +282,40 @@ func TestStatsAdd(t *testing.T) {
 		t.Fatalf("weighted stats wrong: %+v", st)
 	}
 }
+
+func TestHistogramStatsMatchesDirectScoring(t *testing.T) {
+	h := NewHistogram(300)
+	lengths := []int{0, 50, 120, 120, 250, 300, 301, 900, 950, 40000}
+	for _, l := range lengths {
+		h.Add(l)
+	}
+	if h.Fragments() != len(lengths) || h.Counts[120] != 2 {
+		t.Fatalf("histogram counts wrong: %+v", h)
+	}
+	for _, b := range h.Coarse {
+		if b.Start <= h.FineMax || b.End <= b.Start {
+			t.Fatalf("bad coarse bin %+v", b)
+		}
+	}
+	if len(h.Coarse) != 4 || h.Coarse[0].Start != 301 || h.Coarse[3].Bases != 40000 {
+		t.Fatalf("coarse bins = %+v", h.Coarse)
+	}
+
+	sel, err := New(Config{Model: ModelNormal, Min: 100, Max: 280, ScoreMin: 1, ScoreMax: 300, SD: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewStats(sel)
+	for _, l := range lengths {
+		if sel.InScoreRange(l) {
+			want.Add(l, sel.InHardWindow(l), sel.Weight(l))
+		}
+	}
+	got := h.Stats(sel)
+	if got.RawFragmentsScored != want.RawFragmentsScored || got.RawBasesInWindow != want.RawBasesInWindow || got.Mean != want.Mean {
+		t.Fatalf("histogram stats %+v, want %+v", got, want)
+	}
+	if math.Abs(got.WeightedBases-want.WeightedBases) > 1e-9 || math.Abs(got.MeanWeightedLength-want.MeanWeightedLength) > 1e-9 {
+		t.Fatalf("weighted stats %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,214 @@
+// Package varsite predicts which restriction sites VCF alleles abolish or
+// create, and which digest fragments those alleles disrupt.
+//
+// Each sequence ALT allele is spliced into a reference window that reaches one
+// recognition-site length past either side of REF. Sites that overlap the
+// allele in the reference but not in the spliced window are abolished; sites
+// that appear only in the spliced window are created. A fragment is disrupted
+// by an allele that abolishes the cut at either of its ends, since the
+// fragment then merges with a neighbor, or that creates a cut strictly inside
+// it, since it then splits. Either way the reference locus drops out for
+// carriers of that allele.
+package varsite
+
+import (
+	"bytes"
+	"math"
+	"sort"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/vcf"
+)
+
+// Effect is one site abolished or created by one ALT allele.
+type Effect struct {
+	// Cut is the 0-based reference cut coordinate. For created sites inside
+	// an insertion it is clamped to the end of REF.
+	Cut     int
+	Enzyme  int  // plan enzyme index: 0 = A, 1 = B
+	Created bool // false when the allele abolishes a reference site
+	Variant int  // index into the chromosome's records
+	Allele  int  // 1-based ALT index
+	AF      float64
+}
+
+// Chromosome holds the allele effects on one chromosome, sorted by Cut.
+type Chromosome struct {
+	Effects       []Effect
+	Alleles       int // sequence ALT alleles evaluated
+	RefMismatches int // records whose REF disagrees with the reference
+}
+
+// Scan evaluates every sequence ALT allele in recs against seq. recs must be
+// the records of one chromosome; symbolic alleles and records whose REF does
+// not match seq are skipped.
+func Scan(plan digest.Plan, seq []byte, recs []vcf.Record) Chromosome {
+	var out Chromosome
+	span := 0
+	for i := 0; i < plan.Enzymes(); i++ {
+		if n, _ := plan.Site(i); n > span {
+			span = n
+		}
+	}
+	if span == 0 {
+		return out
+	}
+	for vi, rec := range recs {
+		start, end := rec.Start(), rec.End()
+		if end > len(seq) || !bytes.Equal(seq[start:end], []byte(rec.Ref)) {
+			out.RefMismatches++
+			continue
+		}
+		lo := max(0, start-(span-1))
+		hi := min(len(seq), end+(span-1))
+		for ai, alt := range rec.Alt {
+			if !vcf.IsSequence(alt) || alt == rec.Ref {
+				continue
+			}
+			out.Alleles++
+			window := make([]byte, 0, hi-lo-len(rec.Ref)+len(alt))
+			window = append(window, seq[lo:start]...)
+			window = append(window, alt...)
+			window = append(window, seq[end:hi]...)
+			for e := 0; e < plan.Enzymes(); e++ {
+				n, offset := plan.Site(e)
+				// Site starts relative to lo, mapped to reference coordinates.
+				refSites := overlapping(plan, e, seq[lo:hi], start-lo, end-lo, n, offset, func(s int) int { return s })
+				altSites := overlapping(plan, e, window, start-lo, start-lo+len(alt), n, offset, func(s int) int {
+					return mapToRef(s, start-lo, len(rec.Ref), len(alt))
+				})
+				for s := range refSites {
+					if !altSites[s] {
+						out.Effects = append(out.Effects, Effect{Cut: lo + s + offset, Enzyme: e, Variant: vi, Allele: ai + 1, AF: rec.AF[ai]})
+					}
+				}
+				for s := range altSites {
+					if !refSites[s] {
+						out.Effects = append(out.Effects, Effect{Cut: lo + s + offset, Enzyme: e, Created: true, Variant: vi, Allele: ai + 1, AF: rec.AF[ai]})
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(out.Effects, func(i, j int) bool {
+		a, b := out.Effects[i], out.Effects[j]
+		if a.Cut != b.Cut {
+			return a.Cut < b.Cut
+		}
+		if a.Variant != b.Variant {
+			return a.Variant < b.Variant
+		}
+		if a.Allele != b.Allele {
+			return a.Allele < b.Allele
+		}
+		return a.Enzyme < b.Enzyme
+	})
+	return out
+}
+
+// overlapping returns the site starts of enzyme e in window that overlap
+// [from, to), keyed by mapStart(start).
+func overlapping(plan digest.Plan, e int, window []byte, from, to, n, offset int, mapStart func(int) int) map[int]bool {
+	sites := make(map[int]bool)
+	_ = plan.EnzymeCutsEach(e, window, func(cut int) error {
+		if s := cut - offset; s < to && s+n > from {
+			sites[mapStart(s)] = true
+		}
+		return nil
+	})
+	return sites
+}
+
+// mapToRef maps a window offset in the ALT-spliced window back to the
+// reference window, given the allele offset and the REF and ALT lengths.
+func mapToRef(s, at, refLen, altLen int) int {
+	switch {
+	case s <= at:
+		return s
+	case s >= at+altLen:
+		return s - altLen + refLen
+	default:
+		return at + min(s-at, refLen)
+	}
+}
+
+// Fragment summarizes the alleles that disrupt one fragment.
+type Fragment struct {
+	// AFs lists the frequency of each distinct disrupting allele in effect
+	// order; NaN marks an allele without a known frequency.
+	AFs []float64
+	// Risk is the chance that a haplotype carries at least one disrupting
+	// allele, treating alleles as independent and ignoring unknown
+	// frequencies.
+	Risk float64
+}
+
+// Alleles returns the number of distinct disrupting alleles.
+func (f Fragment) Alleles() int {
+	return len(f.AFs)
+}
+
+// Fragment returns the alleles that abolish a cut at either end of fr or
+// create a cut strictly inside it.
+func (c Chromosome) Fragment(fr digest.Fragment) Fragment {
+	var out Fragment
+	type allele struct{ variant, allele int }
+	seen := make(map[allele]bool)
+	keep := 1.0
+	i := sort.Search(len(c.Effects), func(i int) bool { return c.Effects[i].Cut >= fr.Start })
+	for ; i < len(c.Effects) && c.Effects[i].Cut <= fr.End; i++ {
+		e := c.Effects[i]
+		atEnd := e.Cut == fr.Start || e.Cut == fr.End
+		if e.Created == atEnd {
+			continue
+		}
+		key := allele{e.Variant, e.Allele}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out.AFs = append(out.AFs, e.AF)
+		if !math.IsNaN(e.AF) {
+			keep *= 1 - math.Min(1, math.Max(0, e.AF))
+		}
+	}
+	out.Risk = 1 - keep
+	return out
+}
+
+// Summary totals allele effects across a run.
+type Summary struct {
+	Variants       int `json:"variants"`
+	Alleles        int `json:"alleles"`
+	RefMismatches  int `json:"ref_mismatches"`
+	SitesAbolished int `json:"sites_abolished"`
+	SitesCreated   int `json:"sites_created"`
+	// PolymorphicFragments counts score-range fragments with at least one
+	// disrupting allele; the weighted fields scale each by its size weight.
+	PolymorphicFragments         int     `json:"polymorphic_fragments"`
+	WeightedPolymorphicFragments float64 `json:"weighted_polymorphic_fragments"`
+	ExpectedDropout              float64 `json:"expected_dropout"`
+}
+
+// AddChromosome adds the per-allele totals of c.
+func (s *Summary) AddChromosome(c Chromosome) {
+	s.Alleles += c.Alleles
+	s.RefMismatches += c.RefMismatches
+	for _, e := range c.Effects {
+		if e.Created {
+			s.SitesCreated++
+		} else {
+			s.SitesAbolished++
+		}
+	}
+}
+
+// AddFragment adds one score-range fragment with the given size weight.
+func (s *Summary) AddFragment(f Fragment, weight float64) {
+	if f.Alleles() == 0 {
+		return
+	}
+	s.PolymorphicFragments++
+	s.WeightedPolymorphicFragments += weight
+	s.ExpectedDropout += weight * f.Risk
+}
@@ -0,0 +1,85 @@
+package varsite
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ericksamera/radigest/internal/digest"
+	"github.com/ericksamera/radigest/internal/enzyme"
+	"github.com/ericksamera/radigest/internal/vcf"
+)
+
+func ecoRIPlan(t *testing.T) digest.Plan {
+	t.Helper()
+	e, ok := enzyme.Get("EcoRI")
+	if !ok {
+		t.Fatal("missing EcoRI")
+	}
+	plan, err := digest.TryNewPlanWithOptions([]enzyme.Enzyme{e}, digest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestScanFindsAbolishedAndCreatedSites(t *testing.T) {
+	// EcoRI cuts at 5, 25, and 45; GAATTA at 30 is one base from a site.
+	seq := []byte("CCCCGAATTCCCCCCCCCCCCCCCGAATTCGAATTACCCCCCCCGAATTCCCCC")
+	recs := []vcf.Record{
+		{Chrom: "chr1", Pos: 27, Ref: "A", Alt: []string{"C"}, AF: []float64{0.1}},              // abolishes the cut at 25
+		{Chrom: "chr1", Pos: 36, Ref: "A", Alt: []string{"C"}, AF: []float64{0.3}},              // creates a cut at 31
+		{Chrom: "chr1", Pos: 40, Ref: "C", Alt: []string{"<DEL>"}, AF: []float64{1}},            // symbolic: skipped
+		{Chrom: "chr1", Pos: 41, Ref: "G", Alt: []string{"T"}, AF: []float64{0.5}},              // REF mismatch
+		{Chrom: "chr1", Pos: 44, Ref: "CGAATTC", Alt: []string{"C"}, AF: []float64{math.NaN()}}, // deletes the cut at 45, AF unknown
+	}
+	plan := ecoRIPlan(t)
+	chr := Scan(plan, seq, recs)
+	if chr.Alleles != 3 || chr.RefMismatches != 1 {
+		t.Fatalf("alleles=%d mismatches=%d, want 3 and 1", chr.Alleles, chr.RefMismatches)
+	}
+	want := []Effect{
+		{Cut: 25, Variant: 0, Allele: 1, AF: 0.1},
+		{Cut: 31, Created: true, Variant: 1, Allele: 1, AF: 0.3},
+	}
+	if len(chr.Effects) != 3 {
+		t.Fatalf("effects = %+v", chr.Effects)
+	}
+	for i, w := range want {
+		if chr.Effects[i] != w {
+			t.Fatalf("effect %d = %+v, want %+v", i, chr.Effects[i], w)
+		}
+	}
+	if e := chr.Effects[2]; e.Cut != 45 || e.Created || e.Variant != 4 {
+		t.Fatalf("deletion effect = %+v", e)
+	}
+
+	frags := plan.Digest(seq, 1, len(seq))
+	if len(frags) != 2 || frags[0] != (digest.Fragment{Start: 5, End: 25}) {
+		t.Fatalf("unexpected digest %v", frags)
+	}
+	left := chr.Fragment(frags[0])
+	if left.Alleles() != 1 || math.Abs(left.Risk-0.1) > 1e-12 {
+		t.Fatalf("left fragment = %+v", left)
+	}
+	right := chr.Fragment(frags[1])
+	if right.Alleles() != 3 || !math.IsNaN(right.AFs[2]) || math.Abs(right.Risk-(1-0.9*0.7)) > 1e-12 {
+		t.Fatalf("right fragment = %+v", right)
+	}
+
+	var sum Summary
+	sum.AddChromosome(chr)
+	sum.AddFragment(left, 1)
+	sum.AddFragment(right, 0.5)
+	if sum.SitesAbolished != 2 || sum.SitesCreated != 1 || sum.PolymorphicFragments != 2 || sum.WeightedPolymorphicFragments != 1.5 {
+		t.Fatalf("summary = %+v", sum)
+	}
+}
+
+func TestScanIgnoresSNPsOutsideSites(t *testing.T) {
+	seq := []byte(strings.Repeat("C", 10) + "GAATTC" + strings.Repeat("C", 10))
+	chr := Scan(ecoRIPlan(t), seq, []vcf.Record{{Pos: 3, Ref: "C", Alt: []string{"A"}, AF: []float64{0.5}}})
+	if len(chr.Effects) != 0 {
+		t.Fatalf("unexpected effects %+v", chr.Effects)
+	}
+}
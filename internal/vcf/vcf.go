@@ -0,0 +1,342 @@
+// Package vcf reads the small subset of VCF needed to apply SNPs and short
+// indels to a reference: CHROM, POS, ID, REF, ALT, the INFO AF field, and
+// sample GT calls. Plain and gzip/bgzip-compressed files are accepted.
+package vcf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Record is one VCF data line. Pos is 1-based as in the file.
+type Record struct {
+	Chrom string
+	Pos   int
+	ID    string
+	Ref   string
+	Alt   []string
+	// AF holds one frequency per ALT allele: INFO AF when present, otherwise
+	// the ALT fraction of called sample alleles, otherwise NaN.
+	AF []float64
+	// Calls holds one GT call per sample, in Set.Samples order.
+	Calls []Call
+}
+
+// Call is one sample's GT call. Alleles are indices into REF (0) and ALT
+// (1-based), with -1 for a missing allele.
+type Call struct {
+	Alleles []int
+	Phased  bool
+}
+
+// Allele returns the allele carried on haplotype h. A haploid call is
+// treated as homozygous, and a missing allele or haplotype as REF.
+func (c Call) Allele(h int) int {
+	if len(c.Alleles) == 0 {
+		return 0
+	}
+	if h >= len(c.Alleles) {
+		h = 0
+	}
+	if c.Alleles[h] < 0 {
+		return 0
+	}
+	return c.Alleles[h]
+}
+
+// Heterozygous reports whether the call carries two different alleles.
+func (c Call) Heterozygous() bool {
+	for _, a := range c.Alleles[min(1, len(c.Alleles)):] {
+		if a != c.Alleles[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// Start returns the 0-based reference offset of the first REF base.
+func (r Record) Start() int {
+	return r.Pos - 1
+}
+
+// End returns the 0-based exclusive end of the REF allele.
+func (r Record) End() int {
+	return r.Pos - 1 + len(r.Ref)
+}
+
+// IsSequence reports whether allele is a literal A/C/G/T/N allele rather than
+// a symbolic (<DEL>), spanning-deletion (*), missing (.), or breakend allele.
+func IsSequence(allele string) bool {
+	if allele == "" {
+		return false
+	}
+	for i := 0; i < len(allele); i++ {
+		switch allele[i] {
+		case 'A', 'C', 'G', 'T', 'N':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// IsSNP reports whether the record has a single-base REF and at least one
+// single-base ALT allele.
+func (r Record) IsSNP() bool {
+	if len(r.Ref) != 1 {
+		return false
+	}
+	for _, alt := range r.Alt {
+		if len(alt) == 1 && IsSequence(alt) && alt != r.Ref {
+			return true
+		}
+	}
+	return false
+}
+
+// Set holds the records of one VCF grouped by chromosome and sorted by
+// position.
+type Set struct {
+	Samples []string
+	byChrom map[string][]Record
+	records int
+}
+
+// Records returns the records on chrom in position order.
+func (s *Set) Records(chrom string) []Record {
+	if s == nil {
+		return nil
+	}
+	return s.byChrom[chrom]
+}
+
+// Len returns the number of records read.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return s.records
+}
+
+// SNPPositions returns the sorted, distinct 0-based positions of SNP
+// records on each chromosome.
+func (s *Set) SNPPositions() map[string][]int {
+	out := make(map[string][]int)
+	if s == nil {
+		return out
+	}
+	for chrom, recs := range s.byChrom {
+		var pos []int
+		for _, rec := range recs {
+			if !rec.IsSNP() {
+				continue
+			}
+			if p := rec.Start(); len(pos) == 0 || pos[len(pos)-1] != p {
+				pos = append(pos, p)
+			}
+		}
+		if len(pos) > 0 {
+			out[chrom] = pos
+		}
+	}
+	return out
+}
+
+// Read loads every record of the VCF at path.
+func Read(path string) (*Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("vcf %s: %w", path, err)
+		}
+		defer gz.Close()
+		return Parse(gz)
+	}
+	return Parse(br)
+}
+
+// Parse reads VCF text from r.
+func Parse(r io.Reader) (*Set, error) {
+	set := &Set{byChrom: make(map[string][]Record)}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	lineNo := 0
+	sawHeader := false
+	for sc.Scan() {
+		lineNo++
+		line := bytes.TrimRight(sc.Bytes(), "\r")
+		if len(line) == 0 || bytes.HasPrefix(line, []byte("##")) {
+			continue
+		}
+		if line[0] == '#' {
+			cols := strings.Split(string(line[1:]), "\t")
+			if len(cols) < 8 || cols[0] != "CHROM" {
+				return nil, fmt.Errorf("vcf: malformed header at line %d", lineNo)
+			}
+			if len(cols) > 9 {
+				set.Samples = append([]string(nil), cols[9:]...)
+			}
+			sawHeader = true
+			continue
+		}
+		if !sawHeader {
+			return nil, fmt.Errorf("vcf: data before #CHROM header at line %d", lineNo)
+		}
+		rec, err := parseRecord(string(line), len(set.Samples))
+		if err != nil {
+			return nil, fmt.Errorf("vcf: line %d: %w", lineNo, err)
+		}
+		set.byChrom[rec.Chrom] = append(set.byChrom[rec.Chrom], rec)
+		set.records++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("vcf: %w", err)
+	}
+	if !sawHeader {
+		return nil, fmt.Errorf("vcf: missing #CHROM header")
+	}
+	for _, recs := range set.byChrom {
+		sort.SliceStable(recs, func(i, j int) bool { return recs[i].Pos < recs[j].Pos })
+	}
+	return set, nil
+}
+
+func parseRecord(line string, samples int) (Record, error) {
+	cols := strings.Split(line, "\t")
+	if len(cols) < 8 {
+		return Record{}, fmt.Errorf("expected at least 8 columns, got %d", len(cols))
+	}
+	pos, err := strconv.Atoi(cols[1])
+	if err != nil || pos < 1 {
+		return Record{}, fmt.Errorf("invalid POS %q", cols[1])
+	}
+	rec := Record{
+		Chrom: cols[0],
+		Pos:   pos,
+		ID:    cols[2],
+		Ref:   strings.ToUpper(cols[3]),
+	}
+	if !IsSequence(rec.Ref) {
+		return Record{}, fmt.Errorf("invalid REF %q", cols[3])
+	}
+	if cols[4] != "." {
+		rec.Alt = strings.Split(strings.ToUpper(cols[4]), ",")
+	}
+	rec.AF = infoAF(cols[7], len(rec.Alt))
+	if samples > 0 && len(cols) > 9 {
+		rec.Calls = sampleCalls(cols[8], cols[9:], samples)
+		if anyNaN(rec.AF) {
+			counts, called := alleleCounts(rec.Calls, len(rec.Alt))
+			for i := range rec.AF {
+				if math.IsNaN(rec.AF[i]) && called > 0 {
+					rec.AF[i] = float64(counts[i+1]) / float64(called)
+				}
+			}
+		}
+	}
+	return rec, nil
+}
+
+func infoAF(info string, alts int) []float64 {
+	af := make([]float64, alts)
+	for i := range af {
+		af[i] = math.NaN()
+	}
+	for _, field := range strings.Split(info, ";") {
+		value, ok := strings.CutPrefix(field, "AF=")
+		if !ok {
+			continue
+		}
+		for i, v := range strings.Split(value, ",") {
+			if i >= alts {
+				break
+			}
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				af[i] = f
+			}
+		}
+	}
+	return af
+}
+
+func anyNaN(xs []float64) bool {
+	for _, x := range xs {
+		if math.IsNaN(x) {
+			return true
+		}
+	}
+	return false
+}
+
+func sampleCalls(format string, fields []string, samples int) []Call {
+	calls := make([]Call, samples)
+	gt := fieldIndex(format, "GT")
+	if gt < 0 {
+		return calls
+	}
+	for i := 0; i < samples && i < len(fields); i++ {
+		calls[i] = ParseCall(sampleField(fields[i], gt))
+	}
+	return calls
+}
+
+// alleleCounts tallies called alleles; counts[0] is REF.
+func alleleCounts(calls []Call, alts int) ([]int, int) {
+	counts := make([]int, alts+1)
+	called := 0
+	for _, call := range calls {
+		for _, allele := range call.Alleles {
+			if allele >= 0 && allele <= alts {
+				counts[allele]++
+				called++
+			}
+		}
+	}
+	return counts, called
+}
+
+func fieldIndex(format, key string) int {
+	for i, f := range strings.Split(format, ":") {
+		if f == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func sampleField(sample string, i int) string {
+	fields := strings.Split(sample, ":")
+	if i < len(fields) {
+		return fields[i]
+	}
+	return "."
+}
+
+// ParseCall parses a GT value such as "0|1" or "1/1". A call is phased when
+// every separator is '|'; haploid calls count as phased.
+func ParseCall(gt string) Call {
+	parts := strings.FieldsFunc(gt, func(r rune) bool { return r == '|' || r == '/' })
+	call := Call{Alleles: make([]int, len(parts)), Phased: !strings.Contains(gt, "/")}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			n = -1
+		}
+		call.Alleles[i] = n
+	}
+	return call
+}
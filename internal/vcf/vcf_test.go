@@ -0,0 +1,79 @@
+package vcf
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const sample = `##fileformat=VCFv4.2
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	s1	s2
+chr1	12	rs2	a	G,<DEL>	.	PASS	DP=10	GT	0|1	1|1
+chr1	5	rs1	G	T	.	PASS	AF=0.25	GT	0|0	0|1
+chr2	3	.	CT	C	.	PASS	.	GT	./.	./.
+`
+
+func TestParse(t *testing.T) {
+	set, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 3 || len(set.Samples) != 2 || set.Samples[1] != "s2" {
+		t.Fatalf("unexpected set: len=%d samples=%v", set.Len(), set.Samples)
+	}
+	chr1 := set.Records("chr1")
+	if len(chr1) != 2 || chr1[0].ID != "rs1" || chr1[1].Ref != "A" {
+		t.Fatalf("chr1 records not sorted or normalized: %+v", chr1)
+	}
+	if chr1[0].AF[0] != 0.25 {
+		t.Fatalf("INFO AF should win: %v", chr1[0].AF)
+	}
+	if chr1[1].AF[0] != 0.75 || chr1[1].AF[1] != 0 {
+		t.Fatalf("AF from genotypes = %v, want [0.75 0]", chr1[1].AF)
+	}
+	if IsSequence(chr1[1].Alt[1]) {
+		t.Fatal("symbolic allele reported as sequence")
+	}
+	chr2 := set.Records("chr2")
+	if len(chr2) != 1 || chr2[0].Start() != 2 || chr2[0].End() != 4 || !math.IsNaN(chr2[0].AF[0]) {
+		t.Fatalf("unexpected chr2 record: %+v", chr2)
+	}
+}
+
+func TestSNPPositions(t *testing.T) {
+	set, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := set.SNPPositions()
+	if len(got) != 1 || len(got["chr1"]) != 2 || got["chr1"][0] != 4 || got["chr1"][1] != 11 {
+		t.Fatalf("SNPPositions = %v, want chr1:[4 11] and no indel-only chr2", got)
+	}
+}
+
+func TestParseRejectsMalformedRecords(t *testing.T) {
+	for _, text := range []string{
+		"chr1\t1\t.\tA\tG\t.\t.\t.\n",
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\nchr1\t0\t.\tA\tG\t.\t.\t.\n",
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\nchr1\t1\t.\tA\n",
+	} {
+		if _, err := Parse(strings.NewReader(text)); err == nil {
+			t.Fatalf("expected error for %q", text)
+		}
+	}
+}
+
+func TestParseCall(t *testing.T) {
+	c := ParseCall("0|2")
+	if !c.Phased || c.Allele(0) != 0 || c.Allele(1) != 2 || !c.Heterozygous() {
+		t.Fatalf("0|2 parsed as %+v", c)
+	}
+	c = ParseCall("1/.")
+	if c.Phased || c.Allele(0) != 1 || c.Allele(1) != 0 {
+		t.Fatalf("1/. parsed as %+v", c)
+	}
+	c = ParseCall("1")
+	if !c.Phased || c.Allele(1) != 1 || c.Heterozygous() {
+		t.Fatalf("haploid 1 parsed as %+v", c)
+	}
+}